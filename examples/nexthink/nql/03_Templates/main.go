@@ -42,10 +42,10 @@ func main() {
 	fmt.Println("1. Available Templates")
 	fmt.Println("----------------------")
 
-	allTemplates := templates.GetAllTemplates()
+	allTemplates := nql.DefaultTemplateRegistry().List()
 	fmt.Printf("Found %d pre-built templates:\n", len(allTemplates))
-	for i, name := range allTemplates[:5] {
-		fmt.Printf("  %d. %s\n", i+1, name)
+	for i, info := range allTemplates[:5] {
+		fmt.Printf("  %d. %s - %s\n", i+1, info.Name, info.Description)
 	}
 	fmt.Printf("  ... and %d more\n\n", len(allTemplates)-5)
 
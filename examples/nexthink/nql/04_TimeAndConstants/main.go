@@ -74,7 +74,27 @@ func main() {
 	time4 := nql.NewTimeSelection().
 		On("Feb 8, 2024").
 		Build()
-	fmt.Printf("Specific date: %s\n\n", time4)
+	fmt.Printf("Specific date: %s\n", time4)
+
+	// Local day of a Sydney team, rendered for a UTC instance
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	time5 := nql.NewTimeSelection().
+		In(time.UTC).
+		OnTime(time.Date(2024, 2, 8, 0, 0, 0, 0, sydney)).
+		Build()
+	fmt.Printf("Sydney day:    %s\n", time5)
+
+	// Window aligned to whole 15 minute buckets, and its resolved bounds
+	time6 := nql.NewTimeSelection().
+		Between(time.Now().Add(-6*time.Hour), time.Now()).
+		AlignTo(nql.Granularity15Min)
+	if window, err := time6.Window(time.Now()); err == nil {
+		fmt.Printf("Aligned range: %s (%s)\n", time6, window)
+	}
+	if err := time6.Validate(time.Now()); err != nil {
+		fmt.Printf("Invalid selection: %v\n", err)
+	}
+	fmt.Println()
 
 	// =========================================================================
 	// Data Model Constants - Tables
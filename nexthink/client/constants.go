@@ -18,6 +18,10 @@ const (
 	// Increased to 300 seconds (5 minutes) to handle large NQL export operations
 	DefaultTimeout = 300
 
+	// DefaultDownloadTimeout is the default timeout for pre-signed URL downloads in seconds
+	// Used when it exceeds the API timeout, so large export downloads are not cut short
+	DefaultDownloadTimeout = 300
+
 	// MaxRetries is the maximum number of retries for failed requests
 	MaxRetries = 3
 
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+)
+
+var _ interfaces.DownloadClient = (*Transport)(nil)
+
+// GetDownloadClient returns an unauthenticated HTTP client for pre-signed URL downloads
+// such as NQL export files on S3.
+//
+// The client shares the underlying transport of the API client, so proxy settings
+// (including those detected from the environment), TLS configuration, custom
+// transports and OpenTelemetry tracing all apply. Requests bypass the resty
+// middleware, so the bearer token and global headers are never sent; any
+// Authorization header set on a download request is removed.
+//
+// The timeout is set with WithDownloadTimeout, defaulting to the larger of the
+// API timeout and DefaultDownloadTimeout.
+func (t *Transport) GetDownloadClient() *http.Client {
+	base := t.client.Transport()
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	timeout := t.downloadTimeout
+	if timeout <= 0 {
+		timeout = max(t.client.Timeout(), DefaultDownloadTimeout*time.Second)
+	}
+
+	return &http.Client{
+		Transport: &downloadTransport{
+			base:      base,
+			userAgent: t.userAgent,
+		},
+		Timeout: timeout,
+	}
+}
+
+// GetRetryPolicy returns the retry settings configured on the client
+func (t *Transport) GetRetryPolicy() interfaces.RetryPolicy {
+	return interfaces.RetryPolicy{
+		MaxRetries:  t.client.RetryCount(),
+		WaitTime:    t.client.RetryWaitTime(),
+		MaxWaitTime: t.client.RetryMaxWaitTime(),
+	}
+}
+
+// downloadTransport strips credentials from download requests and sets the SDK user agent
+type downloadTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper
+func (dt *downloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.Header.Del("Authorization")
+	if clone.Header.Get("User-Agent") == "" && dt.userAgent != "" {
+		clone.Header.Set("User-Agent", dt.userAgent)
+	}
+	return dt.base.RoundTrip(clone)
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"go.uber.org/zap/zaptest"
+)
+
+// newDownloadTestTransport creates a transport backed by httpmock
+func newDownloadTestTransport(t *testing.T, options ...ClientOption) *Transport {
+	t.Helper()
+
+	httpClient := &http.Client{}
+	httpmock.ActivateNonDefault(httpClient)
+	t.Cleanup(func() {
+		httpmock.DeactivateAndReset()
+	})
+
+	httpmock.RegisterResponder("POST", "https://test-instance-login.us.nexthink.cloud/oauth2/default/v1/token",
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{
+			"access_token": "test-token",
+			"expires_in":   900,
+			"token_type":   "Bearer",
+			"scope":        "service:integration",
+		}))
+
+	options = append([]ClientOption{
+		WithLogger(zaptest.NewLogger(t)),
+		WithTransport(httpClient.Transport),
+	}, options...)
+
+	transport, err := NewTransport("test-id", "test-secret", "test-instance", RegionUS, options...)
+	if err != nil {
+		t.Fatalf("NewTransport() error = %v", err)
+	}
+
+	return transport
+}
+
+func TestTransport_GetDownloadClient_SharesTransportWithoutToken(t *testing.T) {
+	transport := newDownloadTestTransport(t)
+
+	const downloadURL = "https://s3.amazonaws.com/nexthink-exports/export.csv"
+	var gotAuth, gotUserAgent string
+	httpmock.RegisterResponder("GET", downloadURL, func(req *http.Request) (*http.Response, error) {
+		gotAuth = req.Header.Get("Authorization")
+		gotUserAgent = req.Header.Get("User-Agent")
+		return httpmock.NewStringResponse(200, "device.name\n"), nil
+	})
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, downloadURL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer should-be-removed")
+
+	resp, err := transport.GetDownloadClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if httpmock.GetCallCountInfo()["GET "+downloadURL] != 1 {
+		t.Error("download did not go through the configured transport")
+	}
+
+	if gotAuth != "" {
+		t.Errorf("Authorization = %q, want empty", gotAuth)
+	}
+
+	if gotUserAgent != transport.userAgent {
+		t.Errorf("User-Agent = %q, want %q", gotUserAgent, transport.userAgent)
+	}
+}
+
+func TestTransport_GetDownloadClient_Timeout(t *testing.T) {
+	transport := newDownloadTestTransport(t)
+	if got := transport.GetDownloadClient().Timeout; got != DefaultDownloadTimeout*time.Second {
+		t.Errorf("Timeout = %v, want %v", got, DefaultDownloadTimeout*time.Second)
+	}
+
+	httpmock.DeactivateAndReset()
+	transport = newDownloadTestTransport(t, WithDownloadTimeout(30*time.Minute))
+	if got := transport.GetDownloadClient().Timeout; got != 30*time.Minute {
+		t.Errorf("Timeout = %v, want %v", got, 30*time.Minute)
+	}
+}
+
+func TestWithDownloadTimeout_Invalid(t *testing.T) {
+	transport := &Transport{logger: zaptest.NewLogger(t)}
+	if err := WithDownloadTimeout(0)(transport); err == nil {
+		t.Error("WithDownloadTimeout(0) error = nil, want error")
+	}
+}
+
+func TestTransport_GetRetryPolicy(t *testing.T) {
+	transport := newDownloadTestTransport(t,
+		WithRetryCount(5),
+		WithRetryWaitTime(time.Second),
+		WithRetryMaxWaitTime(20*time.Second),
+	)
+
+	policy := transport.GetRetryPolicy()
+	if policy.MaxRetries != 5 || policy.WaitTime != time.Second || policy.MaxWaitTime != 20*time.Second {
+		t.Errorf("GetRetryPolicy() = %+v, want {5 1s 20s}", policy)
+	}
+}
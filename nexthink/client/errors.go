@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +43,8 @@ type APIError struct {
 	Method     string // HTTP method used
 }
 
+var _ interfaces.StatusError = (*APIError)(nil)
+
 // genericErrorResponse represents a generic API error response wrapper
 type genericErrorResponse struct {
 	Error   *APIError `json:"error,omitempty"`
@@ -59,6 +62,11 @@ func (e *APIError) Error() string {
 		e.StatusCode, e.Status, e.Method, e.Endpoint, e.Message)
 }
 
+// HTTPStatus returns the HTTP status code of the error
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
 // ParseErrorResponse parses an error response from the API
 func ParseErrorResponse(body []byte, statusCode int, status, method, endpoint string, logger *zap.Logger) error {
 	apiError := &APIError{
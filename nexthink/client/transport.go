@@ -22,6 +22,9 @@ type Transport struct {
 	BaseURL       string
 	globalHeaders map[string]string
 	userAgent     string
+
+	// downloadTimeout overrides the timeout for pre-signed URL downloads
+	downloadTimeout time.Duration
 }
 
 // NewTransport creates a new Nexthink API transport.
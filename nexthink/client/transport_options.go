@@ -56,6 +56,19 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithDownloadTimeout sets the timeout for pre-signed URL downloads (e.g. NQL export files)
+// Defaults to the larger of the API timeout and DefaultDownloadTimeout
+func WithDownloadTimeout(timeout time.Duration) ClientOption {
+	return func(t *Transport) error {
+		if timeout <= 0 {
+			return fmt.Errorf("download timeout must be positive, got %v", timeout)
+		}
+		t.downloadTimeout = timeout
+		t.logger.Info("Download timeout configured", zap.Duration("timeout", timeout))
+		return nil
+	}
+}
+
 // WithRetryCount sets the number of retries for failed requests
 func WithRetryCount(count int) ClientOption {
 	return func(t *Transport) error {
@@ -21,6 +21,21 @@ type Response struct {
 	Size       int64         // Response body size in bytes
 }
 
+// RetryPolicy describes the retry behaviour configured on the client
+// Services use it to retry requests made outside the API client, such as pre-signed URL downloads
+type RetryPolicy struct {
+	MaxRetries  int           // number of retries after the first attempt
+	WaitTime    time.Duration // wait before the first retry
+	MaxWaitTime time.Duration // maximum wait between retries
+}
+
+// StatusError is implemented by API errors that carry the HTTP status code
+// Services match it with errors.As instead of importing the client package
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
 // MultipartProgressCallback is a callback function for multipart upload progress
 type MultipartProgressCallback func(fieldName string, fileName string, bytesWritten int64, totalBytes int64)
 
@@ -146,6 +161,19 @@ type HTTPClient interface {
 	GetLogger() *zap.Logger
 }
 
+// DownloadClient is optionally implemented by an HTTPClient that supports
+// pre-signed URL downloads. Services check for it with a type assertion and
+// fall back to http.DefaultClient and a default retry policy otherwise.
+type DownloadClient interface {
+	// GetDownloadClient returns an unauthenticated HTTP client for pre-signed URL downloads.
+	// It shares the configured transport (proxy, TLS, custom transport, tracing) but never
+	// sends the bearer token or global headers.
+	GetDownloadClient() *http.Client
+
+	// GetRetryPolicy returns the retry settings configured on the client.
+	GetRetryPolicy() RetryPolicy
+}
+
 // ServiceQueryBuilder defines the query builder contract for services.
 // Provides a fluent interface for constructing URL query parameters.
 type ServiceQueryBuilder interface {
@@ -0,0 +1,659 @@
+package nql
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result set analytics provide group-by, aggregation, joins, distinct, sorting
+// and pivots over result sets and export row iterators. Every operation returns
+// a new V2ResultSet and leaves its input unchanged.
+
+// =============================================================================
+// Row Sources
+// =============================================================================
+
+// All returns an iterator over the rows of the result set
+func (rs *V2ResultSet) All() iter.Seq2[int, map[string]any] {
+	return func(yield func(int, map[string]any) bool) {
+		for i, row := range rs.data {
+			if !yield(i, row) {
+				return
+			}
+		}
+	}
+}
+
+// CollectRows builds a result set from a row iterator such as ExportReader.All()
+func CollectRows(rows iter.Seq2[int, map[string]any]) *V2ResultSet {
+	var data []map[string]any
+	for _, row := range rows {
+		data = append(data, row)
+	}
+	return NewResultSetFromRows(data)
+}
+
+// NewResultSetFromRows creates a result set from rows keyed by column name
+func NewResultSetFromRows(rows []map[string]any) *V2ResultSet {
+	return &V2ResultSet{
+		RowCount: int64(len(rows)),
+		data:     rows,
+	}
+}
+
+// derive creates a result set holding rows derived from rs
+func (rs *V2ResultSet) derive(rows []map[string]any) *V2ResultSet {
+	derived := NewResultSetFromRows(rows)
+	derived.QueryID = rs.QueryID
+	derived.ExecutedQuery = rs.ExecutedQuery
+	derived.ExecutionDateTime = rs.ExecutionDateTime
+	return derived
+}
+
+// =============================================================================
+// Aggregations
+// =============================================================================
+
+// AggregateOp is an aggregation function
+type AggregateOp string
+
+// Aggregation functions
+const (
+	AggregateCount         AggregateOp = "count"
+	AggregateCountDistinct AggregateOp = "count_distinct"
+	AggregateSum           AggregateOp = "sum"
+	AggregateAvg           AggregateOp = "avg"
+	AggregateMin           AggregateOp = "min"
+	AggregateMax           AggregateOp = "max"
+	AggregatePercentile    AggregateOp = "percentile"
+)
+
+// Aggregation computes one output column of a group-by
+type Aggregation struct {
+	// Op is the aggregation function
+	Op AggregateOp
+
+	// Field is the input column (ignored by AggregateCount)
+	Field string
+
+	// As is the output column name
+	// Defaults to "<op>_<field>", or "count" for AggregateCount
+	As string
+
+	// Percentile is the percentile to compute (0-100) for AggregatePercentile
+	Percentile float64
+}
+
+// Count counts the rows of each group
+func Count(as string) Aggregation {
+	return Aggregation{Op: AggregateCount, As: as}
+}
+
+// CountDistinct counts the distinct non-nil values of a field
+func CountDistinct(field, as string) Aggregation {
+	return Aggregation{Op: AggregateCountDistinct, Field: field, As: as}
+}
+
+// Sum adds up the values of a numeric field
+func Sum(field, as string) Aggregation {
+	return Aggregation{Op: AggregateSum, Field: field, As: as}
+}
+
+// Avg averages the values of a numeric field
+func Avg(field, as string) Aggregation {
+	return Aggregation{Op: AggregateAvg, Field: field, As: as}
+}
+
+// Min returns the smallest value of a field
+func Min(field, as string) Aggregation {
+	return Aggregation{Op: AggregateMin, Field: field, As: as}
+}
+
+// Max returns the largest value of a field
+func Max(field, as string) Aggregation {
+	return Aggregation{Op: AggregateMax, Field: field, As: as}
+}
+
+// Percentile computes the p-th percentile (0-100) of a numeric field with linear interpolation
+// Example: nql.Percentile("boot_duration", 95, "p95_boot")
+func Percentile(field string, p float64, as string) Aggregation {
+	return Aggregation{Op: AggregatePercentile, Field: field, As: as, Percentile: p}
+}
+
+// column returns the output column name of the aggregation
+func (a Aggregation) column() string {
+	switch {
+	case a.As != "":
+		return a.As
+	case a.Op == AggregateCount:
+		return "count"
+	case a.Op == AggregatePercentile:
+		return fmt.Sprintf("p%g_%s", a.Percentile, a.Field)
+	default:
+		return string(a.Op) + "_" + a.Field
+	}
+}
+
+// validate checks the aggregation is well formed
+func (a Aggregation) validate() error {
+	switch a.Op {
+	case AggregateCount:
+		return nil
+	case AggregateCountDistinct, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+	case AggregatePercentile:
+		if a.Percentile < 0 || a.Percentile > 100 {
+			return fmt.Errorf("percentile must be between 0 and 100, got %g", a.Percentile)
+		}
+	default:
+		return fmt.Errorf("unsupported aggregation: %s", a.Op)
+	}
+
+	if a.Field == "" {
+		return fmt.Errorf("aggregation %s requires a field", a.Op)
+	}
+	return nil
+}
+
+// aggregator accumulates the values of one aggregation for one group
+type aggregator struct {
+	agg      Aggregation
+	count    int
+	sum      float64
+	values   []float64
+	distinct map[string]bool
+	extreme  any
+
+	// mixed is set once a summed value is not a time.Duration
+	mixed bool
+}
+
+// add accumulates a row
+func (ag *aggregator) add(row map[string]any) error {
+	if ag.agg.Op == AggregateCount {
+		ag.count++
+		return nil
+	}
+
+	val := row[ag.agg.Field]
+	if val == nil {
+		return nil
+	}
+
+	switch ag.agg.Op {
+	case AggregateCountDistinct:
+		if ag.distinct == nil {
+			ag.distinct = make(map[string]bool)
+		}
+		ag.distinct[coerceString(val)] = true
+
+	case AggregateSum, AggregateAvg, AggregatePercentile:
+		f, err := coerceFloat64(val)
+		if err != nil {
+			return fmt.Errorf("%s(%s): %w", ag.agg.Op, ag.agg.Field, err)
+		}
+		if _, ok := val.(time.Duration); !ok {
+			ag.mixed = true
+		}
+		ag.count++
+		ag.sum += f
+		if ag.agg.Op == AggregatePercentile {
+			ag.values = append(ag.values, f)
+		}
+
+	case AggregateMin:
+		if ag.extreme == nil || compareValues(val, ag.extreme) < 0 {
+			ag.extreme = val
+		}
+
+	case AggregateMax:
+		if ag.extreme == nil || compareValues(val, ag.extreme) > 0 {
+			ag.extreme = val
+		}
+	}
+
+	return nil
+}
+
+// result returns the aggregated value (nil when the group had no values)
+func (ag *aggregator) result() any {
+	switch ag.agg.Op {
+	case AggregateCount:
+		return int64(ag.count)
+	case AggregateCountDistinct:
+		return int64(len(ag.distinct))
+	case AggregateSum:
+		return ag.typed(ag.sum)
+	case AggregateAvg:
+		if ag.count == 0 {
+			return nil
+		}
+		return ag.typed(ag.sum / float64(ag.count))
+	case AggregatePercentile:
+		if len(ag.values) == 0 {
+			return nil
+		}
+		return ag.typed(percentile(ag.values, ag.agg.Percentile))
+	default:
+		return ag.extreme
+	}
+}
+
+// typed returns a sum, average or percentile of durations as a time.Duration
+func (ag *aggregator) typed(seconds float64) any {
+	if ag.count == 0 || ag.mixed {
+		return seconds
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
+
+// percentile computes the p-th percentile of values with linear interpolation
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
+
+// =============================================================================
+// Group By
+// =============================================================================
+
+// GroupedRows is a group-by over a row source, ready to be aggregated
+type GroupedRows struct {
+	rows   iter.Seq2[int, map[string]any]
+	fields []string
+	base   *V2ResultSet
+}
+
+// GroupBy groups the rows by the values of the given fields
+// Example: rs.GroupBy("operating_system.platform").Aggregate(nql.Count(""), nql.Avg("number_of_crashes", ""))
+func (rs *V2ResultSet) GroupBy(fields ...string) *GroupedRows {
+	return &GroupedRows{rows: rs.All(), fields: fields, base: rs}
+}
+
+// GroupRows groups the rows of an iterator (such as ExportReader.All()) by the
+// values of the given fields. Rows are aggregated as they are read, so the
+// iterator is never held in memory.
+func GroupRows(rows iter.Seq2[int, map[string]any], fields ...string) *GroupedRows {
+	return &GroupedRows{rows: rows, fields: fields}
+}
+
+// Aggregate computes the aggregations for each group
+// The result has one row per group, in order of first appearance, with the
+// group-by fields followed by one column per aggregation. Without group-by
+// fields the whole input is a single group.
+func (g *GroupedRows) Aggregate(aggs ...Aggregation) (*V2ResultSet, error) {
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("at least one aggregation is required")
+	}
+	for _, agg := range aggs {
+		if err := agg.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	type group struct {
+		key         map[string]any
+		aggregators []*aggregator
+	}
+
+	groups := make(map[string]*group)
+	var order []string
+
+	for i, row := range g.rows {
+		key := groupKey(row, g.fields)
+		grp, ok := groups[key]
+		if !ok {
+			grp = &group{key: make(map[string]any, len(g.fields))}
+			for _, field := range g.fields {
+				grp.key[field] = row[field]
+			}
+			for _, agg := range aggs {
+				grp.aggregators = append(grp.aggregators, &aggregator{agg: agg})
+			}
+			groups[key] = grp
+			order = append(order, key)
+		}
+
+		for _, ag := range grp.aggregators {
+			if err := ag.add(row); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+		}
+	}
+
+	// Aggregating an empty input without group-by fields still yields one row
+	if len(order) == 0 && len(g.fields) == 0 {
+		grp := &group{key: map[string]any{}}
+		for _, agg := range aggs {
+			grp.aggregators = append(grp.aggregators, &aggregator{agg: agg})
+		}
+		groups[""] = grp
+		order = append(order, "")
+	}
+
+	rows := make([]map[string]any, 0, len(order))
+	for _, key := range order {
+		grp := groups[key]
+		row := make(map[string]any, len(g.fields)+len(aggs))
+		for field, val := range grp.key {
+			row[field] = val
+		}
+		for _, ag := range grp.aggregators {
+			row[ag.agg.column()] = ag.result()
+		}
+		rows = append(rows, row)
+	}
+
+	if g.base != nil {
+		return g.base.derive(rows), nil
+	}
+	return NewResultSetFromRows(rows), nil
+}
+
+// groupKey builds a map key from the values of the given fields
+func groupKey(row map[string]any, fields []string) string {
+	var b strings.Builder
+	for _, field := range fields {
+		val, ok := row[field]
+		if !ok || val == nil {
+			b.WriteString("\x00nil")
+		} else {
+			b.WriteString(fmt.Sprintf("%T:", val))
+			b.WriteString(coerceString(val))
+		}
+		b.WriteByte('\x1f')
+	}
+	return b.String()
+}
+
+// =============================================================================
+// Joins
+// =============================================================================
+
+// JoinType selects which rows a join keeps
+type JoinType string
+
+const (
+	// JoinInner keeps only rows with a match on both sides
+	JoinInner JoinType = "inner"
+
+	// JoinLeft keeps every left row; right columns are nil when there is no match
+	JoinLeft JoinType = "left"
+)
+
+// JoinRightPrefix prefixes right-hand columns whose name is already used by the left side
+const JoinRightPrefix = "right."
+
+// InnerJoin joins rs with right on rs.leftKey == right.rightKey, keeping matching rows only
+// Example: devices.InnerJoin(users, "device.name", "user.device")
+func (rs *V2ResultSet) InnerJoin(right *V2ResultSet, leftKey, rightKey string) *V2ResultSet {
+	return rs.Join(right, leftKey, rightKey, JoinInner)
+}
+
+// LeftJoin joins rs with right on rs.leftKey == right.rightKey, keeping every row of rs
+func (rs *V2ResultSet) LeftJoin(right *V2ResultSet, leftKey, rightKey string) *V2ResultSet {
+	return rs.Join(right, leftKey, rightKey, JoinLeft)
+}
+
+// Join joins rs with right on rs.leftKey == right.rightKey
+//
+// Keys match when their string forms are equal; nil keys never match. A left row
+// matching several right rows produces one output row per match. Right-hand
+// columns whose name is already used by the left side are renamed with
+// JoinRightPrefix (the right key is dropped when it has the same name as the left key).
+func (rs *V2ResultSet) Join(right *V2ResultSet, leftKey, rightKey string, joinType JoinType) *V2ResultSet {
+	leftColumns := make(map[string]bool)
+	for _, name := range rs.ColumnNames() {
+		leftColumns[name] = true
+	}
+
+	// Output name of each right column
+	rightColumns := make(map[string]string)
+	for _, name := range right.ColumnNames() {
+		switch {
+		case name == rightKey && rightKey == leftKey:
+			continue
+		case leftColumns[name]:
+			rightColumns[name] = JoinRightPrefix + name
+		default:
+			rightColumns[name] = name
+		}
+	}
+
+	index := make(map[string][]map[string]any)
+	for _, row := range right.data {
+		if key := row[rightKey]; key != nil {
+			k := coerceString(key)
+			index[k] = append(index[k], row)
+		}
+	}
+
+	var rows []map[string]any
+	for _, left := range rs.data {
+		var matches []map[string]any
+		if key := left[leftKey]; key != nil {
+			matches = index[coerceString(key)]
+		}
+
+		if len(matches) == 0 {
+			if joinType == JoinLeft {
+				row := copyRow(left, len(rightColumns))
+				for _, out := range rightColumns {
+					row[out] = nil
+				}
+				rows = append(rows, row)
+			}
+			continue
+		}
+
+		for _, match := range matches {
+			row := copyRow(left, len(rightColumns))
+			for name, out := range rightColumns {
+				row[out] = match[name]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rs.derive(rows)
+}
+
+// copyRow returns a shallow copy of a row with room for extra columns
+func copyRow(row map[string]any, extra int) map[string]any {
+	out := make(map[string]any, len(row)+extra)
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
+
+// =============================================================================
+// Distinct, Select and Sorting
+// =============================================================================
+
+// Select returns the rows with only the given fields (missing fields are nil)
+func (rs *V2ResultSet) Select(fields ...string) *V2ResultSet {
+	rows := make([]map[string]any, 0, len(rs.data))
+	for _, row := range rs.data {
+		out := make(map[string]any, len(fields))
+		for _, field := range fields {
+			out[field] = row[field]
+		}
+		rows = append(rows, out)
+	}
+	return rs.derive(rows)
+}
+
+// Distinct returns the distinct rows, keeping the first occurrence
+// With fields, the rows are first reduced to those fields (like SELECT DISTINCT);
+// without, whole rows are compared.
+func (rs *V2ResultSet) Distinct(fields ...string) *V2ResultSet {
+	source := rs
+	if len(fields) > 0 {
+		source = rs.Select(fields...)
+	} else {
+		fields = rs.ColumnNames()
+	}
+
+	seen := make(map[string]bool)
+	var rows []map[string]any
+	for _, row := range source.data {
+		key := groupKey(row, fields)
+		if !seen[key] {
+			seen[key] = true
+			rows = append(rows, row)
+		}
+	}
+
+	return rs.derive(rows)
+}
+
+// SortBy returns the rows sorted by a field
+// The sort is stable and nil values sort last in both directions.
+func (rs *V2ResultSet) SortBy(field string, descending bool) *V2ResultSet {
+	rows := append([]map[string]any(nil), rs.data...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i][field], rows[j][field]
+		switch {
+		case a == nil || b == nil:
+			return a != nil
+		case descending:
+			return compareValues(a, b) > 0
+		default:
+			return compareValues(a, b) < 0
+		}
+	})
+	return rs.derive(rows)
+}
+
+// TopN returns the first n rows sorted by a field
+// Example: rs.TopN(10, "number_of_crashes", true) returns the 10 devices with the most crashes
+func (rs *V2ResultSet) TopN(n int, field string, descending bool) *V2ResultSet {
+	sorted := rs.SortBy(field, descending)
+	if n >= 0 && n < len(sorted.data) {
+		sorted.data = sorted.data[:n]
+		sorted.RowCount = int64(n)
+	}
+	return sorted
+}
+
+// compareValues orders two non-nil values
+// Numbers and durations compare numerically, times chronologically and
+// everything else by string form.
+func compareValues(a, b any) int {
+	switch ta := a.(type) {
+	case time.Time:
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	case time.Duration:
+		if tb, ok := b.(time.Duration); ok {
+			return cmp.Compare(ta, tb)
+		}
+	}
+
+	if isNumeric(a) && isNumeric(b) {
+		fa, errA := coerceFloat64(a)
+		fb, errB := coerceFloat64(b)
+		if errA == nil && errB == nil {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(coerceString(a), coerceString(b))
+}
+
+// isNumeric checks if a value is a number (numeric strings included)
+func isNumeric(val any) bool {
+	if s, ok := val.(string); ok {
+		_, err := coerceFloat64(s)
+		return err == nil
+	}
+	_, err := coerceFloat64(val)
+	return err == nil
+}
+
+// =============================================================================
+// Pivot
+// =============================================================================
+
+// Pivot reshapes the rows into one row per rowField value with one column per
+// distinct columnField value, each holding the aggregation of valueField
+//
+// Example: rs.Pivot("device.name", "application.name", nql.Sum("number_of_crashes", ""))
+// returns a row per device with one crash total column per application. Cells
+// without data are nil; the aggregation's As name is ignored.
+func (rs *V2ResultSet) Pivot(rowField, columnField string, value Aggregation) (*V2ResultSet, error) {
+	if err := value.validate(); err != nil {
+		return nil, err
+	}
+
+	type pivotRow struct {
+		key   any
+		cells map[string]*aggregator
+	}
+
+	rowsByKey := make(map[string]*pivotRow)
+	var order []string
+
+	for i, row := range rs.data {
+		key := groupKey(row, []string{rowField})
+		pr, ok := rowsByKey[key]
+		if !ok {
+			pr = &pivotRow{key: row[rowField], cells: make(map[string]*aggregator)}
+			rowsByKey[key] = pr
+			order = append(order, key)
+		}
+
+		column := coerceString(row[columnField])
+		if row[columnField] == nil {
+			column = "null"
+		}
+		cell, ok := pr.cells[column]
+		if !ok {
+			cell = &aggregator{agg: value}
+			pr.cells[column] = cell
+		}
+		if err := cell.add(row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	columns := make(map[string]bool)
+	for _, pr := range rowsByKey {
+		for column := range pr.cells {
+			columns[column] = true
+		}
+	}
+
+	rows := make([]map[string]any, 0, len(order))
+	for _, key := range order {
+		pr := rowsByKey[key]
+		row := make(map[string]any, len(columns)+1)
+		row[rowField] = pr.key
+		for column := range columns {
+			if cell, ok := pr.cells[column]; ok {
+				row[column] = cell.result()
+			} else {
+				row[column] = nil
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rs.derive(rows), nil
+}
@@ -0,0 +1,241 @@
+package nql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analyticsDevices() *V2ResultSet {
+	return NewV2ResultSet(&ExecuteNQLV2Response{
+		QueryID: "#devices",
+		Data: []map[string]any{
+			{"device.name": "win-01", "platform": "Windows", "number_of_crashes": float64(3), "memory": "16 GB"},
+			{"device.name": "win-02", "platform": "Windows", "number_of_crashes": float64(1), "memory": "8 GB"},
+			{"device.name": "mac-01", "platform": "macOS", "number_of_crashes": float64(0), "memory": "16 GB"},
+			{"device.name": "win-03", "platform": "Windows", "number_of_crashes": nil, "memory": "32 GB"},
+			{"device.name": "lnx-01", "platform": "Linux", "number_of_crashes": float64(7), "memory": "4 GB"},
+		},
+	})
+}
+
+func analyticsUsers() *V2ResultSet {
+	return NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{
+			{"user.name": "alice", "user.device": "win-01", "platform": "Windows"},
+			{"user.name": "bob", "user.device": "win-01", "platform": "Windows"},
+			{"user.name": "carol", "user.device": "mac-01", "platform": "macOS"},
+			{"user.name": "dave", "user.device": "unknown", "platform": "Linux"},
+		},
+	})
+}
+
+func TestGroupBy_Aggregate(t *testing.T) {
+	result, err := analyticsDevices().GroupBy("platform").Aggregate(
+		Count(""),
+		Sum("number_of_crashes", "crashes"),
+		Avg("number_of_crashes", ""),
+		Min("number_of_crashes", ""),
+		Max("memory", ""),
+		CountDistinct("memory", ""),
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Rows())
+	assert.Equal(t, "#devices", result.QueryID)
+
+	windows, _ := result.GetRow(0)
+	assert.Equal(t, "Windows", windows["platform"])
+	assert.Equal(t, int64(3), windows["count"])
+	assert.Equal(t, float64(4), windows["crashes"])
+	assert.Equal(t, float64(2), windows["avg_number_of_crashes"])
+	assert.Equal(t, float64(1), windows["min_number_of_crashes"])
+	assert.Equal(t, "8 GB", windows["max_memory"])
+	assert.Equal(t, int64(3), windows["count_distinct_memory"])
+}
+
+func TestGroupBy_Percentile(t *testing.T) {
+	rs := NewResultSetFromRows([]map[string]any{
+		{"boot": 10}, {"boot": 20}, {"boot": 30}, {"boot": 40}, {"boot": 50},
+	})
+
+	result, err := rs.GroupBy().Aggregate(Percentile("boot", 50, ""), Percentile("boot", 90, "p90"))
+
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Rows())
+	row, _ := result.GetRow(0)
+	assert.Equal(t, float64(30), row["p50_boot"])
+	assert.InDelta(t, 46, row["p90"], 1e-9)
+}
+
+func TestGroupBy_Errors(t *testing.T) {
+	_, err := analyticsDevices().GroupBy("platform").Aggregate()
+	assert.Error(t, err)
+
+	_, err = analyticsDevices().GroupBy("platform").Aggregate(Sum("device.name", ""))
+	assert.Error(t, err)
+
+	_, err = analyticsDevices().GroupBy().Aggregate(Percentile("number_of_crashes", 101, ""))
+	assert.Error(t, err)
+}
+
+func TestGroupRows_ExportReader(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader(downloadTestCSV+"device-03,Windows\n"), ExportFormatCSV)
+	require.NoError(t, err)
+
+	result, err := GroupRows(reader.All(), "operating_system.platform").Aggregate(Count("devices"))
+
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Rows())
+	row, _ := result.GetRow(0)
+	assert.Equal(t, "Windows", row["operating_system.platform"])
+	assert.Equal(t, int64(2), row["devices"])
+}
+
+func TestCollectRows(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader(downloadTestCSV), ExportFormatCSV)
+	require.NoError(t, err)
+
+	rs := CollectRows(reader.All())
+
+	assert.Equal(t, 2, rs.Rows())
+	name, err := rs.GetString(1, "device.name")
+	require.NoError(t, err)
+	assert.Equal(t, "device-02", name)
+}
+
+func TestJoin(t *testing.T) {
+	devices := analyticsDevices()
+	users := analyticsUsers()
+
+	inner := devices.InnerJoin(users, "device.name", "user.device")
+	require.Equal(t, 3, inner.Rows())
+	row, _ := inner.GetRow(0)
+	assert.Equal(t, "win-01", row["device.name"])
+	assert.Equal(t, "alice", row["user.name"])
+	assert.Equal(t, "Windows", row["platform"])
+	assert.Equal(t, "Windows", row[JoinRightPrefix+"platform"])
+
+	left := devices.LeftJoin(users, "device.name", "user.device")
+	require.Equal(t, 6, left.Rows())
+	row, _ = left.GetRow(5)
+	assert.Equal(t, "lnx-01", row["device.name"])
+	assert.Contains(t, row, "user.name")
+	assert.Nil(t, row["user.name"])
+
+	// Input result sets are unchanged
+	assert.Equal(t, 5, devices.Rows())
+	assert.False(t, devices.HasField("user.name"))
+}
+
+func TestJoin_SameKeyName(t *testing.T) {
+	left := NewResultSetFromRows([]map[string]any{{"device.name": "a", "x": 1}})
+	right := NewResultSetFromRows([]map[string]any{{"device.name": "a", "y": 2}})
+
+	joined := left.InnerJoin(right, "device.name", "device.name")
+
+	require.Equal(t, 1, joined.Rows())
+	row, _ := joined.GetRow(0)
+	assert.Equal(t, map[string]any{"device.name": "a", "x": 1, "y": 2}, row)
+}
+
+func TestDistinct(t *testing.T) {
+	platforms := analyticsDevices().Distinct("platform")
+	require.Equal(t, 3, platforms.Rows())
+	assert.Equal(t, []string{"platform"}, platforms.ColumnNames())
+
+	rs := NewResultSetFromRows([]map[string]any{{"a": 1, "b": "x"}, {"a": 1, "b": "x"}, {"a": 1, "b": "y"}})
+	assert.Equal(t, 2, rs.Distinct().Rows())
+}
+
+func TestTopN(t *testing.T) {
+	devices := analyticsDevices()
+
+	top := devices.TopN(2, "number_of_crashes", true)
+	require.Equal(t, 2, top.Rows())
+	first, _ := top.GetString(0, "device.name")
+	second, _ := top.GetString(1, "device.name")
+	assert.Equal(t, "lnx-01", first)
+	assert.Equal(t, "win-01", second)
+
+	// nil values sort last in both directions
+	ascending := devices.SortBy("number_of_crashes", false)
+	last, _ := ascending.GetString(4, "device.name")
+	assert.Equal(t, "win-03", last)
+
+	assert.Equal(t, 5, devices.TopN(10, "device.name", false).Rows())
+}
+
+func TestPivot(t *testing.T) {
+	rs := NewResultSetFromRows([]map[string]any{
+		{"device.name": "win-01", "application.name": "Teams", "number_of_crashes": 2},
+		{"device.name": "win-01", "application.name": "Outlook", "number_of_crashes": 1},
+		{"device.name": "win-01", "application.name": "Teams", "number_of_crashes": 3},
+		{"device.name": "mac-01", "application.name": "Teams", "number_of_crashes": 4},
+	})
+
+	pivot, err := rs.Pivot("device.name", "application.name", Sum("number_of_crashes", ""))
+
+	require.NoError(t, err)
+	require.Equal(t, 2, pivot.Rows())
+	assert.Equal(t, []string{"Outlook", "Teams", "device.name"}, pivot.ColumnNames())
+
+	win, _ := pivot.GetRow(0)
+	assert.Equal(t, float64(5), win["Teams"])
+	assert.Equal(t, float64(1), win["Outlook"])
+
+	mac, _ := pivot.GetRow(1)
+	assert.Nil(t, mac["Outlook"])
+}
+
+func TestAggregate_ExportReaderDurations(t *testing.T) {
+	data := "device.name,execution_duration,device.last_seen\n" +
+		"device-01,2s,2024-02-08T10:00:00Z\n" +
+		"device-02,10s,2024-02-06T10:00:00Z\n" +
+		"device-03,9s,2024-02-07T10:00:00Z\n"
+
+	reader, err := NewExportReader(strings.NewReader(data), ExportFormatCSV)
+	require.NoError(t, err)
+	rs := CollectRows(reader.All())
+
+	result, err := rs.GroupBy().Aggregate(
+		Sum("execution_duration", "total"),
+		Avg("execution_duration", "avg"),
+		Min("execution_duration", "min"),
+		Max("execution_duration", "max"),
+		Max("device.last_seen", "latest"),
+	)
+	require.NoError(t, err)
+
+	row, _ := result.GetRow(0)
+	assert.Equal(t, 21*time.Second, row["total"])
+	assert.Equal(t, 7*time.Second, row["avg"])
+	assert.Equal(t, 2*time.Second, row["min"])
+	assert.Equal(t, 10*time.Second, row["max"])
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 0, 0, 0, time.UTC), row["latest"])
+
+	sorted := rs.SortBy("execution_duration", true)
+	durations := make([]any, 0, sorted.Rows())
+	for _, row := range sorted.All() {
+		durations = append(durations, row["execution_duration"])
+	}
+	assert.Equal(t, []any{10 * time.Second, 9 * time.Second, 2 * time.Second}, durations)
+
+	names := make([]any, 0, 3)
+	for _, row := range rs.SortBy("device.last_seen", false).All() {
+		names = append(names, row["device.name"])
+	}
+	assert.Equal(t, []any{"device-02", "device-03", "device-01"}, names)
+}
+
+func TestCompareValues_Durations(t *testing.T) {
+	assert.Equal(t, 1, compareValues(10*time.Second, 9*time.Second))
+	assert.Equal(t, -1, compareValues(2*time.Second, 9*time.Second))
+
+	f, err := coerceFloat64(1500 * time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, f)
+}
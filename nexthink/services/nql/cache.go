@@ -0,0 +1,495 @@
+package nql
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"go.uber.org/zap"
+)
+
+// The execute cache serves repeated ExecuteNQLV2 calls from memory (or a
+// pluggable backend), coalesces concurrent identical calls into one request and
+// can serve stale results while refreshing them in the background
+
+// ErrCacheMiss is returned by a cache backend when it has no entry for a key
+var ErrCacheMiss = errors.New("cache miss")
+
+const (
+	// DefaultCacheTTL is how long execute results are fresh by default
+	DefaultCacheTTL = time.Minute
+
+	// DefaultCacheSize is the default number of entries kept by the in-memory LRU backend
+	DefaultCacheSize = 256
+
+	// cacheRefreshTimeout bounds background stale-while-revalidate refreshes
+	cacheRefreshTimeout = 2 * time.Minute
+)
+
+// =============================================================================
+// Cache Status
+// =============================================================================
+
+// CacheStatus describes how an execute response was served
+type CacheStatus string
+
+const (
+	// CacheMiss means the response came from the API and was stored
+	CacheMiss CacheStatus = "miss"
+
+	// CacheHit means the response was fresh in the cache
+	CacheHit CacheStatus = "hit"
+
+	// CacheStale means an expired response was served while it is refreshed in the background
+	CacheStale CacheStatus = "stale"
+
+	// CacheBypass means the query is not cached (its TTL is negative)
+	CacheBypass CacheStatus = "bypass"
+)
+
+// CacheInfo is attached to execute responses when the service cache is enabled
+type CacheInfo struct {
+	// Status is how the response was served
+	Status CacheStatus
+
+	// Key is the cache key of the request
+	Key string
+
+	// StoredAt is the time the response was fetched from the API
+	StoredAt time.Time
+
+	// Age is how old the response was when it was served
+	Age time.Duration
+
+	// Coalesced reports that the call waited for an identical in-flight request
+	// instead of sending its own
+	Coalesced bool
+}
+
+// FromCache reports whether the response was served from the cache
+func (c *CacheInfo) FromCache() bool {
+	return c != nil && (c.Status == CacheHit || c.Status == CacheStale)
+}
+
+// =============================================================================
+// Cache Backend
+// =============================================================================
+
+// CacheEntry is a cached execute response
+type CacheEntry struct {
+	// Response is the API response
+	Response *ExecuteNQLV2Response `json:"response"`
+
+	// Metadata is the HTTP response metadata (status, headers)
+	Metadata *interfaces.Response `json:"metadata,omitempty"`
+
+	// StoredAt is the time the response was fetched
+	StoredAt time.Time `json:"storedAt"`
+
+	// ExpiresAt is the end of the fresh period
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// StaleUntil is the end of the stale-while-revalidate period (equal to ExpiresAt when disabled)
+	StaleUntil time.Time `json:"staleUntil"`
+}
+
+// CacheBackend stores cache entries
+// Implementations must be safe for concurrent use. Entries past StaleUntil are
+// never served, so backends may evict them at any time.
+type CacheBackend interface {
+	// Get returns the entry for a key, or ErrCacheMiss
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+
+	// Set creates or replaces the entry for a key
+	Set(ctx context.Context, key string, entry *CacheEntry) error
+
+	// Delete removes the entry for a key (no error if it does not exist)
+	Delete(ctx context.Context, key string) error
+}
+
+// LRUCacheBackend keeps cache entries in memory, evicting the least recently used
+type LRUCacheBackend struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+var _ CacheBackend = (*LRUCacheBackend)(nil)
+
+// lruItem is an element of the LRU list
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCacheBackend creates an in-memory backend holding at most capacity entries
+// capacity defaults to DefaultCacheSize if not positive.
+func NewLRUCacheBackend(capacity int) *LRUCacheBackend {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &LRUCacheBackend{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheBackend
+func (l *LRUCacheBackend) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, nil
+}
+
+// Set implements CacheBackend
+func (l *LRUCacheBackend) Set(ctx context.Context, key string, entry *CacheEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(elem)
+		return nil
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+// Delete implements CacheBackend
+func (l *LRUCacheBackend) Delete(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache
+func (l *LRUCacheBackend) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// =============================================================================
+// Cache Options
+// =============================================================================
+
+// CacheOptions configures the service execute cache
+type CacheOptions struct {
+	// Backend stores the entries
+	// Defaults to an LRU backend holding DefaultCacheSize entries if nil
+	Backend CacheBackend
+
+	// TTL is how long a response is fresh
+	// Defaults to DefaultCacheTTL if not specified
+	TTL time.Duration
+
+	// QueryTTLs overrides TTL per query ID; a TTL of 0 coalesces concurrent calls
+	// without storing the response, a negative TTL disables caching for the query
+	QueryTTLs map[string]time.Duration
+
+	// StaleWhileRevalidate serves an expired response for this long after its
+	// TTL while a single background request refreshes it (0 disables)
+	StaleWhileRevalidate time.Duration
+}
+
+// DefaultCacheOptions returns cache options with sensible defaults
+func DefaultCacheOptions() *CacheOptions {
+	return &CacheOptions{
+		Backend: NewLRUCacheBackend(DefaultCacheSize),
+		TTL:     DefaultCacheTTL,
+	}
+}
+
+// WithTTL sets the default TTL
+func (o *CacheOptions) WithTTL(ttl time.Duration) *CacheOptions {
+	o.TTL = ttl
+	return o
+}
+
+// WithQueryTTL sets the TTL of a query ID (0 only coalesces calls, negative disables caching for the query)
+func (o *CacheOptions) WithQueryTTL(queryID string, ttl time.Duration) *CacheOptions {
+	if o.QueryTTLs == nil {
+		o.QueryTTLs = make(map[string]time.Duration)
+	}
+	o.QueryTTLs[queryID] = ttl
+	return o
+}
+
+// WithStaleWhileRevalidate sets the stale-while-revalidate window
+func (o *CacheOptions) WithStaleWhileRevalidate(window time.Duration) *CacheOptions {
+	o.StaleWhileRevalidate = window
+	return o
+}
+
+// WithBackend sets the cache backend
+func (o *CacheOptions) WithBackend(backend CacheBackend) *CacheOptions {
+	o.Backend = backend
+	return o
+}
+
+// =============================================================================
+// Service Cache
+// =============================================================================
+
+// CacheKey returns the cache key of an execute request: query ID, platform and
+// parameters (sorted by name)
+func CacheKey(req *ExecuteRequest) string {
+	params := make(url.Values, len(req.Parameters))
+	for name, value := range req.Parameters {
+		params.Set(name, value)
+	}
+	return req.QueryID + "|" + req.Platform + "|" + params.Encode()
+}
+
+// executeFunc performs an uncached execute call
+type executeFunc func(ctx context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error)
+
+// executeCache is the cache layer in front of ExecuteNQLV2
+type executeCache struct {
+	opts   CacheOptions
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	inflight map[string]*cacheCall
+}
+
+// cacheCall is an in-flight API request shared by identical calls
+type cacheCall struct {
+	done  chan struct{}
+	entry *CacheEntry
+	err   error
+}
+
+// EnableCache turns on caching of ExecuteNQLV2 responses
+// Responses are keyed by query ID, platform and parameters (see CacheKey), and
+// carry a CacheInfo describing how they were served. Errors are never cached.
+// Call EnableCache before the service is shared between goroutines.
+func (s *Service) EnableCache(opts *CacheOptions) {
+	if opts == nil {
+		opts = DefaultCacheOptions()
+	}
+	copied := *opts
+	if copied.Backend == nil {
+		copied.Backend = NewLRUCacheBackend(DefaultCacheSize)
+	}
+	if copied.TTL <= 0 {
+		copied.TTL = DefaultCacheTTL
+	}
+	copied.QueryTTLs = maps.Clone(copied.QueryTTLs)
+
+	s.cache = &executeCache{
+		opts:     copied,
+		logger:   s.client.GetLogger(),
+		now:      time.Now,
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+// DisableCache turns off the execute cache
+func (s *Service) DisableCache() {
+	s.cache = nil
+}
+
+// InvalidateCache removes the cached response for a request
+func (s *Service) InvalidateCache(ctx context.Context, req *ExecuteRequest) error {
+	if s.cache == nil || req == nil {
+		return nil
+	}
+	if err := s.cache.opts.Backend.Delete(ctx, CacheKey(req)); err != nil {
+		return fmt.Errorf("failed to invalidate cache entry: %w", err)
+	}
+	return nil
+}
+
+// ttl returns the TTL of a query ID
+func (c *executeCache) ttl(queryID string) time.Duration {
+	if ttl, ok := c.opts.QueryTTLs[queryID]; ok {
+		return ttl
+	}
+	return c.opts.TTL
+}
+
+// execute serves a request from the cache, calling fetch on a miss
+func (c *executeCache) execute(ctx context.Context, req *ExecuteRequest, fetch executeFunc) (*ExecuteNQLV2Response, *interfaces.Response, error) {
+	key := CacheKey(req)
+	ttl := c.ttl(req.QueryID)
+	if ttl < 0 {
+		resp, apiResp, err := fetch(ctx, req)
+		if resp != nil {
+			resp.Cache = &CacheInfo{Status: CacheBypass, Key: key}
+		}
+		return resp, apiResp, err
+	}
+
+	now := c.now()
+	entry, err := c.opts.Backend.Get(ctx, key)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		c.logger.Warn("Cache backend read failed, executing query",
+			zap.String("query_id", req.QueryID),
+			zap.Error(err))
+	}
+
+	if entry != nil {
+		switch {
+		case now.Before(entry.ExpiresAt):
+			return c.serve(entry, CacheHit, key, false)
+		case now.Before(entry.StaleUntil):
+			c.refresh(ctx, req, key, ttl, fetch)
+			return c.serve(entry, CacheStale, key, false)
+		}
+	}
+
+	entry, coalesced, err := c.fetch(ctx, req, key, ttl, fetch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.serve(entry, CacheMiss, key, coalesced)
+}
+
+// serve returns a copy of a cached response annotated with cache information
+func (c *executeCache) serve(entry *CacheEntry, status CacheStatus, key string, coalesced bool) (*ExecuteNQLV2Response, *interfaces.Response, error) {
+	resp := *entry.Response
+	resp.Data = make([]map[string]any, len(entry.Response.Data))
+	for i, row := range entry.Response.Data {
+		resp.Data[i] = maps.Clone(row)
+	}
+	resp.Cache = &CacheInfo{
+		Status:    status,
+		Key:       key,
+		StoredAt:  entry.StoredAt,
+		Age:       c.now().Sub(entry.StoredAt),
+		Coalesced: coalesced,
+	}
+
+	var apiResp *interfaces.Response
+	if entry.Metadata != nil {
+		copied := *entry.Metadata
+		copied.Headers = entry.Metadata.Headers.Clone()
+		apiResp = &copied
+	}
+
+	return &resp, apiResp, nil
+}
+
+// fetch calls the API once for all concurrent callers of the same key and stores the result
+// coalesced is true for callers that waited on another caller's request. When
+// that request ends because its caller's context was cancelled, waiters whose
+// own context is still live retry instead of failing with it.
+func (c *executeCache) fetch(ctx context.Context, req *ExecuteRequest, key string, ttl time.Duration, fetch executeFunc) (*CacheEntry, bool, error) {
+	for {
+		c.mu.Lock()
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+			if isContextError(call.err) && ctx.Err() == nil {
+				continue
+			}
+			return call.entry, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	c.run(ctx, call, req, key, ttl, fetch)
+	return call.entry, false, call.err
+}
+
+// isContextError reports whether err comes from a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// run executes a registered in-flight call, stores a successful result and releases any waiters
+func (c *executeCache) run(ctx context.Context, call *cacheCall, req *ExecuteRequest, key string, ttl time.Duration, fetch executeFunc) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	resp, apiResp, err := fetch(ctx, req)
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	storedAt := c.now()
+	call.entry = &CacheEntry{
+		Response:   resp,
+		Metadata:   apiResp,
+		StoredAt:   storedAt,
+		ExpiresAt:  storedAt.Add(ttl),
+		StaleUntil: storedAt.Add(ttl + max(c.opts.StaleWhileRevalidate, 0)),
+	}
+	if ttl == 0 {
+		return
+	}
+	if err := c.opts.Backend.Set(ctx, key, call.entry); err != nil {
+		c.logger.Warn("Cache backend write failed",
+			zap.String("query_id", req.QueryID),
+			zap.Error(err))
+	}
+}
+
+// refresh re-fetches a stale entry in the background, unless a fetch for the key is already running
+func (c *executeCache) refresh(ctx context.Context, req *ExecuteRequest, key string, ttl time.Duration, fetch executeFunc) {
+	c.mu.Lock()
+	if _, running := c.inflight[key]; running {
+		c.mu.Unlock()
+		return
+	}
+	// Register before starting the goroutine so later stale reads see the refresh
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	// The caller may reuse its request once it has been served
+	copied := *req
+	copied.Parameters = maps.Clone(req.Parameters)
+
+	refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheRefreshTimeout)
+	go func() {
+		defer cancel()
+		c.run(refreshCtx, call, &copied, key, ttl, fetch)
+		if err := call.err; err != nil {
+			c.logger.Warn("Background cache refresh failed",
+				zap.String("query_id", req.QueryID),
+				zap.Error(err))
+		}
+	}()
+}
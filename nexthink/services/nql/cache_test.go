@@ -0,0 +1,308 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// registerCountingExecuteMock registers an ExecuteNQLV2 mock that counts calls
+// and returns the call number in the rows; release, if set, blocks each call until closed
+func registerCountingExecuteMock(baseURL string, calls *atomic.Int32, release <-chan struct{}) {
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute",
+		func(req *http.Request) (*http.Response, error) {
+			n := calls.Add(1)
+			if release != nil {
+				<-release
+			}
+			var body ExecuteRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return httpmock.NewStringResponse(400, "bad request"), nil
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"queryId": body.QueryID,
+				"rows":    1,
+				"data":    []map[string]any{{"call": n, "platform": body.Platform}},
+			})
+		})
+}
+
+// setupCachedService returns a service with the cache enabled and a controllable clock
+func setupCachedService(t *testing.T, opts *CacheOptions) (*Service, *atomic.Int32, *time.Time) {
+	t.Helper()
+
+	service, baseURL := setupMockClient(t)
+	calls := &atomic.Int32{}
+	registerCountingExecuteMock(baseURL, calls, nil)
+
+	service.EnableCache(opts)
+	now := time.Date(2024, 2, 8, 10, 0, 0, 0, time.UTC)
+	service.cache.now = func() time.Time { return now }
+	return service, calls, &now
+}
+
+func TestCacheKey(t *testing.T) {
+	a := CacheKey(&ExecuteRequest{QueryID: "#q", Platform: "windows", Parameters: map[string]string{"b": "2", "a": "1"}})
+	b := CacheKey(&ExecuteRequest{QueryID: "#q", Platform: "windows", Parameters: map[string]string{"a": "1", "b": "2"}})
+	assert.Equal(t, "#q|windows|a=1&b=2", a)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, CacheKey(&ExecuteRequest{QueryID: "#q", Platform: "macos", Parameters: map[string]string{"a": "1", "b": "2"}}))
+}
+
+func TestExecuteCache_HitAndExpiry(t *testing.T) {
+	service, calls, now := setupCachedService(t, DefaultCacheOptions().WithTTL(30*time.Second))
+	ctx := context.Background()
+	req := &ExecuteRequest{QueryID: "#test_query"}
+
+	resp, apiResp, err := service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, apiResp)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+	assert.False(t, resp.Cache.FromCache())
+
+	*now = now.Add(10 * time.Second)
+	resp, apiResp, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, apiResp)
+	assert.Equal(t, 200, apiResp.StatusCode)
+	assert.Equal(t, CacheHit, resp.Cache.Status)
+	assert.True(t, resp.Cache.FromCache())
+	assert.Equal(t, 10*time.Second, resp.Cache.Age)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Callers can modify their copy without affecting the cache
+	resp.Data[0]["call"] = "modified"
+	resp, _, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, resp.Data[0]["call"], 0)
+
+	// Different platforms are cached separately
+	resp, _, err = service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#test_query", Platform: "macos"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+	assert.Equal(t, int32(2), calls.Load())
+
+	*now = now.Add(30 * time.Second)
+	resp, _, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestExecuteCache_QueryTTL(t *testing.T) {
+	opts := DefaultCacheOptions().
+		WithQueryTTL("#live_query", -1).
+		WithQueryTTL("#slow_query", time.Hour)
+	service, calls, now := setupCachedService(t, opts)
+	ctx := context.Background()
+
+	for range 2 {
+		resp, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#live_query"})
+		require.NoError(t, err)
+		assert.Equal(t, CacheBypass, resp.Cache.Status)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	_, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#slow_query"})
+	require.NoError(t, err)
+	*now = now.Add(30 * time.Minute)
+	resp, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#slow_query"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheHit, resp.Cache.Status)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestExecuteCache_ZeroTTL(t *testing.T) {
+	backend := NewLRUCacheBackend(0)
+	service, calls, _ := setupCachedService(t, DefaultCacheOptions().
+		WithBackend(backend).
+		WithQueryTTL("#uncached_query", 0))
+	ctx := context.Background()
+
+	for range 2 {
+		resp, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#uncached_query"})
+		require.NoError(t, err)
+		assert.Equal(t, CacheMiss, resp.Cache.Status)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Zero(t, backend.Len())
+}
+
+func TestExecuteCache_StaleWhileRevalidate(t *testing.T) {
+	opts := DefaultCacheOptions().WithTTL(time.Minute).WithStaleWhileRevalidate(time.Minute)
+	service, calls, now := setupCachedService(t, opts)
+	ctx := context.Background()
+	req := &ExecuteRequest{QueryID: "#test_query"}
+
+	_, _, err := service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+
+	*now = now.Add(90 * time.Second)
+	resp, _, err := service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStale, resp.Cache.Status)
+	assert.InDelta(t, 1, resp.Data[0]["call"], 0)
+
+	require.Eventually(t, func() bool {
+		resp, _, err := service.ExecuteNQLV2(ctx, req)
+		return err == nil && resp.Cache.Status == CacheHit
+	}, time.Second, 5*time.Millisecond)
+
+	resp, _, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, resp.Data[0]["call"], 0)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Past the stale window the call waits for a fresh response
+	*now = now.Add(3 * time.Minute)
+	resp, _, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+}
+
+func TestExecuteCache_SingleFlight(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	calls := &atomic.Int32{}
+	release := make(chan struct{})
+	registerCountingExecuteMock(baseURL, calls, release)
+	service.EnableCache(nil)
+
+	const callers = 8
+	var (
+		wg        sync.WaitGroup
+		coalesced atomic.Int32
+	)
+	for range callers {
+		wg.Go(func() {
+			resp, _, err := service.ExecuteNQLV2(context.Background(), &ExecuteRequest{QueryID: "#test_query"})
+			if assert.NoError(t, err) && resp.Cache.Coalesced {
+				coalesced.Add(1)
+			}
+		})
+	}
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(callers-1), coalesced.Load())
+}
+
+func TestExecuteCache_LeaderCancelled(t *testing.T) {
+	cache := &executeCache{
+		opts:     CacheOptions{Backend: NewLRUCacheBackend(0), TTL: time.Minute},
+		logger:   zap.NewNop(),
+		now:      time.Now,
+		inflight: make(map[string]*cacheCall),
+	}
+	req := &ExecuteRequest{QueryID: "#test_query"}
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	fetch := func(ctx context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, nil, ctx.Err()
+		}
+		return &ExecuteNQLV2Response{QueryID: req.QueryID}, nil, nil
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := cache.execute(leaderCtx, req, fetch)
+		leaderErr <- err
+	}()
+	<-started
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, _, err := cache.execute(context.Background(), req, fetch)
+		waiterErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	assert.NoError(t, <-waiterErr)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestExecuteCache_ErrorsNotCached(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	failing := true
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute",
+		func(req *http.Request) (*http.Response, error) {
+			if failing {
+				return httpmock.NewJsonResponse(500, map[string]any{"message": "unavailable"})
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{"queryId": "#test_query", "data": []map[string]any{{"a": 1}}})
+		})
+	service.EnableCache(nil)
+	ctx := context.Background()
+
+	_, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#test_query"})
+	require.Error(t, err)
+
+	failing = false
+	resp, _, err := service.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: "#test_query"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+}
+
+func TestExecuteCache_InvalidateAndDisable(t *testing.T) {
+	service, calls, _ := setupCachedService(t, nil)
+	ctx := context.Background()
+	req := &ExecuteRequest{QueryID: "#test_query"}
+
+	_, _, err := service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	require.NoError(t, service.InvalidateCache(ctx, req))
+
+	resp, _, err := service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheMiss, resp.Cache.Status)
+	assert.Equal(t, int32(2), calls.Load())
+
+	service.DisableCache()
+	resp, _, err = service.ExecuteNQLV2(ctx, req)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Cache)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestLRUCacheBackend(t *testing.T) {
+	ctx := context.Background()
+	backend := NewLRUCacheBackend(2)
+
+	require.NoError(t, backend.Set(ctx, "a", &CacheEntry{}))
+	require.NoError(t, backend.Set(ctx, "b", &CacheEntry{}))
+	_, err := backend.Get(ctx, "a")
+	require.NoError(t, err)
+
+	// "b" is the least recently used
+	require.NoError(t, backend.Set(ctx, "c", &CacheEntry{}))
+	assert.Equal(t, 2, backend.Len())
+	_, err = backend.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = backend.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	require.NoError(t, backend.Delete(ctx, "a"))
+	require.NoError(t, backend.Delete(ctx, "missing"))
+	assert.Equal(t, 1, backend.Len())
+}
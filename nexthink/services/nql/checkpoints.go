@@ -0,0 +1,197 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Checkpoints record how far an incremental reader has read an event table, so
+// polling resumes from the last watermark after a restart
+
+// ErrCheckpointNotFound is returned when a checkpoint store has no checkpoint for a name
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// =============================================================================
+// Checkpoint
+// =============================================================================
+
+// Checkpoint is the committed position of an incremental reader
+type Checkpoint struct {
+	// Name identifies the reader that owns the checkpoint
+	Name string `json:"name"`
+
+	// Watermark is the latest event timestamp that has been emitted
+	Watermark time.Time `json:"watermark"`
+
+	// Seen holds the keys (and timestamps) of emitted rows at or after
+	// Watermark minus the reader's overlap, used to drop rows that are read again
+	Seen map[string]time.Time `json:"seen,omitempty"`
+
+	// UpdatedAt is the time the checkpoint was created
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// clone returns a deep copy of the checkpoint
+func (c *Checkpoint) clone() *Checkpoint {
+	copied := *c
+	copied.Seen = maps.Clone(c.Seen)
+	return &copied
+}
+
+// =============================================================================
+// Checkpoint Store
+// =============================================================================
+
+// CheckpointStore persists incremental reader checkpoints
+// Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// Load returns the checkpoint for a name, or ErrCheckpointNotFound
+	Load(ctx context.Context, name string) (*Checkpoint, error)
+
+	// Save creates or replaces the checkpoint with the same name
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory
+// Useful for tests and for readers that do not need to survive a restart.
+type MemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]*Checkpoint
+}
+
+var _ CheckpointStore = (*MemoryCheckpointStore)(nil)
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]*Checkpoint)}
+}
+
+// Load implements CheckpointStore
+func (m *MemoryCheckpointStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	checkpoint, ok := m.checkpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, name)
+	}
+	return checkpoint.clone(), nil
+}
+
+// Save implements CheckpointStore
+func (m *MemoryCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	if checkpoint == nil || checkpoint.Name == "" {
+		return fmt.Errorf("checkpoint must have a name")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[checkpoint.Name] = checkpoint.clone()
+	return nil
+}
+
+// FileCheckpointStore keeps checkpoints in a JSON file
+// Every save rewrites the file atomically (write to a temporary file, then
+// rename). The file is owned by a single process.
+type FileCheckpointStore struct {
+	mu          sync.Mutex
+	path        string
+	checkpoints map[string]*Checkpoint
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// NewFileCheckpointStore opens (or creates) a checkpoint store backed by the JSON file at path
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("checkpoint store path cannot be empty")
+	}
+
+	store := &FileCheckpointStore{
+		path:        path,
+		checkpoints: make(map[string]*Checkpoint),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint store: %w", err)
+	}
+
+	if len(data) == 0 {
+		return store, nil
+	}
+
+	var checkpoints []*Checkpoint
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint store %s: %w", path, err)
+	}
+	for _, checkpoint := range checkpoints {
+		store.checkpoints[checkpoint.Name] = checkpoint
+	}
+
+	return store, nil
+}
+
+// Load implements CheckpointStore
+func (f *FileCheckpointStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkpoint, ok := f.checkpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, name)
+	}
+	return checkpoint.clone(), nil
+}
+
+// Save implements CheckpointStore
+func (f *FileCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	if checkpoint == nil || checkpoint.Name == "" {
+		return fmt.Errorf("checkpoint must have a name")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, existed := f.checkpoints[checkpoint.Name]
+	f.checkpoints[checkpoint.Name] = checkpoint.clone()
+	if err := f.persist(); err != nil {
+		if existed {
+			f.checkpoints[checkpoint.Name] = previous
+		} else {
+			delete(f.checkpoints, checkpoint.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// persist atomically writes all checkpoints to the store file, ordered by name
+func (f *FileCheckpointStore) persist() error {
+	names := slices.Sorted(maps.Keys(f.checkpoints))
+	checkpoints := make([]*Checkpoint, len(names))
+	for i, name := range names {
+		checkpoints[i] = f.checkpoints[name]
+	}
+
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint store: %w", err)
+	}
+
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return fmt.Errorf("failed to write checkpoint store: %w", err)
+	}
+
+	return nil
+}
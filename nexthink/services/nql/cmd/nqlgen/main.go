@@ -0,0 +1,68 @@
+// Command nqlgen generates typed Go executors from annotated .nql files.
+//
+// Usage:
+//
+//	nqlgen [-pkg name] [-out file] [path ...]
+//
+// Each path is a .nql file or a directory whose .nql files are read; the
+// default is the current directory. The package name defaults to $GOPACKAGE
+// when run through go generate, otherwise to the output directory name:
+//
+//	//go:generate go run github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/cmd/nqlgen
+//
+// See package nqlgen for the .nql header format.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/nqlgen"
+)
+
+func main() {
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file")
+	out := flag.String("out", "nql_gen.go", "output file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: nqlgen [-pkg name] [-out file] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	if err := run(*pkg, *out, paths); err != nil {
+		fmt.Fprintf(os.Stderr, "nqlgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the output file from the .nql files in paths
+func run(pkg, out string, paths []string) error {
+	if pkg == "" {
+		dir, err := filepath.Abs(filepath.Dir(out))
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(dir)
+	}
+
+	queries, err := nqlgen.Load(paths...)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("no .nql files found in %v", paths)
+	}
+
+	src, err := nqlgen.Generate(pkg, queries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644) //nolint:gosec // generated source files are world-readable
+}
@@ -0,0 +1,333 @@
+package nql
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Column type inference for NQL result columns
+// Export files carry no schema, so column types are inferred from the NQL
+// field naming conventions (number_of_*, *_duration, last_seen, ...)
+
+// =============================================================================
+// Column Types
+// =============================================================================
+
+// ColumnType represents the inferred data type of a result column
+type ColumnType string
+
+const (
+	ColumnTypeString   ColumnType = "string"
+	ColumnTypeInt      ColumnType = "int"
+	ColumnTypeFloat    ColumnType = "float"
+	ColumnTypeBool     ColumnType = "bool"
+	ColumnTypeDateTime ColumnType = "datetime"
+	ColumnTypeDuration ColumnType = "duration"
+	ColumnTypeBytes    ColumnType = "bytes"
+)
+
+// String returns the string representation of the column type
+func (t ColumnType) String() string {
+	return string(t)
+}
+
+// =============================================================================
+// Inference
+// =============================================================================
+
+// durationColumnHints mark columns holding durations
+var durationColumnHints = []string{"duration", "time_until", "page_load_time", "response_time", "uptime", "latency"}
+
+// dateTimeColumnNames are column names (last segment) holding datetimes
+var dateTimeColumnNames = map[string]bool{
+	"last_seen":   true,
+	"first_seen":  true,
+	"last_update": true,
+	"time":        true,
+	"timestamp":   true,
+	"date":        true,
+	"datetime":    true,
+}
+
+// bytesColumnNames are column names (last segment) holding byte sizes
+var bytesColumnNames = map[string]bool{
+	"memory": true,
+	"size":   true,
+	"bytes":  true,
+}
+
+// InferColumnType infers the type of a column from its name
+//
+// The inference follows NQL naming conventions:
+//   - *duration*, time_until_*, page_load_time.*: ColumnTypeDuration
+//   - last_seen, *_time, *_date, *_at: ColumnTypeDateTime
+//   - memory, size, *_size, *_bytes: ColumnTypeBytes
+//   - is_*, has_*: ColumnTypeBool
+//   - number_of_*, days_since_*, count, *_count: ColumnTypeInt
+//   - avg*, *_value, *_ratio, *_percentage, *_rate, *_score*: ColumnTypeFloat
+//
+// Anything else is ColumnTypeString.
+func InferColumnType(name string) ColumnType {
+	full := strings.ToLower(strings.TrimSpace(name))
+	last := full
+	if i := strings.LastIndex(full, "."); i >= 0 {
+		last = full[i+1:]
+	}
+
+	for _, hint := range durationColumnHints {
+		if strings.Contains(full, hint) {
+			return ColumnTypeDuration
+		}
+	}
+
+	switch {
+	case dateTimeColumnNames[last],
+		strings.HasSuffix(last, "_time"),
+		strings.HasSuffix(last, "_date"),
+		strings.HasSuffix(last, "_at"):
+		return ColumnTypeDateTime
+	case bytesColumnNames[last],
+		strings.HasSuffix(last, "_size"),
+		strings.HasSuffix(last, "_bytes"),
+		strings.HasSuffix(last, "_memory"):
+		return ColumnTypeBytes
+	case strings.HasPrefix(last, "is_"),
+		strings.HasPrefix(last, "has_"):
+		return ColumnTypeBool
+	case strings.HasPrefix(last, "number_of_"),
+		strings.HasPrefix(last, "days_since_"),
+		last == "count",
+		strings.HasSuffix(last, "_count"):
+		return ColumnTypeInt
+	case strings.HasPrefix(last, "avg"),
+		strings.HasPrefix(last, "average"),
+		last == "value",
+		strings.HasSuffix(last, "_value"),
+		strings.HasSuffix(last, "_ratio"),
+		strings.HasSuffix(last, "_percentage"),
+		strings.HasSuffix(last, "_rate"),
+		strings.HasSuffix(last, "_strength"),
+		strings.Contains(last, "score"):
+		return ColumnTypeFloat
+	default:
+		return ColumnTypeString
+	}
+}
+
+// =============================================================================
+// Query Inference
+// =============================================================================
+
+// aggregateFunctionPattern matches the aggregate or function applied at the end of an expression
+// Example: "boot.duration.avg()" -> "boot.duration", "avg"
+var aggregateFunctionPattern = regexp.MustCompile(`(?i)^(.*?)\.?\b(count|countif|sum|sumif|avg|average|min|max|last|first|percentile|ratio)\s*\(.*\)$`)
+
+// InferQueryColumnTypes infers the types of the columns produced by a query
+//
+// Columns of list statements and summarize/compute group-by fields are inferred
+// from their names (see InferColumnType). Computed columns ("alias = expr") are
+// inferred from the expression: count() is an int, avg() a float unless the
+// field is a duration or byte size, min/max/sum/last keep the field type, and
+// ratios and divisions are floats. Columns the query does not name are absent.
+func InferQueryColumnTypes(query string) map[string]ColumnType {
+	types := make(map[string]ColumnType)
+	statements, _ := SplitStatements(query)
+
+	for _, stmt := range statements {
+		switch stmt.Keyword {
+		case "list":
+			for _, field := range splitTopLevel(stmt.Body) {
+				types[field] = InferColumnType(field)
+			}
+
+		case "compute", "summarize":
+			body, groupBy := splitGroupBy(stmt.Body)
+			for _, item := range splitTopLevel(body) {
+				alias, expr, ok := strings.Cut(item, "=")
+				if !ok {
+					continue
+				}
+				alias = strings.TrimSpace(alias)
+				types[alias] = inferExpressionType(alias, strings.TrimSpace(expr))
+			}
+			for _, field := range splitTopLevel(groupBy) {
+				field = groupByColumn(field)
+				types[field] = InferColumnType(field)
+			}
+		}
+	}
+
+	return types
+}
+
+// timeBucketPattern matches a summarize time bucket such as 15min, 1 h or 1d
+var timeBucketPattern = regexp.MustCompile(`^\d+\s*(min|h|d)$`)
+
+// QueryColumn is an output column of a query with its inferred type
+type QueryColumn struct {
+	Name string
+	Type ColumnType
+}
+
+// InferQueryColumns returns the output columns of a query in result order
+//
+// The columns are those of the last list or summarize statement; summarize
+// yields its aggregations followed by its group-by fields. Queries without
+// either return their computed columns. Types follow InferQueryColumnTypes,
+// and listed fields computed earlier in the query keep the computed type.
+func InferQueryColumns(query string) []QueryColumn {
+	statements, _ := SplitStatements(query)
+	computed := make(map[string]ColumnType)
+	var columns []QueryColumn
+	projected := false
+
+	for _, stmt := range statements {
+		switch stmt.Keyword {
+		case "list":
+			fields := splitTopLevel(stmt.Body)
+			columns = make([]QueryColumn, 0, len(fields))
+			for _, field := range fields {
+				t, ok := computed[field]
+				if !ok {
+					t = InferColumnType(field)
+				}
+				columns = append(columns, QueryColumn{Name: field, Type: t})
+			}
+			projected = true
+
+		case "compute", "summarize":
+			body, groupBy := splitGroupBy(stmt.Body)
+			var out []QueryColumn
+			for _, item := range splitTopLevel(body) {
+				alias, expr, ok := strings.Cut(item, "=")
+				if !ok {
+					continue
+				}
+				alias = strings.TrimSpace(alias)
+				t := inferExpressionType(alias, strings.TrimSpace(expr))
+				computed[alias] = t
+				out = append(out, QueryColumn{Name: alias, Type: t})
+			}
+
+			if stmt.Keyword == "compute" {
+				if !projected {
+					columns = append(columns, out...)
+				}
+				continue
+			}
+			for _, field := range splitTopLevel(groupBy) {
+				field = groupByColumn(field)
+				out = append(out, QueryColumn{Name: field, Type: InferColumnType(field)})
+			}
+			columns = out
+			projected = true
+		}
+	}
+
+	return columns
+}
+
+// groupByColumn returns the output column of a summarize group-by field
+// "by 1d" or "by 15 min" buckets rows by time into a start_time column, and
+// fields may carry a bucket size ("start_time by 1d").
+func groupByColumn(field string) string {
+	field = strings.TrimSpace(field)
+	if timeBucketPattern.MatchString(field) {
+		return "start_time"
+	}
+	field, _, _ = strings.Cut(field, " ")
+	return field
+}
+
+// splitGroupBy splits a summarize body into its aggregations and its " by " fields
+func splitGroupBy(body string) (string, string) {
+	depth := 0
+	var quote rune
+	lower := strings.ToLower(body)
+
+	for i, r := range body {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case depth == 0 && strings.HasPrefix(lower[i:], " by "):
+			return strings.TrimSpace(body[:i]), strings.TrimSpace(body[i+4:])
+		}
+	}
+
+	return body, ""
+}
+
+// inferExpressionType infers the type of a computed column
+func inferExpressionType(alias, expr string) ColumnType {
+	match := aggregateFunctionPattern.FindStringSubmatch(expr)
+	if match == nil {
+		if strings.Contains(expr, "/") {
+			return ColumnTypeFloat
+		}
+		if fieldType := InferColumnType(expr); fieldType != ColumnTypeString {
+			return fieldType
+		}
+		return InferColumnType(alias)
+	}
+
+	field := strings.TrimSpace(match[1])
+	fieldType := InferColumnType(field)
+
+	switch strings.ToLower(match[2]) {
+	case "count", "countif":
+		return ColumnTypeInt
+	case "ratio":
+		return ColumnTypeFloat
+	case "avg", "average", "percentile":
+		if fieldType == ColumnTypeDuration || fieldType == ColumnTypeBytes {
+			return fieldType
+		}
+		return ColumnTypeFloat
+	default:
+		if fieldType != ColumnTypeString {
+			return fieldType
+		}
+		if aliasType := InferColumnType(alias); aliasType != ColumnTypeString {
+			return aliasType
+		}
+		if strings.HasPrefix(strings.ToLower(match[2]), "sum") {
+			return ColumnTypeFloat
+		}
+		return ColumnTypeString
+	}
+}
+
+// convertColumnValue converts a string cell to the Go type for t
+// int and bytes columns become int64, float float64, bool bool, datetime
+// time.Time (UTC) and duration time.Duration. Empty cells become nil.
+func convertColumnValue(t ColumnType, raw string) (any, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	switch t {
+	case ColumnTypeInt:
+		return coerceInt64(raw)
+	case ColumnTypeFloat:
+		return coerceFloat64(raw)
+	case ColumnTypeBool:
+		return coerceBool(raw)
+	case ColumnTypeDateTime:
+		return parseTimeValue(raw, time.UTC)
+	case ColumnTypeDuration:
+		return parseDurationValue(raw, time.Second)
+	case ColumnTypeBytes:
+		return parseByteSizeValue(raw)
+	default:
+		return raw, nil
+	}
+}
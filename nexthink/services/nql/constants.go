@@ -23,4 +23,8 @@ const (
 	MaxQueryIDLength  = 256
 	MaxPlatformLength = 50
 	MaxExportIDLength = 256
+
+	// MaxExecuteRows is the maximum number of rows the execute endpoints return
+	// Larger result sets are truncated; use the export API to retrieve them in full
+	MaxExecuteRows = 1000
 )
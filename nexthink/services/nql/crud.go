@@ -1,10 +1,10 @@
 package nql
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"time"
 
 	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
@@ -53,9 +53,13 @@ type (
 		//  - Rows: Number of rows returned
 		//  - ExecutionDateTime: ISO format string with execution timestamp
 		//  - Data: Array of objects (map[string]any) with key-value pairs
+		//  - Cache: How the response was served, when the cache is enabled
 		//
 		// V2 provides cleaner structured data compared to V1.
 		//
+		// When the service cache is enabled (EnableCache), identical requests are
+		// served from the cache and concurrent identical calls share one request.
+		//
 		// Use this for:
 		//  - Real-time queries with small result sets
 		//  - Interactive dashboards
@@ -162,6 +166,26 @@ type (
 		// Returns the raw export data as bytes (CSV or JSON format).
 		DownloadNQLExport(ctx context.Context, downloadURL string) ([]byte, error)
 
+		// DownloadNQLExportTo streams a completed export from the S3 URL to a writer
+		//
+		// Unlike DownloadNQLExport, the export is never held in memory, which makes
+		// this suitable for multi-gigabyte exports. Gzip-encoded objects are
+		// decompressed transparently and the received size is checked against
+		// the Content-Length reported by S3.
+		//
+		// Returns the number of bytes written to w.
+		DownloadNQLExportTo(ctx context.Context, downloadURL string, w io.Writer) (int64, error)
+
+		// DownloadNQLExportResumable downloads a completed export to a file, resuming on failure
+		//
+		// The export is written to a temporary "<path>.part" file. Interrupted transfers
+		// resume with HTTP Range requests after an exponential backoff, the result is
+		// verified against the ETag/Content-MD5 reported by S3, and the file is renamed
+		// to path only once complete.
+		//
+		// Returns details of the completed download.
+		DownloadNQLExportResumable(ctx context.Context, downloadURL, path string, opts *ResumableDownloadOptions) (*DownloadResult, error)
+
 		// WaitForNQLExport polls the export status until it completes or fails
 		//
 		// This is a convenience method that polls GetNQLExportStatus() at regular intervals
@@ -175,11 +199,65 @@ type (
 		//
 		// Returns the final status response when export completes or an error if it fails.
 		WaitForNQLExport(ctx context.Context, exportID string, pollInterval, timeout time.Duration) (*NQLExportStatusResponse, error)
+
+		// WaitForNQLExportWithStrategy polls the export status using a poll strategy
+		//
+		// Like WaitForNQLExport, but the wait between status checks comes from the
+		// strategy (fixed, exponential, exponential with jitter, capped). Use
+		// DefaultPollStrategy() to keep many concurrent exports within the rate limit.
+		//
+		// Transient status check failures (network errors, 429, 5xx) are tolerated up to
+		// DefaultMaxStatusErrors consecutive times, and Retry-After headers are respected.
+		//
+		// Returns the final status response when export completes or an error if it fails.
+		WaitForNQLExportWithStrategy(ctx context.Context, exportID string, strategy PollStrategy, timeout time.Duration) (*NQLExportStatusResponse, error)
+
+		// ResumeExport waits for an already started export and downloads its result
+		//
+		// Use after a restart to pick up an export started by ExportWorkflow instead of
+		// re-running the query. When opts.JobStore records the export, its format and
+		// output path are reused and the job is marked downloaded once the result has
+		// been fetched, so a completed export is downloaded exactly once.
+		//
+		// Returns ErrExportAlreadyDownloaded if the recorded job was already downloaded.
+		ResumeExport(ctx context.Context, exportID string, opts *ExportOptions) (*ExportResult, error)
+
+		// ExportMany runs several export workflows concurrently
+		//
+		// At most opts.Concurrency exports run at once and all export API calls share one
+		// rate budget (opts.RequestsPerSecond or opts.RateLimiter). Each export is streamed
+		// to its own writer (opts.WriterFor) or file (opts.OutputDir), or buffered.
+		//
+		// Returns a summary with one result per request. A failing job does not stop the
+		// others; the error joins the job failures and is nil when every job succeeded.
+		ExportMany(ctx context.Context, reqs []ExportRequest, opts *BatchExportOptions) (*BatchExportSummary, error)
+
+		// Query runs a query and returns all of its rows
+		//
+		// Executes the query with ExecuteNQLV2 and, when the result reaches the execute
+		// row limit (MaxExecuteRows), re-runs it through the export API and streams the
+		// full result. Either way the rows are returned as a V2 result set.
+		Query(ctx context.Context, req *ExecuteRequest) (*QueryResult, error)
+
+		// QueryWithOptions runs a query like Query with a custom truncation threshold
+		// and fallback export options
+		QueryWithOptions(ctx context.Context, req *ExecuteRequest, opts *QueryOptions) (*QueryResult, error)
+
+		// ExecuteAcrossPlatforms runs a query on several platforms concurrently and merges the results
+		//
+		// The merged result set has a synthetic "platform" column (PlatformColumn), and
+		// columns returned by only some platforms are nil for the others. Per-platform
+		// failures are reported in the result rather than failing the call; an error is
+		// returned only for invalid input or when every platform failed.
+		ExecuteAcrossPlatforms(ctx context.Context, queryID string, platforms []string) (*MultiPlatformResult, error)
 	}
 
 	// Service implements the NQLServiceInterface
 	Service struct {
 		client interfaces.HTTPClient
+
+		// cache is the optional ExecuteNQLV2 cache (see EnableCache)
+		cache *executeCache
 	}
 )
 
@@ -223,11 +301,21 @@ func (s *Service) ExecuteNQLV1(ctx context.Context, req *ExecuteRequest) (*Execu
 // ExecuteNQLV2 executes an NQL query synchronously using API V2
 // URL: POST https://instance.api.region.nexthink.cloud/api/v2/nql/execute
 // Nexthink API docs: https://docs.nexthink.com/api/nql/execute-an-nql
+// When the cache is enabled (see EnableCache) responses may be served from it.
 func (s *Service) ExecuteNQLV2(ctx context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error) {
 	if err := ValidateExecuteRequest(req); err != nil {
 		return nil, nil, err
 	}
 
+	if cache := s.cache; cache != nil {
+		return cache.execute(ctx, req, s.executeNQLV2)
+	}
+
+	return s.executeNQLV2(ctx, req)
+}
+
+// executeNQLV2 sends an execute request to the API without the cache
+func (s *Service) executeNQLV2(ctx context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error) {
 	endpoint := EndpointNqlExecuteV2
 
 	headers := map[string]string{
@@ -297,43 +385,21 @@ func (s *Service) GetNQLExportStatus(ctx context.Context, exportID string) (*NQL
 
 // DownloadNQLExport downloads a completed export from an S3 pre-signed URL.
 //
-// Note: This method uses a standard HTTP client (not the SDK transport) because:
-//   - S3 URLs are external to the Nexthink API
-//   - They don't require Nexthink authentication
-//   - They're pre-signed with temporary credentials from AWS
-//   - The download is a simple GET request to AWS S3
+// Note: S3 URLs are external to the Nexthink API and pre-signed with temporary AWS
+// credentials, so the download uses the client's unauthenticated download client:
+//   - Proxy, TLS, custom transport and tracing settings of the SDK client apply
+//   - The Nexthink bearer token is never sent to S3
+//   - Connection failures and transient statuses are retried with the client's retry policy
+//   - The timeout is set with client.WithDownloadTimeout
 //
-// The HTTP client is configured with a 5-minute timeout for large downloads.
+// The whole export is buffered in memory. Use DownloadNQLExportTo for large exports.
 func (s *Service) DownloadNQLExport(ctx context.Context, downloadURL string) ([]byte, error) {
-	if downloadURL == "" {
-		return nil, fmt.Errorf("download URL cannot be empty")
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %w", err)
-	}
-
-	client := &http.Client{
-		Timeout: 5 * time.Minute,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download export: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, resp.Status)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read export data: %w", err)
+	var buf bytes.Buffer
+	if _, err := s.downloadExportTo(ctx, downloadURL, &buf, nil); err != nil {
+		return nil, err
 	}
 
-	return data, nil
+	return buf.Bytes(), nil
 }
 
 // WaitForNQLExport polls the export status until it completes or fails
@@ -350,37 +416,29 @@ func (s *Service) WaitForNQLExport(ctx context.Context, exportID string, pollInt
 		timeout = 10 * time.Minute
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
+	return s.WaitForNQLExportWithStrategy(ctx, exportID, NewFixedPollStrategy(pollInterval), timeout)
+}
 
-	status, _, err := s.GetNQLExportStatus(timeoutCtx, exportID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get initial export status: %w", err)
+// WaitForNQLExportWithStrategy polls the export status using a poll strategy until it completes or fails
+// A nil strategy uses DefaultPollStrategy.
+func (s *Service) WaitForNQLExportWithStrategy(ctx context.Context, exportID string, strategy PollStrategy, timeout time.Duration) (*NQLExportStatusResponse, error) {
+	if err := ValidateExportID(exportID); err != nil {
+		return nil, err
 	}
 
-	if isTerminalStatus(status.Status) {
-		return status, nil
+	if strategy == nil {
+		strategy = DefaultPollStrategy()
 	}
 
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			return status, fmt.Errorf("timeout waiting for export to complete after %v", timeout)
-
-		case <-ticker.C:
-			status, _, err = s.GetNQLExportStatus(timeoutCtx, exportID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get export status: %w", err)
-			}
-
-			if isTerminalStatus(status.Status) {
-				return status, nil
-			}
-		}
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
 	}
+
+	return s.pollExportStatus(ctx, exportID, pollConfig{
+		strategy:        strategy,
+		timeout:         timeout,
+		maxStatusErrors: DefaultMaxStatusErrors,
+	})
 }
 
 // ExecuteQueryBuilder executes an NQL query built with QueryBuilder
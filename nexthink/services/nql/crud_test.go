@@ -15,7 +15,8 @@ import (
 )
 
 // setupMockClient creates a test client with httpmock activated
-func setupMockClient(t *testing.T) (*Service, string) {
+// Additional client options are applied after the defaults
+func setupMockClient(t *testing.T, options ...client.ClientOption) (*Service, string) {
 	t.Helper()
 
 	logger := zap.NewNop()
@@ -40,12 +41,13 @@ func setupMockClient(t *testing.T) (*Service, string) {
 		}))
 
 	// Create transport with the mocked HTTP transport
-	transport, err := client.NewTransport("client-id", "client-secret", "test-instance", "us",
+	options = append([]client.ClientOption{
 		client.WithLogger(logger),
 		client.WithBaseURL(baseURL),
 		client.WithCustomTokenURL(tokenURL),
 		client.WithTransport(httpClient.Transport),
-	)
+	}, options...)
+	transport, err := client.NewTransport("client-id", "client-secret", "test-instance", "us", options...)
 	require.NoError(t, err)
 
 	return NewService(transport), baseURL
@@ -1,5 +1,7 @@
 package nql
 
+import "strings"
+
 // Data model constants for NQL queries
 // Provides type-safe access to table names, namespaces, and common fields
 
@@ -295,3 +297,45 @@ const (
 	LocationTypeRemote = "Remote"
 	LocationTypeOffice = "Office"
 )
+
+// =============================================================================
+// Deprecated Fields
+// =============================================================================
+
+// DeprecatedFields maps legacy field names to their current replacement
+// The legacy names are still accepted but should not be used in new queries
+var DeprecatedFields = map[string]string{
+	FieldUsername: FieldUserName,
+}
+
+// =============================================================================
+// Table Helpers
+// =============================================================================
+
+// inventoryTables lists the inventory object tables (shortcut and full forms)
+var inventoryTables = map[string]bool{
+	TableDevices:                   true,
+	TableUsers:                     true,
+	TableApplications:              true,
+	TableBinaries:                  true,
+	TableCampaigns:                 true,
+	TablePackages:                  true,
+	"device.devices":               true,
+	"user.users":                   true,
+	"application.applications":     true,
+	"binary.binaries":              true,
+	"campaign.campaigns":           true,
+	"package.packages":             true,
+	"remote_action.remote_actions": true,
+	"workflow.workflows":           true,
+}
+
+// IsInventoryTable checks if the table is an inventory object table
+func IsInventoryTable(table string) bool {
+	return inventoryTables[table]
+}
+
+// IsEventTable checks if the table is an event table (namespace.table format)
+func IsEventTable(table string) bool {
+	return strings.Contains(table, ".") && !inventoryTables[table]
+}
@@ -0,0 +1,535 @@
+package nql
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct decoding maps NQL result rows onto Go structs using `nql` struct tags
+//
+//	type Device struct {
+//	    Name     string        `nql:"device.name"`
+//	    Platform string        `nql:"operating_system.platform,enum=Windows|macOS|Linux"`
+//	    Memory   int64         `nql:"hardware.memory,bytes"`
+//	    Boot     time.Duration `nql:"boot.duration"`
+//	    LastSeen *time.Time    `nql:"device.last_seen"`
+//	    Internal string        `nql:"-"`
+//	}
+//
+// Untagged exported fields match columns by case-insensitive field name.
+// Supported tag options:
+//   - bytes: parse formatted byte sizes ("1.5 GB") into integer fields
+//   - enum=a|b|c: reject string values outside the listed set
+
+// =============================================================================
+// Row Source
+// =============================================================================
+
+// RowSource is implemented by result sets whose rows can be addressed by column name
+// Both V1ResultSet and V2ResultSet implement RowSource.
+type RowSource interface {
+	// Rows returns the number of rows
+	Rows() int
+
+	// ColumnNames returns the column names in a stable order
+	ColumnNames() []string
+
+	// RowMap returns a row as a map keyed by column name
+	RowMap(row int) (map[string]any, error)
+}
+
+var (
+	_ RowSource = (*V1ResultSet)(nil)
+	_ RowSource = (*V2ResultSet)(nil)
+)
+
+// =============================================================================
+// Decode Options
+// =============================================================================
+
+// DecodeOptions configures struct decoding
+type DecodeOptions struct {
+	// Strict reports columns that map to no field, and tagged fields whose
+	// column is absent from the result set
+	Strict bool
+
+	// DurationUnit is the unit applied to plain numeric values decoded into
+	// time.Duration fields. Defaults to time.Second
+	DurationUnit time.Duration
+
+	// Location is used for datetimes without an explicit offset (V1 DateTime
+	// objects, naive ISO strings). Defaults to UTC
+	Location *time.Location
+}
+
+// withDefaults returns a copy of the options with defaults applied
+func (opts *DecodeOptions) withDefaults() DecodeOptions {
+	var o DecodeOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.DurationUnit <= 0 {
+		o.DurationUnit = time.Second
+	}
+	if o.Location == nil {
+		o.Location = time.UTC
+	}
+	return o
+}
+
+// =============================================================================
+// Public Decode Functions
+// =============================================================================
+
+// Decode decodes all rows into dest, which must be a pointer to a slice of structs
+// (or struct pointers)
+// Example: var devices []Device; err := rs.Decode(&devices)
+func (rs *V2ResultSet) Decode(dest any) error {
+	return DecodeInto(rs, dest, nil)
+}
+
+// DecodeWithOptions decodes all rows into dest using the given options
+func (rs *V2ResultSet) DecodeWithOptions(dest any, opts *DecodeOptions) error {
+	return DecodeInto(rs, dest, opts)
+}
+
+// Decode decodes all rows into dest, which must be a pointer to a slice of structs
+// (or struct pointers). Columns are matched using the V1 headers.
+func (rs *V1ResultSet) Decode(dest any) error {
+	return DecodeInto(rs, dest, nil)
+}
+
+// DecodeWithOptions decodes all rows into dest using the given options
+func (rs *V1ResultSet) DecodeWithOptions(dest any, opts *DecodeOptions) error {
+	return DecodeInto(rs, dest, opts)
+}
+
+// DecodeRows decodes every row of a result set into a slice of T
+// Example: devices, err := nql.DecodeRows[Device](rs, &nql.DecodeOptions{Strict: true})
+func DecodeRows[T any](src RowSource, opts *DecodeOptions) ([]T, error) {
+	var result []T
+	if err := DecodeInto(src, &result, opts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DecodeInto decodes every row of a result set into dest, which must be a
+// pointer to a slice of structs or struct pointers
+func DecodeInto(src RowSource, dest any, opts *DecodeOptions) error {
+	if src == nil {
+		return fmt.Errorf("result set cannot be nil")
+	}
+	if v := reflect.ValueOf(src); v.Kind() == reflect.Pointer && v.IsNil() {
+		return fmt.Errorf("result set cannot be nil")
+	}
+
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Pointer || destVal.IsNil() || destVal.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("decode destination must be a non-nil pointer to a slice, got %T", dest)
+	}
+
+	sliceVal := destVal.Elem()
+	elemType := sliceVal.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("decode destination element must be a struct or struct pointer, got %s", elemType)
+	}
+
+	o := opts.withDefaults()
+	plan := planFor(structType)
+
+	columns := src.ColumnNames()
+	if o.Strict {
+		// Column names come from the returned rows, so an empty result has none to check
+		if err := plan.checkStrict(columns, src.Rows() > 0); err != nil {
+			return err
+		}
+	}
+	bindings := plan.bind(columns)
+
+	result := reflect.MakeSlice(sliceVal.Type(), 0, src.Rows())
+	for i := 0; i < src.Rows(); i++ {
+		row, err := src.RowMap(i)
+		if err != nil {
+			return err
+		}
+
+		elem := reflect.New(structType).Elem()
+		if err := decodeRow(row, elem, bindings, &o); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+
+		if elemType.Kind() == reflect.Pointer {
+			result = reflect.Append(result, elem.Addr())
+		} else {
+			result = reflect.Append(result, elem)
+		}
+	}
+
+	sliceVal.Set(result)
+	return nil
+}
+
+// DecodeRow decodes a single row map into dest, which must be a pointer to a struct
+// Useful when processing rows one at a time (e.g. from IterateRows)
+func DecodeRow(row map[string]any, dest any, opts *DecodeOptions) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Pointer || destVal.IsNil() || destVal.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+
+	o := opts.withDefaults()
+	plan := planFor(destVal.Elem().Type())
+
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	if o.Strict {
+		if err := plan.checkStrict(columns, true); err != nil {
+			return err
+		}
+	}
+
+	return decodeRow(row, destVal.Elem(), plan.bind(columns), &o)
+}
+
+// =============================================================================
+// Decode Plan
+// =============================================================================
+
+// decodeField describes how a struct field maps to a result column
+type decodeField struct {
+	index    []int
+	name     string // Go field name, for error messages
+	column   string // column name from tag, or field name when untagged
+	tagged   bool
+	bytes    bool
+	enum     map[string]bool
+	enumList string
+}
+
+// decodePlan is the cached field mapping for a struct type
+type decodePlan struct {
+	fields []decodeField
+}
+
+// binding pairs a field with the actual column name found in the result set
+type binding struct {
+	field  *decodeField
+	column string
+}
+
+// planCache caches decode plans by struct type
+var planCache sync.Map
+
+// planFor returns the (cached) decode plan for a struct type
+func planFor(t reflect.Type) *decodePlan {
+	if cached, ok := planCache.Load(t); ok {
+		return cached.(*decodePlan)
+	}
+
+	plan := &decodePlan{}
+	collectFields(t, nil, plan)
+
+	actual, _ := planCache.LoadOrStore(t, plan)
+	return actual.(*decodePlan)
+}
+
+// collectFields walks a struct type (flattening embedded structs) and records mapped fields
+func collectFields(t reflect.Type, parent []int, plan *decodePlan) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag, hasTag := sf.Tag.Lookup("nql")
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct {
+			collectFields(sf.Type, index, plan)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		field := decodeField{
+			index:  index,
+			name:   sf.Name,
+			column: sf.Name,
+		}
+
+		if hasTag {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				field.column = parts[0]
+				field.tagged = true
+			}
+			for _, opt := range parts[1:] {
+				switch {
+				case opt == "bytes":
+					field.bytes = true
+				case strings.HasPrefix(opt, "enum="):
+					field.enumList = strings.TrimPrefix(opt, "enum=")
+					field.enum = make(map[string]bool)
+					for _, v := range strings.Split(field.enumList, "|") {
+						field.enum[v] = true
+					}
+				}
+			}
+		}
+
+		plan.fields = append(plan.fields, field)
+	}
+}
+
+// bind resolves each field to a column present in the result set
+func (p *decodePlan) bind(columns []string) []binding {
+	exact := make(map[string]bool, len(columns))
+	folded := make(map[string]string, len(columns))
+	for _, column := range columns {
+		exact[column] = true
+		folded[strings.ToLower(column)] = column
+	}
+
+	bindings := make([]binding, 0, len(p.fields))
+	for i := range p.fields {
+		field := &p.fields[i]
+		switch {
+		case exact[field.column]:
+			bindings = append(bindings, binding{field: field, column: field.column})
+		case !field.tagged && folded[strings.ToLower(field.column)] != "":
+			bindings = append(bindings, binding{field: field, column: folded[strings.ToLower(field.column)]})
+		}
+	}
+
+	return bindings
+}
+
+// checkStrict reports unknown columns and, when checkMissing is set, missing tagged columns
+func (p *decodePlan) checkStrict(columns []string, checkMissing bool) error {
+	bound := make(map[string]bool)
+	for _, b := range p.bind(columns) {
+		bound[b.column] = true
+	}
+
+	present := make(map[string]bool, len(columns))
+	var unknown []string
+	for _, column := range columns {
+		present[column] = true
+		if !bound[column] {
+			unknown = append(unknown, column)
+		}
+	}
+
+	var missing []string
+	for _, field := range p.fields {
+		if checkMissing && field.tagged && !present[field.column] {
+			missing = append(missing, field.column)
+		}
+	}
+
+	var problems []string
+	if len(unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("unknown columns: %s", strings.Join(unknown, ", ")))
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing columns: %s", strings.Join(missing, ", ")))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("strict decode failed: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// =============================================================================
+// Value Assignment
+// =============================================================================
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	dateTimeType        = reflect.TypeOf(DateTime{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodeRow assigns the bound columns of a row to the struct value
+func decodeRow(row map[string]any, elem reflect.Value, bindings []binding, opts *DecodeOptions) error {
+	for _, b := range bindings {
+		raw, ok := row[b.column]
+		if !ok {
+			continue
+		}
+
+		target := elem.FieldByIndex(b.field.index)
+		if err := assignValue(target, raw, b.field, opts); err != nil {
+			return fmt.Errorf("column %q (field %s): %w", b.column, b.field.name, err)
+		}
+	}
+	return nil
+}
+
+// assignValue coerces a raw result value into the target field
+func assignValue(target reflect.Value, raw any, field *decodeField, opts *DecodeOptions) error {
+	if raw == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	if target.Kind() == reflect.Pointer {
+		ptr := reflect.New(target.Type().Elem())
+		if err := assignValue(ptr.Elem(), raw, field, opts); err != nil {
+			return err
+		}
+		target.Set(ptr)
+		return nil
+	}
+
+	switch target.Type() {
+	case timeType:
+		t, err := parseTimeValue(raw, opts.Location)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := parseDurationValue(raw, opts.DurationUnit)
+		if err != nil {
+			return err
+		}
+		target.SetInt(int64(d))
+		return nil
+	case dateTimeType:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to DateTime", raw)
+		}
+		dt, err := dateTimeFromMap(m)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(*dt))
+		return nil
+	}
+
+	if s, ok := raw.(string); ok && reflect.PointerTo(target.Type()).Implements(textUnmarshalerType) {
+		if err := checkEnum(s, field); err != nil {
+			return err
+		}
+		return target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		s := coerceString(raw)
+		if err := checkEnum(s, field); err != nil {
+			return err
+		}
+		target.SetString(s)
+
+	case reflect.Bool:
+		b, err := coerceBool(raw)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := coerceIntField(raw, field)
+		if err != nil {
+			return err
+		}
+		if target.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, target.Type())
+		}
+		target.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := coerceIntField(raw, field)
+		if err != nil {
+			return err
+		}
+		if n < 0 || target.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value %d overflows %s", n, target.Type())
+		}
+		target.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		f, err := coerceFloat64(raw)
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			// Treat a scalar as a single-element list
+			items = []any{raw}
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assignValue(slice.Index(i), item, field, opts); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		target.Set(slice)
+
+	default:
+		rawVal := reflect.ValueOf(raw)
+		switch {
+		case rawVal.Type().AssignableTo(target.Type()):
+			target.Set(rawVal)
+		case rawVal.Type().ConvertibleTo(target.Type()):
+			target.Set(rawVal.Convert(target.Type()))
+		default:
+			return fmt.Errorf("cannot convert %T to %s", raw, target.Type())
+		}
+	}
+
+	return nil
+}
+
+// coerceIntField converts a value for an integer field, parsing byte sizes when
+// requested by the tag or when the value is a formatted size string
+func coerceIntField(raw any, field *decodeField) (int64, error) {
+	if field.bytes {
+		return parseByteSizeValue(raw)
+	}
+
+	n, err := coerceInt64(raw)
+	if err != nil {
+		if _, isString := raw.(string); isString {
+			if size, sizeErr := parseByteSizeValue(raw); sizeErr == nil {
+				return size, nil
+			}
+		}
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// checkEnum validates a string value against the field's enum option
+func checkEnum(value string, field *decodeField) error {
+	if field.enum == nil || field.enum[value] {
+		return nil
+	}
+	return fmt.Errorf("value %q is not one of %s", value, strings.ReplaceAll(field.enumList, "|", ", "))
+}
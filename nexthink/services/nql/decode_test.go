@@ -0,0 +1,218 @@
+package nql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type decodeTestDevice struct {
+	Name     string        `nql:"device.name"`
+	Platform string        `nql:"operating_system.platform,enum=Windows|macOS|Linux"`
+	Memory   int64         `nql:"hardware.memory,bytes"`
+	Boot     time.Duration `nql:"boot.duration"`
+	LastSeen *time.Time    `nql:"device.last_seen"`
+	Crashes  int           `nql:"total_crashes"`
+	Score    float64       `nql:"dex.score"`
+	Tags     []string      `nql:"device.tags"`
+	Ignored  string        `nql:"-"`
+	Entity   string
+}
+
+type upperPlatform string
+
+func (p *upperPlatform) UnmarshalText(text []byte) error {
+	*p = upperPlatform(strings.ToUpper(string(text)))
+	return nil
+}
+
+func newDecodeTestResultSet() *V2ResultSet {
+	return NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{
+			{
+				"device.name":               "device-01",
+				"operating_system.platform": "Windows",
+				"hardware.memory":           "16 GB",
+				"boot.duration":             "12.5 s",
+				"device.last_seen":          "2024-02-08T10:15:30Z",
+				"total_crashes":             float64(3),
+				"dex.score":                 "87.5",
+				"device.tags":               []any{"vdi", "finance"},
+				"entity":                    "HQ",
+			},
+			{
+				"device.name":               "device-02",
+				"operating_system.platform": "macOS",
+				"hardware.memory":           float64(8589934592),
+				"boot.duration":             float64(30),
+				"device.last_seen":          nil,
+				"total_crashes":             "0",
+				"dex.score":                 float64(92),
+				"device.tags":               nil,
+				"entity":                    "Remote",
+			},
+		},
+	})
+}
+
+func TestV2ResultSet_Decode(t *testing.T) {
+	var devices []decodeTestDevice
+	require.NoError(t, newDecodeTestResultSet().Decode(&devices))
+	require.Len(t, devices, 2)
+
+	first := devices[0]
+	assert.Equal(t, "device-01", first.Name)
+	assert.Equal(t, "Windows", first.Platform)
+	assert.Equal(t, int64(16<<30), first.Memory)
+	assert.Equal(t, 12500*time.Millisecond, first.Boot)
+	require.NotNil(t, first.LastSeen)
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC), *first.LastSeen)
+	assert.Equal(t, 3, first.Crashes)
+	assert.Equal(t, 87.5, first.Score)
+	assert.Equal(t, []string{"vdi", "finance"}, first.Tags)
+	assert.Equal(t, "HQ", first.Entity, "untagged field should match case-insensitively")
+
+	second := devices[1]
+	assert.Equal(t, int64(8<<30), second.Memory)
+	assert.Equal(t, 30*time.Second, second.Boot)
+	assert.Nil(t, second.LastSeen)
+	assert.Nil(t, second.Tags)
+}
+
+func TestDecodeRows_Generic(t *testing.T) {
+	devices, err := DecodeRows[*decodeTestDevice](newDecodeTestResultSet(), &DecodeOptions{DurationUnit: time.Millisecond})
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, 30*time.Millisecond, devices[1].Boot)
+}
+
+func TestDecode_EnumViolation(t *testing.T) {
+	rs := NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{{"operating_system.platform": "Solaris"}},
+	})
+
+	var devices []decodeTestDevice
+	err := rs.Decode(&devices)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "row 0")
+	assert.Contains(t, err.Error(), "is not one of Windows, macOS, Linux")
+}
+
+func TestDecode_TextUnmarshaler(t *testing.T) {
+	type row struct {
+		Platform upperPlatform `nql:"operating_system.platform"`
+	}
+
+	rs := NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{{"operating_system.platform": "macOS"}},
+	})
+
+	rows, err := DecodeRows[row](rs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, upperPlatform("MACOS"), rows[0].Platform)
+}
+
+func TestDecode_Strict(t *testing.T) {
+	type row struct {
+		Name    string `nql:"device.name"`
+		Missing string `nql:"device.missing"`
+	}
+
+	rs := NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{{"device.name": "a", "device.extra": 1}},
+	})
+
+	_, err := DecodeRows[row](rs, &DecodeOptions{Strict: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown columns: device.extra")
+	assert.Contains(t, err.Error(), "missing columns: device.missing")
+
+	rows, err := DecodeRows[row](rs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a", rows[0].Name)
+}
+
+func TestDecode_StrictEmptyResult(t *testing.T) {
+	type row struct {
+		Name string `nql:"device.name"`
+	}
+
+	rs := NewV2ResultSet(&ExecuteNQLV2Response{Data: []map[string]any{}})
+
+	rows, err := DecodeRows[row](rs, &DecodeOptions{Strict: true})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestV1ResultSet_Decode(t *testing.T) {
+	type row struct {
+		Name     string    `nql:"device.name"`
+		Count    int64     `nql:"count"`
+		LastSeen time.Time `nql:"last_seen"`
+		Raw      DateTime  `nql:"raw"`
+	}
+
+	dt := map[string]any{"year": float64(2024), "month": float64(2), "day": float64(8), "hour": float64(10)}
+	rs := NewV1ResultSet(&ExecuteNQLV1Response{
+		Headers: []string{"device.name", "count", "last_seen", "raw"},
+		Data: [][]any{
+			{"device-01", float64(42), dt, dt},
+		},
+	})
+
+	var rows []row
+	require.NoError(t, rs.Decode(&rows))
+	require.Len(t, rows, 1)
+	assert.Equal(t, "device-01", rows[0].Name)
+	assert.Equal(t, int64(42), rows[0].Count)
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 0, 0, 0, time.UTC), rows[0].LastSeen)
+	assert.Equal(t, int64(2024), rows[0].Raw.Year)
+}
+
+func TestDecode_InvalidDestination(t *testing.T) {
+	rs := newDecodeTestResultSet()
+
+	var notSlice decodeTestDevice
+	assert.Error(t, rs.Decode(&notSlice))
+
+	var ints []int
+	assert.Error(t, rs.Decode(&ints))
+
+	assert.Error(t, DecodeInto(nil, &ints, nil))
+}
+
+func TestDecodeRow(t *testing.T) {
+	var device decodeTestDevice
+	err := DecodeRow(map[string]any{"device.name": "x", "hardware.memory": "512 MB"}, &device, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "x", device.Name)
+	assert.Equal(t, int64(512<<20), device.Memory)
+}
+
+func TestParseValueHelpers(t *testing.T) {
+	d, err := parseDurationValue("2 h 5 min", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour+5*time.Minute, d)
+
+	d, err = parseDurationValue("150 ms", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 150*time.Millisecond, d)
+
+	_, err = parseDurationValue("soon", 0)
+	assert.Error(t, err)
+
+	size, err := parseByteSizeValue("1.5 KB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1536), size)
+
+	_, err = parseByteSizeValue("lots")
+	assert.Error(t, err)
+
+	ts, err := parseTimeValue("2024-02-08 10:00:00", time.FixedZone("CET", 3600))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02-08T09:00:00Z", ts.UTC().Format(time.RFC3339))
+}
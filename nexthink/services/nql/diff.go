@@ -0,0 +1,429 @@
+package nql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result set diffs compare two executions of a query row by row, and snapshots
+// persist a result set so it can be diffed against a later execution
+
+// =============================================================================
+// Diff
+// =============================================================================
+
+// FieldChange is the before and after value of one changed field
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// ChangedRow is a row present in both result sets with different field values
+type ChangedRow struct {
+	// Key holds the key field values identifying the row
+	Key map[string]any `json:"key"`
+
+	// Before is the row in the previous result set
+	Before map[string]any `json:"before"`
+
+	// After is the row in the current result set
+	After map[string]any `json:"after"`
+
+	// Changes lists the changed fields, sorted by field name
+	Changes []FieldChange `json:"changes"`
+}
+
+// ResultSetDiff is the difference between two result sets
+type ResultSetDiff struct {
+	// KeyFields are the fields identifying a row
+	KeyFields []string `json:"keyFields"`
+
+	// Added holds the rows only in the current result set, in current order
+	Added []map[string]any `json:"added"`
+
+	// Removed holds the rows only in the previous result set, in previous order
+	Removed []map[string]any `json:"removed"`
+
+	// Changed holds the rows whose values differ, in current order
+	Changed []ChangedRow `json:"changed"`
+
+	// Unchanged is the number of rows identical in both result sets
+	Unchanged int `json:"unchanged"`
+}
+
+// HasChanges checks if any row was added, removed or changed
+func (d *ResultSetDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// String returns a one-line summary of the diff
+func (d *ResultSetDiff) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed, %d unchanged",
+		len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged)
+}
+
+// DiffOptions configures DiffWithOptions
+type DiffOptions struct {
+	// KeyFields are the fields identifying a row (required)
+	KeyFields []string
+
+	// CompareFields optionally restricts the comparison to these fields
+	// By default every field except the key fields is compared
+	CompareFields []string
+
+	// IgnoreFields are never compared (for example volatile counters or timestamps)
+	IgnoreFields []string
+}
+
+// Diff compares two result sets row by row, matching rows on the key fields
+// Example: diff, err := nql.Diff(yesterday, today, "device.name")
+func Diff(prev, curr *V2ResultSet, keyFields ...string) (*ResultSetDiff, error) {
+	return DiffWithOptions(prev, curr, &DiffOptions{KeyFields: keyFields})
+}
+
+// DiffWithOptions compares two result sets row by row
+//
+// Rows are matched on the key fields; a nil result set is treated as empty.
+// Numeric values compare by value, so a row loaded from a snapshot matches the
+// same row from a live execution. Duplicate keys within one result set are an error.
+func DiffWithOptions(prev, curr *V2ResultSet, opts *DiffOptions) (*ResultSetDiff, error) {
+	if opts == nil || len(opts.KeyFields) == 0 {
+		return nil, fmt.Errorf("at least one key field is required")
+	}
+
+	prevIndex, err := indexRowsByKey(prev, opts.KeyFields)
+	if err != nil {
+		return nil, fmt.Errorf("previous result set: %w", err)
+	}
+	currIndex, err := indexRowsByKey(curr, opts.KeyFields)
+	if err != nil {
+		return nil, fmt.Errorf("current result set: %w", err)
+	}
+
+	diff := &ResultSetDiff{KeyFields: opts.KeyFields}
+	skip := make(map[string]bool)
+	for _, field := range opts.KeyFields {
+		skip[field] = true
+	}
+	for _, field := range opts.IgnoreFields {
+		skip[field] = true
+	}
+
+	for _, row := range rowsOf(curr) {
+		before, ok := prevIndex[diffKey(row, opts.KeyFields)]
+		if !ok {
+			diff.Added = append(diff.Added, row)
+			continue
+		}
+
+		changes := diffRow(before, row, opts.CompareFields, skip)
+		if len(changes) == 0 {
+			diff.Unchanged++
+			continue
+		}
+
+		key := make(map[string]any, len(opts.KeyFields))
+		for _, field := range opts.KeyFields {
+			key[field] = row[field]
+		}
+		diff.Changed = append(diff.Changed, ChangedRow{
+			Key:     key,
+			Before:  before,
+			After:   row,
+			Changes: changes,
+		})
+	}
+
+	for _, row := range rowsOf(prev) {
+		if _, ok := currIndex[diffKey(row, opts.KeyFields)]; !ok {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+
+	return diff, nil
+}
+
+// rowsOf returns the rows of a possibly nil result set
+func rowsOf(rs *V2ResultSet) []map[string]any {
+	if rs == nil {
+		return nil
+	}
+	return rs.data
+}
+
+// indexRowsByKey indexes rows by their key field values
+func indexRowsByKey(rs *V2ResultSet, keyFields []string) (map[string]map[string]any, error) {
+	rows := rowsOf(rs)
+	index := make(map[string]map[string]any, len(rows))
+	for i, row := range rows {
+		key := diffKey(row, keyFields)
+		if _, exists := index[key]; exists {
+			return nil, fmt.Errorf("duplicate key at row %d: %v", i, keyValues(row, keyFields))
+		}
+		index[key] = row
+	}
+	return index, nil
+}
+
+// keyValues returns the key field values of a row for error messages
+func keyValues(row map[string]any, keyFields []string) []any {
+	values := make([]any, len(keyFields))
+	for i, field := range keyFields {
+		values[i] = row[field]
+	}
+	return values
+}
+
+// diffRow compares two rows, returning the changed fields sorted by name
+func diffRow(before, after map[string]any, compareFields []string, skip map[string]bool) []FieldChange {
+	fields := compareFields
+	if len(fields) == 0 {
+		seen := make(map[string]bool)
+		for field := range before {
+			seen[field] = true
+		}
+		for field := range after {
+			seen[field] = true
+		}
+		for field := range seen {
+			fields = append(fields, field)
+		}
+	}
+
+	var changes []FieldChange
+	for _, field := range fields {
+		if skip[field] {
+			continue
+		}
+		if !valuesEqual(before[field], after[field]) {
+			changes = append(changes, FieldChange{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// valuesEqual compares two result values after normalization
+// Numbers of different types are equal by value, durations equal their length
+// in seconds and times equal their string form.
+func valuesEqual(a, b any) bool {
+	na, nb := normalizeDiffValue(a), normalizeDiffValue(b)
+
+	if ta, ok := na.(time.Time); ok {
+		if tb, ok := nb.(time.Time); ok {
+			return ta.Equal(tb)
+		}
+		return false
+	}
+
+	return reflect.DeepEqual(na, nb)
+}
+
+// diffKey builds the key of a row from normalized key field values
+// Typed rows (export readers) and JSON rows (snapshots) get the same key.
+func diffKey(row map[string]any, keyFields []string) string {
+	var b strings.Builder
+	for _, field := range keyFields {
+		switch v := normalizeDiffValue(row[field]).(type) {
+		case nil:
+			b.WriteString("\x00nil")
+		case time.Time:
+			b.WriteString("t:" + v.UTC().Format(time.RFC3339Nano))
+		case float64:
+			b.WriteString("n:" + strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			b.WriteString("b:" + strconv.FormatBool(v))
+		default:
+			b.WriteString("s:" + coerceString(v))
+		}
+		b.WriteByte('\x1f')
+	}
+	return b.String()
+}
+
+// normalizeDiffValue converts a value to the form its JSON round trip compares as
+// Numbers become float64, durations seconds, and times and datetime strings time.Time.
+func normalizeDiffValue(val any) any {
+	switch v := val.(type) {
+	case nil, bool:
+		return v
+	case time.Time:
+		return v
+	case *DateTime, DateTime:
+		if t, err := parseTimeValue(v, time.UTC); err == nil {
+			return t
+		}
+		return coerceString(v)
+	case time.Duration:
+		return v.Seconds()
+	case string:
+		s := strings.TrimSpace(v)
+		if len(s) >= len("2006-01-02") && s[4] == '-' {
+			for _, layout := range timeLayouts {
+				if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+					return t
+				}
+			}
+		}
+		return v
+	}
+
+	if f, err := coerceFloat64(val); err == nil {
+		return f
+	}
+	return coerceString(val)
+}
+
+// snapshotValue converts a value to the form it is compared in once read back
+// Durations are stored as seconds, as the execute endpoint returns them.
+func snapshotValue(val any) any {
+	if d, ok := val.(time.Duration); ok {
+		return d.Seconds()
+	}
+	return val
+}
+
+// =============================================================================
+// Snapshots
+// =============================================================================
+
+// SnapshotVersion is the version of the snapshot format written by this package
+const SnapshotVersion = 1
+
+// Snapshot is a persisted result set
+// Snapshots are stored as JSON; numbers are read back as float64, times as
+// strings and durations as seconds, which Diff compares by value against live
+// results.
+type Snapshot struct {
+	Version           int              `json:"version"`
+	QueryID           string           `json:"queryId,omitempty"`
+	ExecutedQuery     string           `json:"executedQuery,omitempty"`
+	ExecutionDateTime string           `json:"executionDateTime,omitempty"`
+	CapturedAt        time.Time        `json:"capturedAt"`
+	Columns           []string         `json:"columns"`
+	Rows              []map[string]any `json:"rows"`
+}
+
+// NewSnapshot captures a result set
+func NewSnapshot(rs *V2ResultSet) *Snapshot {
+	snapshot := &Snapshot{
+		Version:    SnapshotVersion,
+		CapturedAt: time.Now().UTC(),
+		Rows:       []map[string]any{},
+	}
+	if rs == nil {
+		return snapshot
+	}
+
+	snapshot.QueryID = rs.QueryID
+	snapshot.ExecutedQuery = rs.ExecutedQuery
+	snapshot.ExecutionDateTime = rs.ExecutionDateTime
+	snapshot.Columns = rs.ColumnNames()
+	for _, row := range rs.data {
+		copied := make(map[string]any, len(row))
+		for field, val := range row {
+			copied[field] = snapshotValue(val)
+		}
+		snapshot.Rows = append(snapshot.Rows, copied)
+	}
+	return snapshot
+}
+
+// ResultSet returns the snapshot rows as a result set
+func (s *Snapshot) ResultSet() *V2ResultSet {
+	return &V2ResultSet{
+		QueryID:           s.QueryID,
+		ExecutedQuery:     s.ExecutedQuery,
+		RowCount:          int64(len(s.Rows)),
+		ExecutionDateTime: s.ExecutionDateTime,
+		data:              s.Rows,
+	}
+}
+
+// WriteSnapshot writes a snapshot of a result set to w
+func WriteSnapshot(w io.Writer, rs *V2ResultSet) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewSnapshot(rs)); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	return &snapshot, nil
+}
+
+// SaveSnapshot writes a snapshot of a result set to a file
+// The file is replaced atomically, so readers never see a partial snapshot.
+func SaveSnapshot(path string, rs *V2ResultSet) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	err = WriteSnapshot(tmp, rs)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write snapshot: %w", closeErr)
+	}
+	if err == nil {
+		if renameErr := os.Rename(tmp.Name(), path); renameErr != nil {
+			err = fmt.Errorf("failed to write snapshot: %w", renameErr)
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a snapshot file saved by SaveSnapshot
+func LoadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	return ReadSnapshot(file)
+}
+
+// DiffSnapshot compares the snapshot saved at path with a result set
+// A missing snapshot file is treated as an empty previous result set, so the
+// first run reports every row as added.
+func DiffSnapshot(path string, curr *V2ResultSet, keyFields ...string) (*ResultSetDiff, error) {
+	var prev *V2ResultSet
+	snapshot, err := LoadSnapshot(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		prev = snapshot.ResultSet()
+	}
+
+	return Diff(prev, curr, keyFields...)
+}
@@ -0,0 +1,158 @@
+package nql
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffYesterday() *V2ResultSet {
+	return NewV2ResultSet(&ExecuteNQLV2Response{
+		QueryID: "#compliance",
+		Data: []map[string]any{
+			{"device.name": "win-01", "os.version": "22H2", "tag": "baseline", "number_of_crashes": float64(1)},
+			{"device.name": "win-02", "os.version": "22H2", "tag": "baseline", "number_of_crashes": float64(0)},
+			{"device.name": "mac-01", "os.version": "14.1", "tag": "baseline", "number_of_crashes": float64(2)},
+		},
+	})
+}
+
+func diffToday() *V2ResultSet {
+	return NewV2ResultSet(&ExecuteNQLV2Response{
+		QueryID: "#compliance",
+		Data: []map[string]any{
+			{"device.name": "win-01", "os.version": "23H2", "tag": "baseline", "number_of_crashes": float64(1)},
+			{"device.name": "mac-01", "os.version": "14.1", "tag": "baseline", "number_of_crashes": 2},
+			{"device.name": "lnx-01", "os.version": "22.04", "tag": "pilot", "number_of_crashes": float64(0)},
+		},
+	})
+}
+
+func TestDiff(t *testing.T) {
+	diff, err := Diff(diffYesterday(), diffToday(), "device.name")
+
+	require.NoError(t, err)
+	assert.True(t, diff.HasChanges())
+	assert.Equal(t, "1 added, 1 removed, 1 changed, 1 unchanged", diff.String())
+
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "lnx-01", diff.Added[0]["device.name"])
+
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, "win-02", diff.Removed[0]["device.name"])
+
+	require.Len(t, diff.Changed, 1)
+	changed := diff.Changed[0]
+	assert.Equal(t, map[string]any{"device.name": "win-01"}, changed.Key)
+	assert.Equal(t, []FieldChange{{Field: "os.version", Before: "22H2", After: "23H2"}}, changed.Changes)
+}
+
+func TestDiffWithOptions(t *testing.T) {
+	diff, err := DiffWithOptions(diffYesterday(), diffToday(), &DiffOptions{
+		KeyFields:    []string{"device.name"},
+		IgnoreFields: []string{"os.version"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changed)
+	assert.Equal(t, 2, diff.Unchanged)
+
+	diff, err = DiffWithOptions(diffYesterday(), diffToday(), &DiffOptions{
+		KeyFields:     []string{"device.name"},
+		CompareFields: []string{"tag"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changed)
+}
+
+func TestDiff_Errors(t *testing.T) {
+	_, err := Diff(diffYesterday(), diffToday())
+	assert.Error(t, err)
+
+	duplicate := NewResultSetFromRows([]map[string]any{{"device.name": "a"}, {"device.name": "a"}})
+	_, err = Diff(nil, duplicate, "device.name")
+	assert.ErrorContains(t, err, "duplicate key")
+}
+
+func TestDiff_NilPrevious(t *testing.T) {
+	diff, err := Diff(nil, diffToday(), "device.name")
+
+	require.NoError(t, err)
+	assert.Len(t, diff.Added, 3)
+	assert.Empty(t, diff.Removed)
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf, diffToday()))
+
+	snapshot, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotVersion, snapshot.Version)
+	assert.Equal(t, "#compliance", snapshot.QueryID)
+	assert.Contains(t, snapshot.Columns, "os.version")
+
+	// Numbers come back as float64 but still compare equal to the live rows
+	diff, err := Diff(snapshot.ResultSet(), diffToday(), "device.name")
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.Equal(t, 3, diff.Unchanged)
+}
+
+func TestReadSnapshot_UnsupportedVersion(t *testing.T) {
+	_, err := ReadSnapshot(strings.NewReader(`{"version": 99, "rows": []}`))
+	assert.ErrorContains(t, err, "unsupported snapshot version")
+}
+
+func TestDiffSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compliance.json")
+
+	// No snapshot yet: everything is new
+	diff, err := DiffSnapshot(path, diffYesterday(), "device.name")
+	require.NoError(t, err)
+	assert.Len(t, diff.Added, 3)
+
+	require.NoError(t, SaveSnapshot(path, diffYesterday()))
+
+	diff, err = DiffSnapshot(path, diffToday(), "device.name")
+	require.NoError(t, err)
+	assert.Len(t, diff.Added, 1)
+	assert.Len(t, diff.Removed, 1)
+	assert.Len(t, diff.Changed, 1)
+}
+
+func TestDiff_TypedAgainstSnapshot(t *testing.T) {
+	lastSeen := time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC)
+	typed := NewResultSetFromRows([]map[string]any{
+		{"device.name": "win-01", "device.last_seen": lastSeen, "number_of_crashes": int64(3), "boot_time": 12500 * time.Millisecond},
+		{"device.name": "win-02", "device.last_seen": lastSeen.Add(time.Hour), "number_of_crashes": int64(0), "boot_time": 30 * time.Second},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf, typed))
+	snapshot, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, snapshot.Rows[0]["boot_time"])
+
+	// Keyed by a time: the snapshot holds strings, the live rows time.Time
+	diff, err := Diff(snapshot.ResultSet(), typed, "device.last_seen")
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.Equal(t, 2, diff.Unchanged)
+
+	// The execute endpoint returns the same rows as JSON types
+	execute := NewResultSetFromRows([]map[string]any{
+		{"device.name": "win-01", "device.last_seen": "2024-02-08T10:15:30Z", "number_of_crashes": float64(3), "boot_time": 12.5},
+		{"device.name": "win-02", "device.last_seen": "2024-02-08T11:15:30Z", "number_of_crashes": float64(1), "boot_time": 30.0},
+	})
+	diff, err = Diff(typed, execute, "device.name")
+	require.NoError(t, err)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, []FieldChange{{Field: "number_of_crashes", Before: int64(0), After: float64(1)}}, diff.Changed[0].Changes)
+
+	assert.False(t, valuesEqual("3", 3), "strings and numbers stay distinct")
+}
@@ -0,0 +1,579 @@
+package nql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query cost estimation scores how expensive an NQL query is likely to be
+// before it is saved or scheduled. Scores are relative: 1 is a scan of the
+// devices table, and event tables cost their volume per day of time selection.
+
+// =============================================================================
+// Cost Levels
+// =============================================================================
+
+// CostLevel classifies an estimated query cost
+type CostLevel string
+
+const (
+	CostLow       CostLevel = "low"
+	CostModerate  CostLevel = "moderate"
+	CostHigh      CostLevel = "high"
+	CostExcessive CostLevel = "excessive"
+)
+
+// Cost level thresholds (scores at or above the value reach the level)
+const (
+	CostModerateThreshold  = 100.0
+	CostHighThreshold      = 1000.0
+	CostExcessiveThreshold = 10000.0
+)
+
+// String returns the string representation of the cost level
+func (cl CostLevel) String() string {
+	return string(cl)
+}
+
+// rank orders the cost levels from low to excessive
+func (cl CostLevel) rank() int {
+	switch cl {
+	case CostModerate:
+		return 1
+	case CostHigh:
+		return 2
+	case CostExcessive:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// costLevelOf returns the level of a score
+func costLevelOf(score float64) CostLevel {
+	switch {
+	case score >= CostExcessiveThreshold:
+		return CostExcessive
+	case score >= CostHighThreshold:
+		return CostHigh
+	case score >= CostModerateThreshold:
+		return CostModerate
+	default:
+		return CostLow
+	}
+}
+
+// =============================================================================
+// Cost Model
+// =============================================================================
+
+// Cost warning rule IDs
+const (
+	RuleCostEventScan       = "cost/event-scan"
+	RuleCostHighResolution  = "cost/high-resolution"
+	RuleCostJoins           = "cost/joins"
+	RuleCostHighCardinality = "cost/high-cardinality-group"
+	RuleCostTimeBuckets     = "cost/time-buckets"
+	RuleCostMissingLimit    = "cost/missing-limit"
+)
+
+// InventoryTableCosts is the relative scan cost of inventory tables
+// Tables not listed cost 1, the cost of the devices table.
+var InventoryTableCosts = map[string]float64{
+	TableBinaries:      5,
+	"binary.binaries":  5,
+	TablePackages:      3,
+	"package.packages": 3,
+}
+
+// EventTableCosts is the relative scan cost of one day of an event table
+// Sampled tables (one row per device every 5 to 15 minutes) are the most
+// expensive; tables not listed cost DefaultEventTableCost per day.
+var EventTableCosts = map[string]float64{
+	TableConnectivityEvents:             100,
+	TableDevicePerformanceEvents:        100,
+	TableExecutionEvents:                100,
+	TableSessionEvents:                  50,
+	TableWebEvents:                      50,
+	TableWebPageViews:                   25,
+	TableWebTransactions:                25,
+	TableConnectionEvents:               25,
+	TableSessionVDIEvents:               25,
+	TableCollaborationSessions:          10,
+	TableWebErrors:                      10,
+	TableSessionLogins:                  2,
+	TableDevicePerformanceBoots:         2,
+	TableDexScores:                      2,
+	TableDexApplicationScores:           2,
+	TableExecutionCrashes:               1,
+	TableDevicePerformanceHardResets:    1,
+	TableDevicePerformanceSystemCrashes: 1,
+	TableRemoteActionExecutions:         1,
+	TableWorkflowExecutions:             1,
+}
+
+// DefaultEventTableCost is the daily scan cost of event tables missing from EventTableCosts
+const DefaultEventTableCost = 10.0
+
+// HighCardinalityFields are grouping fields with roughly one value per object
+// Fields ending in .uid or .sid are treated as high cardinality as well.
+var HighCardinalityFields = map[string]bool{
+	FieldDeviceName:         true,
+	FieldUserName:           true,
+	FieldUsername:           true,
+	FieldBinaryName:         true,
+	FieldDeviceCollectorUID: true,
+	FieldUserSID:            true,
+	"web.url":               true,
+	"binary.path":           true,
+}
+
+const (
+	// highResolutionCostFactor is the row multiplier of "by 30s" data over 15 minute samples
+	highResolutionCostFactor = 30
+
+	// joinCostFactor is the share of the running cost added by each with/include
+	joinCostFactor = 0.5
+
+	// highCardinalityCostFactor is the share of the running cost added per high-cardinality grouping field
+	highCardinalityCostFactor = 1.0
+
+	// unboundedListCostFactor is the share of the running cost added by a list without limit
+	unboundedListCostFactor = 1.0
+
+	// maxCheapJoins is the number of with/include statements before a warning
+	maxCheapJoins = 2
+
+	// maxTimeBuckets is the number of time buckets before a warning
+	maxTimeBuckets = 1000
+
+	// eventScanWarnCost is the scan cost of one event table before a warning
+	eventScanWarnCost = CostHighThreshold
+
+	// suggestedLimit is the limit added by suggested rewrites
+	suggestedLimit = 100
+)
+
+// =============================================================================
+// Cost Estimate
+// =============================================================================
+
+// CostFactor is one contribution to an estimated query cost
+type CostFactor struct {
+	// Name identifies the factor (scan, high-resolution, join, group-by, time-buckets, unbounded-list)
+	Name string `json:"name"`
+
+	// Detail describes what the factor applies to
+	Detail string `json:"detail"`
+
+	// Cost is the score added by the factor
+	Cost float64 `json:"cost"`
+
+	// Line is the 1-based line of the statement the factor applies to
+	Line int `json:"line,omitempty"`
+}
+
+// CostEstimate is the estimated cost of an NQL query
+type CostEstimate struct {
+	// Query is the estimated query text
+	Query string `json:"query"`
+
+	// Score is the relative cost of the query, the sum of the factor costs
+	Score float64 `json:"score"`
+
+	// Level classifies the score
+	Level CostLevel `json:"level"`
+
+	// Factors are the cost contributions in query order
+	Factors []CostFactor `json:"factors"`
+
+	// Warnings flag the statements that drive the cost
+	Warnings []LintIssue `json:"warnings,omitempty"`
+
+	// Rewrite is a cheaper form of the query applying the warning suggestions
+	// Empty when no rewrite is suggested; comments are not preserved.
+	Rewrite string `json:"rewrite,omitempty"`
+
+	// RewriteScore is the estimated score of Rewrite
+	RewriteScore float64 `json:"rewrite_score,omitempty"`
+}
+
+// HasWarnings checks if the estimate flagged any cost driver
+func (ce *CostEstimate) HasWarnings() bool {
+	return len(ce.Warnings) > 0
+}
+
+// Exceeds checks if the estimated cost is above the given level
+// Example: est.Exceeds(nql.CostModerate) is true for high and excessive queries
+func (ce *CostEstimate) Exceeds(level CostLevel) bool {
+	return ce.Level.rank() > level.rank()
+}
+
+// String returns a one-line summary of the estimate
+func (ce *CostEstimate) String() string {
+	return fmt.Sprintf("cost %s (%.0f), %d warning(s)", ce.Level, ce.Score, len(ce.Warnings))
+}
+
+// ToJSON converts the estimate to JSON
+func (ce *CostEstimate) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(ce, "", "  ")
+}
+
+// =============================================================================
+// Estimation
+// =============================================================================
+
+// Estimate scores the expected cost of a query and suggests a cheaper rewrite
+//
+// The score weighs event-table scans by volume and time-range length,
+// high-resolution data, with/include joins, high-cardinality and fine-grained
+// summarize by, and list statements without a limit.
+func Estimate(query string) *CostEstimate {
+	est := estimate(query)
+
+	if rewrite, ok := suggestRewrite(NewLintQuery(query)); ok {
+		est.Rewrite = rewrite
+		est.RewriteScore = estimate(rewrite).Score
+	}
+
+	return est
+}
+
+// Estimate scores the expected cost of the built query
+func (qb *QueryBuilder) Estimate() *CostEstimate {
+	return Estimate(qb.Build())
+}
+
+// estimate scores a query without suggesting a rewrite
+func estimate(query string) *CostEstimate {
+	lq := NewLintQuery(query)
+	e := &estimator{est: &CostEstimate{Query: query, Factors: []CostFactor{}}}
+
+	if len(lq.Statements) > 0 {
+		first := lq.Statements[0]
+		if first.IsTableSelection() {
+			e.scan(lq.Table, lq.TimeSelection, first, "")
+		}
+	}
+
+	joins := 0
+	for _, stmt := range lq.Statements[min(1, len(lq.Statements)):] {
+		switch stmt.Keyword {
+		case "with", "include":
+			joins++
+			table, selection := splitTableSelection(stmt.Body)
+			e.scan(table, selection, stmt, stmt.Keyword)
+			e.add(CostFactor{
+				Name:   "join",
+				Detail: fmt.Sprintf("%s %s", stmt.Keyword, table),
+				Cost:   e.est.Score * joinCostFactor,
+				Line:   stmt.Line,
+			})
+			if joins == maxCheapJoins+1 {
+				e.warn(LintIssue{
+					RuleID:  RuleCostJoins,
+					Message: fmt.Sprintf("more than %d with/include statements multiply the rows joined per object", maxCheapJoins),
+					Line:    stmt.Line,
+					Column:  stmt.Column,
+				})
+			}
+
+		case "summarize":
+			e.summarize(lq, stmt)
+
+		case "list":
+			if lq.Has("limit") {
+				continue
+			}
+			e.add(CostFactor{
+				Name:   "unbounded-list",
+				Detail: "list without limit",
+				Cost:   e.est.Score * unboundedListCostFactor,
+				Line:   stmt.Line,
+			})
+			e.warn(LintIssue{
+				RuleID:     RuleCostMissingLimit,
+				Message:    "list without limit returns every matching row",
+				Line:       stmt.Line,
+				Column:     stmt.Column,
+				Suggestion: fmt.Sprintf("| limit %d", suggestedLimit),
+			})
+		}
+	}
+
+	e.est.Score = math.Round(e.est.Score*100) / 100
+	e.est.Level = costLevelOf(e.est.Score)
+	return e.est
+}
+
+// estimator accumulates cost factors and warnings
+type estimator struct {
+	est *CostEstimate
+}
+
+// add records a cost factor
+func (e *estimator) add(factor CostFactor) {
+	factor.Cost = math.Round(factor.Cost*100) / 100
+	e.est.Factors = append(e.est.Factors, factor)
+	e.est.Score += factor.Cost
+}
+
+// warn records a cost warning
+func (e *estimator) warn(issue LintIssue) {
+	if issue.Severity == "" {
+		issue.Severity = SeverityWarning
+	}
+	e.est.Warnings = append(e.est.Warnings, issue)
+}
+
+// scan adds the cost of reading a table for a time selection
+// keyword is "with" or "include" for joined tables and empty for the table selection.
+func (e *estimator) scan(table, selection string, stmt Statement, keyword string) {
+	if !IsEventTable(table) {
+		cost, ok := InventoryTableCosts[table]
+		if !ok {
+			cost = 1
+		}
+		e.add(CostFactor{Name: "scan", Detail: table, Cost: cost, Line: stmt.Line})
+		return
+	}
+
+	cost, window := eventScanCost(table, selection)
+	e.add(CostFactor{
+		Name:   "scan",
+		Detail: fmt.Sprintf("%s over %s", table, formatWindow(window)),
+		Cost:   cost,
+		Line:   stmt.Line,
+	})
+
+	if IsHighResolution(selection) {
+		e.add(CostFactor{
+			Name:   "high-resolution",
+			Detail: fmt.Sprintf("%s by 30s", table),
+			Cost:   cost * (highResolutionCostFactor - 1),
+			Line:   stmt.Line,
+		})
+		e.warn(LintIssue{
+			RuleID:  RuleCostHighResolution,
+			Message: fmt.Sprintf("high-resolution data reads about %dx the rows of %s", highResolutionCostFactor, table),
+			Line:    stmt.Line,
+			Column:  stmt.Column,
+		})
+		cost *= highResolutionCostFactor
+	}
+
+	if narrowed, ok := narrowScan(table, selection); ok {
+		e.warn(LintIssue{
+			RuleID:     RuleCostEventScan,
+			Message:    fmt.Sprintf("scanning %s over %s is expensive", table, formatWindow(window)),
+			Line:       stmt.Line,
+			Column:     stmt.Column,
+			Suggestion: strings.TrimSpace(keyword + " " + narrowed),
+		})
+	}
+}
+
+// summarize adds the cost of grouping by high-cardinality fields or time buckets
+func (e *estimator) summarize(lq *LintQuery, stmt Statement) {
+	_, by := splitGroupBy(stmt.Body)
+	if by == "" {
+		return
+	}
+
+	var highCardinality []string
+	for _, field := range splitTopLevel(by) {
+		field = strings.TrimSpace(field)
+
+		if timeBucketPattern.MatchString(field) {
+			e.timeBuckets(lq, stmt, field)
+			continue
+		}
+		if isHighCardinalityField(field) {
+			highCardinality = append(highCardinality, field)
+		}
+	}
+
+	if len(highCardinality) == 0 {
+		return
+	}
+
+	e.add(CostFactor{
+		Name:   "group-by",
+		Detail: fmt.Sprintf("summarize by %s", strings.Join(highCardinality, ", ")),
+		Cost:   e.est.Score * highCardinalityCostFactor * float64(len(highCardinality)),
+		Line:   stmt.Line,
+	})
+
+	if lq.Has("limit") {
+		return
+	}
+	e.warn(LintIssue{
+		RuleID:     RuleCostHighCardinality,
+		Message:    fmt.Sprintf("summarize by %s returns about one row per object and has no limit", strings.Join(highCardinality, ", ")),
+		Line:       stmt.Line,
+		Column:     stmt.Column,
+		Suggestion: fmt.Sprintf("| limit %d", suggestedLimit),
+	})
+}
+
+// timeBuckets adds the cost of summarizing into time buckets
+func (e *estimator) timeBuckets(lq *LintQuery, stmt Statement, granularity string) {
+	bucket, err := ParseTimeGranularity(granularity)
+	if err != nil || bucket <= 0 {
+		return
+	}
+
+	buckets := math.Ceil(float64(selectionWindow(lq.TimeSelection)) / float64(bucket))
+	if buckets <= maxTimeBuckets {
+		return
+	}
+
+	e.add(CostFactor{
+		Name:   "time-buckets",
+		Detail: fmt.Sprintf("%.0f buckets of %s", buckets, granularity),
+		Cost:   e.est.Score * buckets / maxTimeBuckets,
+		Line:   stmt.Line,
+	})
+	e.warn(LintIssue{
+		RuleID:  RuleCostTimeBuckets,
+		Message: fmt.Sprintf("summarize by %s produces %.0f time buckets", granularity, buckets),
+		Line:    stmt.Line,
+		Column:  stmt.Column,
+	})
+}
+
+// =============================================================================
+// Rewrite
+// =============================================================================
+
+// suggestRewrite narrows expensive event scans and bounds unlimited results
+func suggestRewrite(lq *LintQuery) (string, bool) {
+	if len(lq.Statements) == 0 {
+		return "", false
+	}
+
+	changed := false
+	statements := make([]string, 0, len(lq.Statements)+1)
+
+	for i, stmt := range lq.Statements {
+		switch {
+		case i == 0 && stmt.IsTableSelection():
+			if narrowed, ok := narrowScan(lq.Table, lq.TimeSelection); ok {
+				statements = append(statements, narrowed)
+				changed = true
+				continue
+			}
+		case stmt.Keyword == "with" || stmt.Keyword == "include":
+			table, selection := splitTableSelection(stmt.Body)
+			if narrowed, ok := narrowScan(table, selection); ok {
+				statements = append(statements, stmt.Keyword+" "+narrowed)
+				changed = true
+				continue
+			}
+		}
+		statements = append(statements, stmt.String())
+	}
+
+	if !lq.Has("limit") && needsLimit(lq) {
+		statements = append(statements, "limit "+strconv.Itoa(suggestedLimit))
+		changed = true
+	}
+
+	if !changed {
+		return "", false
+	}
+	return strings.Join(statements, "\n| "), true
+}
+
+// narrowScan returns the table selection narrowed to the past 7 days (2 days
+// for high-resolution data) when scanning it over its selection is expensive
+func narrowScan(table, selection string) (string, bool) {
+	if !IsEventTable(table) {
+		return "", false
+	}
+
+	cost, window := eventScanCost(table, selection)
+	narrowed := Past7Days
+	if IsHighResolution(selection) {
+		cost *= highResolutionCostFactor
+		narrowed = "during past " + formatWindow(HighResolutionRetention) + " by 30s"
+	}
+
+	limit, _ := TimeSelectionLookback(narrowed)
+	if cost < eventScanWarnCost || window <= limit {
+		return "", false
+	}
+	return table + " " + narrowed, true
+}
+
+// eventScanCost returns the cost and window of scanning an event table
+// The cost excludes the high-resolution multiplier.
+func eventScanCost(table, selection string) (float64, time.Duration) {
+	perDay, ok := EventTableCosts[table]
+	if !ok {
+		perDay = DefaultEventTableCost
+	}
+
+	window := selectionWindow(selection)
+	return perDay * window.Hours() / 24, window
+}
+
+// needsLimit checks if the query lists rows or groups by a high-cardinality field
+func needsLimit(lq *LintQuery) bool {
+	if lq.Has("list") {
+		return true
+	}
+	for _, stmt := range lq.Find("summarize") {
+		_, by := splitGroupBy(stmt.Body)
+		for _, field := range splitTopLevel(by) {
+			if isHighCardinalityField(strings.TrimSpace(field)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// selectionWindow returns the length of a time selection
+// Event tables without a time selection default to the past 24 hours; selections
+// that cannot be resolved (e.g. with parameters) are assumed to be 24 hours long.
+func selectionWindow(selection string) time.Duration {
+	if !hasTimeSelection(selection) {
+		return 24 * time.Hour
+	}
+	if lookback, ok := TimeSelectionLookback(selection); ok && strings.HasPrefix(strings.ToLower(selection), "during") {
+		return lookback
+	}
+
+	window, err := ResolveTimeSelection(selection, time.Now(), time.UTC)
+	if err != nil {
+		return 24 * time.Hour
+	}
+	return window.Duration()
+}
+
+// isHighCardinalityField checks if a grouping field has about one value per object
+func isHighCardinalityField(field string) bool {
+	return HighCardinalityFields[field] || strings.HasSuffix(field, ".uid") || strings.HasSuffix(field, ".sid")
+}
+
+// formatWindow formats a duration in NQL units
+// Example: 720h -> 30d, 90m -> 90min
+func formatWindow(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dmin", d/time.Minute)
+	}
+}
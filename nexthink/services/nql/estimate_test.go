@@ -0,0 +1,164 @@
+package nql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func factorNames(factors []CostFactor) []string {
+	names := make([]string, 0, len(factors))
+	for _, factor := range factors {
+		names = append(names, factor.Name)
+	}
+	return names
+}
+
+func TestEstimate_InventoryQuery(t *testing.T) {
+	est := Estimate("devices | list device.name | limit 10")
+
+	assert.Equal(t, 1.0, est.Score)
+	assert.Equal(t, CostLow, est.Level)
+	assert.Equal(t, []string{"scan"}, factorNames(est.Factors))
+	assert.False(t, est.HasWarnings())
+	assert.Empty(t, est.Rewrite)
+}
+
+func TestEstimate_EventScan(t *testing.T) {
+	week := Estimate("execution.events during past 7d | summarize n = count()")
+	month := Estimate("execution.events during past 30d | summarize n = count()")
+	crashes := Estimate("execution.crashes during past 30d | summarize n = count()")
+
+	assert.Equal(t, 700.0, week.Score)
+	assert.Equal(t, CostModerate, week.Level)
+	assert.False(t, week.HasWarnings())
+
+	assert.Equal(t, 3000.0, month.Score)
+	assert.Equal(t, CostHigh, month.Level)
+	require.Len(t, month.Warnings, 1)
+	assert.Equal(t, RuleCostEventScan, month.Warnings[0].RuleID)
+	assert.Equal(t, SeverityWarning, month.Warnings[0].Severity)
+	assert.Equal(t, "execution.events during past 7d", month.Warnings[0].Suggestion)
+	assert.Equal(t, "execution.events during past 7d\n| summarize n = count()", month.Rewrite)
+	assert.Equal(t, week.Score, month.RewriteScore)
+
+	assert.Equal(t, 30.0, crashes.Score, "low-volume tables are cheap over long windows")
+	assert.Empty(t, crashes.Rewrite)
+}
+
+func TestEstimate_DefaultAndAbsoluteWindows(t *testing.T) {
+	assert.Equal(t, 100.0, Estimate("execution.events").Score, "event tables default to the past 24 hours")
+	assert.Equal(t, 300.0, Estimate("execution.events from 2024-02-01 to 2024-02-03").Score)
+	assert.Equal(t, 100.0, Estimate("execution.events during past $period").Score, "unresolvable selections count as one day")
+}
+
+func TestEstimate_HighResolution(t *testing.T) {
+	est := Estimate("device_performance.events during past 7d by 30s | summarize cpu = cpu_usage.avg() by 15 min")
+
+	assert.Equal(t, []string{"scan", "high-resolution"}, factorNames(est.Factors))
+	assert.Equal(t, 21000.0, est.Score)
+	assert.Equal(t, CostExcessive, est.Level)
+	assert.Equal(t, []string{RuleCostHighResolution, RuleCostEventScan}, ruleIDs(est.Warnings))
+	assert.Equal(t, "device_performance.events during past 2d by 30s\n| summarize cpu = cpu_usage.avg() by 15 min", est.Rewrite)
+	assert.Equal(t, 6000.0, est.RewriteScore)
+}
+
+func TestEstimate_Joins(t *testing.T) {
+	one := Estimate(NewTemplates().DevicesWithCrashes("during past 7d", "").Query())
+	assert.Equal(t, []string{"scan", "scan", "join"}, factorNames(one.Factors))
+	assert.Equal(t, 12.0, one.Score)
+	assert.False(t, one.HasWarnings())
+
+	three := Estimate(`devices
+| with execution.crashes during past 7d
+| include session.logins during past 7d
+| include dex.scores during past 7d
+| compute crashes = count()
+| list device.name, crashes
+| limit 10`)
+	require.Len(t, three.Warnings, 1)
+	assert.Equal(t, RuleCostJoins, three.Warnings[0].RuleID)
+	assert.Equal(t, 4, three.Warnings[0].Line)
+	assert.Greater(t, three.Score, one.Score)
+}
+
+func TestEstimate_HighCardinalityGroup(t *testing.T) {
+	unbounded := Estimate("execution.events during past 7d | summarize n = count() by device.name")
+	bounded := Estimate("execution.events during past 7d | summarize n = count() by device.name | limit 50")
+	lowCardinality := Estimate("execution.events during past 7d | summarize n = count() by operating_system.platform")
+
+	assert.Equal(t, 1400.0, unbounded.Score)
+	assert.Equal(t, []string{RuleCostHighCardinality}, ruleIDs(unbounded.Warnings))
+	assert.Equal(t, "| limit 100", unbounded.Warnings[0].Suggestion)
+	assert.Equal(t, "execution.events during past 7d\n| summarize n = count() by device.name\n| limit 100", unbounded.Rewrite)
+
+	assert.Equal(t, 1400.0, bounded.Score)
+	assert.False(t, bounded.HasWarnings())
+
+	assert.Equal(t, 700.0, lowCardinality.Score)
+	assert.True(t, isHighCardinalityField("binary.uid"))
+}
+
+func TestEstimate_TimeBuckets(t *testing.T) {
+	coarse := Estimate("execution.crashes during past 7d | summarize n = count() by 1h")
+	fine := Estimate("execution.crashes from 2024-01-01 to 2024-03-31 | summarize n = count() by 15 min")
+
+	assert.False(t, coarse.HasWarnings())
+	assert.Equal(t, []string{"scan", "time-buckets"}, factorNames(fine.Factors))
+	assert.Equal(t, []string{RuleCostTimeBuckets}, ruleIDs(fine.Warnings))
+}
+
+func TestEstimate_MissingLimit(t *testing.T) {
+	est := NewQueryBuilder().
+		FromDevices().
+		List("device.name").
+		Estimate()
+
+	assert.Equal(t, []string{"scan", "unbounded-list"}, factorNames(est.Factors))
+	assert.Equal(t, 2.0, est.Score)
+	assert.Equal(t, []string{RuleCostMissingLimit}, ruleIDs(est.Warnings))
+	assert.Equal(t, "devices\n| list device.name\n| limit 100", est.Rewrite)
+	assert.Equal(t, 1.0, est.RewriteScore)
+}
+
+func TestEstimate_Templates(t *testing.T) {
+	registry := DefaultTemplateRegistry()
+	for _, name := range registry.Names() {
+		tmpl, err := registry.Render(name, nil)
+		require.NoError(t, err, name)
+
+		est := Estimate(tmpl.Query())
+		assert.False(t, est.Exceeds(CostHigh), "%s: %s", name, est)
+		if est.Exceeds(CostModerate) {
+			assert.True(t, est.HasWarnings(), "%s: %s", name, est)
+			assert.NotEmpty(t, est.Rewrite, name)
+			assert.LessOrEqual(t, est.RewriteScore, est.Score, name)
+		}
+	}
+
+	est := Estimate(NewTemplates().NetworkConnectivityIssues("during past 7d").Query())
+	assert.Equal(t, CostHigh, est.Level)
+	assert.Equal(t, []string{RuleCostHighCardinality}, ruleIDs(est.Warnings))
+}
+
+func TestCostEstimate_Exceeds(t *testing.T) {
+	est := &CostEstimate{Level: CostHigh}
+
+	assert.True(t, est.Exceeds(CostLow))
+	assert.True(t, est.Exceeds(CostModerate))
+	assert.False(t, est.Exceeds(CostHigh))
+	assert.False(t, est.Exceeds(CostExcessive))
+}
+
+func TestCostEstimate_ToJSON(t *testing.T) {
+	data, err := Estimate("execution.events during past 30d").ToJSON()
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "high", decoded["level"])
+	assert.Equal(t, 3000.0, decoded["score"])
+	assert.Contains(t, decoded, "rewrite")
+}
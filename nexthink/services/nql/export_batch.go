@@ -0,0 +1,397 @@
+package nql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Batch exports run many export workflows concurrently, capped by a worker
+// limit and a rate budget shared by all jobs
+
+// DefaultExportConcurrency is the default number of exports ExportMany runs at once
+const DefaultExportConcurrency = 4
+
+// =============================================================================
+// Rate Limiting
+// =============================================================================
+
+// RateLimiter throttles API calls
+// Wait blocks until a call may be made or ctx is done. Implementations must be
+// safe for concurrent use.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucketLimiter is a token bucket RateLimiter
+// Tokens are added at a fixed rate up to burst; each call consumes one token.
+type TokenBucketLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+var _ RateLimiter = (*TokenBucketLimiter)(nil)
+
+// NewRateLimiter creates a token bucket allowing requestsPerSecond calls on
+// average, with bursts of up to burst calls (minimum 1)
+func NewRateLimiter(requestsPerSecond float64, burst int) *TokenBucketLimiter {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = 1
+	}
+	burst = max(burst, 1)
+
+	return &TokenBucketLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait implements RateLimiter
+func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise returns how long until the next one
+func (l *TokenBucketLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) * float64(l.interval))
+}
+
+// =============================================================================
+// Batch Export Options
+// =============================================================================
+
+// BatchExportOptions configures ExportMany
+type BatchExportOptions struct {
+	// Export is the template for every job's export options
+	// Writer and OutputPath are ignored; use OutputDir or WriterFor instead.
+	// Defaults to DefaultExportOptions() if not specified
+	Export *ExportOptions
+
+	// Concurrency is the maximum number of exports running at once
+	// Defaults to DefaultExportConcurrency if not specified
+	Concurrency int
+
+	// RequestsPerSecond is the rate budget for export API calls shared by all jobs
+	// Zero means no limit. Ignored when RateLimiter is set
+	RequestsPerSecond float64
+
+	// RateLimiter optionally supplies the shared rate limiter directly
+	RateLimiter RateLimiter
+
+	// OutputDir optionally streams each export to a file in this directory
+	// Files are named "<index>_<query>.<format>"; the directory is created if needed
+	OutputDir string
+
+	// WriterFor optionally returns the writer each export is streamed to
+	// Writers implementing io.Closer are closed when the job finishes. Takes
+	// precedence over OutputDir. Without either, exports are buffered in ExportResult.Data
+	WriterFor func(index int, req *ExportRequest) (io.Writer, error)
+
+	// FailFast cancels the remaining jobs after the first failure
+	FailFast bool
+
+	// OnJobProgress is an optional callback for each job's status checks
+	OnJobProgress func(index int, status string, elapsedTime time.Duration)
+
+	// OnJobDownloadProgress is an optional callback for each job's download progress
+	OnJobDownloadProgress func(index int, bytesDownloaded, totalBytes int64)
+
+	// OnJobComplete is an optional callback fired when a job succeeds or fails
+	OnJobComplete func(result BatchExportResult)
+}
+
+// DefaultBatchExportOptions returns batch export options with sensible defaults
+// Polling uses DefaultPollStrategy so concurrent jobs back off instead of polling in lockstep.
+func DefaultBatchExportOptions() *BatchExportOptions {
+	return &BatchExportOptions{
+		Export:      DefaultExportOptions().WithPollStrategy(DefaultPollStrategy()),
+		Concurrency: DefaultExportConcurrency,
+	}
+}
+
+// =============================================================================
+// Batch Export Result
+// =============================================================================
+
+// BatchExportResult is the outcome of one job in a batch export
+type BatchExportResult struct {
+	// Index is the position of the request in the ExportMany input
+	Index int
+
+	// Request is the export request
+	Request ExportRequest
+
+	// Result is the export result (nil when Err is set)
+	Result *ExportResult
+
+	// Err is the reason the job failed
+	Err error
+
+	// Duration is the time the job took, including waiting for a worker slot
+	Duration time.Duration
+}
+
+// BatchExportSummary consolidates the results of a batch export
+type BatchExportSummary struct {
+	// Results holds one entry per request, in request order
+	Results []BatchExportResult
+
+	// Succeeded is the number of jobs that completed
+	Succeeded int
+
+	// Failed is the number of jobs that failed
+	Failed int
+
+	// TotalBytes is the number of bytes written across all jobs
+	TotalBytes int64
+
+	// TotalDuration is the wall-clock time of the batch
+	TotalDuration time.Duration
+}
+
+// Failures returns the results of the failed jobs
+func (bs *BatchExportSummary) Failures() []BatchExportResult {
+	var failures []BatchExportResult
+	for _, result := range bs.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Err returns the job failures joined into one error, or nil when every job succeeded
+func (bs *BatchExportSummary) Err() error {
+	var errs []error
+	for _, result := range bs.Failures() {
+		errs = append(errs, fmt.Errorf("export %d (%s): %w", result.Index, result.Request.QueryID, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// =============================================================================
+// Export Many
+// =============================================================================
+
+// ExportMany runs several export workflows concurrently
+//
+// At most opts.Concurrency exports run at once, and every StartNQLExport and
+// GetNQLExportStatus call draws from one shared rate budget. Each export is
+// streamed to its own writer or file, or buffered when neither is configured.
+//
+// A failing job does not stop the others (unless FailFast is set). The summary is
+// always returned with one result per request; the error is summary.Err() and is
+// non-nil if any job failed.
+func (s *Service) ExportMany(ctx context.Context, reqs []ExportRequest, opts *BatchExportOptions) (*BatchExportSummary, error) {
+	if opts == nil {
+		opts = DefaultBatchExportOptions()
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultExportConcurrency
+	}
+	concurrency = min(concurrency, max(len(reqs), 1))
+
+	limiter := opts.RateLimiter
+	if limiter == nil && opts.RequestsPerSecond > 0 {
+		limiter = NewRateLimiter(opts.RequestsPerSecond, concurrency)
+	}
+
+	if opts.OutputDir != "" && opts.WriterFor == nil {
+		if err := os.MkdirAll(opts.OutputDir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	s.client.GetLogger().Info("Starting batch NQL export",
+		zap.Int("exports", len(reqs)),
+		zap.Int("concurrency", concurrency))
+
+	startTime := time.Now()
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summary := &BatchExportSummary{Results: make([]BatchExportResult, len(reqs))}
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for range concurrency {
+		wg.Go(func() {
+			for i := range indexes {
+				result := s.runBatchExport(batchCtx, i, reqs[i], opts, limiter, startTime)
+
+				mu.Lock()
+				summary.Results[i] = result
+				if result.Err != nil {
+					summary.Failed++
+				} else {
+					summary.Succeeded++
+					summary.TotalBytes += result.Result.Size()
+				}
+				mu.Unlock()
+
+				if result.Err != nil && opts.FailFast {
+					cancel()
+				}
+				if opts.OnJobComplete != nil {
+					opts.OnJobComplete(result)
+				}
+			}
+		})
+	}
+
+	for i := range reqs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	summary.TotalDuration = time.Since(startTime)
+
+	s.client.GetLogger().Info("Batch NQL export completed",
+		zap.Int("succeeded", summary.Succeeded),
+		zap.Int("failed", summary.Failed),
+		zap.Int64("total_bytes", summary.TotalBytes),
+		zap.Duration("total_duration", summary.TotalDuration))
+
+	return summary, summary.Err()
+}
+
+// runBatchExport runs the export workflow for one job of a batch
+func (s *Service) runBatchExport(
+	ctx context.Context,
+	index int,
+	req ExportRequest,
+	batch *BatchExportOptions,
+	limiter RateLimiter,
+	startTime time.Time,
+) BatchExportResult {
+	outcome := BatchExportResult{Index: index, Request: req}
+	defer func() { outcome.Duration = time.Since(startTime) }()
+
+	if err := ctx.Err(); err != nil {
+		outcome.Err = err
+		return outcome
+	}
+
+	opts := batch.jobOptions(index, limiter)
+	if req.Format == "" {
+		req.Format = opts.Format
+	}
+
+	var closer io.Closer
+	switch {
+	case batch.WriterFor != nil:
+		w, err := batch.WriterFor(index, &req)
+		if err != nil {
+			outcome.Err = fmt.Errorf("failed to open output writer: %w", err)
+			return outcome
+		}
+		opts.Writer = w
+		closer, _ = w.(io.Closer)
+	case batch.OutputDir != "":
+		opts.OutputPath = filepath.Join(batch.OutputDir, batchOutputFileName(index, &req))
+	}
+
+	outcome.Result, outcome.Err = s.ExportWorkflow(ctx, &req, opts)
+	outcome.Request = req
+
+	if closer != nil {
+		if err := closer.Close(); err != nil && outcome.Err == nil {
+			outcome.Result = nil
+			outcome.Err = fmt.Errorf("failed to close output writer: %w", err)
+		}
+	}
+
+	if outcome.Err != nil {
+		s.client.GetLogger().Warn("Batch export job failed",
+			zap.Int("index", index),
+			zap.String("query_id", req.QueryID),
+			zap.Error(outcome.Err))
+	}
+
+	return outcome
+}
+
+// jobOptions builds the export options for one job from the batch template
+func (opts *BatchExportOptions) jobOptions(index int, limiter RateLimiter) *ExportOptions {
+	jobOpts := DefaultExportOptions()
+	if opts.Export != nil {
+		copied := *opts.Export
+		jobOpts = &copied
+	}
+
+	jobOpts.Writer = nil
+	jobOpts.OutputPath = ""
+	jobOpts.applyDefaults()
+	if limiter != nil {
+		jobOpts.RateLimiter = limiter
+	}
+
+	if opts.OnJobProgress != nil {
+		jobOpts.OnProgress = func(status string, elapsedTime time.Duration) {
+			opts.OnJobProgress(index, status, elapsedTime)
+		}
+	}
+	if opts.OnJobDownloadProgress != nil {
+		jobOpts.OnDownloadProgress = func(bytesDownloaded, totalBytes int64) {
+			opts.OnJobDownloadProgress(index, bytesDownloaded, totalBytes)
+		}
+	}
+
+	return jobOpts
+}
+
+// batchOutputFileName returns the file name for a job's export in OutputDir
+func batchOutputFileName(index int, req *ExportRequest) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimPrefix(req.QueryID, "#"))
+
+	return fmt.Sprintf("%03d_%s.%s", index, name, req.Format)
+}
@@ -0,0 +1,223 @@
+package nql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerBatchExportMocks registers export mocks where each query gets its own export
+// Queries containing "fail" end with an ERROR status. Returns the number of API calls made.
+func registerBatchExportMocks(t *testing.T, baseURL, resultsFileURL string) *atomic.Int32 {
+	t.Helper()
+
+	calls := &atomic.Int32{}
+
+	httpmock.RegisterResponder("POST", baseURL+"/api/v1/nql/export",
+		func(req *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			var body ExportRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return httpmock.NewStringResponse(400, "bad request"), nil
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"exportId": "export-" + strings.TrimPrefix(body.QueryID, "#"),
+			})
+		})
+
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(regexp.QuoteMeta(baseURL+"/api/v1/nql/status/")+`.+`),
+		func(req *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			if strings.Contains(req.URL.Path, "fail") {
+				return httpmock.NewJsonResponse(200, map[string]any{
+					"status":           "ERROR",
+					"errorDescription": "query failed",
+				})
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"status":         "COMPLETED",
+				"resultsFileUrl": resultsFileURL,
+			})
+		})
+
+	return calls
+}
+
+func batchRequests(queryIDs ...string) []ExportRequest {
+	reqs := make([]ExportRequest, len(queryIDs))
+	for i, id := range queryIDs {
+		reqs[i] = ExportRequest{QueryID: id}
+	}
+	return reqs
+}
+
+func TestExportMany_ConcurrencyCap(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+
+	var inFlight, maxInFlight atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = io.WriteString(w, downloadTestCSV)
+	})
+	registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+
+	opts := DefaultBatchExportOptions()
+	opts.Concurrency = 2
+
+	summary, err := service.ExportMany(context.Background(),
+		batchRequests("#q1", "#q2", "#q3", "#q4", "#q5", "#q6"), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, 6, summary.Succeeded)
+	assert.Equal(t, int64(6*len(downloadTestCSV)), summary.TotalBytes)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+	for i, result := range summary.Results {
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, downloadTestCSV, string(result.Result.Data))
+	}
+}
+
+func TestExportMany_PartialFailure(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+
+	var mu sync.Mutex
+	completed := map[int]bool{}
+	opts := DefaultBatchExportOptions()
+	opts.OnJobComplete = func(result BatchExportResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		completed[result.Index] = result.Err == nil
+	}
+
+	summary, err := service.ExportMany(context.Background(), batchRequests("#ok_1", "#fail_1", "#ok_2"), opts)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "#fail_1")
+	assert.Equal(t, 2, summary.Succeeded)
+	assert.Equal(t, 1, summary.Failed)
+	require.Len(t, summary.Failures(), 1)
+	assert.Equal(t, 1, summary.Failures()[0].Index)
+	assert.Equal(t, map[int]bool{0: true, 1: false, 2: true}, completed)
+}
+
+func TestExportMany_OutputDir(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+	dir := filepath.Join(t.TempDir(), "exports")
+
+	opts := DefaultBatchExportOptions()
+	opts.OutputDir = dir
+
+	summary, err := service.ExportMany(context.Background(), batchRequests("#devices", "#apps"), opts)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "001_apps.csv")
+	assert.Equal(t, path, summary.Results[1].Result.OutputPath)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(data))
+}
+
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestExportMany_WriterFor(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+
+	buffers := []*closingBuffer{{}, {}}
+	opts := DefaultBatchExportOptions()
+	opts.WriterFor = func(index int, req *ExportRequest) (io.Writer, error) {
+		return buffers[index], nil
+	}
+
+	_, err := service.ExportMany(context.Background(), batchRequests("#q1", "#q2"), opts)
+	require.NoError(t, err)
+
+	for i, buf := range buffers {
+		assert.Equal(t, downloadTestCSV, buf.String(), "buffer %d", i)
+		assert.True(t, buf.closed, "buffer %d", i)
+	}
+}
+
+func TestExportMany_FailFast(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+
+	opts := DefaultBatchExportOptions()
+	opts.Concurrency = 1
+	opts.FailFast = true
+
+	summary, err := service.ExportMany(context.Background(), batchRequests("#fail_1", "#q2", "#q3"), opts)
+
+	require.Error(t, err)
+	assert.Equal(t, 3, summary.Failed)
+	assert.ErrorIs(t, summary.Results[2].Err, context.Canceled)
+}
+
+func TestExportMany_SharedRateBudget(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	calls := registerBatchExportMocks(t, baseURL, server.URL+"/export.csv")
+
+	opts := DefaultBatchExportOptions()
+	opts.Concurrency = 4
+	opts.RequestsPerSecond = 40
+
+	start := time.Now()
+	summary, err := service.ExportMany(context.Background(), batchRequests("#q1", "#q2", "#q3", "#q4"), opts)
+	require.NoError(t, err)
+	assert.Equal(t, 4, summary.Succeeded)
+
+	// 8 calls with a burst of 4 at 40/s take at least 100ms
+	assert.Equal(t, int32(8), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestTokenBucketLimiter_ContextCanceled(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestBatchOutputFileName(t *testing.T) {
+	name := batchOutputFileName(7, &ExportRequest{QueryID: "#devices/by platform", Format: ExportFormatJSON})
+	assert.Equal(t, "007_devices_by_platform.json", name)
+}
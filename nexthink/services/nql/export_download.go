@@ -0,0 +1,271 @@
+package nql
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"go.uber.org/zap"
+)
+
+// Streaming export downloads copy S3 export objects straight to an io.Writer
+// so multi-gigabyte exports never have to be held in memory
+
+// =============================================================================
+// Download Progress
+// =============================================================================
+
+// DownloadProgressFunc reports download progress
+// bytesDownloaded is the number of bytes received from S3 so far and totalBytes
+// is the Content-Length of the object (-1 when unknown)
+type DownloadProgressFunc func(bytesDownloaded, totalBytes int64)
+
+// DownloadResult describes a completed streaming download
+type DownloadResult struct {
+	// BytesDownloaded is the number of bytes received from S3 (compressed size for gzip objects)
+	BytesDownloaded int64
+
+	// BytesWritten is the number of bytes written to the destination (decompressed size)
+	BytesWritten int64
+
+	// ContentLength is the Content-Length reported by S3 (-1 when unknown)
+	ContentLength int64
+
+	// Decompressed is true when the object was gzip-encoded and decompressed on the fly
+	Decompressed bool
+
+	// Attempts is the number of requests made (resumable downloads only)
+	Attempts int
+
+	// Checksum is the verified MD5 (hex) of the downloaded object (resumable downloads only)
+	// Empty when S3 provided no usable ETag or Content-MD5
+	Checksum string
+}
+
+// gzipMagic is the two-byte header that starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// =============================================================================
+// Streaming Download
+// =============================================================================
+
+// DownloadNQLExportTo streams a completed export from an S3 pre-signed URL to w
+//
+// The object is copied in fixed-size chunks, so memory use is independent of the
+// export size. Gzip-encoded objects are decompressed transparently, and the number
+// of bytes received is verified against the Content-Length reported by S3.
+//
+// Returns the number of bytes written to w.
+func (s *Service) DownloadNQLExportTo(ctx context.Context, downloadURL string, w io.Writer) (int64, error) {
+	result, err := s.downloadExportTo(ctx, downloadURL, w, nil)
+	if result == nil {
+		return 0, err
+	}
+	return result.BytesWritten, err
+}
+
+// DownloadNQLExportToFile streams a completed export to a file at path
+// The file is created (or truncated) and removed again if the download fails.
+func (s *Service) DownloadNQLExportToFile(ctx context.Context, downloadURL, path string, progress DownloadProgressFunc) (*DownloadResult, error) {
+	if path == "" {
+		return nil, fmt.Errorf("output path cannot be empty")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	result, err := s.downloadExportTo(ctx, downloadURL, file, progress)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// downloadExportTo performs the streaming download with optional progress reporting
+func (s *Service) downloadExportTo(ctx context.Context, downloadURL string, w io.Writer, progress DownloadProgressFunc) (*DownloadResult, error) {
+	if downloadURL == "" {
+		return nil, fmt.Errorf("download URL cannot be empty")
+	}
+
+	if w == nil {
+		return nil, fmt.Errorf("download writer cannot be nil")
+	}
+
+	resp, err := s.doDownloadRequest(ctx, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	// When the transport already decompressed the body the length is unknown
+	contentLength := resp.ContentLength
+	if resp.Uncompressed {
+		contentLength = -1
+	}
+
+	counter := &countingReader{
+		reader:   resp.Body,
+		total:    contentLength,
+		progress: progress,
+	}
+
+	body := bufio.NewReader(counter)
+	result := &DownloadResult{ContentLength: contentLength}
+
+	var source io.Reader = body
+	if isGzipResponse(resp, body) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		source = gz
+		result.Decompressed = true
+	}
+
+	written, err := io.Copy(w, source)
+	result.BytesWritten = written
+	result.BytesDownloaded = counter.read
+	if err != nil {
+		return result, fmt.Errorf("failed to stream export data: %w", err)
+	}
+
+	if contentLength >= 0 && counter.read != contentLength {
+		return result, fmt.Errorf("incomplete download: received %d of %d bytes", counter.read, contentLength)
+	}
+
+	return result, nil
+}
+
+// minDownloadRetryWait is the shortest wait between download retries (resty's default wait time)
+const minDownloadRetryWait = 100 * time.Millisecond
+
+// defaultDownloadRetryPolicy is used when the client does not implement interfaces.DownloadClient
+var defaultDownloadRetryPolicy = interfaces.RetryPolicy{
+	MaxRetries:  3,
+	WaitTime:    time.Second,
+	MaxWaitTime: 30 * time.Second,
+}
+
+// downloadClient returns the HTTP client used for S3 downloads
+// The SDK client shares its proxy, TLS and tracing settings but never sends the
+// bearer token; other HTTPClient implementations fall back to http.DefaultClient.
+func (s *Service) downloadClient() *http.Client {
+	if dc, ok := s.client.(interfaces.DownloadClient); ok {
+		return dc.GetDownloadClient()
+	}
+	return http.DefaultClient
+}
+
+// downloadRetryPolicy returns the client's retry policy, or a default one
+func (s *Service) downloadRetryPolicy() interfaces.RetryPolicy {
+	if dc, ok := s.client.(interfaces.DownloadClient); ok {
+		return dc.GetRetryPolicy()
+	}
+	return defaultDownloadRetryPolicy
+}
+
+// doDownloadRequest sends a GET request for the download URL
+// Connection failures and transient statuses (408, 429, 5xx) are retried with the
+// client's retry policy, waiting at least as long as a Retry-After header asks;
+// a response with any other status is returned as is.
+func (s *Service) doDownloadRequest(ctx context.Context, downloadURL string) (*http.Response, error) {
+	policy := s.downloadRetryPolicy()
+	wait := max(policy.WaitTime, minDownloadRetryWait)
+	maxWait := max(policy.MaxWaitTime, wait)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create download request: %w", err)
+		}
+
+		resp, err := s.downloadClient().Do(req)
+		if err == nil && !isRetryableDownloadStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := wait
+		if err == nil {
+			if attempt >= policy.MaxRetries {
+				return resp, nil
+			}
+			resp.Body.Close()
+			delay = max(wait, retryAfterHeader(resp.Header))
+			err = fmt.Errorf("download failed with status %d: %s", resp.StatusCode, resp.Status)
+		} else if attempt >= policy.MaxRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to download export: %w", err)
+		}
+
+		s.client.GetLogger().Warn("Retrying export download",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("wait", delay),
+			zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to download export: %w", ctx.Err())
+		case <-timer.C:
+		}
+
+		wait = min(wait*2, maxWait)
+	}
+}
+
+// isGzipResponse checks the Content-Encoding header and the gzip magic bytes
+func isGzipResponse(resp *http.Response, body *bufio.Reader) bool {
+	if resp.Uncompressed {
+		return false
+	}
+
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return true
+	}
+
+	header, err := body.Peek(len(gzipMagic))
+	return err == nil && header[0] == gzipMagic[0] && header[1] == gzipMagic[1]
+}
+
+// =============================================================================
+// Helper Types
+// =============================================================================
+
+// countingReader counts bytes read and reports progress
+type countingReader struct {
+	reader   io.Reader
+	read     int64
+	total    int64
+	progress DownloadProgressFunc
+}
+
+// Read implements io.Reader
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	if n > 0 {
+		cr.read += int64(n)
+		if cr.progress != nil {
+			cr.progress(cr.read, cr.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,275 @@
+package nql
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/mocks"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const downloadTestCSV = "device.name,operating_system.platform\ndevice-01,Windows\ndevice-02,macOS\n"
+
+// newS3Server starts a test server standing in for S3
+// Downloads go through the SDK transport, which setupMockClient points at httpmock,
+// so requests without a registered responder are passed through to the network.
+func newS3Server(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	httpmock.RegisterNoResponder(httpmock.InitialTransport.RoundTrip)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newDownloadServer starts a test server that serves body with the given headers
+func newDownloadServer(t *testing.T, body []byte, headers map[string]string) *httptest.Server {
+	t.Helper()
+
+	return newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		if _, ok := headers["Content-Length"]; !ok {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		_, _ = w.Write(body)
+	})
+}
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func TestDownloadNQLExportTo_Success(t *testing.T) {
+	service, _ := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+
+	var out bytes.Buffer
+	n, err := service.DownloadNQLExportTo(context.Background(), server.URL+"/export.csv", &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(downloadTestCSV)), n)
+	assert.Equal(t, downloadTestCSV, out.String())
+}
+
+func TestDownloadNQLExportTo_GzipMagicBytes(t *testing.T) {
+	service, _ := setupMockClient(t)
+	server := newDownloadServer(t, gzipBytes(t, downloadTestCSV), map[string]string{
+		"Content-Type": "application/octet-stream",
+	})
+
+	var out bytes.Buffer
+	n, err := service.DownloadNQLExportTo(context.Background(), server.URL+"/export.csv.gz", &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(downloadTestCSV)), n)
+	assert.Equal(t, downloadTestCSV, out.String())
+}
+
+func TestDownloadNQLExportTo_ContentLengthMismatch(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		// Announce more bytes than are sent, then drop the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(downloadTestCSV)+100))
+		_, _ = w.Write([]byte(downloadTestCSV))
+	})
+
+	var out bytes.Buffer
+	_, err := service.DownloadNQLExportTo(context.Background(), server.URL, &out)
+
+	require.Error(t, err)
+}
+
+func TestDownloadNQLExportTo_HTTPError(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := service.DownloadNQLExportTo(context.Background(), server.URL, &bytes.Buffer{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download failed with status 403")
+}
+
+func TestDownloadNQLExportTo_Validation(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	_, err := service.DownloadNQLExportTo(context.Background(), "", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "download URL cannot be empty")
+
+	_, err = service.DownloadNQLExportTo(context.Background(), "https://example.com", nil)
+	assert.ErrorContains(t, err, "download writer cannot be nil")
+}
+
+func TestDownloadNQLExportToFile_Progress(t *testing.T) {
+	service, _ := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	var lastDownloaded, lastTotal int64
+	result, err := service.DownloadNQLExportToFile(context.Background(), server.URL, path, func(downloaded, total int64) {
+		lastDownloaded, lastTotal = downloaded, total
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(downloadTestCSV)), result.BytesWritten)
+	assert.Equal(t, int64(len(downloadTestCSV)), lastDownloaded)
+	assert.Equal(t, int64(len(downloadTestCSV)), lastTotal)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(data))
+}
+
+func TestDownloadNQLExport_BuffersStream(t *testing.T) {
+	service, _ := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+
+	data, err := service.DownloadNQLExport(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(data))
+}
+
+func TestExportWorkflow_StreamToWriter(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-stream", server.URL+"/export.csv")
+
+	var out bytes.Buffer
+	var progressCalls int
+	opts := DefaultExportOptions().
+		WithWriter(&out).
+		WithOnDownloadProgress(func(downloaded, total int64) { progressCalls++ })
+
+	result, err := service.ExportWorkflow(context.Background(), &ExportRequest{QueryID: "#test_query"}, opts)
+
+	require.NoError(t, err)
+	assert.Nil(t, result.Data)
+	assert.Equal(t, int64(len(downloadTestCSV)), result.BytesWritten)
+	assert.Equal(t, int64(len(downloadTestCSV)), result.Size())
+	assert.Equal(t, downloadTestCSV, out.String())
+	assert.Positive(t, progressCalls)
+}
+
+func TestExportWorkflow_StreamToFile(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-file", server.URL+"/export.csv")
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	result, err := service.ExportWorkflow(context.Background(), &ExportRequest{QueryID: "#test_query"},
+		DefaultExportOptions().WithOutputFile(path))
+
+	require.NoError(t, err)
+	assert.Equal(t, path, result.OutputPath)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(data))
+}
+
+func TestDownloadNQLExportTo_UsesSDKTransportWithoutToken(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	var authorization, userAgent string
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		userAgent = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(downloadTestCSV))
+	})
+
+	_, err := service.DownloadNQLExportTo(context.Background(), server.URL, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	// The request went through the httpmock transport configured on the SDK client
+	assert.Positive(t, httpmock.GetCallCountInfo()["NO_RESPONDER"])
+	assert.Empty(t, authorization)
+	assert.Contains(t, userAgent, "go-api-sdk-nexthink")
+}
+
+func TestDownloadNQLExportTo_RetryAfter(t *testing.T) {
+	service, _ := setupMockClient(t, client.WithRetryCount(1), client.WithRetryWaitTime(time.Millisecond))
+
+	var calls atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(downloadTestCSV))
+	})
+
+	start := time.Now()
+	var out bytes.Buffer
+	_, err := service.DownloadNQLExportTo(context.Background(), server.URL, &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, out.String())
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the Retry-After wait is honoured")
+}
+
+func TestDownloadNQLExportTo_RetryWaitFloor(t *testing.T) {
+	service, _ := setupMockClient(t,
+		client.WithRetryCount(2),
+		client.WithRetryWaitTime(0),
+		client.WithRetryMaxWaitTime(0))
+
+	var calls atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(downloadTestCSV))
+	})
+
+	start := time.Now()
+	_, err := service.DownloadNQLExportTo(context.Background(), server.URL, &bytes.Buffer{})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), 2*minDownloadRetryWait)
+}
+
+func TestDownloadNQLExportTo_ClientWithoutDownloadSupport(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	// Embedding the interface hides the transport's DownloadClient methods
+	basic := NewService(struct{ interfaces.HTTPClient }{service.client})
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+
+	var out bytes.Buffer
+	_, err := basic.DownloadNQLExportTo(context.Background(), server.URL, &out)
+
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, out.String())
+}
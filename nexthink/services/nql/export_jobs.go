@@ -0,0 +1,576 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Export jobs record started exports in a durable store, so an export can be
+// resumed (polled and downloaded) after a process restart instead of re-running
+// the query
+
+var (
+	// ErrExportJobNotFound is returned when a job store has no job for an export ID
+	ErrExportJobNotFound = errors.New("export job not found")
+
+	// ErrExportAlreadyDownloaded is returned when resuming an export that was already downloaded
+	ErrExportAlreadyDownloaded = errors.New("export already downloaded")
+)
+
+// =============================================================================
+// Export Job
+// =============================================================================
+
+// ExportJobState is the lifecycle state of a recorded export job
+type ExportJobState string
+
+const (
+	// ExportJobPending means the export was started and has not finished yet
+	ExportJobPending ExportJobState = "pending"
+
+	// ExportJobCompleted means the export finished but its result has not been downloaded
+	ExportJobCompleted ExportJobState = "completed"
+
+	// ExportJobDownloaded means the export result was downloaded
+	ExportJobDownloaded ExportJobState = "downloaded"
+
+	// ExportJobFailed means the export finished with an ERROR status
+	ExportJobFailed ExportJobState = "failed"
+)
+
+// ExportJob is a recorded export
+type ExportJob struct {
+	ExportID     string         `json:"exportId"`
+	QueryID      string         `json:"queryId"`
+	Platform     string         `json:"platform,omitempty"`
+	Format       string         `json:"format"`
+	Status       string         `json:"status"`
+	State        ExportJobState `json:"state"`
+	OutputPath   string         `json:"outputPath,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	CompletedAt  *time.Time     `json:"completedAt,omitempty"`
+	DownloadedAt *time.Time     `json:"downloadedAt,omitempty"`
+}
+
+// NeedsResume checks if the job still has to be polled or downloaded
+func (j *ExportJob) NeedsResume() bool {
+	return j.State == ExportJobPending || j.State == ExportJobCompleted
+}
+
+// clone returns a deep copy of the job
+func (j *ExportJob) clone() *ExportJob {
+	c := *j
+	if j.CompletedAt != nil {
+		t := *j.CompletedAt
+		c.CompletedAt = &t
+	}
+	if j.DownloadedAt != nil {
+		t := *j.DownloadedAt
+		c.DownloadedAt = &t
+	}
+	return &c
+}
+
+// newExportJob creates the job record for a freshly started export
+func newExportJob(exportID string, req *ExportRequest, status string, opts *ExportOptions) *ExportJob {
+	now := time.Now().UTC()
+	return &ExportJob{
+		ExportID:   exportID,
+		QueryID:    req.QueryID,
+		Platform:   req.Platform,
+		Format:     req.Format,
+		Status:     status,
+		State:      ExportJobPending,
+		OutputPath: opts.OutputPath,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// =============================================================================
+// Export Job Store
+// =============================================================================
+
+// ExportJobStore persists export jobs
+// Implementations must be safe for concurrent use.
+type ExportJobStore interface {
+	// Save creates or replaces the job with the same export ID
+	Save(ctx context.Context, job *ExportJob) error
+
+	// Get returns the job for an export ID, or ErrExportJobNotFound
+	Get(ctx context.Context, exportID string) (*ExportJob, error)
+
+	// List returns all jobs ordered by creation time
+	List(ctx context.Context) ([]*ExportJob, error)
+
+	// Delete removes the job for an export ID (no error if it does not exist)
+	Delete(ctx context.Context, exportID string) error
+}
+
+// MemoryExportJobStore keeps export jobs in memory
+// Useful for tests and for tracking jobs within a single process.
+type MemoryExportJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*ExportJob
+}
+
+var _ ExportJobStore = (*MemoryExportJobStore)(nil)
+
+// NewMemoryExportJobStore creates an empty in-memory job store
+func NewMemoryExportJobStore() *MemoryExportJobStore {
+	return &MemoryExportJobStore{jobs: make(map[string]*ExportJob)}
+}
+
+// Save implements ExportJobStore
+func (m *MemoryExportJobStore) Save(ctx context.Context, job *ExportJob) error {
+	if job == nil || job.ExportID == "" {
+		return fmt.Errorf("export job must have an export ID")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ExportID] = job.clone()
+	return nil
+}
+
+// Get implements ExportJobStore
+func (m *MemoryExportJobStore) Get(ctx context.Context, exportID string) (*ExportJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[exportID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExportJobNotFound, exportID)
+	}
+	return job.clone(), nil
+}
+
+// List implements ExportJobStore
+func (m *MemoryExportJobStore) List(ctx context.Context) ([]*ExportJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedJobs(m.jobs), nil
+}
+
+// Delete implements ExportJobStore
+func (m *MemoryExportJobStore) Delete(ctx context.Context, exportID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, exportID)
+	return nil
+}
+
+// FileExportJobStore keeps export jobs in a JSON file
+// Every change rewrites the file atomically (write to a temporary file, then
+// rename), so a crash never leaves a partially written store. The file is owned
+// by a single process; concurrent writers from several processes are not supported.
+type FileExportJobStore struct {
+	mu   sync.Mutex
+	path string
+	jobs map[string]*ExportJob
+}
+
+var _ ExportJobStore = (*FileExportJobStore)(nil)
+
+// NewFileExportJobStore opens (or creates) a job store backed by the JSON file at path
+func NewFileExportJobStore(path string) (*FileExportJobStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("job store path cannot be empty")
+	}
+
+	store := &FileExportJobStore{
+		path: path,
+		jobs: make(map[string]*ExportJob),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+
+	if len(data) == 0 {
+		return store, nil
+	}
+
+	var jobs []*ExportJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job store %s: %w", path, err)
+	}
+	for _, job := range jobs {
+		store.jobs[job.ExportID] = job
+	}
+
+	return store, nil
+}
+
+// Save implements ExportJobStore
+func (f *FileExportJobStore) Save(ctx context.Context, job *ExportJob) error {
+	if job == nil || job.ExportID == "" {
+		return fmt.Errorf("export job must have an export ID")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, existed := f.jobs[job.ExportID]
+	f.jobs[job.ExportID] = job.clone()
+	if err := f.persist(); err != nil {
+		if existed {
+			f.jobs[job.ExportID] = previous
+		} else {
+			delete(f.jobs, job.ExportID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get implements ExportJobStore
+func (f *FileExportJobStore) Get(ctx context.Context, exportID string) (*ExportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, ok := f.jobs[exportID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrExportJobNotFound, exportID)
+	}
+	return job.clone(), nil
+}
+
+// List implements ExportJobStore
+func (f *FileExportJobStore) List(ctx context.Context) ([]*ExportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedJobs(f.jobs), nil
+}
+
+// Delete implements ExportJobStore
+func (f *FileExportJobStore) Delete(ctx context.Context, exportID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous, existed := f.jobs[exportID]
+	if !existed {
+		return nil
+	}
+
+	delete(f.jobs, exportID)
+	if err := f.persist(); err != nil {
+		f.jobs[exportID] = previous
+		return err
+	}
+	return nil
+}
+
+// persist atomically writes all jobs to the store file
+func (f *FileExportJobStore) persist() error {
+	data, err := json.MarshalIndent(sortedJobs(f.jobs), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job store: %w", err)
+	}
+
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return fmt.Errorf("failed to write job store: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// sortedJobs returns copies of the jobs ordered by creation time, then export ID
+func sortedJobs(jobs map[string]*ExportJob) []*ExportJob {
+	list := make([]*ExportJob, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job.clone())
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ExportID < list[j].ExportID
+	})
+
+	return list
+}
+
+// =============================================================================
+// Job Tracking
+// =============================================================================
+
+// trackExportJob records a status check result on the job
+// Store failures are logged rather than failing the export.
+func (s *Service) trackExportJob(ctx context.Context, store ExportJobStore, job *ExportJob, status *NQLExportStatusResponse) {
+	if store == nil || job == nil || job.Status == status.Status {
+		return
+	}
+
+	now := time.Now().UTC()
+	job.Status = status.Status
+	job.UpdatedAt = now
+
+	switch status.Status {
+	case ExportStatusCompleted:
+		job.State = ExportJobCompleted
+		job.CompletedAt = &now
+	case ExportStatusError:
+		job.State = ExportJobFailed
+		job.Error = status.ErrorDescription
+		job.CompletedAt = &now
+	}
+
+	s.saveExportJob(ctx, store, job)
+}
+
+// markExportJobDownloaded records that the export result was downloaded
+func (s *Service) markExportJobDownloaded(ctx context.Context, store ExportJobStore, job *ExportJob, result *ExportResult) {
+	if store == nil || job == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	job.State = ExportJobDownloaded
+	job.UpdatedAt = now
+	job.DownloadedAt = &now
+	if result.OutputPath != "" {
+		job.OutputPath = result.OutputPath
+	}
+
+	s.saveExportJob(ctx, store, job)
+}
+
+// saveExportJob saves the job, logging failures
+func (s *Service) saveExportJob(ctx context.Context, store ExportJobStore, job *ExportJob) {
+	if err := store.Save(ctx, job); err != nil {
+		s.client.GetLogger().Warn("Failed to update export job",
+			zap.String("export_id", job.ExportID),
+			zap.String("state", string(job.State)),
+			zap.Error(err))
+	}
+}
+
+// =============================================================================
+// Resume Export
+// =============================================================================
+
+// ResumeExport waits for an already started export and downloads its result
+//
+// Use this after a restart to pick up an export started by ExportWorkflow
+// instead of re-running the query. When opts.JobStore is set, the recorded job
+// supplies the format when opts.KeepRecordedFormat is set or opts.Format is
+// empty, and the output path unless opts sets its own destination, is updated as the
+// export progresses, and is marked downloaded at the end.
+// Resuming a job that was already downloaded returns ErrExportAlreadyDownloaded.
+func (s *Service) ResumeExport(ctx context.Context, exportID string, opts *ExportOptions) (*ExportResult, error) {
+	if err := ValidateExportID(exportID); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = DefaultExportOptions()
+	}
+
+	var job *ExportJob
+	if opts.JobStore != nil {
+		recorded, err := opts.JobStore.Get(ctx, exportID)
+		switch {
+		case errors.Is(err, ErrExportJobNotFound):
+			// Untracked export: resume it without a job record
+		case err != nil:
+			return nil, fmt.Errorf("failed to load export job %s: %w", exportID, err)
+		default:
+			job = recorded
+		}
+	}
+
+	if job != nil {
+		switch job.State {
+		case ExportJobDownloaded:
+			return nil, fmt.Errorf("%w: %s", ErrExportAlreadyDownloaded, exportID)
+		case ExportJobFailed:
+			return nil, fmt.Errorf("export failed: %s", job.Error)
+		}
+
+		resumed := *opts
+		if (resumed.KeepRecordedFormat || resumed.Format == "") && job.Format != "" {
+			resumed.Format = job.Format
+		}
+		if !resumed.isStreaming() {
+			resumed.OutputPath = job.OutputPath
+		}
+		opts = &resumed
+	}
+
+	opts.applyDefaults()
+
+	initialStatus := ""
+	if job != nil {
+		initialStatus = job.Status
+	}
+
+	s.client.GetLogger().Info("Resuming NQL export",
+		zap.String("export_id", exportID),
+		zap.String("last_status", initialStatus))
+
+	return s.completeExport(ctx, exportID, opts.Format, initialStatus, opts, time.Now(), job)
+}
+
+// =============================================================================
+// Export Job Manager
+// =============================================================================
+
+// ExportJobOutcome is the result of resuming one export job
+type ExportJobOutcome struct {
+	Job    *ExportJob
+	Result *ExportResult
+	Err    error
+}
+
+// ExportJobManager starts exports through a job store and resumes unfinished
+// jobs, typically at process startup
+//
+// Example:
+//
+//	store, _ := nql.NewFileExportJobStore("/var/lib/worker/exports.json")
+//	manager := nql.NewExportJobManager(nqlService, store, opts)
+//	for _, outcome := range manager.ResumePending(ctx) { ... }
+type ExportJobManager struct {
+	service *Service
+	store   ExportJobStore
+	opts    *ExportOptions
+}
+
+// NewExportJobManager creates a job manager
+// opts are the export options used for every job (nil uses DefaultExportOptions);
+// the manager's store replaces opts.JobStore.
+func NewExportJobManager(service *Service, store ExportJobStore, opts *ExportOptions) *ExportJobManager {
+	return &ExportJobManager{
+		service: service,
+		store:   store,
+		opts:    opts,
+	}
+}
+
+// options returns a copy of the manager's export options bound to its store
+func (m *ExportJobManager) options() *ExportOptions {
+	opts := DefaultExportOptions()
+	if m.opts != nil {
+		copied := *m.opts
+		opts = &copied
+	}
+	opts.JobStore = m.store
+	return opts
+}
+
+// Start runs an export workflow and records it in the store
+func (m *ExportJobManager) Start(ctx context.Context, req *ExportRequest) (*ExportResult, error) {
+	return m.service.ExportWorkflow(ctx, req, m.options())
+}
+
+// Pending returns the jobs that still have to be polled or downloaded
+func (m *ExportJobManager) Pending(ctx context.Context) ([]*ExportJob, error) {
+	jobs, err := m.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+
+	pending := make([]*ExportJob, 0, len(jobs))
+	for _, job := range jobs {
+		if job.NeedsResume() {
+			pending = append(pending, job)
+		}
+	}
+
+	return pending, nil
+}
+
+// ResumePending resumes every pending job in creation order
+// Each job keeps its recorded format and is downloaded at most once; jobs that
+// fail stay in the store with their last known state so they can be retried or inspected.
+func (m *ExportJobManager) ResumePending(ctx context.Context) ([]ExportJobOutcome, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]ExportJobOutcome, 0, len(pending))
+	for _, job := range pending {
+		if ctx.Err() != nil {
+			return outcomes, ctx.Err()
+		}
+
+		opts := m.options()
+		opts.KeepRecordedFormat = true
+		result, err := m.service.ResumeExport(ctx, job.ExportID, opts)
+
+		// Reload the job to report its updated state
+		if updated, getErr := m.store.Get(ctx, job.ExportID); getErr == nil {
+			job = updated
+		}
+		outcomes = append(outcomes, ExportJobOutcome{Job: job, Result: result, Err: err})
+	}
+
+	return outcomes, nil
+}
+
+// Prune deletes downloaded and failed jobs last updated before the cutoff
+// Returns the number of jobs deleted.
+func (m *ExportJobManager) Prune(ctx context.Context, olderThan time.Duration) (int, error) {
+	jobs, err := m.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	deleted := 0
+	for _, job := range jobs {
+		if job.NeedsResume() || job.UpdatedAt.After(cutoff) {
+			continue
+		}
+		if err := m.store.Delete(ctx, job.ExportID); err != nil {
+			return deleted, fmt.Errorf("failed to delete export job %s: %w", job.ExportID, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,230 @@
+package nql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pendingJob returns a job as recorded by ExportWorkflow before a restart
+func pendingJob(exportID, outputPath string) *ExportJob {
+	now := time.Now().UTC()
+	return &ExportJob{
+		ExportID:   exportID,
+		QueryID:    "#test_query",
+		Format:     ExportFormatCSV,
+		Status:     ExportStatusInProgress,
+		State:      ExportJobPending,
+		OutputPath: outputPath,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+func TestMemoryExportJobStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryExportJobStore()
+
+	job := pendingJob("export-1", "")
+	require.NoError(t, store.Save(ctx, job))
+
+	// The store keeps its own copy
+	job.State = ExportJobFailed
+	got, err := store.Get(ctx, "export-1")
+	require.NoError(t, err)
+	assert.Equal(t, ExportJobPending, got.State)
+
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrExportJobNotFound)
+
+	require.NoError(t, store.Delete(ctx, "export-1"))
+	jobs, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestFileExportJobStore_PersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jobs.json")
+
+	store, err := NewFileExportJobStore(path)
+	require.NoError(t, err)
+
+	first := pendingJob("export-1", "")
+	second := pendingJob("export-2", "")
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	require.NoError(t, store.Save(ctx, second))
+	require.NoError(t, store.Save(ctx, first))
+
+	reopened, err := NewFileExportJobStore(path)
+	require.NoError(t, err)
+
+	jobs, err := reopened.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "export-1", jobs[0].ExportID)
+	assert.Equal(t, "export-2", jobs[1].ExportID)
+	assert.Equal(t, "#test_query", jobs[0].QueryID)
+
+	require.NoError(t, reopened.Delete(ctx, "export-1"))
+	reopened, err = NewFileExportJobStore(path)
+	require.NoError(t, err)
+	_, err = reopened.Get(ctx, "export-1")
+	assert.ErrorIs(t, err, ErrExportJobNotFound)
+}
+
+func TestFileExportJobStore_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err := NewFileExportJobStore(path)
+	assert.Error(t, err)
+}
+
+func TestExportWorkflow_RecordsJob(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-tracked", server.URL+"/export.csv")
+	store := NewMemoryExportJobStore()
+
+	_, err := service.ExportWorkflow(context.Background(), &ExportRequest{QueryID: "#test_query"},
+		DefaultExportOptions().WithJobStore(store))
+	require.NoError(t, err)
+
+	job, err := store.Get(context.Background(), "export-tracked")
+	require.NoError(t, err)
+	assert.Equal(t, ExportJobDownloaded, job.State)
+	assert.Equal(t, ExportStatusCompleted, job.Status)
+	assert.Equal(t, "#test_query", job.QueryID)
+	assert.NotNil(t, job.CompletedAt)
+	assert.NotNil(t, job.DownloadedAt)
+}
+
+func TestResumeExport_DownloadsOnce(t *testing.T) {
+	ctx := context.Background()
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-resume", server.URL+"/export.csv")
+
+	path := filepath.Join(t.TempDir(), "export.csv")
+	store := NewMemoryExportJobStore()
+	require.NoError(t, store.Save(ctx, pendingJob("export-resume", path)))
+
+	result, err := service.ResumeExport(ctx, "export-resume", DefaultExportOptions().WithJobStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, path, result.OutputPath)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(data))
+
+	_, err = service.ResumeExport(ctx, "export-resume", DefaultExportOptions().WithJobStore(store))
+	assert.ErrorIs(t, err, ErrExportAlreadyDownloaded)
+}
+
+func TestResumeExport_JSONJob(t *testing.T) {
+	ctx := context.Background()
+	service, baseURL := setupMockClient(t)
+	body := `[{"device.name":"device-01"}]`
+	server := newDownloadServer(t, []byte(body), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-json", server.URL+"/export.json")
+
+	store := NewMemoryExportJobStore()
+	job := pendingJob("export-json", "")
+	job.Format = ExportFormatJSON
+	require.NoError(t, store.Save(ctx, job))
+
+	outcomes, err := NewExportJobManager(service, store, nil).ResumePending(ctx)
+	require.NoError(t, err)
+	require.Len(t, outcomes, 1)
+	require.NoError(t, outcomes[0].Err)
+	assert.Equal(t, ExportFormatJSON, outcomes[0].Result.Format)
+	assert.Equal(t, body, string(outcomes[0].Result.Data))
+
+	// The default options keep the recorded format
+	job.State = ExportJobPending
+	require.NoError(t, store.Save(ctx, job))
+	opts := DefaultExportOptions().WithJobStore(store)
+	assert.Equal(t, ExportFormatCSV, opts.Format)
+	result, err := service.ResumeExport(ctx, "export-json", opts)
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatJSON, result.Format)
+
+	// An explicit format overrides the recorded one
+	job.State = ExportJobPending
+	require.NoError(t, store.Save(ctx, job))
+	result, err = service.ResumeExport(ctx, "export-json",
+		DefaultExportOptions().WithJobStore(store).WithFormat(ExportFormatCSV))
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatCSV, result.Format)
+
+	// So does a format set in a struct literal, even when it is the default
+	job.State = ExportJobPending
+	require.NoError(t, store.Save(ctx, job))
+	result, err = service.ResumeExport(ctx, "export-json", &ExportOptions{Format: ExportFormatCSV, JobStore: store})
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatCSV, result.Format)
+}
+
+func TestResumeExport_Untracked(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-untracked", server.URL+"/export.csv")
+
+	result, err := service.ResumeExport(context.Background(), "export-untracked",
+		DefaultExportOptions().WithJobStore(NewMemoryExportJobStore()))
+
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(result.Data))
+}
+
+func TestResumeExport_Validation(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	_, err := service.ResumeExport(context.Background(), "", nil)
+	assert.Error(t, err)
+}
+
+func TestExportJobManager_ResumePending(t *testing.T) {
+	ctx := context.Background()
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-pending", server.URL+"/export.csv")
+
+	store := NewMemoryExportJobStore()
+	require.NoError(t, store.Save(ctx, pendingJob("export-pending", "")))
+
+	done := pendingJob("export-done", "")
+	done.State = ExportJobDownloaded
+	done.UpdatedAt = time.Now().Add(-48 * time.Hour)
+	require.NoError(t, store.Save(ctx, done))
+
+	manager := NewExportJobManager(service, store, nil)
+
+	pending, err := manager.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "export-pending", pending[0].ExportID)
+
+	outcomes, err := manager.ResumePending(ctx)
+	require.NoError(t, err)
+	require.Len(t, outcomes, 1)
+	require.NoError(t, outcomes[0].Err)
+	assert.Equal(t, ExportJobDownloaded, outcomes[0].Job.State)
+	assert.Equal(t, downloadTestCSV, string(outcomes[0].Result.Data))
+
+	// Nothing left to resume on the next startup
+	outcomes, err = manager.ResumePending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, outcomes)
+
+	deleted, err := manager.Prune(ctx, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
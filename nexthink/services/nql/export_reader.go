@@ -0,0 +1,514 @@
+package nql
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+	"time"
+)
+
+// Export readers stream rows out of downloaded CSV or JSON export files one at
+// a time, so exports of any size can be processed in constant memory
+
+// =============================================================================
+// Export Reader
+// =============================================================================
+
+// ExportReader iterates over the rows of an export file
+//
+// Example:
+//
+//	reader, err := nql.NewExportReader(file, nql.ExportFormatCSV)
+//	for reader.Next() {
+//	    name, _ := reader.GetString(nql.FieldDeviceName)
+//	}
+//	if err := reader.Err(); err != nil { ... }
+type ExportReader struct {
+	format  string
+	columns []string
+	known   map[string]bool
+	types   map[string]ColumnType
+
+	// raw keeps JSON values as decoded and reads CSV cells as JSON scalars
+	// instead of converting them by column type
+	raw bool
+
+	csv     *csv.Reader
+	decoder *json.Decoder
+
+	// pending holds the first JSON row, read ahead to discover the columns and
+	// kept unconverted so column types set before Next still apply to it
+	pending map[string]any
+
+	row   map[string]any
+	index int
+	err   error
+	done  bool
+}
+
+// NewExportReader creates a reader over an export file in the given format
+// (ExportFormatCSV or ExportFormatJSON). CSV files must start with a header row.
+// JSON files may be an array of row objects or newline-delimited row objects.
+func NewExportReader(r io.Reader, format string) (*ExportReader, error) {
+	return newExportReader(r, format, false)
+}
+
+// newExportReader creates an export reader, optionally without type conversion
+func newExportReader(r io.Reader, format string, raw bool) (*ExportReader, error) {
+	if r == nil {
+		return nil, fmt.Errorf("export reader source cannot be nil")
+	}
+
+	reader := &ExportReader{
+		format: strings.ToLower(strings.TrimSpace(format)),
+		known:  make(map[string]bool),
+		types:  make(map[string]ColumnType),
+		index:  -1,
+		raw:    raw,
+	}
+
+	var err error
+	switch reader.format {
+	case ExportFormatCSV:
+		err = reader.initCSV(r)
+	case ExportFormatJSON:
+		err = reader.initJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// initCSV reads the CSV header row
+func (r *ExportReader) initCSV(src io.Reader) error {
+	r.csv = csv.NewReader(bufio.NewReader(src))
+	r.csv.ReuseRecord = true
+
+	header, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		r.addColumn(name)
+	}
+
+	return nil
+}
+
+// initJSON detects the JSON layout and reads ahead the first row to discover the columns
+func (r *ExportReader) initJSON(src io.Reader) error {
+	buffered := bufio.NewReader(src)
+	r.decoder = json.NewDecoder(buffered)
+
+	first, err := peekNonSpace(buffered)
+	if errors.Is(err, io.EOF) {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read JSON export: %w", err)
+	}
+
+	if first == '[' {
+		if _, err := r.decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read JSON export: %w", err)
+		}
+	}
+
+	row, err := r.readJSONRow()
+	if errors.Is(err, io.EOF) {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.pending = row
+
+	return nil
+}
+
+// Format returns the export format being read
+func (r *ExportReader) Format() string {
+	return r.format
+}
+
+// Columns returns the column names in file order
+// For JSON exports, columns first seen after the first row are appended as they appear.
+func (r *ExportReader) Columns() []string {
+	return append([]string(nil), r.columns...)
+}
+
+// ColumnType returns the inferred type of a column
+func (r *ExportReader) ColumnType(column string) ColumnType {
+	if t, ok := r.types[column]; ok {
+		return t
+	}
+	return InferColumnType(column)
+}
+
+// SetColumnType overrides the inferred type of a column
+// Must be called before the first call to Next to affect every row.
+func (r *ExportReader) SetColumnType(column string, t ColumnType) {
+	r.types[column] = t
+}
+
+// SetColumnTypes overrides the inferred types of several columns
+// Example: reader.SetColumnTypes(nql.InferQueryColumnTypes(executedQuery))
+func (r *ExportReader) SetColumnTypes(types map[string]ColumnType) {
+	for column, t := range types {
+		r.types[column] = t
+	}
+}
+
+// Next advances to the next row
+// Returns false when there are no more rows or an error occurred (see Err).
+func (r *ExportReader) Next() bool {
+	if r.done || r.err != nil {
+		r.row = nil
+		return false
+	}
+
+	var row map[string]any
+	var err error
+	if r.csv != nil {
+		row, err = r.readCSVRow()
+	} else if r.pending != nil {
+		row, r.pending = r.convertJSONRow(r.pending), nil
+	} else {
+		row, err = r.readJSONRow()
+		if err == nil {
+			row = r.convertJSONRow(row)
+		}
+	}
+
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			r.err = err
+		}
+		r.done = true
+		r.row = nil
+		return false
+	}
+
+	r.row = row
+	r.index++
+	return true
+}
+
+// Row returns the current row
+// CSV cells are converted according to their column type; cells that cannot be
+// converted are kept as strings and empty cells are nil.
+func (r *ExportReader) Row() map[string]any {
+	return r.row
+}
+
+// Index returns the zero-based index of the current row
+func (r *ExportReader) Index() int {
+	return r.index
+}
+
+// Err returns the first error encountered while reading
+func (r *ExportReader) Err() error {
+	return r.err
+}
+
+// All returns an iterator over the remaining rows and their indices
+// Check Err after the loop to detect read errors.
+//
+// Example: for i, row := range reader.All() { ... }
+func (r *ExportReader) All() iter.Seq2[int, map[string]any] {
+	return func(yield func(int, map[string]any) bool) {
+		for r.Next() {
+			if !yield(r.index, r.row) {
+				return
+			}
+		}
+	}
+}
+
+// =============================================================================
+// Typed Getters
+// =============================================================================
+
+// Get retrieves a field value from the current row
+func (r *ExportReader) Get(field string) (any, error) {
+	if r.row == nil {
+		return nil, fmt.Errorf("no current row: call Next first")
+	}
+
+	val, exists := r.row[field]
+	if !exists {
+		return nil, fmt.Errorf("field '%s' not found in row %d", field, r.index)
+	}
+
+	return val, nil
+}
+
+// GetString retrieves a field value from the current row as a string
+func (r *ExportReader) GetString(field string) (string, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return "", err
+	}
+
+	return coerceString(val), nil
+}
+
+// GetInt retrieves a field value from the current row as an int64
+func (r *ExportReader) GetInt(field string) (int64, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := coerceInt64(val)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d cannot be converted to int64: %w", field, r.index, err)
+	}
+
+	return i, nil
+}
+
+// GetFloat retrieves a field value from the current row as a float64
+func (r *ExportReader) GetFloat(field string) (float64, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := coerceFloat64(val)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d cannot be converted to float64: %w", field, r.index, err)
+	}
+
+	return f, nil
+}
+
+// GetBool retrieves a field value from the current row as a bool
+func (r *ExportReader) GetBool(field string) (bool, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := coerceBool(val)
+	if err != nil {
+		return false, fmt.Errorf("field '%s' at row %d is not a boolean: %w", field, r.index, err)
+	}
+
+	return b, nil
+}
+
+// GetDuration retrieves a field value from the current row as a duration
+// Accepts formatted strings ("12.3 s", "150 ms", "2 h 5 min") and plain numbers in seconds
+func (r *ExportReader) GetDuration(field string) (time.Duration, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := parseDurationValue(val, time.Second)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d: %w", field, r.index, err)
+	}
+
+	return d, nil
+}
+
+// GetBytes retrieves a field value from the current row as a byte size in bytes
+// Accepts formatted strings ("1.5 GB", "512 KB") and plain numbers
+func (r *ExportReader) GetBytes(field string) (int64, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := parseByteSizeValue(val)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d: %w", field, r.index, err)
+	}
+
+	return n, nil
+}
+
+// GetTime retrieves a field value from the current row as a datetime in UTC
+// Accepts ISO 8601 strings and Unix timestamps; empty cells return the zero time
+func (r *ExportReader) GetTime(field string) (time.Time, error) {
+	return r.GetTimeIn(field, time.UTC)
+}
+
+// GetTimeIn retrieves a datetime value, interpreting values without a zone in loc
+func (r *ExportReader) GetTimeIn(field string, loc *time.Location) (time.Time, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := parseTimeValue(val, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("field '%s' at row %d: %w", field, r.index, err)
+	}
+
+	return t, nil
+}
+
+// GetEnum retrieves a string value from the current row that must be one of the allowed values
+func (r *ExportReader) GetEnum(field string, allowed ...string) (string, error) {
+	val, err := r.Get(field)
+	if err != nil {
+		return "", err
+	}
+
+	str, err := checkEnumValue(val, allowed)
+	if err != nil {
+		return "", fmt.Errorf("field '%s' at row %d: %w", field, r.index, err)
+	}
+
+	return str, nil
+}
+
+// Decode decodes the current row into dest, which must be a pointer to a struct
+// See DecodeRow for the supported struct tags.
+func (r *ExportReader) Decode(dest any, opts *DecodeOptions) error {
+	if r.row == nil {
+		return fmt.Errorf("no current row: call Next first")
+	}
+	return DecodeRow(r.row, dest, opts)
+}
+
+// =============================================================================
+// Format Readers
+// =============================================================================
+
+// addColumn registers a column and infers its type
+func (r *ExportReader) addColumn(name string) {
+	r.columns = append(r.columns, name)
+	r.known[name] = true
+	if _, ok := r.types[name]; !ok {
+		r.types[name] = InferColumnType(name)
+	}
+}
+
+// readCSVRow reads and converts the next CSV record
+// Returns io.EOF at end of file.
+func (r *ExportReader) readCSVRow() (map[string]any, error) {
+	record, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV row %d: %w", r.index+1, err)
+	}
+
+	row := make(map[string]any, len(r.columns))
+	for i, column := range r.columns {
+		if i >= len(record) {
+			row[column] = nil
+			continue
+		}
+		if r.raw {
+			row[column] = jsonScalarValue(record[i])
+			continue
+		}
+		val, err := convertColumnValue(r.types[column], record[i])
+		if err != nil {
+			val = record[i]
+		}
+		row[column] = val
+	}
+
+	return row, nil
+}
+
+// readJSONRow reads the next JSON row object, preserving key order for new columns
+// Values are returned as decoded; see convertJSONRow. Returns io.EOF at the end
+// of the array or stream.
+func (r *ExportReader) readJSONRow() (map[string]any, error) {
+	if !r.decoder.More() {
+		return nil, io.EOF
+	}
+
+	tok, err := r.decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON row %d: %w", r.index+1, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("failed to read JSON row %d: expected object, got %v", r.index+1, tok)
+	}
+
+	row := make(map[string]any, len(r.columns))
+	for r.decoder.More() {
+		keyTok, err := r.decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON row %d: %w", r.index+1, err)
+		}
+		key, _ := keyTok.(string)
+
+		var val any
+		if err := r.decoder.Decode(&val); err != nil {
+			return nil, fmt.Errorf("failed to read JSON row %d field '%s': %w", r.index+1, key, err)
+		}
+
+		if !r.known[key] {
+			r.addColumn(key)
+		}
+		row[key] = val
+	}
+
+	if _, err := r.decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON row %d: %w", r.index+1, err)
+	}
+
+	return row, nil
+}
+
+// convertJSONRow converts the string values of a decoded JSON row by column type
+func (r *ExportReader) convertJSONRow(row map[string]any) map[string]any {
+	if r.raw {
+		return row
+	}
+
+	for key, val := range row {
+		if s, ok := val.(string); ok {
+			if converted, err := convertColumnValue(r.types[key], s); err == nil {
+				row[key] = converted
+			}
+		}
+	}
+
+	return row
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
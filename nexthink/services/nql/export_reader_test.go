@@ -0,0 +1,296 @@
+package nql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportReaderCSV = "\ufeffdevice.name,operating_system.platform,number_of_crashes,hardware.memory,device.last_seen,execution_duration,avg_cpu\n" +
+	"device-01,Windows,3,16 GB,2024-02-08T10:15:30Z,12.5 s,0.25\n" +
+	"device-02,macOS,,8 GB,2024-02-07T09:00:00Z,150 ms,0.5\n"
+
+func TestInferColumnType(t *testing.T) {
+	tests := map[string]ColumnType{
+		FieldDeviceName:                ColumnTypeString,
+		FieldOSPlatform:                ColumnTypeString,
+		FieldNumberOfCrashes:           ColumnTypeInt,
+		FieldDeviceDaysSinceLastSeen:   ColumnTypeInt,
+		"count":                        ColumnTypeInt,
+		FieldHardwareMemory:            ColumnTypeBytes,
+		FieldBinarySize:                ColumnTypeBytes,
+		FieldDeviceLastSeen:            ColumnTypeDateTime,
+		FieldOSLastUpdate:              ColumnTypeDateTime,
+		"start_time":                   ColumnTypeDateTime,
+		FieldExecutionDuration:         ColumnTypeDuration,
+		FieldTimeUntilDesktopIsVisible: ColumnTypeDuration,
+		FieldPageLoadTimeOverall:       ColumnTypeDuration,
+		FieldDexEndpointValue:          ColumnTypeFloat,
+		FieldDexBootSpeedScoreImpact:   ColumnTypeFloat,
+		"avg_cpu":                      ColumnTypeFloat,
+		"device.is_virtual":            ColumnTypeBool,
+		FieldExperienceLevel:           ColumnTypeString,
+	}
+
+	for name, want := range tests {
+		assert.Equal(t, want, InferColumnType(name), name)
+	}
+}
+
+func TestInferQueryColumnTypes(t *testing.T) {
+	types := InferQueryColumnTypes(`devices during past 7d
+| list device.name, device.last_seen
+| compute crash_count = execution.crashes.count(), avg_boot = boot.duration.avg(), ratio = a / b
+| summarize avg_crashes = crash_count.avg(), memory = device.memory.max(), days = count() by device.name, start_time by 1d`)
+
+	assert.Equal(t, map[string]ColumnType{
+		"device.name":      ColumnTypeString,
+		"device.last_seen": ColumnTypeDateTime,
+		"crash_count":      ColumnTypeInt,
+		"avg_boot":         ColumnTypeDuration,
+		"ratio":            ColumnTypeFloat,
+		"avg_crashes":      ColumnTypeFloat,
+		"memory":           ColumnTypeBytes,
+		"days":             ColumnTypeInt,
+		"start_time":       ColumnTypeDateTime,
+	}, types)
+}
+
+func TestInferQueryColumns(t *testing.T) {
+	columns := InferQueryColumns(`devices during past 7d
+| with execution.crashes during past 7d
+| compute crash_count = count(), crash_ratio = count() / 7
+| list device.name, crash_count, device.last_seen`)
+
+	assert.Equal(t, []QueryColumn{
+		{Name: "device.name", Type: ColumnTypeString},
+		{Name: "crash_count", Type: ColumnTypeInt},
+		{Name: "device.last_seen", Type: ColumnTypeDateTime},
+	}, columns)
+
+	columns = InferQueryColumns(`execution.crashes during past 7d
+| summarize crashes = count(), avg_boot = boot.duration.avg() by device.name, start_time by 1d`)
+
+	assert.Equal(t, []QueryColumn{
+		{Name: "crashes", Type: ColumnTypeInt},
+		{Name: "avg_boot", Type: ColumnTypeDuration},
+		{Name: "device.name", Type: ColumnTypeString},
+		{Name: "start_time", Type: ColumnTypeDateTime},
+	}, columns)
+
+	columns = InferQueryColumns("execution.crashes during past 7d | summarize crashes = count() by 1d")
+	assert.Equal(t, []QueryColumn{
+		{Name: "crashes", Type: ColumnTypeInt},
+		{Name: "start_time", Type: ColumnTypeDateTime},
+	}, columns)
+
+	columns = InferQueryColumns("execution.crashes during past 7d | summarize crashes = count() by 15 min")
+	assert.Equal(t, []QueryColumn{
+		{Name: "crashes", Type: ColumnTypeInt},
+		{Name: "start_time", Type: ColumnTypeDateTime},
+	}, columns)
+
+	columns = InferQueryColumns("devices | include execution.crashes | compute total = count()")
+	assert.Equal(t, []QueryColumn{{Name: "total", Type: ColumnTypeInt}}, columns)
+
+	assert.Empty(t, InferQueryColumns("devices during past 7d"))
+}
+
+func TestExportReader_CSV(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader(exportReaderCSV), ExportFormatCSV)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"device.name", "operating_system.platform", "number_of_crashes",
+		"hardware.memory", "device.last_seen", "execution_duration", "avg_cpu",
+	}, reader.Columns())
+
+	require.True(t, reader.Next())
+	assert.Equal(t, 0, reader.Index())
+
+	name, err := reader.GetString(FieldDeviceName)
+	require.NoError(t, err)
+	assert.Equal(t, "device-01", name)
+
+	crashes, err := reader.GetInt(FieldNumberOfCrashes)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), crashes)
+
+	cpu, err := reader.GetFloat("avg_cpu")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, cpu, 0.0001)
+
+	row := reader.Row()
+	assert.Equal(t, int64(16<<30), row[FieldHardwareMemory])
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC), row[FieldDeviceLastSeen])
+	assert.Equal(t, 12500*time.Millisecond, row[FieldExecutionDuration])
+
+	require.True(t, reader.Next())
+	assert.Nil(t, reader.Row()[FieldNumberOfCrashes])
+	crashes, err = reader.GetInt(FieldNumberOfCrashes)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), crashes)
+
+	assert.False(t, reader.Next())
+	assert.NoError(t, reader.Err())
+}
+
+func TestExportReader_TypedGetters(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader(exportReaderCSV), ExportFormatCSV)
+	require.NoError(t, err)
+	require.True(t, reader.Next())
+
+	memory, err := reader.GetBytes(FieldHardwareMemory)
+	require.NoError(t, err)
+	assert.Equal(t, int64(16<<30), memory)
+
+	lastSeen, err := reader.GetTime(FieldDeviceLastSeen)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC), lastSeen)
+
+	duration, err := reader.GetDuration(FieldExecutionDuration)
+	require.NoError(t, err)
+	assert.Equal(t, 12500*time.Millisecond, duration)
+
+	platform, err := reader.GetEnum(FieldOSPlatform, PlatformWindows, PlatformMacOS)
+	require.NoError(t, err)
+	assert.Equal(t, PlatformWindows, platform)
+
+	_, err = reader.GetEnum(FieldOSPlatform, PlatformLinux)
+	assert.ErrorContains(t, err, "not one of")
+
+	_, err = reader.GetDuration(FieldDeviceName)
+	assert.Error(t, err)
+
+	// Raw string cells are parsed by the getters
+	reader, err = NewExportReader(strings.NewReader(exportReaderCSV), ExportFormatCSV)
+	require.NoError(t, err)
+	reader.SetColumnTypes(map[string]ColumnType{
+		FieldHardwareMemory:    ColumnTypeString,
+		FieldDeviceLastSeen:    ColumnTypeString,
+		FieldExecutionDuration: ColumnTypeString,
+	})
+	require.True(t, reader.Next())
+	require.True(t, reader.Next())
+
+	memory, err = reader.GetBytes(FieldHardwareMemory)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8<<30), memory)
+
+	lastSeen, err = reader.GetTime(FieldDeviceLastSeen)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 7, 9, 0, 0, 0, time.UTC), lastSeen)
+
+	duration, err = reader.GetDuration(FieldExecutionDuration)
+	require.NoError(t, err)
+	assert.Equal(t, 150*time.Millisecond, duration)
+}
+
+func TestExportReader_JSONArray(t *testing.T) {
+	data := `[
+		{"device.name": "device-01", "number_of_crashes": 3, "device.is_virtual": true},
+		{"device.name": "device-02", "number_of_crashes": "5", "extra": "x"}
+	]`
+
+	reader, err := NewExportReader(strings.NewReader(data), "JSON")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"device.name", "number_of_crashes", "device.is_virtual"}, reader.Columns())
+
+	var names []string
+	var total int64
+	for i, row := range reader.All() {
+		assert.Equal(t, i, reader.Index())
+		names = append(names, row[FieldDeviceName].(string))
+		n, err := reader.GetInt(FieldNumberOfCrashes)
+		require.NoError(t, err)
+		total += n
+	}
+
+	require.NoError(t, reader.Err())
+	assert.Equal(t, []string{"device-01", "device-02"}, names)
+	assert.Equal(t, int64(8), total)
+	assert.Contains(t, reader.Columns(), "extra")
+}
+
+func TestExportReader_NDJSON(t *testing.T) {
+	data := "{\"device.name\":\"a\"}\n{\"device.name\":\"b\"}\n"
+
+	reader, err := NewExportReader(strings.NewReader(data), ExportFormatJSON)
+	require.NoError(t, err)
+
+	count := 0
+	for reader.Next() {
+		count++
+	}
+	require.NoError(t, reader.Err())
+	assert.Equal(t, 2, count)
+}
+
+func TestExportReader_Empty(t *testing.T) {
+	for _, tc := range []struct{ format, data string }{
+		{ExportFormatCSV, ""},
+		{ExportFormatJSON, "  "},
+		{ExportFormatJSON, "[]"},
+	} {
+		reader, err := NewExportReader(strings.NewReader(tc.data), tc.format)
+		require.NoError(t, err)
+		assert.False(t, reader.Next())
+		assert.NoError(t, reader.Err())
+	}
+}
+
+func TestExportReader_Errors(t *testing.T) {
+	_, err := NewExportReader(strings.NewReader(""), "xml")
+	assert.ErrorContains(t, err, "unsupported export format")
+
+	reader, err := NewExportReader(strings.NewReader("a,b\n1,2,3\n"), ExportFormatCSV)
+	require.NoError(t, err)
+	assert.False(t, reader.Next())
+	assert.Error(t, reader.Err())
+
+	reader, err = NewExportReader(strings.NewReader(`[{"a":1}, 5]`), ExportFormatJSON)
+	require.NoError(t, err)
+	assert.True(t, reader.Next())
+	assert.False(t, reader.Next())
+	assert.ErrorContains(t, reader.Err(), "expected object")
+
+	_, err = reader.Get("a")
+	assert.ErrorContains(t, err, "no current row")
+}
+
+func TestExportReader_SetColumnTypeAndDecode(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader("device.name,score\ndevice-01,42\n"), ExportFormatCSV)
+	require.NoError(t, err)
+	reader.SetColumnType("score", ColumnTypeInt)
+	assert.Equal(t, ColumnTypeInt, reader.ColumnType("score"))
+
+	require.True(t, reader.Next())
+	assert.Equal(t, int64(42), reader.Row()["score"])
+
+	var device struct {
+		Name  string `nql:"device.name"`
+		Score int    `nql:"score"`
+	}
+	require.NoError(t, reader.Decode(&device, nil))
+	assert.Equal(t, "device-01", device.Name)
+	assert.Equal(t, 42, device.Score)
+}
+
+func TestExportReader_JSONSetColumnTypeAppliesToFirstRow(t *testing.T) {
+	data := `[{"n": "12"}, {"n": "13"}]`
+
+	reader, err := NewExportReader(strings.NewReader(data), ExportFormatJSON)
+	require.NoError(t, err)
+	reader.SetColumnType("n", ColumnTypeInt)
+
+	var values []any
+	for _, row := range reader.All() {
+		values = append(values, row["n"])
+	}
+
+	require.NoError(t, reader.Err())
+	assert.Equal(t, []any{int64(12), int64(13)}, values)
+}
@@ -0,0 +1,507 @@
+package nql
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/md5" //nolint:gosec // S3 ETags and Content-MD5 headers are MD5 digests
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Resumable downloads write exports to a temporary file, resume interrupted
+// transfers with HTTP Range requests and verify the result against the
+// checksums S3 provides before atomically renaming it into place
+
+// ErrChecksumMismatch is returned when a downloaded export does not match the
+// ETag or Content-MD5 reported by S3
+var ErrChecksumMismatch = errors.New("export checksum mismatch")
+
+// =============================================================================
+// Resumable Download Options
+// =============================================================================
+
+// ResumableDownloadOptions configures resumable downloads
+type ResumableDownloadOptions struct {
+	// MaxRetries is the number of times an interrupted download is resumed
+	// Defaults to 5 if not specified
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry, doubled on each retry
+	// Defaults to 1 second if not specified
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries
+	// A longer Retry-After on a 429 or 503 response is still honoured.
+	// Defaults to 30 seconds if not specified
+	MaxBackoff time.Duration
+
+	// SkipChecksum disables ETag/Content-MD5 verification
+	SkipChecksum bool
+
+	// OnProgress is an optional callback for download progress
+	// bytesDownloaded includes bytes received by earlier attempts
+	OnProgress DownloadProgressFunc
+
+	// OnRetry is an optional callback fired before each retry
+	// offset is the byte position the download resumes from
+	OnRetry func(attempt int, offset int64, err error)
+}
+
+// DefaultResumableDownloadOptions returns resumable download options with sensible defaults
+func DefaultResumableDownloadOptions() *ResumableDownloadOptions {
+	return &ResumableDownloadOptions{
+		MaxRetries:     5,
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// withDefaults returns a copy of the options with unset values defaulted
+func (opts *ResumableDownloadOptions) withDefaults() ResumableDownloadOptions {
+	resolved := *DefaultResumableDownloadOptions()
+	if opts == nil {
+		return resolved
+	}
+
+	resolved.SkipChecksum = opts.SkipChecksum
+	resolved.OnProgress = opts.OnProgress
+	resolved.OnRetry = opts.OnRetry
+	if opts.MaxRetries > 0 {
+		resolved.MaxRetries = opts.MaxRetries
+	}
+	if opts.InitialBackoff > 0 {
+		resolved.InitialBackoff = opts.InitialBackoff
+	}
+	if opts.MaxBackoff > 0 {
+		resolved.MaxBackoff = opts.MaxBackoff
+	}
+
+	return resolved
+}
+
+// backoff returns the wait before the given retry attempt (1-based)
+func (opts *ResumableDownloadOptions) backoff(attempt int) time.Duration {
+	wait := opts.InitialBackoff
+	for i := 1; i < attempt && wait < opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, opts.MaxBackoff)
+}
+
+// =============================================================================
+// Resumable Download
+// =============================================================================
+
+// DownloadNQLExportResumable downloads a completed export to path, resuming on failure
+//
+// The export is written to "<path>.part" first. When the connection drops, the
+// download resumes from the last received byte with an HTTP Range request
+// (guarded by If-Range so a changed object restarts from scratch), waiting with
+// exponential backoff between attempts. Once complete, the file is verified
+// against the Content-MD5 header or single-part ETag when S3 provides them,
+// decompressed if gzip-encoded, and atomically renamed to path.
+//
+// Client errors such as an expired pre-signed URL (403) are not retried.
+func (s *Service) DownloadNQLExportResumable(ctx context.Context, downloadURL, path string, opts *ResumableDownloadOptions) (*DownloadResult, error) {
+	if downloadURL == "" {
+		return nil, fmt.Errorf("download URL cannot be empty")
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("output path cannot be empty")
+	}
+
+	resolved := opts.withDefaults()
+	partPath := path + ".part"
+
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	dl := &resumableDownload{
+		service: s,
+		url:     downloadURL,
+		file:    file,
+		opts:    &resolved,
+		total:   -1,
+	}
+
+	result, err := dl.run(ctx)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close temporary file: %w", closeErr)
+	}
+	if err == nil {
+		err = dl.finalize(partPath, path, result)
+	}
+	if err != nil {
+		_ = os.Remove(partPath)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// resumableDownload tracks the state of a resumable download across attempts
+type resumableDownload struct {
+	service *Service
+	url     string
+	file    *os.File
+	opts    *ResumableDownloadOptions
+
+	offset      int64
+	total       int64
+	received    int64
+	etag        string
+	contentMD5  string
+	gzipEncoded bool
+}
+
+// run downloads the object, retrying retryable failures
+func (d *resumableDownload) run(ctx context.Context) (*DownloadResult, error) {
+	var lastErr error
+	var retryAfter time.Duration
+
+	for attempt := 0; attempt <= d.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if d.opts.OnRetry != nil {
+				d.opts.OnRetry(attempt, d.offset, lastErr)
+			}
+
+			d.service.client.GetLogger().Warn("Resuming interrupted export download",
+				zap.Int("attempt", attempt),
+				zap.Int64("offset", d.offset),
+				zap.Error(lastErr))
+
+			timer := time.NewTimer(max(d.opts.backoff(attempt), retryAfter))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("download cancelled: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		retryable, wait, err := d.attempt(ctx)
+		if err == nil {
+			return &DownloadResult{
+				BytesDownloaded: d.received,
+				ContentLength:   d.total,
+				Attempts:        attempt + 1,
+			}, nil
+		}
+
+		if !retryable || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr, retryAfter = err, wait
+	}
+
+	return nil, fmt.Errorf("download failed after %d attempts: %w", d.opts.MaxRetries+1, lastErr)
+}
+
+// attempt performs a single (possibly ranged) request and appends the body to the file
+// Returns whether a failure can be retried and the wait requested by a
+// Retry-After header, if any.
+func (d *resumableDownload) attempt(ctx context.Context) (bool, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	// Keep the stored bytes intact so ranges and checksums line up
+	req.Header.Set("Accept-Encoding", "identity")
+	if d.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.offset))
+		if d.etag != "" {
+			req.Header.Set("If-Range", d.etag)
+		}
+	}
+
+	resp, err := d.service.downloadClient().Do(req)
+	if err != nil {
+		return true, 0, fmt.Errorf("failed to download export: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// end is the offset the body should reach: the object size when known,
+	// otherwise the end of the returned range or of the declared body
+	end := int64(-1)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Full object: either the first request, or the server ignored the range
+		if err := d.restart(); err != nil {
+			return false, 0, err
+		}
+		d.total = resp.ContentLength
+		d.etag = resp.Header.Get("ETag")
+		d.contentMD5 = resp.Header.Get("Content-MD5")
+		d.gzipEncoded = strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip")
+
+	case http.StatusPartialContent:
+		start, last, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != d.offset {
+			if err := d.restart(); err != nil {
+				return false, 0, err
+			}
+			return true, 0, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), d.offset)
+		}
+		if total >= 0 {
+			d.total = total
+		}
+		end = last + 1
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		if d.total >= 0 && d.offset == d.total {
+			return false, 0, nil
+		}
+		if err := d.restart(); err != nil {
+			return false, 0, err
+		}
+		return true, 0, fmt.Errorf("range not satisfiable at offset %d", d.offset)
+
+	default:
+		err := fmt.Errorf("download failed with status %d: %s", resp.StatusCode, resp.Status)
+		return isRetryableDownloadStatus(resp.StatusCode), retryAfterHeader(resp.Header), err
+	}
+
+	if d.total >= 0 {
+		end = d.total
+	} else if end < 0 && resp.ContentLength >= 0 {
+		end = d.offset + resp.ContentLength
+	}
+
+	if _, err := d.file.Seek(d.offset, io.SeekStart); err != nil {
+		return false, 0, fmt.Errorf("failed to seek temporary file: %w", err)
+	}
+
+	base := d.offset
+	counter := &countingReader{reader: resp.Body, total: d.total}
+	if d.opts.OnProgress != nil {
+		counter.progress = func(read, total int64) {
+			d.opts.OnProgress(base+read, total)
+		}
+	}
+
+	written, err := io.Copy(d.file, counter)
+	d.offset += written
+	d.received += counter.read
+	if err != nil {
+		return true, 0, fmt.Errorf("failed to stream export data: %w", err)
+	}
+
+	if end >= 0 && d.offset != end {
+		return true, 0, fmt.Errorf("incomplete download: stream ended at byte %d, expected %d", d.offset, end)
+	}
+
+	return false, 0, nil
+}
+
+// restart discards any partial data so the download starts from the beginning
+func (d *resumableDownload) restart() error {
+	d.offset = 0
+	if err := d.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to reset temporary file: %w", err)
+	}
+	return nil
+}
+
+// finalize verifies the checksum, decompresses gzip data and renames the file into place
+func (d *resumableDownload) finalize(partPath, path string, result *DownloadResult) error {
+	if !d.opts.SkipChecksum {
+		checksum, err := d.verifyChecksum(partPath)
+		if err != nil {
+			return err
+		}
+		result.Checksum = checksum
+	}
+
+	gzipped, err := d.isGzipFile(partPath)
+	if err != nil {
+		return err
+	}
+
+	if !gzipped {
+		result.BytesWritten = d.offset
+		if err := os.Rename(partPath, path); err != nil {
+			return fmt.Errorf("failed to move download into place: %w", err)
+		}
+		return nil
+	}
+
+	written, err := decompressFile(partPath, path)
+	if err != nil {
+		return err
+	}
+	result.BytesWritten = written
+	result.Decompressed = true
+
+	return os.Remove(partPath)
+}
+
+// verifyChecksum compares the file's MD5 against Content-MD5 or a single-part ETag
+// Returns the verified checksum (hex encoded), or "" when S3 provided none.
+func (d *resumableDownload) verifyChecksum(partPath string) (string, error) {
+	expected := expectedMD5(d.contentMD5, d.etag)
+	if expected == "" {
+		return "", nil
+	}
+
+	file, err := os.Open(partPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open temporary file: %w", err)
+	}
+	defer file.Close()
+
+	hash := md5.New() //nolint:gosec // matching the digest S3 computed
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to checksum download: %w", err)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if actual != expected {
+		return "", fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
+	}
+
+	return actual, nil
+}
+
+// isGzipFile checks the Content-Encoding header and the gzip magic bytes of the file
+func (d *resumableDownload) isGzipFile(partPath string) (bool, error) {
+	if d.gzipEncoded {
+		return true, nil
+	}
+
+	file, err := os.Open(partPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open temporary file: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(gzipMagic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, fmt.Errorf("failed to read temporary file: %w", err)
+	}
+
+	return n == len(gzipMagic) && header[0] == gzipMagic[0] && header[1] == gzipMagic[1], nil
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+// md5ETagPattern matches single-part S3 ETags, which are the object's MD5 digest
+// Multipart ETags ("<hash>-<parts>") are not content digests and are skipped
+var md5ETagPattern = regexp.MustCompile(`^(?:W/)?"?([0-9a-fA-F]{32})"?$`)
+
+// expectedMD5 returns the hex MD5 the download should match, or "" if unknown
+func expectedMD5(contentMD5, etag string) string {
+	if contentMD5 != "" {
+		if raw, err := base64.StdEncoding.DecodeString(contentMD5); err == nil && len(raw) == md5.Size {
+			return hex.EncodeToString(raw)
+		}
+	}
+
+	if match := md5ETagPattern.FindStringSubmatch(etag); match != nil {
+		return strings.ToLower(match[1])
+	}
+
+	return ""
+}
+
+// parseContentRange parses a "bytes start-end/total" Content-Range header
+// end is the last byte of the range; total is -1 when the server reports it as "*".
+func parseContentRange(header string) (start, end, total int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	rangePart, totalPart, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	startPart, endPart, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(startPart, 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	end, err = strconv.ParseInt(endPart, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, false
+	}
+
+	total = -1
+	if totalPart != "*" {
+		if total, err = strconv.ParseInt(totalPart, 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+
+	return start, end, total, true
+}
+
+// isRetryableDownloadStatus checks if an HTTP status indicates a transient failure
+func isRetryableDownloadStatus(status int) bool {
+	return status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= http.StatusInternalServerError
+}
+
+// decompressFile gunzips src into a temporary file next to dst and renames it to dst
+func decompressFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open temporary file: %w", err)
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(bufio.NewReader(in))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	written, err := io.Copy(out, gz)
+	if err == nil {
+		err = out.Chmod(0o644)
+	}
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return 0, fmt.Errorf("failed to decompress export: %w", err)
+	}
+
+	return written, nil
+}
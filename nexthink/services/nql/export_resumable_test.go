@@ -0,0 +1,268 @@
+package nql
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // S3 ETags are MD5 digests
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resumableTestData returns a payload large enough to be split across attempts
+func resumableTestData() []byte {
+	return bytes.Repeat([]byte("device-01,Windows,3\n"), 4096)
+}
+
+// md5ETag returns the quoted S3-style ETag for data
+func md5ETag(data []byte) string {
+	sum := md5.Sum(data) //nolint:gosec // matching S3 ETags
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// newFlakyServer serves data with Range support, dropping the connection
+// halfway through the first failures responses
+func newFlakyServer(t *testing.T, data []byte, etag string, failures int32, ranges *[]string) *httptest.Server {
+	t.Helper()
+
+	var calls atomic.Int32
+	return newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		if ranges != nil {
+			*ranges = append(*ranges, r.Header.Get("Range"))
+		}
+		w.Header().Set("ETag", etag)
+
+		if calls.Add(1) <= failures {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	})
+}
+
+func fastResumableOptions() *ResumableDownloadOptions {
+	return &ResumableDownloadOptions{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func TestDownloadNQLExportResumable_ResumesWithRange(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+
+	var ranges []string
+	server := newFlakyServer(t, data, md5ETag(data), 1, &ranges)
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	opts := fastResumableOptions()
+	var retries []int64
+	opts.OnRetry = func(attempt int, offset int64, err error) {
+		retries = append(retries, offset)
+	}
+
+	result, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, int64(len(data)), result.BytesWritten)
+	assert.Equal(t, int64(len(data)), result.ContentLength)
+	assert.Equal(t, md5ETag(data)[1:33], result.Checksum)
+	assert.Equal(t, []int64{int64(len(data) / 2)}, retries)
+	require.Len(t, ranges, 2)
+	assert.Empty(t, ranges[0])
+	assert.Equal(t, "bytes="+strconv.Itoa(len(data)/2)+"-", ranges[1])
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, written)
+
+	_, err = os.Stat(path + ".part")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadNQLExportResumable_ChecksumMismatch(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+	server := newFlakyServer(t, data, md5ETag([]byte("something else")), 0, nil)
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	_, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, fastResumableOptions())
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, statErr := os.Stat(path)
+	assert.True(t, os.IsNotExist(statErr))
+	_, statErr = os.Stat(path + ".part")
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestDownloadNQLExportResumable_MultipartETagSkipsChecksum(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+	server := newFlakyServer(t, data, `"0123456789abcdef0123456789abcdef-3"`, 0, nil)
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	result, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, fastResumableOptions())
+	require.NoError(t, err)
+	assert.Empty(t, result.Checksum)
+}
+
+func TestDownloadNQLExportResumable_GivesUp(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+	server := newFlakyServer(t, data, md5ETag(data), 100, nil)
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	_, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, fastResumableOptions())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download failed after 4 attempts")
+}
+
+func TestDownloadNQLExportResumable_ClientErrorNotRetried(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	var calls atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := service.DownloadNQLExportResumable(context.Background(), server.URL,
+		filepath.Join(t.TempDir(), "export.csv"), fastResumableOptions())
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDownloadNQLExportResumable_RetryAfter(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+
+	var calls atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write(data)
+	})
+
+	start := time.Now()
+	result, err := service.DownloadNQLExportResumable(context.Background(), server.URL,
+		filepath.Join(t.TempDir(), "export.csv"), fastResumableOptions())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Attempts)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the Retry-After wait is honoured")
+}
+
+func TestDownloadNQLExportResumable_Gzip(t *testing.T) {
+	service, _ := setupMockClient(t)
+	compressed := gzipBytes(t, downloadTestCSV)
+	server := newDownloadServer(t, compressed, map[string]string{
+		"Content-Encoding": "gzip",
+		"ETag":             md5ETag(compressed),
+	})
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	result, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, fastResumableOptions())
+	require.NoError(t, err)
+	assert.True(t, result.Decompressed)
+	assert.Equal(t, int64(len(downloadTestCSV)), result.BytesWritten)
+	assert.NotEmpty(t, result.Checksum)
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloadTestCSV, string(written))
+}
+
+func TestExportWorkflow_ResumableDownload(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	data := resumableTestData()
+	server := newFlakyServer(t, data, md5ETag(data), 1, nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-resumable", server.URL+"/export.csv")
+	path := filepath.Join(t.TempDir(), "export.csv")
+
+	var retries int
+	opts := DefaultExportOptions().
+		WithResumableDownload(path, fastResumableOptions()).
+		WithOnDownloadRetry(func(attempt int, offset int64, err error) { retries++ })
+
+	result, err := service.ExportWorkflow(context.Background(), &ExportRequest{QueryID: "#test_query"}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retries)
+	assert.Equal(t, int64(len(data)), result.BytesWritten)
+	assert.Equal(t, path, result.OutputPath)
+}
+
+func TestDownloadNQLExportResumable_TruncatedUnknownLength(t *testing.T) {
+	service, _ := setupMockClient(t)
+	data := resumableTestData()
+	half := len(data) / 2
+
+	// The second response has no length and an unknown total, and its
+	// connection closes before the end of the declared range
+	var calls atomic.Int32
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		case 2:
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			defer conn.Close()
+			_, _ = fmt.Fprintf(buf, "HTTP/1.1 206 Partial Content\r\nContent-Range: bytes %d-%d/*\r\nConnection: close\r\n\r\n", half, len(data)-1)
+			_, _ = buf.Write(data[half : half+100])
+			_ = buf.Flush()
+		default:
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		}
+	})
+
+	path := filepath.Join(t.TempDir(), "export.csv")
+	result, err := service.DownloadNQLExportResumable(context.Background(), server.URL, path, fastResumableOptions())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Attempts, "the truncated range is retried")
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, written)
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, end, total, ok := parseContentRange("bytes 100-199/1000")
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(199), end)
+	assert.Equal(t, int64(1000), total)
+
+	_, end, total, ok = parseContentRange("bytes 0-99/*")
+	assert.True(t, ok)
+	assert.Equal(t, int64(99), end)
+	assert.Equal(t, int64(-1), total)
+
+	_, _, _, ok = parseContentRange("items 0-1/2")
+	assert.False(t, ok)
+
+	_, _, _, ok = parseContentRange("bytes 10-5/20")
+	assert.False(t, ok)
+}
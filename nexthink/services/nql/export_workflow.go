@@ -1,8 +1,10 @@
 package nql
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,11 +22,25 @@ type ExportOptions struct {
 	// Format specifies the export format ("csv" or "json")
 	// Defaults to "csv" if not specified
 	Format string
+
+	// KeepRecordedFormat makes ResumeExport use the recorded job's format instead of Format
+	// Set by DefaultExportOptions and cleared by WithFormat. ResumeExport also
+	// uses the recorded format when Format is empty.
+	KeepRecordedFormat bool
 	
 	// PollInterval is how often to check export status
-	// Defaults to 5 seconds if not specified
+	// Defaults to 5 seconds if not specified. Ignored when PollStrategy is set
 	PollInterval time.Duration
 	
+	// PollStrategy optionally controls the wait between status checks
+	// (fixed, exponential, exponential with jitter, capped). See DefaultPollStrategy
+	PollStrategy PollStrategy
+	
+	// MaxStatusErrors is the number of consecutive transient status check failures
+	// (network errors, 429, 5xx) tolerated before the wait is aborted
+	// Defaults to DefaultMaxStatusErrors if not specified
+	MaxStatusErrors int
+	
 	// Timeout is the maximum time to wait for export completion
 	// Defaults to 10 minutes if not specified
 	Timeout time.Duration
@@ -36,20 +52,52 @@ type ExportOptions struct {
 	// OnStatusChange is an optional callback that fires when status changes
 	// Called only when the export status transitions to a new state
 	OnStatusChange func(oldStatus, newStatus string, elapsedTime time.Duration)
+	
+	// Writer optionally streams the downloaded export to this writer
+	// When set, ExportResult.Data is left empty and memory use stays bounded
+	Writer io.Writer
+	
+	// OutputPath optionally streams the downloaded export to this file
+	// Ignored when Writer is set
+	OutputPath string
+	
+	// OnDownloadProgress is an optional callback for download progress
+	// Called as bytes are received from S3 (totalBytes is -1 when unknown)
+	OnDownloadProgress DownloadProgressFunc
+	
+	// Resumable optionally makes OutputPath downloads resumable and checksummed
+	// See DownloadNQLExportResumable. Ignored unless OutputPath is set.
+	Resumable *ResumableDownloadOptions
+	
+	// OnDownloadRetry is an optional callback fired before a resumable download retries
+	// Called with the retry attempt, the byte offset it resumes from and the failure
+	OnDownloadRetry func(attempt int, offset int64, err error)
+	
+	// JobStore optionally records the export so it can be resumed after a restart
+	// See ResumeExport and ExportJobManager
+	JobStore ExportJobStore
+	
+	// RateLimiter optionally throttles the export API calls (start and status checks)
+	// Share one limiter between concurrent exports to keep them within a common budget
+	RateLimiter RateLimiter
 }
 
 // DefaultExportOptions returns export options with sensible defaults
+// New exports use csv and resumed exports keep their recorded format.
 func DefaultExportOptions() *ExportOptions {
 	return &ExportOptions{
-		Format:       ExportFormatCSV,
-		PollInterval: 5 * time.Second,
-		Timeout:      10 * time.Minute,
+		Format:             ExportFormatCSV,
+		KeepRecordedFormat: true,
+		PollInterval:       5 * time.Second,
+		Timeout:            10 * time.Minute,
 	}
 }
 
 // WithFormat sets the export format
+// Resumed exports use it instead of their recorded format.
 func (opts *ExportOptions) WithFormat(format string) *ExportOptions {
 	opts.Format = format
+	opts.KeepRecordedFormat = false
 	return opts
 }
 
@@ -59,6 +107,19 @@ func (opts *ExportOptions) WithPollInterval(interval time.Duration) *ExportOptio
 	return opts
 }
 
+// WithPollStrategy sets the poll strategy
+// Example: opts.WithPollStrategy(nql.DefaultPollStrategy())
+func (opts *ExportOptions) WithPollStrategy(strategy PollStrategy) *ExportOptions {
+	opts.PollStrategy = strategy
+	return opts
+}
+
+// WithMaxStatusErrors sets the number of consecutive transient status errors tolerated
+func (opts *ExportOptions) WithMaxStatusErrors(maxErrors int) *ExportOptions {
+	opts.MaxStatusErrors = maxErrors
+	return opts
+}
+
 // WithTimeout sets the timeout
 func (opts *ExportOptions) WithTimeout(timeout time.Duration) *ExportOptions {
 	opts.Timeout = timeout
@@ -77,6 +138,70 @@ func (opts *ExportOptions) WithOnStatusChange(callback func(oldStatus, newStatus
 	return opts
 }
 
+// WithWriter streams the export to a writer instead of buffering it in memory
+func (opts *ExportOptions) WithWriter(w io.Writer) *ExportOptions {
+	opts.Writer = w
+	return opts
+}
+
+// WithOutputFile streams the export to a file instead of buffering it in memory
+func (opts *ExportOptions) WithOutputFile(path string) *ExportOptions {
+	opts.OutputPath = path
+	return opts
+}
+
+// WithOnDownloadProgress sets the download progress callback
+func (opts *ExportOptions) WithOnDownloadProgress(callback DownloadProgressFunc) *ExportOptions {
+	opts.OnDownloadProgress = callback
+	return opts
+}
+
+// WithResumableDownload makes the download to path resumable and checksummed
+// A nil resumable uses DefaultResumableDownloadOptions.
+func (opts *ExportOptions) WithResumableDownload(path string, resumable *ResumableDownloadOptions) *ExportOptions {
+	if resumable == nil {
+		resumable = DefaultResumableDownloadOptions()
+	}
+	opts.OutputPath = path
+	opts.Resumable = resumable
+	return opts
+}
+
+// WithJobStore records the export in a job store so it can be resumed after a restart
+func (opts *ExportOptions) WithJobStore(store ExportJobStore) *ExportOptions {
+	opts.JobStore = store
+	return opts
+}
+
+// WithRateLimiter throttles the export API calls with a (possibly shared) rate limiter
+func (opts *ExportOptions) WithRateLimiter(limiter RateLimiter) *ExportOptions {
+	opts.RateLimiter = limiter
+	return opts
+}
+
+// WithOnDownloadRetry sets the resumable download retry callback
+func (opts *ExportOptions) WithOnDownloadRetry(callback func(attempt int, offset int64, err error)) *ExportOptions {
+	opts.OnDownloadRetry = callback
+	return opts
+}
+
+// resumableOptions merges the workflow callbacks into the resumable download options
+func (opts *ExportOptions) resumableOptions() *ResumableDownloadOptions {
+	resumable := *opts.Resumable
+	if resumable.OnProgress == nil {
+		resumable.OnProgress = opts.OnDownloadProgress
+	}
+	if resumable.OnRetry == nil {
+		resumable.OnRetry = opts.OnDownloadRetry
+	}
+	return &resumable
+}
+
+// isStreaming checks if the export should be streamed rather than buffered
+func (opts *ExportOptions) isStreaming() bool {
+	return opts.Writer != nil || opts.OutputPath != ""
+}
+
 // =============================================================================
 // Export Result
 // =============================================================================
@@ -87,8 +212,15 @@ type ExportResult struct {
 	ExportID string
 	
 	// Data contains the exported data
+	// Empty when the export was streamed to ExportOptions.Writer or OutputPath
 	Data []byte
 	
+	// BytesWritten is the number of bytes written (decompressed size)
+	BytesWritten int64
+	
+	// OutputPath is the file the export was streamed to, if any
+	OutputPath string
+	
 	// Format is the format of the data ("csv" or "json")
 	Format string
 	
@@ -104,6 +236,9 @@ type ExportResult struct {
 
 // Size returns the size of the exported data in bytes
 func (er *ExportResult) Size() int64 {
+	if er.Data == nil {
+		return er.BytesWritten
+	}
 	return int64(len(er.Data))
 }
 
@@ -142,17 +277,7 @@ func (s *Service) ExportWorkflow(ctx context.Context, req *ExportRequest, opts *
 	if opts == nil {
 		opts = DefaultExportOptions()
 	}
-	
-	// Apply defaults for unset options
-	if opts.Format == "" {
-		opts.Format = ExportFormatCSV
-	}
-	if opts.PollInterval <= 0 {
-		opts.PollInterval = 5 * time.Second
-	}
-	if opts.Timeout <= 0 {
-		opts.Timeout = 10 * time.Minute
-	}
+	opts.applyDefaults()
 	
 	// Set format in request
 	if req.Format == "" {
@@ -167,6 +292,12 @@ func (s *Service) ExportWorkflow(ctx context.Context, req *ExportRequest, opts *
 		zap.String("query_id", req.QueryID),
 		zap.String("format", req.Format))
 	
+	if opts.RateLimiter != nil {
+		if err := opts.RateLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start export: %w", err)
+		}
+	}
+	
 	startResp, _, err := s.StartNQLExport(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start export: %w", err)
@@ -177,11 +308,56 @@ func (s *Service) ExportWorkflow(ctx context.Context, req *ExportRequest, opts *
 		zap.String("export_id", exportID),
 		zap.String("initial_status", startResp.Status))
 	
+	// Record the job so the export can be resumed after a restart
+	var job *ExportJob
+	if opts.JobStore != nil {
+		job = newExportJob(exportID, req, startResp.Status, opts)
+		if err := opts.JobStore.Save(ctx, job); err != nil {
+			return nil, fmt.Errorf("failed to record export job %s: %w", exportID, err)
+		}
+	}
+	
+	return s.completeExport(ctx, exportID, req.Format, startResp.Status, opts, startTime, job)
+}
+
+// applyDefaults fills in unset options
+func (opts *ExportOptions) applyDefaults() {
+	if opts.Format == "" {
+		opts.Format = ExportFormatCSV
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.PollStrategy == nil {
+		opts.PollStrategy = NewFixedPollStrategy(opts.PollInterval)
+	}
+	if opts.MaxStatusErrors <= 0 {
+		opts.MaxStatusErrors = DefaultMaxStatusErrors
+	}
+}
+
+// completeExport waits for a started export and downloads the result
+// Shared by ExportWorkflow and ResumeExport; job is nil when no JobStore is configured.
+func (s *Service) completeExport(
+	ctx context.Context,
+	exportID string,
+	format string,
+	initialStatus string,
+	opts *ExportOptions,
+	startTime time.Time,
+	job *ExportJob,
+) (*ExportResult, error) {
 	// Step 2: Wait for completion with progress callbacks
-	lastStatus := startResp.Status
+	lastStatus := initialStatus
 	pollCount := 0
 	
-	finalStatus, err := s.waitForExportWithCallbacks(ctx, exportID, opts, &lastStatus, &pollCount, startTime)
+	finalStatus, err := s.waitForExportWithCallbacks(ctx, exportID, opts, &lastStatus, &pollCount, startTime,
+		func(status *NQLExportStatusResponse) {
+			s.trackExportJob(ctx, opts.JobStore, job, status)
+		})
 	if err != nil {
 		return nil, fmt.Errorf("failed waiting for export: %w", err)
 	}
@@ -192,28 +368,60 @@ func (s *Service) ExportWorkflow(ctx context.Context, req *ExportRequest, opts *
 	}
 	
 	s.client.GetLogger().Info("Downloading export data",
-		zap.String("export_id", exportID))
+		zap.String("export_id", exportID),
+		zap.Bool("streaming", opts.isStreaming()))
 	
-	data, err := s.DownloadNQLExport(ctx, finalStatus.ResultsFileURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download export: %w", err)
+	result := &ExportResult{
+		ExportID: exportID,
+		Format:   format,
 	}
 	
-	totalDuration := time.Since(startTime)
+	switch {
+	case opts.Writer != nil:
+		download, err := s.downloadExportTo(ctx, finalStatus.ResultsFileURL, opts.Writer, opts.OnDownloadProgress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download export: %w", err)
+		}
+		result.BytesWritten = download.BytesWritten
+		
+	case opts.OutputPath != "" && opts.Resumable != nil:
+		download, err := s.DownloadNQLExportResumable(ctx, finalStatus.ResultsFileURL, opts.OutputPath, opts.resumableOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to download export: %w", err)
+		}
+		result.BytesWritten = download.BytesWritten
+		result.OutputPath = opts.OutputPath
+		
+	case opts.OutputPath != "":
+		download, err := s.DownloadNQLExportToFile(ctx, finalStatus.ResultsFileURL, opts.OutputPath, opts.OnDownloadProgress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download export: %w", err)
+		}
+		result.BytesWritten = download.BytesWritten
+		result.OutputPath = opts.OutputPath
+		
+	default:
+		var buf bytes.Buffer
+		download, err := s.downloadExportTo(ctx, finalStatus.ResultsFileURL, &buf, opts.OnDownloadProgress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download export: %w", err)
+		}
+		result.Data = buf.Bytes()
+		result.BytesWritten = download.BytesWritten
+	}
+	
+	result.TotalDuration = time.Since(startTime)
+	result.PollCount = pollCount
+	
+	s.markExportJobDownloaded(ctx, opts.JobStore, job, result)
 	
 	s.client.GetLogger().Info("Export workflow completed successfully",
 		zap.String("export_id", exportID),
-		zap.Int64("data_size", int64(len(data))),
-		zap.Duration("total_duration", totalDuration),
+		zap.Int64("data_size", result.Size()),
+		zap.Duration("total_duration", result.TotalDuration),
 		zap.Int("poll_count", pollCount))
 	
-	return &ExportResult{
-		ExportID:      exportID,
-		Data:          data,
-		Format:        req.Format,
-		TotalDuration: totalDuration,
-		PollCount:     pollCount,
-	}, nil
+	return result, nil
 }
 
 // waitForExportWithCallbacks polls for export completion with progress callbacks
@@ -224,55 +432,21 @@ func (s *Service) waitForExportWithCallbacks(
 	lastStatus *string,
 	pollCount *int,
 	startTime time.Time,
+	onStatus func(status *NQLExportStatusResponse),
 ) (*NQLExportStatusResponse, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
-	defer cancel()
-	
-	ticker := time.NewTicker(opts.PollInterval)
-	defer ticker.Stop()
-	
-	// Check initial status
-	status, _, err := s.GetNQLExportStatus(timeoutCtx, exportID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get initial export status: %w", err)
-	}
-	*pollCount++
-	
-	// Initial progress callback
-	elapsedTime := time.Since(startTime)
-	if opts.OnProgress != nil {
-		opts.OnProgress(status.Status, elapsedTime)
-	}
-	
-	// Check for status change
-	if opts.OnStatusChange != nil && status.Status != *lastStatus {
-		opts.OnStatusChange(*lastStatus, status.Status, elapsedTime)
-		*lastStatus = status.Status
-	}
-	
-	// Check if already completed
-	if isTerminalStatus(status.Status) {
-		if status.Status == ExportStatusError {
-			return nil, fmt.Errorf("export failed: %s", status.ErrorDescription)
-		}
-		return status, nil
-	}
-	
-	// Poll until terminal status
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			return nil, fmt.Errorf("timeout waiting for export to complete after %v (status: %s)", opts.Timeout, status.Status)
-			
-		case <-ticker.C:
-			status, _, err = s.GetNQLExportStatus(timeoutCtx, exportID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get export status: %w", err)
-			}
+	status, err := s.pollExportStatus(ctx, exportID, pollConfig{
+		strategy:        opts.PollStrategy,
+		timeout:         opts.Timeout,
+		maxStatusErrors: opts.MaxStatusErrors,
+		limiter:         opts.RateLimiter,
+		onStatus: func(status *NQLExportStatusResponse) {
 			*pollCount++
-			
 			elapsedTime := time.Since(startTime)
 			
+			if onStatus != nil {
+				onStatus(status)
+			}
+			
 			// Progress callback
 			if opts.OnProgress != nil {
 				opts.OnProgress(status.Status, elapsedTime)
@@ -283,16 +457,17 @@ func (s *Service) waitForExportWithCallbacks(
 				opts.OnStatusChange(*lastStatus, status.Status, elapsedTime)
 				*lastStatus = status.Status
 			}
-			
-			// Check for terminal status
-			if isTerminalStatus(status.Status) {
-				if status.Status == ExportStatusError {
-					return nil, fmt.Errorf("export failed: %s", status.ErrorDescription)
-				}
-				return status, nil
-			}
-		}
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
+	
+	if status.Status == ExportStatusError {
+		return nil, fmt.Errorf("export failed: %s", status.ErrorDescription)
+	}
+	
+	return status, nil
 }
 
 // =============================================================================
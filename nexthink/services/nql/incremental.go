@@ -0,0 +1,387 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Incremental readers poll an event table for rows newer than a stored
+// watermark, so events can be fed downstream without re-reading the full range
+
+const (
+	// IncrementalStartParameter is the query parameter holding the window start
+	IncrementalStartParameter = "window_start"
+
+	// IncrementalEndParameter is the query parameter holding the window end
+	IncrementalEndParameter = "window_end"
+
+	// DefaultIncrementalLookback is how far back the first poll reads when there is no checkpoint
+	DefaultIncrementalLookback = 24 * time.Hour
+)
+
+// =============================================================================
+// Incremental Options
+// =============================================================================
+
+// IncrementalOptions configures an IncrementalReader
+type IncrementalOptions struct {
+	// QueryID is the saved query that runs IncrementalReader.Query() (format: #query_name)
+	QueryID string
+
+	// Platform optionally specifies the platform for the query
+	Platform string
+
+	// Parameters optionally supplies values for other query parameters
+	Parameters map[string]string
+
+	// TimestampField is the event time field used as the watermark (e.g. crash.time)
+	TimestampField string
+
+	// KeyFields identify a row for deduplication
+	// Defaults to the whole row if not specified
+	KeyFields []string
+
+	// Name identifies the checkpoint in the store
+	// Defaults to QueryID (plus the platform, if set)
+	Name string
+
+	// Store persists the watermark between polls
+	// Defaults to an in-memory store if nil
+	Store CheckpointStore
+
+	// StartFrom is the watermark used when there is no checkpoint
+	// Defaults to now minus DefaultIncrementalLookback if zero
+	StartFrom time.Time
+
+	// Overlap re-reads this much time before the watermark on every poll to
+	// pick up late-arriving events; re-read rows are dropped by key
+	Overlap time.Duration
+
+	// Location is the time zone of the window parameters and of timestamps
+	// without an offset. Defaults to UTC if nil
+	Location *time.Location
+
+	// Query configures how each poll runs (execute with export fallback)
+	// Defaults to DefaultQueryOptions if nil
+	Query *QueryOptions
+}
+
+// =============================================================================
+// Changes and Batches
+// =============================================================================
+
+// Change is a new row read by an incremental reader
+type Change struct {
+	// Row is the event row
+	Row map[string]any
+
+	// Timestamp is the parsed value of the timestamp field
+	Timestamp time.Time
+
+	// Key is the deduplication key of the row
+	Key string
+}
+
+// IncrementalBatch is the result of a single poll
+type IncrementalBatch struct {
+	// Changes are the new rows, ordered by timestamp
+	Changes []Change
+
+	// WindowStart and WindowEnd are the time selection sent to the query
+	WindowStart time.Time
+	WindowEnd   time.Time
+
+	// Duplicates counts rows dropped because they were already emitted
+	Duplicates int
+
+	// Checkpoint is the position to commit once the changes are processed
+	Checkpoint *Checkpoint
+
+	// Result is the raw query result
+	Result *QueryResult
+}
+
+// =============================================================================
+// Incremental Reader
+// =============================================================================
+
+// IncrementalReader reads new rows from an event table on every poll
+//
+// The reader rewrites the builder's time selection to
+// "from $window_start to $window_end" (see Query). Save that query in Nexthink
+// under opts.QueryID; every poll fills in the window from the committed
+// watermark to now. Rows at or before the watermark that were already emitted
+// are dropped by key, so boundary rows are never delivered twice.
+//
+// Example:
+//
+//	qb := nql.NewQueryBuilder().From(nql.TableExecutionCrashes).
+//		List("crash.time", "device.name", "binary.name")
+//	reader, _ := nql.NewIncrementalReader(service, qb, nql.IncrementalOptions{
+//		QueryID:        "#new_crashes",
+//		TimestampField: "crash.time",
+//		KeyFields:      []string{"crash.time", "device.name", "binary.name"},
+//		Store:          store,
+//	})
+//	fmt.Println(reader.Query()) // save this as #new_crashes
+//
+//	for change, err := range reader.Stream(ctx, time.Minute) { ... }
+type IncrementalReader struct {
+	service *Service
+	query   string
+	opts    IncrementalOptions
+	now     func() time.Time
+}
+
+// NewIncrementalReader creates a reader for the event table selected by qb
+// The builder is not modified.
+func NewIncrementalReader(service *Service, qb *QueryBuilder, opts IncrementalOptions) (*IncrementalReader, error) {
+	if qb == nil {
+		return nil, fmt.Errorf("query builder cannot be nil")
+	}
+	if opts.TimestampField == "" {
+		return nil, fmt.Errorf("timestamp field cannot be empty")
+	}
+	if err := validateQueryID(opts.QueryID); err != nil {
+		return nil, err
+	}
+	if opts.Overlap < 0 {
+		return nil, fmt.Errorf("overlap cannot be negative")
+	}
+
+	windowed := qb.Clone()
+	windowed.During(NewTimeSelection().
+		From("$" + IncrementalStartParameter).
+		To("$" + IncrementalEndParameter).
+		Build())
+	if windowed.sortField == "" && len(windowed.summarizeClauses) == 0 {
+		windowed.SortAsc(opts.TimestampField)
+	}
+	if err := windowed.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Name == "" {
+		opts.Name = opts.QueryID
+		if opts.Platform != "" {
+			opts.Name += "@" + opts.Platform
+		}
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryCheckpointStore()
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	return &IncrementalReader{
+		service: service,
+		query:   windowed.Build(),
+		opts:    opts,
+		now:     time.Now,
+	}, nil
+}
+
+// Query returns the NQL query to save under the reader's query ID
+// The time selection is "from $window_start to $window_end", and the rows are
+// sorted by the timestamp field unless the builder sets its own sort.
+func (r *IncrementalReader) Query() string {
+	return r.query
+}
+
+// Checkpoint returns the committed checkpoint, or the initial one if nothing has been committed
+func (r *IncrementalReader) Checkpoint(ctx context.Context) (*Checkpoint, error) {
+	checkpoint, err := r.opts.Store.Load(ctx, r.opts.Name)
+	if errors.Is(err, ErrCheckpointNotFound) {
+		start := r.opts.StartFrom
+		if start.IsZero() {
+			start = r.now().Add(-DefaultIncrementalLookback)
+		}
+		return &Checkpoint{Name: r.opts.Name, Watermark: start}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint %s: %w", r.opts.Name, err)
+	}
+	return checkpoint, nil
+}
+
+// Poll reads the rows added since the committed checkpoint
+// The checkpoint is not advanced; call Commit once the changes are processed,
+// which gives at-least-once delivery.
+func (r *IncrementalReader) Poll(ctx context.Context) (*IncrementalBatch, error) {
+	checkpoint, err := r.Checkpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := r.opts.Location
+	batch := &IncrementalBatch{
+		WindowStart: checkpoint.Watermark.Add(-r.opts.Overlap).Truncate(time.Second),
+		WindowEnd:   r.now(),
+	}
+
+	params := maps.Clone(r.opts.Parameters)
+	if params == nil {
+		params = make(map[string]string, 2)
+	}
+	params[IncrementalStartParameter] = FormatDateTime(batch.WindowStart.In(loc))
+	params[IncrementalEndParameter] = FormatDateTime(batch.WindowEnd.In(loc))
+
+	result, err := r.service.QueryWithOptions(ctx, &ExecuteRequest{
+		QueryID:    r.opts.QueryID,
+		Platform:   r.opts.Platform,
+		Parameters: params,
+	}, r.opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	batch.Result = result
+
+	seen := maps.Clone(checkpoint.Seen)
+	if seen == nil {
+		seen = make(map[string]time.Time)
+	}
+
+	for i, row := range result.All() {
+		raw, ok := row[r.opts.TimestampField]
+		if !ok || raw == nil {
+			return nil, fmt.Errorf("row %d has no %s value", i, r.opts.TimestampField)
+		}
+		ts, err := parseTimeValue(raw, loc)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid %s: %w", i, r.opts.TimestampField, err)
+		}
+
+		// Rows before the window were emitted by an earlier poll (NQL may round
+		// the window start down)
+		if ts.Before(batch.WindowStart) {
+			batch.Duplicates++
+			continue
+		}
+
+		key, err := r.rowKey(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		if _, dup := seen[key]; dup {
+			batch.Duplicates++
+			continue
+		}
+		seen[key] = ts
+
+		batch.Changes = append(batch.Changes, Change{Row: row, Timestamp: ts, Key: key})
+	}
+
+	sort.SliceStable(batch.Changes, func(i, j int) bool {
+		return batch.Changes[i].Timestamp.Before(batch.Changes[j].Timestamp)
+	})
+
+	batch.Checkpoint = r.nextCheckpoint(checkpoint, batch.Changes, seen)
+
+	r.service.client.GetLogger().Debug("Incremental poll completed",
+		zap.String("reader", r.opts.Name),
+		zap.Time("window_start", batch.WindowStart),
+		zap.Time("window_end", batch.WindowEnd),
+		zap.Int("changes", len(batch.Changes)),
+		zap.Int("duplicates", batch.Duplicates))
+
+	return batch, nil
+}
+
+// nextCheckpoint advances the watermark to the newest emitted row and keeps the
+// keys still inside the overlap window
+func (r *IncrementalReader) nextCheckpoint(current *Checkpoint, changes []Change, seen map[string]time.Time) *Checkpoint {
+	watermark := current.Watermark
+	if n := len(changes); n > 0 && changes[n-1].Timestamp.After(watermark) {
+		watermark = changes[n-1].Timestamp
+	}
+
+	cutoff := watermark.Add(-r.opts.Overlap).Truncate(time.Second)
+	maps.DeleteFunc(seen, func(_ string, ts time.Time) bool {
+		return ts.Before(cutoff)
+	})
+
+	return &Checkpoint{
+		Name:      r.opts.Name,
+		Watermark: watermark,
+		Seen:      seen,
+		UpdatedAt: r.now(),
+	}
+}
+
+// rowKey returns the deduplication key of a row
+func (r *IncrementalReader) rowKey(row map[string]any) (string, error) {
+	var data []byte
+	var err error
+	if len(r.opts.KeyFields) == 0 {
+		// encoding/json sorts map keys, so the encoding is stable
+		data, err = json.Marshal(row)
+	} else {
+		values := make([]any, len(r.opts.KeyFields))
+		for i, field := range r.opts.KeyFields {
+			values[i] = row[field]
+		}
+		data, err = json.Marshal(values)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode row key: %w", err)
+	}
+	return string(data), nil
+}
+
+// Commit saves the batch checkpoint, so the next poll starts after its changes
+func (r *IncrementalReader) Commit(ctx context.Context, batch *IncrementalBatch) error {
+	if batch == nil || batch.Checkpoint == nil {
+		return fmt.Errorf("batch has no checkpoint")
+	}
+	if err := r.opts.Store.Save(ctx, batch.Checkpoint); err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %w", r.opts.Name, err)
+	}
+	return nil
+}
+
+// Stream polls every interval and yields each new row until ctx is cancelled
+//
+// A batch is committed after all of its changes have been yielded; if the
+// consumer stops early, the batch is read again on the next poll. A poll or
+// commit error is yielded once and ends the stream.
+func (r *IncrementalReader) Stream(ctx context.Context, interval time.Duration) iter.Seq2[Change, error] {
+	return func(yield func(Change, error) bool) {
+		for {
+			batch, err := r.Poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					yield(Change{}, err)
+				}
+				return
+			}
+
+			for _, change := range batch.Changes {
+				if !yield(change, nil) {
+					return
+				}
+			}
+
+			if err := r.Commit(ctx, batch); err != nil {
+				yield(Change{}, err)
+				return
+			}
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTable serves execute requests from a list of crash events, honouring the window parameters
+type eventTable struct {
+	mu      sync.Mutex
+	events  []map[string]any
+	windows [][2]string
+}
+
+func (e *eventTable) add(ts, device string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, map[string]any{"crash.time": ts, "device.name": device})
+}
+
+func (e *eventTable) register(baseURL string) {
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute",
+		func(req *http.Request) (*http.Response, error) {
+			var body ExecuteRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return httpmock.NewStringResponse(400, "bad request"), nil
+			}
+			start := body.Parameters[IncrementalStartParameter]
+			end := body.Parameters[IncrementalEndParameter]
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.windows = append(e.windows, [2]string{start, end})
+
+			// NQL rounds the window start down to the minute
+			from, _ := time.Parse(time.DateTime, start)
+			from = from.Truncate(time.Minute)
+			rows := []map[string]any{}
+			for _, event := range e.events {
+				ts, _ := time.Parse(time.RFC3339, event["crash.time"].(string))
+				if !ts.Before(from) {
+					rows = append(rows, event)
+				}
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"queryId": body.QueryID,
+				"rows":    len(rows),
+				"data":    rows,
+			})
+		})
+}
+
+func newTestIncrementalReader(t *testing.T, opts IncrementalOptions) (*IncrementalReader, *eventTable, *time.Time) {
+	t.Helper()
+
+	service, baseURL := setupMockClient(t)
+	table := &eventTable{}
+	table.register(baseURL)
+
+	opts.QueryID = "#new_crashes"
+	opts.TimestampField = "crash.time"
+	if opts.KeyFields == nil {
+		opts.KeyFields = []string{"crash.time", "device.name"}
+	}
+	if opts.StartFrom.IsZero() {
+		opts.StartFrom = time.Date(2024, 2, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	qb := NewQueryBuilder().From(TableExecutionCrashes).DuringPast(7, Days).List("crash.time", "device.name")
+	reader, err := NewIncrementalReader(service, qb, opts)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+	reader.now = func() time.Time { return now }
+	return reader, table, &now
+}
+
+func TestIncrementalReader_Query(t *testing.T) {
+	qb := NewQueryBuilder().From(TableExecutionCrashes).DuringPast(7, Days).List("crash.time", "device.name")
+	reader, err := NewIncrementalReader(nil, qb, IncrementalOptions{QueryID: "#new_crashes", TimestampField: "crash.time"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "execution.crashes from $window_start to $window_end\n| list crash.time, device.name\n| sort crash.time asc", reader.Query())
+	assert.Equal(t, "execution.crashes during past 7d\n| list crash.time, device.name", qb.Build(), "the builder is not modified")
+}
+
+func TestIncrementalReader_InvalidOptions(t *testing.T) {
+	qb := NewQueryBuilder().From(TableExecutionCrashes)
+
+	_, err := NewIncrementalReader(nil, nil, IncrementalOptions{QueryID: "#q", TimestampField: "crash.time"})
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(nil, qb, IncrementalOptions{QueryID: "#q"})
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(nil, qb, IncrementalOptions{QueryID: "q", TimestampField: "crash.time"})
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(nil, NewQueryBuilder(), IncrementalOptions{QueryID: "#q", TimestampField: "crash.time"})
+	assert.Error(t, err)
+}
+
+func TestIncrementalReader_PollAndCommit(t *testing.T) {
+	reader, table, now := newTestIncrementalReader(t, IncrementalOptions{})
+	ctx := context.Background()
+
+	table.add("2024-02-08T09:59:00Z", "device-00")
+	table.add("2024-02-08T10:30:00Z", "device-01")
+	table.add("2024-02-08T11:15:00Z", "device-02")
+	table.add("2024-02-08T11:15:00Z", "device-03")
+
+	batch, err := reader.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch.Changes, 3)
+	assert.Equal(t, "device-01", batch.Changes[0].Row["device.name"])
+	assert.Equal(t, time.Date(2024, 2, 8, 11, 15, 0, 0, time.UTC), batch.Checkpoint.Watermark)
+	assert.Equal(t, [2]string{"2024-02-08 10:00:00", "2024-02-08 12:00:00"}, table.windows[0])
+
+	// Without a commit the same rows are read again
+	again, err := reader.Poll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, again.Changes, 3)
+
+	require.NoError(t, reader.Commit(ctx, batch))
+
+	// Rows at the watermark are re-read and dropped; a new row at the same time is emitted
+	*now = now.Add(time.Hour)
+	table.add("2024-02-08T11:15:00Z", "device-04")
+	table.add("2024-02-08T12:30:00Z", "device-05")
+
+	batch, err = reader.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [2]string{"2024-02-08 11:15:00", "2024-02-08 13:00:00"}, table.windows[2])
+	require.Len(t, batch.Changes, 2)
+	assert.Equal(t, "device-04", batch.Changes[0].Row["device.name"])
+	assert.Equal(t, "device-05", batch.Changes[1].Row["device.name"])
+	assert.Equal(t, 2, batch.Duplicates)
+	require.NoError(t, reader.Commit(ctx, batch))
+
+	// Nothing new
+	batch, err = reader.Poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batch.Changes)
+	assert.Equal(t, time.Date(2024, 2, 8, 12, 30, 0, 0, time.UTC), batch.Checkpoint.Watermark)
+	assert.Len(t, batch.Checkpoint.Seen, 1, "only keys at the watermark are kept")
+}
+
+func TestIncrementalReader_Overlap(t *testing.T) {
+	reader, table, _ := newTestIncrementalReader(t, IncrementalOptions{Overlap: 30 * time.Minute})
+	ctx := context.Background()
+
+	table.add("2024-02-08T11:00:00Z", "device-01")
+	batch, err := reader.Poll(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch.Changes, 1)
+	require.NoError(t, reader.Commit(ctx, batch))
+
+	// A late event inside the overlap window is picked up once
+	table.add("2024-02-08T10:45:00Z", "device-02")
+	batch, err = reader.Poll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 30, 0, 0, time.UTC), batch.WindowStart)
+	require.Len(t, batch.Changes, 1)
+	assert.Equal(t, "device-02", batch.Changes[0].Row["device.name"])
+	assert.Equal(t, time.Date(2024, 2, 8, 11, 0, 0, 0, time.UTC), batch.Checkpoint.Watermark, "the watermark never moves back")
+	require.NoError(t, reader.Commit(ctx, batch))
+
+	batch, err = reader.Poll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batch.Changes)
+}
+
+func TestIncrementalReader_MissingTimestamp(t *testing.T) {
+	reader, table, _ := newTestIncrementalReader(t, IncrementalOptions{})
+	table.mu.Lock()
+	table.events = append(table.events, map[string]any{"crash.time": "2024-02-08T11:00:00Z"})
+	table.mu.Unlock()
+	reader.opts.TimestampField = "device.last_seen"
+
+	_, err := reader.Poll(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "row 0 has no device.last_seen value")
+}
+
+func TestIncrementalReader_Stream(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	reader, table, _ := newTestIncrementalReader(t, IncrementalOptions{Store: store})
+	table.add("2024-02-08T10:30:00Z", "device-01")
+	table.add("2024-02-08T11:00:00Z", "device-02")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var devices []any
+	for change, err := range reader.Stream(ctx, 10*time.Millisecond) {
+		require.NoError(t, err)
+		devices = append(devices, change.Row["device.name"])
+		if len(devices) == 2 {
+			table.add("2024-02-08T11:30:00Z", "device-03")
+		}
+		if len(devices) == 3 {
+			break
+		}
+	}
+
+	assert.Equal(t, []any{"device-01", "device-02", "device-03"}, devices)
+
+	checkpoint, err := store.Load(context.Background(), "#new_crashes")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 8, 11, 0, 0, 0, time.UTC), checkpoint.Watermark, "a batch the consumer stopped in is not committed")
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	ctx := context.Background()
+
+	store, err := NewFileCheckpointStore(path)
+	require.NoError(t, err)
+
+	_, err = store.Load(ctx, "#new_crashes")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+
+	watermark := time.Date(2024, 2, 8, 11, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Save(ctx, &Checkpoint{
+		Name:      "#new_crashes",
+		Watermark: watermark,
+		Seen:      map[string]time.Time{`["2024-02-08T11:00:00Z","device-01"]`: watermark},
+	}))
+	assert.Error(t, store.Save(ctx, &Checkpoint{}))
+
+	reopened, err := NewFileCheckpointStore(path)
+	require.NoError(t, err)
+	checkpoint, err := reopened.Load(ctx, "#new_crashes")
+	require.NoError(t, err)
+	assert.True(t, watermark.Equal(checkpoint.Watermark))
+	assert.Len(t, checkpoint.Seen, 1)
+}
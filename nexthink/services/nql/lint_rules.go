@@ -0,0 +1,419 @@
+package nql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in lint rules for common NQL anti-patterns
+
+// =============================================================================
+// Rule IDs
+// =============================================================================
+
+const (
+	RuleEventTableTimeSelection = "event-table-time-selection"
+	RuleListWithoutLimit        = "list-without-limit"
+	RuleWhereAfterSummarize     = "where-after-summarize"
+	RuleHighResolutionRetention = "high-resolution-retention"
+	RuleSortUnlistedField       = "sort-unlisted-field"
+	RuleDeprecatedField         = "deprecated-field"
+
+	// SyntaxRulePrefix prefixes the IDs of rules adapted from QueryValidator
+	SyntaxRulePrefix = "syntax/"
+)
+
+// DefaultLintRules returns the built-in rule set
+// Includes the QueryValidator syntax rules followed by the anti-pattern rules
+func DefaultLintRules() []LintRule {
+	rules := make([]LintRule, 0)
+
+	for _, rule := range NewQueryValidator().GetValidationRules() {
+		rules = append(rules, NewValidationLintRule(rule))
+	}
+
+	return append(rules,
+		eventTableTimeSelectionRule(),
+		listWithoutLimitRule(),
+		whereAfterSummarizeRule(),
+		highResolutionRetentionRule(),
+		sortUnlistedFieldRule(),
+		deprecatedFieldRule(DeprecatedFields),
+	)
+}
+
+// =============================================================================
+// Function Rules
+// =============================================================================
+
+// funcRule adapts a check function to the LintRule interface
+type funcRule struct {
+	id          string
+	description string
+	severity    LintSeverity
+	check       func(query *LintQuery) []LintIssue
+}
+
+func (r *funcRule) ID() string                         { return r.id }
+func (r *funcRule) Description() string                { return r.description }
+func (r *funcRule) Severity() LintSeverity             { return r.severity }
+func (r *funcRule) Check(query *LintQuery) []LintIssue { return r.check(query) }
+
+// NewLintRule creates a lint rule from a check function
+// Example:
+//
+//	rule := nql.NewLintRule("no-users-table", "Users table is not allowed", nql.SeverityError,
+//	    func(q *nql.LintQuery) []nql.LintIssue {
+//	        if q.Table == nql.TableUsers {
+//	            return []nql.LintIssue{{Message: "query selects the users table", Line: q.Statements[0].Line}}
+//	        }
+//	        return nil
+//	    })
+func NewLintRule(id, description string, severity LintSeverity, check func(query *LintQuery) []LintIssue) LintRule {
+	return &funcRule{
+		id:          id,
+		description: description,
+		severity:    severity,
+		check:       check,
+	}
+}
+
+// NewValidationLintRule adapts a QueryValidator ValidationRule into an error-severity lint rule
+func NewValidationLintRule(rule ValidationRule) LintRule {
+	return NewLintRule(
+		SyntaxRulePrefix+rule.Name,
+		fmt.Sprintf("Query passes the %s syntax validation", rule.Name),
+		SeverityError,
+		func(query *LintQuery) []LintIssue {
+			if err := rule.Validate(query.Text); err != nil {
+				return []LintIssue{{Message: err.Error()}}
+			}
+			return nil
+		},
+	)
+}
+
+// =============================================================================
+// Anti-pattern Rules
+// =============================================================================
+
+// eventTableTimeSelectionRule flags event tables queried without an explicit time selection
+func eventTableTimeSelectionRule() LintRule {
+	return NewLintRule(
+		RuleEventTableTimeSelection,
+		"Event tables should have an explicit time selection",
+		SeverityWarning,
+		func(query *LintQuery) []LintIssue {
+			var issues []LintIssue
+
+			if len(query.Statements) > 0 && IsEventTable(query.Table) && query.TimeSelection == "" {
+				issues = append(issues, LintIssue{
+					Message:    fmt.Sprintf("event table %s has no time selection and defaults to the past 24 hours", query.Table),
+					Line:       query.Statements[0].Line,
+					Column:     query.Statements[0].Column,
+					Suggestion: fmt.Sprintf("%s %s", query.Table, Past7Days),
+				})
+			}
+
+			for _, stmt := range query.Statements {
+				if stmt.Keyword != "with" && stmt.Keyword != "include" {
+					continue
+				}
+
+				table, selection := splitTableSelection(stmt.Body)
+				if IsEventTable(table) && !hasTimeSelection(selection) {
+					issues = append(issues, LintIssue{
+						Message:    fmt.Sprintf("%s on event table %s has no time selection", stmt.Keyword, table),
+						Line:       stmt.Line,
+						Column:     stmt.Column,
+						Suggestion: fmt.Sprintf("%s %s %s", stmt.Keyword, table, Past7Days),
+					})
+				}
+			}
+
+			return issues
+		},
+	)
+}
+
+// listWithoutLimitRule flags list statements that are not bounded by a limit
+func listWithoutLimitRule() LintRule {
+	return NewLintRule(
+		RuleListWithoutLimit,
+		"List statements should be bounded by a limit",
+		SeverityWarning,
+		func(query *LintQuery) []LintIssue {
+			lists := query.Find("list")
+			if len(lists) == 0 || query.Has("limit") {
+				return nil
+			}
+
+			return []LintIssue{{
+				Message:    "list without limit returns every matching row and may be truncated by the API",
+				Line:       lists[0].Line,
+				Column:     lists[0].Column,
+				Suggestion: "| limit 100",
+			}}
+		},
+	)
+}
+
+// whereAfterSummarizeRule flags where statements after summarize that reference unaggregated fields
+func whereAfterSummarizeRule() LintRule {
+	return NewLintRule(
+		RuleWhereAfterSummarize,
+		"Where after summarize may only reference aggregates and grouping fields",
+		SeverityError,
+		func(query *LintQuery) []LintIssue {
+			idx := query.indexOf("summarize")
+			if idx < 0 {
+				return nil
+			}
+
+			allowed := summarizeOutputs(query.Statements[idx].Body)
+
+			var issues []LintIssue
+			for _, stmt := range query.Statements[idx+1:] {
+				if stmt.Keyword != "where" {
+					continue
+				}
+
+				for _, ident := range extractIdentifiers(stmt.Body) {
+					if allowed[ident] {
+						continue
+					}
+					issues = append(issues, LintIssue{
+						Message: fmt.Sprintf("where after summarize references %s, which is neither an aggregate nor a summarize by field", ident),
+						Line:    stmt.Line,
+						Column:  stmt.Column,
+					})
+				}
+			}
+
+			return issues
+		},
+	)
+}
+
+// highResolutionRetentionRule flags high-resolution time selections beyond the retention window
+func highResolutionRetentionRule() LintRule {
+	return NewLintRule(
+		RuleHighResolutionRetention,
+		"High-resolution time selections must stay within the retention window",
+		SeverityError,
+		func(query *LintQuery) []LintIssue {
+			var issues []LintIssue
+
+			check := func(selection string, stmt Statement) {
+				if !IsHighResolution(selection) {
+					return
+				}
+				lookback, ok := TimeSelectionLookback(selection)
+				if !ok || lookback <= HighResolutionRetention {
+					return
+				}
+				issues = append(issues, LintIssue{
+					Message: fmt.Sprintf("high-resolution data is only retained for %v but the time selection reaches back %v",
+						HighResolutionRetention, lookback),
+					Line:       stmt.Line,
+					Column:     stmt.Column,
+					Suggestion: "during past 2d by 30s",
+				})
+			}
+
+			for _, stmt := range query.Statements {
+				switch {
+				case stmt.IsTableSelection():
+					check(query.TimeSelection, stmt)
+				case stmt.Keyword == "with" || stmt.Keyword == "include":
+					_, selection := splitTableSelection(stmt.Body)
+					check(selection, stmt)
+				}
+			}
+
+			return issues
+		},
+	)
+}
+
+// sortUnlistedFieldRule flags sort statements on fields that are not part of the output
+func sortUnlistedFieldRule() LintRule {
+	return NewLintRule(
+		RuleSortUnlistedField,
+		"Sort should reference a field returned by list or summarize",
+		SeverityWarning,
+		func(query *LintQuery) []LintIssue {
+			var outputs map[string]bool
+
+			if idx := query.indexOf("summarize"); idx >= 0 {
+				outputs = summarizeOutputs(query.Statements[idx].Body)
+			} else if lists := query.Find("list"); len(lists) > 0 {
+				outputs = make(map[string]bool)
+				for _, list := range lists {
+					for _, field := range splitTopLevel(list.Body) {
+						outputs[field] = true
+					}
+				}
+			} else {
+				return nil
+			}
+
+			var issues []LintIssue
+			for _, stmt := range query.Find("sort") {
+				field, _, _ := strings.Cut(stmt.Body, " ")
+				if field == "" || outputs[field] {
+					continue
+				}
+				issues = append(issues, LintIssue{
+					Message: fmt.Sprintf("sort on %s, which is not returned by the query", field),
+					Line:    stmt.Line,
+					Column:  stmt.Column,
+				})
+			}
+
+			return issues
+		},
+	)
+}
+
+// deprecatedFieldRule flags references to deprecated data model fields
+func deprecatedFieldRule(deprecated map[string]string) LintRule {
+	return NewLintRule(
+		RuleDeprecatedField,
+		"Deprecated data model fields should be replaced",
+		SeverityWarning,
+		func(query *LintQuery) []LintIssue {
+			var issues []LintIssue
+
+			for _, stmt := range query.Statements {
+				if stmt.IsTableSelection() {
+					continue
+				}
+				for _, ident := range extractIdentifiers(stmt.Body) {
+					replacement, ok := deprecated[ident]
+					if !ok {
+						continue
+					}
+					issues = append(issues, LintIssue{
+						Message:    fmt.Sprintf("field %s is deprecated, use %s instead", ident, replacement),
+						Line:       stmt.Line,
+						Column:     stmt.Column,
+						Suggestion: replacement,
+					})
+				}
+			}
+
+			return issues
+		},
+	)
+}
+
+// NewDeprecatedFieldRule creates a deprecated-field rule for a custom field map
+// Keys are deprecated field names and values are their replacements
+func NewDeprecatedFieldRule(deprecated map[string]string) LintRule {
+	return deprecatedFieldRule(deprecated)
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+var (
+	// stringLiteralPattern matches single or double quoted string literals
+	stringLiteralPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+
+	// identifierPattern matches field references, optionally followed by a call
+	identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_.]*(\()?`)
+
+	// numericLiteralPattern matches numbers and NQL durations/sizes (e.g. 10, 7d, 1.5GB)
+	numericLiteralPattern = regexp.MustCompile(`\b\d+(\.\d+)?[A-Za-z]*\b`)
+
+	// enumComparisonPattern matches a bare word compared with == or != (e.g. "== windows")
+	enumComparisonPattern = regexp.MustCompile(`(?:==|!=)\s*([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// enumListPattern matches the value list of an in comparison (e.g. "in [windows, macos]")
+	enumListPattern = regexp.MustCompile(`(?i)\bin\s*\[[^\]]*\]`)
+)
+
+// nqlKeywords are words that may appear in expressions but are not field references
+var nqlKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "contains": true,
+	"true": true, "false": true, "null": true, "by": true, "asc": true, "desc": true,
+	"during": true, "past": true, "from": true, "to": true, "ago": true, "on": true,
+}
+
+// extractIdentifiers returns the field references found in an expression
+// String literals, numeric literals, keywords and function names are ignored
+func extractIdentifiers(expr string) []string {
+	expr = stringLiteralPattern.ReplaceAllString(expr, " ")
+	expr = numericLiteralPattern.ReplaceAllString(expr, " ")
+	expr = stripEnumLiterals(expr)
+
+	seen := make(map[string]bool)
+	var result []string
+
+	for _, match := range identifierPattern.FindAllStringSubmatch(expr, -1) {
+		ident := strings.TrimSuffix(match[0], "(")
+		if match[1] != "" {
+			// Method call: keep the receiver (e.g. "field" in "field.sum(")
+			idx := strings.LastIndex(ident, ".")
+			if idx < 0 {
+				continue
+			}
+			ident = ident[:idx]
+		}
+
+		ident = strings.Trim(ident, ".")
+		if ident == "" || nqlKeywords[strings.ToLower(ident)] || seen[ident] {
+			continue
+		}
+
+		seen[ident] = true
+		result = append(result, ident)
+	}
+
+	return result
+}
+
+// stripEnumLiterals blanks the enum values compared against in an expression
+// Enum values are written unquoted (platform == windows); a dotted word or a
+// call after the operator is a field reference and is kept.
+func stripEnumLiterals(expr string) string {
+	expr = enumListPattern.ReplaceAllString(expr, " ")
+
+	out := []byte(expr)
+	for _, loc := range enumComparisonPattern.FindAllStringSubmatchIndex(expr, -1) {
+		start, end := loc[2], loc[3]
+		if end < len(expr) && (expr[end] == '.' || expr[end] == '(') {
+			continue
+		}
+		for i := start; i < end; i++ {
+			out[i] = ' '
+		}
+	}
+	return string(out)
+}
+
+// summarizeOutputs returns the aliases and grouping fields produced by a summarize body
+// Example: "total = count() by platform" -> {total, platform}
+func summarizeOutputs(body string) map[string]bool {
+	outputs := make(map[string]bool)
+
+	aggregates, by := body, ""
+	if idx := strings.LastIndex(body, " by "); idx >= 0 {
+		aggregates, by = body[:idx], body[idx+len(" by "):]
+	}
+
+	for _, part := range splitTopLevel(aggregates) {
+		if alias, _, ok := strings.Cut(part, "="); ok {
+			outputs[strings.TrimSpace(alias)] = true
+		}
+	}
+
+	for _, field := range splitTopLevel(by) {
+		outputs[field] = true
+	}
+
+	return outputs
+}
@@ -0,0 +1,485 @@
+package nql
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Query linting flags NQL anti-patterns that are syntactically valid but
+// likely to produce slow, truncated or misleading results
+
+// =============================================================================
+// Severity
+// =============================================================================
+
+// LintSeverity represents the severity of a lint issue
+type LintSeverity string
+
+const (
+	SeverityError   LintSeverity = "error"
+	SeverityWarning LintSeverity = "warning"
+	SeverityInfo    LintSeverity = "info"
+)
+
+// String returns the string representation of the severity
+func (ls LintSeverity) String() string {
+	return string(ls)
+}
+
+// sarifLevel maps a severity to its SARIF result level
+func (ls LintSeverity) sarifLevel() string {
+	switch ls {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// =============================================================================
+// Lint Rule Interface
+// =============================================================================
+
+// LintRule is a single check run by the Linter
+//
+// Rules receive the parsed query and return zero or more issues. The Linter
+// fills in RuleID and Severity on returned issues when they are left empty.
+type LintRule interface {
+	// ID is the stable rule identifier used in reports and nolint comments
+	ID() string
+
+	// Description is a short human-readable summary of what the rule checks
+	Description() string
+
+	// Severity is the default severity of issues reported by the rule
+	Severity() LintSeverity
+
+	// Check inspects the query and returns any issues found
+	Check(query *LintQuery) []LintIssue
+}
+
+// LintIssue is a single finding reported by a lint rule
+type LintIssue struct {
+	RuleID     string       `json:"rule_id"`
+	Severity   LintSeverity `json:"severity"`
+	Message    string       `json:"message"`
+	Line       int          `json:"line,omitempty"`
+	Column     int          `json:"column,omitempty"`
+	Suggestion string       `json:"suggestion,omitempty"`
+}
+
+// String returns a compact one-line representation of the issue
+func (li LintIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s [%s] %s", li.Line, li.Column, li.Severity, li.RuleID, li.Message)
+}
+
+// =============================================================================
+// Lint Query
+// =============================================================================
+
+// LintQuery is the parsed form of a query passed to lint rules
+type LintQuery struct {
+	// Text is the original query text
+	Text string
+
+	// Table is the table selected by the first statement
+	Table string
+
+	// TimeSelection is the time selection of the first statement (may be empty)
+	TimeSelection string
+
+	// Statements are the pipe-separated statements in query order
+	Statements []Statement
+
+	// Comments are the comment blocks found in the query
+	Comments []QueryComment
+}
+
+// NewLintQuery parses a query for linting
+func NewLintQuery(query string) *LintQuery {
+	statements, comments := SplitStatements(query)
+
+	lq := &LintQuery{
+		Text:       query,
+		Statements: statements,
+		Comments:   comments,
+	}
+
+	if len(statements) > 0 && statements[0].IsTableSelection() {
+		lq.Table, lq.TimeSelection = splitTableSelection(statements[0].Body)
+	}
+
+	return lq
+}
+
+// Find returns all statements with the given keyword
+func (lq *LintQuery) Find(keyword string) []Statement {
+	var result []Statement
+	for _, stmt := range lq.Statements {
+		if stmt.Keyword == keyword {
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
+
+// Has checks if the query contains a statement with the given keyword
+func (lq *LintQuery) Has(keyword string) bool {
+	return len(lq.Find(keyword)) > 0
+}
+
+// indexOf returns the position of the first statement with the given keyword, or -1
+func (lq *LintQuery) indexOf(keyword string) int {
+	for i, stmt := range lq.Statements {
+		if stmt.Keyword == keyword {
+			return i
+		}
+	}
+	return -1
+}
+
+// =============================================================================
+// Linter
+// =============================================================================
+
+// Linter runs a set of lint rules against NQL queries
+type Linter struct {
+	rules    []LintRule
+	disabled map[string]bool
+}
+
+// NewLinter creates a linter with the given rules
+// Use DefaultLintRules() for the built-in rule set
+func NewLinter(rules ...LintRule) *Linter {
+	return &Linter{
+		rules:    rules,
+		disabled: make(map[string]bool),
+	}
+}
+
+// NewDefaultLinter creates a linter with the built-in rule set
+func NewDefaultLinter() *Linter {
+	return NewLinter(DefaultLintRules()...)
+}
+
+// Register adds rules to the linter
+func (l *Linter) Register(rules ...LintRule) *Linter {
+	l.rules = append(l.rules, rules...)
+	return l
+}
+
+// Disable turns off rules by ID for every query linted
+func (l *Linter) Disable(ruleIDs ...string) *Linter {
+	for _, id := range ruleIDs {
+		l.disabled[id] = true
+	}
+	return l
+}
+
+// Rules returns the registered rules
+func (l *Linter) Rules() []LintRule {
+	return l.rules
+}
+
+// Lint runs every enabled rule against the query and returns a report
+func (l *Linter) Lint(query string) *LintReport {
+	lq := NewLintQuery(query)
+	suppressions := parseSuppressions(lq)
+
+	report := &LintReport{Query: query}
+
+	for _, rule := range l.rules {
+		if l.disabled[rule.ID()] {
+			continue
+		}
+		report.rules = append(report.rules, rule)
+
+		for _, issue := range rule.Check(lq) {
+			if issue.RuleID == "" {
+				issue.RuleID = rule.ID()
+			}
+			if issue.Severity == "" {
+				issue.Severity = rule.Severity()
+			}
+
+			if suppressions.suppresses(issue) {
+				report.Suppressed++
+				continue
+			}
+
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Line != report.Issues[j].Line {
+			return report.Issues[i].Line < report.Issues[j].Line
+		}
+		return report.Issues[i].Column < report.Issues[j].Column
+	})
+
+	return report
+}
+
+// =============================================================================
+// Suppressions
+// =============================================================================
+
+// nolintPattern matches "nolint" or "nolint:rule-a,rule-b" comment content
+var nolintPattern = regexp.MustCompile(`^nolint(?::\s*([\w\-./]+(?:\s*,\s*[\w\-./]+)*))?$`)
+
+// suppressionSet records which rules are suppressed query-wide and per line
+type suppressionSet struct {
+	global map[string]bool
+	lines  map[int]map[string]bool
+}
+
+// parseSuppressions collects nolint comments from the query
+//
+// A nolint comment placed before the first statement suppresses issues for the
+// whole query. Any other nolint comment suppresses issues on its own line, or on
+// the next statement line when the comment stands alone on its line.
+// A bare "nolint" suppresses every rule.
+func parseSuppressions(lq *LintQuery) *suppressionSet {
+	set := &suppressionSet{
+		global: make(map[string]bool),
+		lines:  make(map[int]map[string]bool),
+	}
+
+	firstLine := 0
+	if len(lq.Statements) > 0 {
+		firstLine = lq.Statements[0].Line
+	}
+
+	for _, comment := range lq.Comments {
+		match := nolintPattern.FindStringSubmatch(comment.Text)
+		if match == nil {
+			continue
+		}
+
+		ids := []string{"*"}
+		if match[1] != "" {
+			ids = strings.Split(match[1], ",")
+		}
+
+		var target map[string]bool
+		switch {
+		case comment.Standalone && comment.Line < firstLine:
+			target = set.global
+		default:
+			line := comment.Line
+			if comment.Standalone {
+				line = nextStatementLine(lq.Statements, comment.Line)
+			}
+			if set.lines[line] == nil {
+				set.lines[line] = make(map[string]bool)
+			}
+			target = set.lines[line]
+		}
+
+		for _, id := range ids {
+			target[strings.TrimSpace(id)] = true
+		}
+	}
+
+	return set
+}
+
+// nextStatementLine returns the first statement line after the given line
+func nextStatementLine(statements []Statement, line int) int {
+	for _, stmt := range statements {
+		if stmt.Line > line {
+			return stmt.Line
+		}
+	}
+	return line
+}
+
+// suppresses checks whether an issue is covered by a nolint comment
+func (ss *suppressionSet) suppresses(issue LintIssue) bool {
+	if ss.global["*"] || ss.global[issue.RuleID] {
+		return true
+	}
+
+	lineRules := ss.lines[issue.Line]
+	return lineRules["*"] || lineRules[issue.RuleID]
+}
+
+// =============================================================================
+// Lint Report
+// =============================================================================
+
+// LintReport contains the results of linting a query
+type LintReport struct {
+	// Query is the linted query text
+	Query string `json:"query"`
+
+	// Issues are the unsuppressed findings sorted by position
+	Issues []LintIssue `json:"issues"`
+
+	// Suppressed is the number of issues silenced by nolint comments
+	Suppressed int `json:"suppressed"`
+
+	rules []LintRule
+}
+
+// HasIssues checks if the report contains any issues
+func (lr *LintReport) HasIssues() bool {
+	return len(lr.Issues) > 0
+}
+
+// HasErrors checks if the report contains any error-severity issues
+func (lr *LintReport) HasErrors() bool {
+	return len(lr.BySeverity(SeverityError)) > 0
+}
+
+// BySeverity returns the issues with the given severity
+func (lr *LintReport) BySeverity(severity LintSeverity) []LintIssue {
+	var result []LintIssue
+	for _, issue := range lr.Issues {
+		if issue.Severity == severity {
+			result = append(result, issue)
+		}
+	}
+	return result
+}
+
+// ToJSON converts the report to JSON
+func (lr *LintReport) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(lr, "", "  ")
+}
+
+// ToSARIF converts the report to a SARIF 2.1.0 log
+// artifactURI identifies the linted query in the log (e.g. a .nql file path)
+// The tool driver lists the rules that ran; disabled rules are left out.
+func (lr *LintReport) ToSARIF(artifactURI string) ([]byte, error) {
+	if artifactURI == "" {
+		artifactURI = "query.nql"
+	}
+
+	driver := sarifDriver{
+		Name:           "nql-lint",
+		InformationURI: "https://github.com/deploymenttheory/go-api-sdk-nexthink",
+	}
+	for _, rule := range lr.rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID(),
+			ShortDescription:     sarifMessage{Text: rule.Description()},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Severity().sarifLevel()},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(lr.Issues))
+	for _, issue := range lr.Issues {
+		result := sarifResult{
+			RuleID:  issue.RuleID,
+			Level:   issue.Severity.sarifLevel(),
+			Message: sarifMessage{Text: issue.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: artifactURI},
+				},
+			}},
+		}
+		if issue.Line > 0 {
+			result.Locations[0].PhysicalLocation.Region = &sarifRegion{
+				StartLine:   issue.Line,
+				StartColumn: issue.Column,
+			}
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: driver},
+			Results: results,
+		}},
+	}
+
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// SARIF 2.1.0 document structure (subset used by the linter)
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri,omitempty"`
+		Rules          []sarifRule `json:"rules,omitempty"`
+	}
+
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+// =============================================================================
+// Public Lint Functions
+// =============================================================================
+
+// LintNQLQuery lints a query with the built-in rule set (convenience function)
+func LintNQLQuery(query string) *LintReport {
+	return NewDefaultLinter().Lint(query)
+}
+
+// Lint lints the built query with the built-in rule set
+func (qb *QueryBuilder) Lint() *LintReport {
+	return LintNQLQuery(qb.Build())
+}
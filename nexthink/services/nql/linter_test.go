@@ -0,0 +1,306 @@
+package nql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleIDs(issues []LintIssue) []string {
+	ids := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		ids = append(ids, issue.RuleID)
+	}
+	return ids
+}
+
+func TestSplitStatements(t *testing.T) {
+	query := "/* header */\ndevices during past 7d\n| where name == \"a|b\" | list device.name, device.entity /* note */\n| limit 10"
+
+	statements, comments := SplitStatements(query)
+
+	require.Len(t, statements, 4)
+	assert.True(t, statements[0].IsTableSelection())
+	assert.Equal(t, "devices during past 7d", statements[0].Body)
+	assert.Equal(t, 2, statements[0].Line)
+
+	assert.Equal(t, "where", statements[1].Keyword)
+	assert.Equal(t, `name == "a|b"`, statements[1].Body)
+	assert.Equal(t, 3, statements[1].Line)
+	assert.Equal(t, 3, statements[1].Column)
+
+	assert.Equal(t, "list", statements[2].Keyword)
+	assert.Equal(t, 3, statements[2].Line)
+	assert.Equal(t, "limit", statements[3].Keyword)
+	assert.Equal(t, 4, statements[3].Line)
+
+	require.Len(t, comments, 2)
+	assert.Equal(t, "header", comments[0].Text)
+	assert.True(t, comments[0].Standalone)
+	assert.Equal(t, 1, comments[0].Column)
+	assert.Equal(t, 3, comments[1].Line)
+	assert.Equal(t, 57, comments[1].Column)
+	assert.False(t, comments[1].Standalone)
+}
+
+func TestLinter_CleanQuery(t *testing.T) {
+	query := NewQueryBuilder().
+		FromDevices().
+		DuringPast(7, Days).
+		List("device.name").
+		SortAsc("device.name").
+		Limit(100).
+		Build()
+
+	report := LintNQLQuery(query)
+
+	assert.False(t, report.HasIssues(), "unexpected issues: %v", report.Issues)
+}
+
+func TestLinter_Rules(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		ruleID string
+		line   int
+	}{
+		{
+			name:   "event table without time selection",
+			query:  "execution.crashes\n| list binary.name\n| limit 10",
+			ruleID: RuleEventTableTimeSelection,
+			line:   1,
+		},
+		{
+			name:   "with event table without time selection",
+			query:  "devices\n| with execution.crashes\n| list device.name\n| limit 10",
+			ruleID: RuleEventTableTimeSelection,
+			line:   2,
+		},
+		{
+			name:   "list without limit",
+			query:  "devices during past 7d\n| list device.name",
+			ruleID: RuleListWithoutLimit,
+			line:   2,
+		},
+		{
+			name:   "where after summarize on unaggregated field",
+			query:  "devices\n| summarize total = count() by operating_system.platform\n| where device.name == \"x\"",
+			ruleID: RuleWhereAfterSummarize,
+			line:   3,
+		},
+		{
+			name:   "high resolution beyond retention",
+			query:  "session.vdi_events during past 7d by 30s\n| summarize total = count()",
+			ruleID: RuleHighResolutionRetention,
+			line:   1,
+		},
+		{
+			name:   "sort on unlisted field",
+			query:  "devices\n| list device.name\n| sort device.entity asc\n| limit 10",
+			ruleID: RuleSortUnlistedField,
+			line:   3,
+		},
+		{
+			name:   "deprecated field",
+			query:  "users\n| list username\n| limit 10",
+			ruleID: RuleDeprecatedField,
+			line:   2,
+		},
+		{
+			name:   "syntax rule",
+			query:  "devices\n| list device.name\n| summarize c = count()",
+			ruleID: SyntaxRulePrefix + "ListSummarizeConflict",
+			line:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := LintNQLQuery(tt.query)
+
+			var found *LintIssue
+			for i := range report.Issues {
+				if report.Issues[i].RuleID == tt.ruleID {
+					found = &report.Issues[i]
+					break
+				}
+			}
+
+			require.NotNil(t, found, "expected %s, got %v", tt.ruleID, ruleIDs(report.Issues))
+			assert.Equal(t, tt.line, found.Line)
+			assert.NotEmpty(t, found.Message)
+		})
+	}
+}
+
+func TestLinter_WhereAfterSummarizeAllowsAggregates(t *testing.T) {
+	query := "devices\n| summarize total = count() by operating_system.platform\n| where total > 10 and operating_system.platform == \"Windows\""
+
+	report := LintNQLQuery(query)
+
+	assert.NotContains(t, ruleIDs(report.Issues), RuleWhereAfterSummarize)
+
+	// Unquoted enum values are not field references
+	query = "devices\n| summarize total = count() by operating_system.platform\n| where operating_system.platform == windows or operating_system.platform in [macos, linux]"
+	report = LintNQLQuery(query)
+	assert.NotContains(t, ruleIDs(report.Issues), RuleWhereAfterSummarize)
+
+	query = "devices\n| summarize total = count() by operating_system.platform\n| where total != device.entity"
+	report = LintNQLQuery(query)
+	assert.Contains(t, ruleIDs(report.Issues), RuleWhereAfterSummarize)
+}
+
+func TestLinter_HighResolutionWithinRetention(t *testing.T) {
+	report := LintNQLQuery("session.vdi_events during past 1d by 30s\n| summarize total = count()")
+
+	assert.NotContains(t, ruleIDs(report.Issues), RuleHighResolutionRetention)
+}
+
+func TestLinter_Suppressions(t *testing.T) {
+	t.Run("inline suppression", func(t *testing.T) {
+		report := LintNQLQuery("devices during past 7d\n| list device.name /* nolint:list-without-limit */")
+
+		assert.NotContains(t, ruleIDs(report.Issues), RuleListWithoutLimit)
+		assert.Equal(t, 1, report.Suppressed)
+	})
+
+	t.Run("standalone suppression applies to next line", func(t *testing.T) {
+		report := LintNQLQuery("execution.crashes during past 7d\n/* nolint:list-without-limit */\n| list binary.name")
+
+		assert.NotContains(t, ruleIDs(report.Issues), RuleListWithoutLimit)
+	})
+
+	t.Run("header suppression is query-wide", func(t *testing.T) {
+		report := LintNQLQuery("/* nolint */\nexecution.crashes\n| list binary.name")
+
+		assert.False(t, report.HasIssues())
+		assert.Equal(t, 2, report.Suppressed)
+	})
+
+	t.Run("suppression of another rule does not hide issue", func(t *testing.T) {
+		report := LintNQLQuery("devices during past 7d\n| list device.name /* nolint:deprecated-field */")
+
+		assert.Contains(t, ruleIDs(report.Issues), RuleListWithoutLimit)
+	})
+}
+
+func TestLinter_CustomRuleAndDisable(t *testing.T) {
+	custom := NewLintRule("no-users", "Users table is not allowed", SeverityError, func(q *LintQuery) []LintIssue {
+		if q.Table == TableUsers {
+			return []LintIssue{{Message: "users table selected", Line: q.Statements[0].Line}}
+		}
+		return nil
+	})
+
+	linter := NewDefaultLinter().Register(custom).Disable(RuleListWithoutLimit)
+	report := linter.Lint("users\n| list user.name")
+
+	assert.Equal(t, []string{"no-users"}, ruleIDs(report.Issues))
+	assert.True(t, report.HasErrors())
+	assert.Len(t, report.BySeverity(SeverityError), 1)
+
+	data, err := report.ToSARIF("")
+	require.NoError(t, err)
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(data, &log))
+	var ids []string
+	for _, rule := range log.Runs[0].Tool.Driver.Rules {
+		ids = append(ids, rule.ID)
+	}
+	assert.Contains(t, ids, "no-users")
+	assert.NotContains(t, ids, RuleListWithoutLimit, "disabled rules are not listed")
+}
+
+func TestLintReport_ToJSON(t *testing.T) {
+	report := LintNQLQuery("devices\n| list device.name")
+
+	data, err := report.ToJSON()
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "devices\n| list device.name", decoded["query"])
+	assert.Len(t, decoded["issues"], 1)
+}
+
+func TestLintReport_ToSARIF(t *testing.T) {
+	report := LintNQLQuery("devices\n| list device.name")
+
+	data, err := report.ToSARIF("queries/devices.nql")
+	require.NoError(t, err)
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(data, &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.NotEmpty(t, log.Runs[0].Tool.Driver.Rules)
+	require.Len(t, log.Runs[0].Results, 1)
+
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, RuleListWithoutLimit, result.RuleID)
+	assert.Equal(t, "warning", result.Level)
+	assert.Equal(t, "queries/devices.nql", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 2, result.Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestTimeSelectionLookback(t *testing.T) {
+	tests := []struct {
+		selection string
+		expected  string
+		ok        bool
+	}{
+		{"during past 7d", "168h0m0s", true},
+		{"during past 15min", "15m0s", true},
+		{"from 21d ago to 13d ago", "504h0m0s", true},
+		{"on Feb 8, 2024", "0s", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selection, func(t *testing.T) {
+			d, ok := TimeSelectionLookback(tt.selection)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, d.String())
+		})
+	}
+}
+
+func TestParseNQLDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"7d", 168 * time.Hour, false},
+		{" 24h ", 24 * time.Hour, false},
+		{"15 min", 15 * time.Minute, false},
+		{"30s", 30 * time.Second, false},
+		{"1.5d", 0, true},
+		{"-3h", 0, true},
+		{"x15min", 0, true},
+		{"past 7d garbage", 0, true},
+		{"7days", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, err := ParseNQLDuration(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+func TestIsEventTable(t *testing.T) {
+	assert.True(t, IsEventTable(TableExecutionCrashes))
+	assert.True(t, IsEventTable(TableWebErrors))
+	assert.False(t, IsEventTable(TableDevices))
+	assert.False(t, IsEventTable("device.devices"))
+}
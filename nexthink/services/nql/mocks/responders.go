@@ -118,6 +118,27 @@ func (m *NQLMock) RegisterGetNQLExportStatusErrorMock() {
 	)
 }
 
+// RegisterCompletedExportMock registers StartNQLExport and GetNQLExportStatus mocks
+// for an export that completes immediately with the given results file URL
+func (m *NQLMock) RegisterCompletedExportMock(exportID, resultsFileURL string) {
+	httpmock.RegisterResponder(
+		"POST",
+		m.baseURL+"/api/v1/nql/export",
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{
+			"exportId": exportID,
+		}),
+	)
+
+	httpmock.RegisterResponder(
+		"GET",
+		m.baseURL+"/api/v1/nql/status/"+exportID,
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{
+			"status":         "COMPLETED",
+			"resultsFileUrl": resultsFileURL,
+		}),
+	)
+}
+
 // RegisterUnauthorizedErrorMock registers the mock for unauthorized errors
 func (m *NQLMock) RegisterUnauthorizedErrorMock() {
 	httpmock.RegisterResponder(
@@ -9,6 +9,9 @@ type ExecuteRequest struct {
 
 	// Platform optionally specifies the platform for the query
 	Platform string `json:"platform,omitempty"`
+
+	// Parameters optionally supplies values for the query parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
 // ExecuteNQLV1Response represents the response from an NQL execute V1 operation
@@ -51,6 +54,10 @@ type ExecuteNQLV2Response struct {
 	// Data is the list of rows with the data returned by the query execution
 	// Each row is an object with key-value pairs
 	Data []map[string]any `json:"data,omitempty"`
+
+	// Cache describes how the response was served when the service cache is
+	// enabled (see Service.EnableCache); nil otherwise
+	Cache *CacheInfo `json:"-"`
 }
 
 // DateTime represents a date and time object from the Nexthink API
@@ -74,6 +81,9 @@ type ExportRequest struct {
 	// Platform optionally specifies the platform for the query
 	Platform string `json:"platform,omitempty"`
 
+	// Parameters optionally supplies values for the query parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
+
 	// Format specifies the export format (csv or json)
 	// Defaults to csv if not specified
 	Format string `json:"format,omitempty"`
@@ -0,0 +1,304 @@
+package nqlgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// =============================================================================
+// Generation
+// =============================================================================
+
+// Generate renders the Go source for queries in package pkg
+// The output is gofmt'd and starts with the standard "Code generated" header.
+func Generate(pkg string, queries []*Query) ([]byte, error) {
+	if !isIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	data := fileData{Package: pkg}
+	for _, q := range queries {
+		view := newQueryView(q)
+		data.Queries = append(data.Queries, view)
+		for _, field := range view.Fields {
+			if strings.Contains(field.GoType, "time.") {
+				data.ImportTime = true
+			}
+		}
+		for _, param := range view.Params {
+			if param.Format != "" {
+				data.ImportStrconv = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return src, nil
+}
+
+// fileData is the template input for a generated file
+type fileData struct {
+	Package       string
+	ImportTime    bool
+	ImportStrconv bool
+	Queries       []queryView
+}
+
+// queryView is the template input for one query
+type queryView struct {
+	*Query
+	File        string
+	TextLiteral string
+	DocLines    []string
+	Fields      []fieldView
+	Params      []paramView
+}
+
+// fieldView is a row struct field
+type fieldView struct {
+	GoName string
+	GoType string
+	Tag    string
+	Column string
+}
+
+// paramView is a params struct field
+type paramView struct {
+	Name   string
+	GoName string
+	GoType string
+	Format string
+}
+
+// newQueryView prepares a query for the template
+func newQueryView(q *Query) queryView {
+	view := queryView{
+		Query:       q,
+		File:        filepath.Base(q.Path),
+		TextLiteral: goStringLiteral(q.Text),
+	}
+	if q.Description != "" {
+		view.DocLines = strings.Split(q.Description, "\n")
+	}
+
+	used := make(map[string]int)
+	for _, col := range q.Columns {
+		goType, option := columnGoType(col.Type)
+		tag := col.Name
+		if option != "" {
+			tag += "," + option
+		}
+		view.Fields = append(view.Fields, fieldView{
+			GoName: uniqueName(GoName(col.Name), used),
+			GoType: goType,
+			Tag:    strconv.Quote(tag),
+			Column: col.Name,
+		})
+	}
+
+	used = make(map[string]int)
+	for _, param := range q.Params {
+		goType, format := paramGoType(param.Type)
+		view.Params = append(view.Params, paramView{
+			Name:   param.Name,
+			GoName: uniqueName(GoName(param.Name), used),
+			GoType: goType,
+			Format: format,
+		})
+	}
+
+	return view
+}
+
+// columnGoType maps a column type to its Go field type and nql tag option
+func columnGoType(t nql.ColumnType) (string, string) {
+	switch t {
+	case nql.ColumnTypeInt:
+		return "int64", ""
+	case nql.ColumnTypeFloat:
+		return "float64", ""
+	case nql.ColumnTypeBool:
+		return "bool", ""
+	case nql.ColumnTypeDateTime:
+		return "*time.Time", ""
+	case nql.ColumnTypeDuration:
+		return "time.Duration", ""
+	case nql.ColumnTypeBytes:
+		return "int64", "bytes"
+	default:
+		return "string", ""
+	}
+}
+
+// paramGoType maps a parameter type to its Go type and the format expression
+// template converting it to a string (empty for strings)
+func paramGoType(t ParamType) (string, string) {
+	switch t {
+	case ParamInt:
+		return "int64", "strconv.FormatInt(%s, 10)"
+	case ParamFloat:
+		return "float64", "strconv.FormatFloat(%s, 'f', -1, 64)"
+	case ParamBool:
+		return "bool", "strconv.FormatBool(%s)"
+	default:
+		return "string", ""
+	}
+}
+
+// =============================================================================
+// Names
+// =============================================================================
+
+// initialisms are rendered in upper case in Go names
+var initialisms = map[string]bool{
+	"api": true, "cpu": true, "dex": true, "dns": true, "gpu": true, "http": true,
+	"id": true, "ip": true, "isp": true, "os": true, "ram": true, "sid": true,
+	"ssd": true, "uid": true, "url": true, "uuid": true, "vpn": true,
+}
+
+// GoName converts an NQL column or file name to an exported Go identifier
+// Example: "device.public_ip.country" -> "DevicePublicIPCountry"
+func GoName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, part := range parts {
+		lower := strings.ToLower(part)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// uniqueName appends a numeric suffix to names already in used
+func uniqueName(name string, used map[string]int) string {
+	used[name]++
+	if n := used[name]; n > 1 {
+		return fmt.Sprintf("%s%d", name, n)
+	}
+	return name
+}
+
+// isIdentifier reports whether s is a valid Go identifier
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// goStringLiteral returns a raw string literal for s when possible
+func goStringLiteral(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// =============================================================================
+// Template
+// =============================================================================
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"format": func(expr, value string) string { return fmt.Sprintf(expr, value) },
+}).Parse(`// Code generated by nqlgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+{{- if .ImportStrconv}}
+	"strconv"
+{{- end}}
+{{- if .ImportTime}}
+	"time"
+{{- end}}
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+{{range .Queries}}
+// =============================================================================
+// {{.Name}} ({{.File}})
+// =============================================================================
+
+// {{.Name}}QueryID is the saved query executed by Run{{.Name}}
+const {{.Name}}QueryID = {{printf "%q" .QueryID}}
+
+// {{.Name}}Query is the NQL text of {{.QueryID}}
+const {{.Name}}Query = {{.TextLiteral}}
+
+// {{.Name}}Row is a result row of {{.QueryID}}
+type {{.Name}}Row struct {
+{{- range .Fields}}
+	{{.GoName}} {{.GoType}} ` + "`nql:{{.Tag}}`" + `
+{{- end}}
+}
+{{if .Params}}
+// {{.Name}}Params are the parameters of {{.QueryID}}
+type {{.Name}}Params struct {
+{{- range .Params}}
+	{{.GoName}} {{.GoType}}
+{{- end}}
+}
+
+// Parameters returns the parameters as sent to the API
+func (p {{.Name}}Params) Parameters() map[string]string {
+	return map[string]string{
+{{- range .Params}}
+		{{printf "%q" .Name}}: {{if .Format}}{{format .Format (printf "p.%s" .GoName)}}{{else}}p.{{.GoName}}{{end}},
+{{- end}}
+	}
+}
+{{end}}
+// Run{{.Name}} executes {{.QueryID}} and decodes the result rows
+// Results beyond the execute row limit are fetched through an export (see nql.Service.Query).
+{{- range .DocLines}}
+//{{if .}} {{.}}{{end}}
+{{- end}}
+func Run{{.Name}}(ctx context.Context, svc *nql.Service{{if .Params}}, params {{.Name}}Params{{end}}) ([]{{.Name}}Row, error) {
+	result, err := svc.Query(ctx, &nql.ExecuteRequest{
+		QueryID: {{.Name}}QueryID,
+{{- if .Platform}}
+		Platform: {{printf "%q" .Platform}},
+{{- end}}
+{{- if .Params}}
+		Parameters: params.Parameters(),
+{{- end}}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", {{.Name}}QueryID, err)
+	}
+	return nql.DecodeRows[{{.Name}}Row](result.V2ResultSet, nil)
+}
+{{end}}`))
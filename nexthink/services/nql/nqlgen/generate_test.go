@@ -0,0 +1,111 @@
+package nqlgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	queries, err := Load("testdata")
+	require.NoError(t, err)
+
+	src, err := Generate("reports", queries)
+	require.NoError(t, err)
+
+	require.NoError(t, typeCheck(t, src))
+
+	code := string(src)
+	for _, want := range []string{
+		"// Code generated by nqlgen. DO NOT EDIT.",
+		"package reports",
+		`const DevicesWithCrashesQueryID = "#devices_with_crashes"`,
+		"DevicePublicIPCountry string     `nql:\"device.public_ip.country\"`",
+		"DeviceLastSeen        *time.Time `nql:\"device.last_seen\"`",
+		"HardwareMemory        int64      `nql:\"hardware.memory,bytes\"`",
+		`"min_crashes": strconv.FormatInt(p.MinCrashes, 10),`,
+		"func RunDevicesWithCrashes(ctx context.Context, svc *nql.Service, params DevicesWithCrashesParams) ([]DevicesWithCrashesRow, error) {",
+		"// Devices with at least $min_crashes crashes in the past week.",
+		"func RunDailyBootTimes(ctx context.Context, svc *nql.Service) ([]DailyBootTimesRow, error) {",
+		`Platform: "windows",`,
+		"result, err := svc.Query(ctx, &nql.ExecuteRequest{",
+		"return nql.DecodeRows[DevicesWithCrashesRow](result.V2ResultSet, nil)",
+		"AvgBoot   time.Duration `nql:\"avg_boot\"`",
+		"StartTime *time.Time    `nql:\"start_time\"`",
+	} {
+		assert.Contains(t, code, want)
+	}
+	assert.NotContains(t, code, "DailyBootTimesParams")
+}
+
+// typeCheck type-checks a generated file against the compiled packages it imports
+// The export data comes from go list, so the nql package is checked as built.
+func typeCheck(t *testing.T, src []byte) error {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "nql_gen.go", src, parser.AllErrors)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"list", "-export", "-f", "{{.ImportPath}}={{.Export}}"}
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		require.NoError(t, err)
+		args = append(args, path)
+	}
+	out, err := exec.Command("go", args...).Output()
+	require.NoError(t, err, "go list -export")
+
+	exports := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, export, _ := strings.Cut(line, "=")
+		exports[path] = export
+	}
+	lookup := func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup)}
+	_, err = conf.Check("reports", fset, []*ast.File{file}, nil)
+	return err
+}
+
+func TestGenerate_Imports(t *testing.T) {
+	q, err := Parse("devices.nql", []byte("/* @query_id #devices */ devices | list device.name"))
+	require.NoError(t, err)
+
+	src, err := Generate("reports", []*Query{q})
+	require.NoError(t, err)
+	assert.NotContains(t, string(src), `"time"`)
+	assert.NotContains(t, string(src), `"strconv"`)
+
+	_, err = Generate("my-reports", []*Query{q})
+	assert.Error(t, err)
+}
+
+func TestGenerate_DuplicateFieldNames(t *testing.T) {
+	q, err := Parse("devices.nql", []byte("/* @query_id #devices */ devices | list device.name, device_name"))
+	require.NoError(t, err)
+
+	src, err := Generate("reports", []*Query{q})
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "DeviceName2 string `nql:\"device_name\"`")
+}
@@ -0,0 +1,319 @@
+// Package nqlgen generates typed Go executors from annotated .nql files.
+//
+// Each .nql file holds one saved query. A leading comment declares the query
+// ID and its parameters; the output columns are inferred from the list,
+// summarize and compute statements (see nql.InferQueryColumns):
+//
+//	/*
+//	 * @query_id #devices_with_crashes
+//	 * @param    min_crashes int
+//	 *
+//	 * Devices with at least $min_crashes crashes in the past week.
+//	 */
+//	devices during past 7d
+//	| with execution.crashes during past 7d
+//	| compute crash_count = count()
+//	| where crash_count >= $min_crashes
+//	| list device.name, crash_count
+//
+// generates a DevicesWithCrashesRow struct, a DevicesWithCrashesParams struct
+// and RunDevicesWithCrashes(ctx, svc, params) returning []DevicesWithCrashesRow.
+//
+// Supported annotations:
+//   - @query_id #id: saved query identifier (required)
+//   - @name Name: Go name prefix (defaults to the file name in CamelCase)
+//   - @platform platform: platform sent with the execute request
+//   - @param name [string|int|float|bool]: query parameter (default string)
+//
+// Other header lines form the description. The nqlgen command in cmd/nqlgen
+// wraps this package for use with go:generate.
+package nqlgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// =============================================================================
+// Query Files
+// =============================================================================
+
+// ParamType is the Go type of a query parameter
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamInt    ParamType = "int"
+	ParamFloat  ParamType = "float"
+	ParamBool   ParamType = "bool"
+)
+
+// Param is a declared query parameter
+type Param struct {
+	Name string
+	Type ParamType
+}
+
+// Query is a parsed .nql file
+type Query struct {
+	// Path is the source file
+	Path string
+
+	// Name is the Go name prefix (DevicesWithCrashes)
+	Name string
+
+	// QueryID is the saved query identifier
+	QueryID string
+
+	// Platform is sent with the execute request when set
+	Platform string
+
+	// Description is the free text of the header comment
+	Description string
+
+	// Params are the declared parameters in declaration order
+	Params []Param
+
+	// Text is the NQL query without the header comment
+	Text string
+
+	// Columns are the inferred output columns in result order
+	Columns []nql.QueryColumn
+}
+
+var (
+	// paramRefPattern matches $parameter references in query text
+	paramRefPattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+	// goNamePattern matches exported Go identifiers
+	goNamePattern = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*$`)
+)
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+// ParseFile parses a .nql file
+func ParseFile(path string) (*Query, error) {
+	src, err := os.ReadFile(path) //nolint:gosec // path is chosen by the developer running the generator
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parses the contents of a .nql file
+// path is used for the default name and in error messages.
+func Parse(path string, src []byte) (*Query, error) {
+	header, text, err := splitHeader(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	q := &Query{
+		Path: path,
+		Name: GoName(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))),
+		Text: text,
+	}
+	if err := q.parseHeader(header); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := q.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	q.Columns = nql.InferQueryColumns(q.Text)
+	if len(q.Columns) == 0 {
+		return nil, fmt.Errorf("%s: cannot infer output columns, add a list, summarize or compute statement", path)
+	}
+	return q, nil
+}
+
+// splitHeader separates the leading /* ... */ comment from the query text
+func splitHeader(src string) (string, string, error) {
+	trimmed := strings.TrimSpace(src)
+	if !strings.HasPrefix(trimmed, "/*") {
+		return "", "", fmt.Errorf("missing header comment with @query_id")
+	}
+	end := strings.Index(trimmed, "*/")
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated header comment")
+	}
+	return trimmed[2:end], strings.TrimSpace(trimmed[end+2:]), nil
+}
+
+// parseHeader reads the annotations and description from the header comment
+func (q *Query) parseHeader(header string) error {
+	var description []string
+
+	for i, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+
+		if !strings.HasPrefix(line, "@") {
+			if line != "" || len(description) > 0 {
+				description = append(description, line)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		key, args := fields[0], fields[1:]
+		switch key {
+		case "@query_id":
+			if len(args) != 1 {
+				return fmt.Errorf("header line %d: @query_id takes one value", i+1)
+			}
+			q.QueryID = args[0]
+		case "@name":
+			if len(args) != 1 {
+				return fmt.Errorf("header line %d: @name takes one value", i+1)
+			}
+			q.Name = args[0]
+		case "@platform":
+			if len(args) != 1 {
+				return fmt.Errorf("header line %d: @platform takes one value", i+1)
+			}
+			q.Platform = args[0]
+		case "@param":
+			param, err := parseParam(args)
+			if err != nil {
+				return fmt.Errorf("header line %d: %w", i+1, err)
+			}
+			q.Params = append(q.Params, param)
+		default:
+			return fmt.Errorf("header line %d: unknown annotation %s", i+1, key)
+		}
+	}
+
+	q.Description = strings.TrimSpace(strings.Join(description, "\n"))
+	return nil
+}
+
+// parseParam parses the arguments of a @param annotation
+func parseParam(args []string) (Param, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return Param{}, fmt.Errorf("@param takes a name and an optional type")
+	}
+
+	param := Param{Name: strings.TrimPrefix(args[0], "$"), Type: ParamString}
+	if len(args) == 2 {
+		param.Type = ParamType(args[1])
+	}
+	switch param.Type {
+	case ParamString, ParamInt, ParamFloat, ParamBool:
+	default:
+		return Param{}, fmt.Errorf("parameter %s has unsupported type %q", param.Name, param.Type)
+	}
+	return param, nil
+}
+
+// validate checks the annotations, the parameter references and the query syntax
+func (q *Query) validate() error {
+	if q.QueryID == "" {
+		return fmt.Errorf("missing @query_id")
+	}
+	if !strings.HasPrefix(q.QueryID, "#") {
+		return fmt.Errorf("query ID must start with '#', got: %s", q.QueryID)
+	}
+	if !goNamePattern.MatchString(q.Name) {
+		return fmt.Errorf("name %q is not an exported Go identifier, set @name", q.Name)
+	}
+	if q.Text == "" {
+		return fmt.Errorf("query is empty")
+	}
+
+	declared := make(map[string]bool, len(q.Params))
+	for _, param := range q.Params {
+		if declared[param.Name] {
+			return fmt.Errorf("parameter %s is declared twice", param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	used := make(map[string]bool)
+	statements, _ := nql.SplitStatements(q.Text)
+	for _, stmt := range statements {
+		for _, match := range paramRefPattern.FindAllStringSubmatch(stmt.Body, -1) {
+			used[match[1]] = true
+		}
+	}
+	for name := range used {
+		if !declared[name] {
+			return fmt.Errorf("query references undeclared parameter $%s", name)
+		}
+	}
+	for _, param := range q.Params {
+		if !used[param.Name] {
+			return fmt.Errorf("parameter %s is declared but not used", param.Name)
+		}
+	}
+
+	report := nql.LintNQLQuery(q.Text)
+	if report.HasErrors() {
+		issues := report.BySeverity(nql.SeverityError)
+		messages := make([]string, len(issues))
+		for i, issue := range issues {
+			messages[i] = issue.String()
+		}
+		return fmt.Errorf("invalid query: %s", strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+// =============================================================================
+// Loading
+// =============================================================================
+
+// Load parses the given .nql files and every .nql file in the given directories
+// Queries are returned sorted by name; duplicate names or query IDs are errors.
+func Load(paths ...string) ([]*Query, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.nql"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	queries := make([]*Query, 0, len(files))
+	names := make(map[string]string)
+	ids := make(map[string]string)
+	for _, file := range files {
+		q, err := ParseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[q.Name]; ok {
+			return nil, fmt.Errorf("%s: name %s is already used by %s", file, q.Name, other)
+		}
+		if other, ok := ids[q.QueryID]; ok {
+			return nil, fmt.Errorf("%s: query ID %s is already used by %s", file, q.QueryID, other)
+		}
+		names[q.Name] = file
+		ids[q.QueryID] = file
+		queries = append(queries, q)
+	}
+
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	return queries, nil
+}
@@ -0,0 +1,79 @@
+package nqlgen
+
+import (
+	"testing"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	q, err := ParseFile("testdata/devices_with_crashes.nql")
+	require.NoError(t, err)
+
+	assert.Equal(t, "DevicesWithCrashes", q.Name)
+	assert.Equal(t, "#devices_with_crashes", q.QueryID)
+	assert.Empty(t, q.Platform)
+	assert.Equal(t, "Devices with at least $min_crashes crashes in the past week.", q.Description)
+	assert.Equal(t, []Param{
+		{Name: "min_crashes", Type: ParamInt},
+		{Name: "platform", Type: ParamString},
+	}, q.Params)
+	assert.True(t, len(q.Text) > 0 && q.Text[:7] == "devices", "header comment is stripped")
+	assert.Equal(t, []nql.QueryColumn{
+		{Name: "device.name", Type: nql.ColumnTypeString},
+		{Name: "device.public_ip.country", Type: nql.ColumnTypeString},
+		{Name: "crash_count", Type: nql.ColumnTypeInt},
+		{Name: "device.last_seen", Type: nql.ColumnTypeDateTime},
+		{Name: "hardware.memory", Type: nql.ColumnTypeBytes},
+	}, q.Columns)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no header", "devices | list device.name", "missing header comment"},
+		{"unterminated header", "/* @query_id #q\ndevices | list device.name", "unterminated"},
+		{"no query id", "/* @name Devices */ devices | list device.name", "missing @query_id"},
+		{"bad query id", "/* @query_id devices */ devices | list device.name", "must start with '#'"},
+		{"unknown annotation", "/* @query_id #q\n@owner me */ devices | list device.name", "unknown annotation @owner"},
+		{"bad param type", "/* @query_id #q\n@param since time */ devices | where x == $since | list device.name", "unsupported type"},
+		{"undeclared param", "/* @query_id #q */ devices | where device.name == $name | list device.name", "undeclared parameter $name"},
+		{"unused param", "/* @query_id #q\n@param name */ devices | list device.name", "declared but not used"},
+		{"duplicate param", "/* @query_id #q\n@param name\n@param name */ devices | where device.name == $name | list device.name", "declared twice"},
+		{"bad name", "/* @query_id #q\n@name devices */ devices | list device.name", "not an exported Go identifier"},
+		{"no columns", "/* @query_id #q */ devices during past 7d", "cannot infer output columns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("query.nql", []byte(tt.src))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+			assert.Contains(t, err.Error(), "query.nql")
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	queries, err := Load("testdata", "testdata/boot_times.nql")
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "DailyBootTimes", queries[0].Name)
+	assert.Equal(t, "windows", queries[0].Platform)
+	assert.Equal(t, "DevicesWithCrashes", queries[1].Name)
+
+	_, err = Load("testdata/missing.nql")
+	assert.Error(t, err)
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "DevicePublicIPCountry", GoName("device.public_ip.country"))
+	assert.Equal(t, "DevicesWithCrashes", GoName("devices_with_crashes"))
+	assert.Equal(t, "OperatingSystemPlatform", GoName("operating_system.platform"))
+	assert.Equal(t, "X95thPercentile", GoName("95th_percentile"))
+}
@@ -0,0 +1,1130 @@
+// Package nqltest evaluates NQL queries against in-memory fixture tables, so
+// query builders, templates and result processing can be tested without a
+// Nexthink tenant.
+//
+// The evaluator supports a useful subset of NQL:
+//   - a table selection with an optional time selection, applied to the
+//     timestamps of event tables (event tables default to the past 24 hours;
+//     time selections on inventory tables are ignored)
+//   - with and include on event tables, related to the objects by their
+//     uid or name column, followed by where (filtering the events) and compute
+//   - where, list, summarize (with by fields and by 15 min / 1 h / 1 d time
+//     buckets), sort and limit
+//   - the aggregates count, countif, sum, avg, min, max, last, p95 and p05
+//   - comparisons (case-insensitive, with * wildcards), in / !in, and / or /
+//     not, arithmetic, and duration (60s, 7d) and byte size (1GB) literals
+//
+// Other statements fail with ErrUnsupported.
+//
+//	data := nqltest.NewDataset()
+//	if err := data.LoadDir("testdata/fixtures"); err != nil {
+//		t.Fatal(err)
+//	}
+//	ev := nqltest.NewEvaluator(data, nqltest.Options{Now: now})
+//	resp, err := ev.Execute(query, nil)
+//
+// Evaluator.RegisterResponder serves registered queries through httpmock, so
+// code calling Service.ExecuteNQLV2 runs against the fixtures unchanged.
+package nqltest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+var (
+	// ErrUnsupported is returned for NQL the evaluator does not implement
+	ErrUnsupported = errors.New("unsupported NQL")
+
+	// ErrUnknownQuery is returned when executing a query ID that was not registered
+	ErrUnknownQuery = errors.New("query not registered")
+)
+
+// =============================================================================
+// Evaluator
+// =============================================================================
+
+// Options configures an Evaluator
+type Options struct {
+	// Now is the evaluation time of relative time selections
+	// Defaults to the current time at each execution if zero
+	Now time.Time
+
+	// Location is the time zone of the simulated instance, used for time
+	// selections, time buckets and timestamps without an offset
+	// Defaults to UTC if nil
+	Location *time.Location
+}
+
+// Evaluator executes NQL queries against a Dataset
+type Evaluator struct {
+	data *Dataset
+	opts Options
+
+	mu      sync.RWMutex
+	queries map[string]string
+}
+
+// NewEvaluator creates an evaluator over the given fixtures
+func NewEvaluator(data *Dataset, opts Options) *Evaluator {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return &Evaluator{
+		data:    data,
+		opts:    opts,
+		queries: make(map[string]string),
+	}
+}
+
+// Register associates a query ID (e.g. "#devices_with_crashes") with its NQL text
+func (e *Evaluator) Register(queryID, query string) *Evaluator {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.queries[queryID] = query
+	return e
+}
+
+// ExecuteRequest executes the registered query of an execute request
+func (e *Evaluator) ExecuteRequest(req *nql.ExecuteRequest) (*nql.ExecuteNQLV2Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	e.mu.RLock()
+	query, ok := e.queries[req.QueryID]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuery, req.QueryID)
+	}
+
+	resp, err := e.Execute(query, req.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", req.QueryID, err)
+	}
+	resp.QueryID = req.QueryID
+	return resp, nil
+}
+
+// Execute evaluates an NQL query with the given parameter values
+func (e *Evaluator) Execute(query string, params map[string]string) (*nql.ExecuteNQLV2Response, error) {
+	executed, err := substituteParameters(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	r := &run{
+		data: e.data,
+		now:  now.In(e.opts.Location),
+		loc:  e.opts.Location,
+	}
+	rows, err := r.execute(executed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nql.ExecuteNQLV2Response{
+		ExecutedQuery:     executed,
+		Rows:              int64(len(rows)),
+		ExecutionDateTime: r.now.UTC().Format(time.RFC3339),
+		Data:              rows,
+	}, nil
+}
+
+// =============================================================================
+// HTTP Mocking
+// =============================================================================
+
+// Responder returns an httpmock responder for the v2 execute endpoint
+// Unregistered query IDs get a 404 and evaluation errors a 400 response.
+func (e *Evaluator) Responder() httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		var execReq nql.ExecuteRequest
+		if err := json.NewDecoder(req.Body).Decode(&execReq); err != nil {
+			return errorResponse(http.StatusBadRequest, "bad_request", err.Error())
+		}
+
+		resp, err := e.ExecuteRequest(&execReq)
+		switch {
+		case errors.Is(err, ErrUnknownQuery):
+			return errorResponse(http.StatusNotFound, "not_found", err.Error())
+		case err != nil:
+			return errorResponse(http.StatusBadRequest, "bad_request", err.Error())
+		}
+		return httpmock.NewJsonResponse(http.StatusOK, resp)
+	}
+}
+
+// RegisterResponder registers Responder for the v2 execute endpoint of baseURL
+// httpmock must be activated on the client's transport, as in the service tests.
+func (e *Evaluator) RegisterResponder(baseURL string) {
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute", e.Responder())
+}
+
+// errorResponse builds an API error response
+func errorResponse(status int, code, message string) (*http.Response, error) {
+	return httpmock.NewJsonResponse(status, map[string]any{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
+
+// =============================================================================
+// Parameters
+// =============================================================================
+
+// parameterPattern matches $name query parameters
+var parameterPattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// substituteParameters replaces $name parameters with their values
+func substituteParameters(query string, params map[string]string) (string, error) {
+	var missing []string
+	result := parameterPattern.ReplaceAllStringFunc(query, func(match string) string {
+		value, ok := params[match[1:]]
+		if !ok {
+			missing = append(missing, match)
+			return match
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing query parameters: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// =============================================================================
+// Execution
+// =============================================================================
+
+// run is the state of one query evaluation
+type run struct {
+	data *Dataset
+	now  time.Time
+	loc  *time.Location
+
+	// object qualifies unqualified fields of inventory rows; empty after list or summarize
+	object string
+
+	// events is the queried event table, nil for inventory tables
+	events *table
+	window nql.TimeWindow
+
+	records   []*record
+	columns   map[string]bool
+	joins     []*join
+	afterJoin bool
+	projected bool
+}
+
+// record is a row being evaluated
+type record struct {
+	values map[string]any
+
+	// events holds the related events of each join
+	events [][]map[string]any
+}
+
+// join is a with or include statement
+type join struct {
+	table   *table
+	include bool
+	object  string
+	columns map[string]bool
+}
+
+// execute evaluates a query and returns its result rows
+func (r *run) execute(query string) ([]map[string]any, error) {
+	statements, _ := nql.SplitStatements(query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	if err := r.selectTable(statements[0].Body); err != nil {
+		return nil, fmt.Errorf("line %d: %w", statements[0].Line, err)
+	}
+
+	for _, stmt := range statements[1:] {
+		if err := r.statement(stmt); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", stmt.Line, stmt.Keyword, err)
+		}
+	}
+
+	rows := make([]map[string]any, len(r.records))
+	for i, rec := range r.records {
+		rows[i] = rec.values
+	}
+	return rows, nil
+}
+
+// statement evaluates one pipe statement
+func (r *run) statement(stmt nql.Statement) error {
+	if r.projected {
+		switch stmt.Keyword {
+		case "where", "sort", "limit":
+		case "with", "include", "compute", "list", "summarize":
+			return fmt.Errorf("%w: %s after list or summarize", ErrUnsupported, stmt.Keyword)
+		}
+	}
+
+	switch stmt.Keyword {
+	case "with", "include":
+		return r.join(stmt.Body, stmt.Keyword == "include")
+	case "where":
+		return r.where(stmt.Body)
+	case "compute":
+		return r.compute(stmt.Body)
+	case "list":
+		return r.list(stmt.Body)
+	case "summarize":
+		return r.summarize(stmt.Body)
+	case "sort":
+		return r.sort(stmt.Body)
+	case "limit":
+		return r.limit(stmt.Body)
+	default:
+		return fmt.Errorf("%w: %s statement", ErrUnsupported, stmt.Keyword)
+	}
+}
+
+// selectTable loads the rows of the table selection
+func (r *run) selectTable(body string) error {
+	tableName, selection := splitTableSelection(body)
+	t, ok := r.data.snapshot(tableName)
+	if !ok {
+		return fmt.Errorf("no fixture for table %s", tableName)
+	}
+
+	rows := t.rows
+	if nql.IsInventoryTable(tableName) {
+		r.object = objectName(tableName)
+	} else {
+		filtered, window, err := r.inWindow(t, selection)
+		if err != nil {
+			return err
+		}
+		rows, r.events, r.window = filtered, t, window
+	}
+
+	r.columns = columnsOf(t.rows)
+	r.records = make([]*record, len(rows))
+	for i, row := range rows {
+		r.records[i] = &record{values: maps.Clone(row)}
+	}
+	return nil
+}
+
+// join relates the events of an event table to the current objects
+func (r *run) join(body string, include bool) error {
+	if r.object == "" || r.events != nil {
+		return fmt.Errorf("%w: with and include require an inventory table", ErrUnsupported)
+	}
+
+	tableName, selection := splitTableSelection(body)
+	t, ok := r.data.snapshot(tableName)
+	if !ok {
+		return fmt.Errorf("no fixture for table %s", tableName)
+	}
+	if nql.IsInventoryTable(tableName) {
+		return fmt.Errorf("%w: with and include require an event table", ErrUnsupported)
+	}
+
+	events, _, err := r.inWindow(t, selection)
+	if err != nil {
+		return err
+	}
+
+	j := &join{table: t, include: include, object: objectName(tableName), columns: columnsOf(t.rows)}
+	key, err := joinKey(r.object, r.columns, j.columns)
+	if err != nil {
+		return fmt.Errorf("%s: %w", tableName, err)
+	}
+
+	byKey := make(map[string][]map[string]any)
+	for _, event := range events {
+		if value := event[key]; value != nil {
+			k := fmt.Sprint(value)
+			byKey[k] = append(byKey[k], event)
+		}
+	}
+
+	kept := r.records[:0]
+	for _, rec := range r.records {
+		related := byKey[fmt.Sprint(rec.values[key])]
+		if rec.values[key] == nil {
+			related = nil
+		}
+		if len(related) == 0 && !include {
+			continue
+		}
+		rec.events = append(rec.events, related)
+		kept = append(kept, rec)
+	}
+
+	r.records = kept
+	r.joins = append(r.joins, j)
+	r.afterJoin = true
+	return nil
+}
+
+// where filters the events of the last join, or the rows
+func (r *run) where(body string) error {
+	cond, err := parseExpression(body)
+	if err != nil {
+		return err
+	}
+
+	if r.afterJoin {
+		idx := len(r.joins) - 1
+		j := r.joins[idx]
+		kept := r.records[:0]
+		for _, rec := range r.records {
+			var events []map[string]any
+			for _, event := range rec.events[idx] {
+				ok, err := matches(cond, &rowScope{values: event, columns: j.columns, object: j.object})
+				if err != nil {
+					return err
+				}
+				if ok {
+					events = append(events, event)
+				}
+			}
+			rec.events[idx] = events
+			if len(events) > 0 || j.include {
+				kept = append(kept, rec)
+			}
+		}
+		r.records = kept
+		return nil
+	}
+
+	kept := r.records[:0]
+	for _, rec := range r.records {
+		ok, err := matches(cond, r.rowScope(rec))
+		if err != nil {
+			return err
+		}
+		if ok {
+			kept = append(kept, rec)
+		}
+	}
+	r.records = kept
+	return nil
+}
+
+// compute aggregates the related events of each object
+func (r *run) compute(body string) error {
+	if len(r.joins) == 0 {
+		return fmt.Errorf("compute requires a with or include statement")
+	}
+
+	items, err := parseAssignmentList(body)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for _, rec := range r.records {
+			value, err := eval(item.expr, &computeScope{run: r, record: rec})
+			if err != nil {
+				return err
+			}
+			rec.values[item.alias] = normalizeNumber(value)
+		}
+		r.columns[item.alias] = true
+	}
+
+	r.afterJoin = false
+	return nil
+}
+
+// list projects the rows onto the listed fields
+func (r *run) list(body string) error {
+	fields := splitList(body)
+	if len(fields) == 0 {
+		return fmt.Errorf("list requires at least one field")
+	}
+	resolved := make([]string, len(fields))
+	for i, field := range fields {
+		column, ok := resolveColumn(r.columns, r.object, field)
+		if !ok {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		resolved[i] = column
+	}
+
+	for _, rec := range r.records {
+		values := make(map[string]any, len(fields))
+		for i, field := range fields {
+			values[field] = rec.values[resolved[i]]
+		}
+		rec.values, rec.events = values, nil
+	}
+
+	r.project(fields)
+	return nil
+}
+
+// summarize aggregates the rows, optionally grouped by fields and time buckets
+func (r *run) summarize(body string) error {
+	p, err := newParser(body)
+	if err != nil {
+		return err
+	}
+	items, err := p.assignments()
+	if err != nil {
+		return err
+	}
+	groups, err := r.groupBy(p)
+	if err != nil {
+		return err
+	}
+
+	type group struct {
+		values map[string]any
+		rows   []map[string]any
+	}
+	var order []*group
+	byKey := make(map[string]*group)
+
+	for _, rec := range r.records {
+		values := make(map[string]any, len(groups))
+		keyParts := make([]string, len(groups))
+		for i, g := range groups {
+			value, err := g.value(rec.values)
+			if err != nil {
+				return err
+			}
+			values[g.output] = value
+			keyParts[i] = fmt.Sprintf("%T:%v", value, value)
+		}
+
+		key := strings.Join(keyParts, "\x00")
+		grp, ok := byKey[key]
+		if !ok {
+			grp = &group{values: values}
+			byKey[key] = grp
+			order = append(order, grp)
+		}
+		grp.rows = append(grp.rows, rec.values)
+	}
+
+	for _, g := range groups {
+		if g.bucket {
+			// Time buckets are returned in chronological order
+			output := g.output
+			sort.SliceStable(order, func(i, j int) bool {
+				return fmt.Sprint(order[i].values[output]) < fmt.Sprint(order[j].values[output])
+			})
+		}
+	}
+
+	if len(groups) == 0 && len(order) == 0 {
+		// An ungrouped summarize returns one row even without input rows
+		order = append(order, &group{values: map[string]any{}})
+	}
+
+	timeField := ""
+	if r.events != nil {
+		timeField = r.events.resolvedTimeField()
+	}
+
+	records := make([]*record, len(order))
+	for i, grp := range order {
+		scope := &aggregateScope{rows: grp.rows, columns: r.columns, object: r.object, timeField: timeField, loc: r.loc}
+		for _, item := range items {
+			value, err := eval(item.expr, scope)
+			if err != nil {
+				return err
+			}
+			grp.values[item.alias] = normalizeNumber(value)
+		}
+		records[i] = &record{values: grp.values}
+	}
+
+	outputs := make([]string, 0, len(items)+len(groups))
+	for _, g := range groups {
+		outputs = append(outputs, g.output)
+	}
+	for _, item := range items {
+		outputs = append(outputs, item.alias)
+	}
+
+	r.records = records
+	r.project(outputs)
+	return nil
+}
+
+// grouping is a summarize "by" item
+type grouping struct {
+	output string
+	bucket bool
+	value  func(row map[string]any) (any, error)
+}
+
+// groupBy parses the "by" items of a summarize statement
+func (r *run) groupBy(p *parser) ([]grouping, error) {
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+	if !p.isKeyword("by") {
+		return nil, p.errorf("expected by, found %q", p.peek().text)
+	}
+	p.next()
+
+	var groups []grouping
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case tokenNumber:
+			granularity := tok.text
+			if tok.unit == "" && p.peek().kind == tokenIdent {
+				granularity += " " + p.next().text
+			}
+			g, err := r.timeBucket(granularity)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, g)
+
+		case tokenIdent:
+			field := tok.text
+			column, ok := resolveColumn(r.columns, r.object, field)
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", field)
+			}
+			groups = append(groups, grouping{
+				output: field,
+				value:  func(row map[string]any) (any, error) { return row[column], nil },
+			})
+
+		default:
+			return nil, p.errorf("unexpected %q", tok.text)
+		}
+
+		if p.peek().text != "," {
+			break
+		}
+		p.next()
+	}
+
+	return groups, p.expectEnd()
+}
+
+// timeBucket groups event rows by start_time buckets of the given granularity
+// Buckets are aligned to midnight of the first day of the time selection.
+func (r *run) timeBucket(granularity string) (grouping, error) {
+	bucket, err := nql.ParseTimeGranularity(granularity)
+	if err != nil {
+		return grouping{}, err
+	}
+	if r.events == nil || len(r.joins) > 0 {
+		return grouping{}, fmt.Errorf("%w: time buckets require an event table", ErrUnsupported)
+	}
+
+	start := r.window.Start.In(r.loc)
+	anchor := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, r.loc)
+	timeField := r.events.resolvedTimeField()
+
+	return grouping{
+		output: "start_time",
+		bucket: true,
+		value: func(row map[string]any) (any, error) {
+			ts, err := parseTimestamp(row[timeField], r.loc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", timeField, err)
+			}
+			offset := ts.Sub(anchor) / bucket * bucket
+			return anchor.Add(offset).Format(time.RFC3339), nil
+		},
+	}, nil
+}
+
+// sort orders the rows by one or more fields
+func (r *run) sort(body string) error {
+	type key struct {
+		column string
+		desc   bool
+	}
+
+	items := splitList(body)
+	if len(items) == 0 {
+		return fmt.Errorf("sort requires at least one field")
+	}
+
+	var keys []key
+	for _, item := range items {
+		field, direction, _ := strings.Cut(item, " ")
+		column, ok := resolveColumn(r.columns, r.object, field)
+		if !ok {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		switch strings.ToLower(strings.TrimSpace(direction)) {
+		case "", "asc":
+			keys = append(keys, key{column: column})
+		case "desc":
+			keys = append(keys, key{column: column, desc: true})
+		default:
+			return fmt.Errorf("invalid sort direction %q", direction)
+		}
+	}
+
+	sort.SliceStable(r.records, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := r.records[i].values[k.column], r.records[j].values[k.column]
+			// Nulls sort last in both directions
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				return false
+			case b == nil:
+				return true
+			}
+			c, ok := compare(a, b)
+			if !ok {
+				c = strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+			}
+			if c != 0 {
+				return (c < 0) != k.desc
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// limit truncates the rows
+func (r *run) limit(body string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(body))
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid limit %q", body)
+	}
+	if len(r.records) > n {
+		r.records = r.records[:n]
+	}
+	return nil
+}
+
+// project switches to the output columns of list or summarize
+func (r *run) project(outputs []string) {
+	r.columns = make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		r.columns[output] = true
+	}
+	r.object, r.joins, r.afterJoin, r.projected = "", nil, false, true
+}
+
+// rowScope returns the scope of a record
+func (r *run) rowScope(rec *record) *rowScope {
+	return &rowScope{values: rec.values, columns: r.columns, object: r.object}
+}
+
+// inWindow returns the rows of an event table in a time selection
+func (r *run) inWindow(t *table, selection string) ([]map[string]any, nql.TimeWindow, error) {
+	if selection == "" {
+		selection = nql.Past24Hours
+	}
+	window, err := nql.ResolveTimeSelection(selection, r.now, r.loc)
+	if err != nil {
+		return nil, nql.TimeWindow{}, err
+	}
+	if len(t.rows) == 0 {
+		return nil, window, nil
+	}
+
+	timeField := t.resolvedTimeField()
+	if timeField == "" {
+		return nil, window, fmt.Errorf("event table %s has no time or start_time column (see Dataset.SetTimeField)", t.name)
+	}
+
+	var rows []map[string]any
+	for _, row := range t.rows {
+		ts, err := parseTimestamp(row[timeField], r.loc)
+		if err != nil {
+			return nil, window, fmt.Errorf("%s.%s: %w", t.name, timeField, err)
+		}
+		if window.Contains(ts) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, window, nil
+}
+
+// resolvedTimeField returns the timestamp column of an event table
+func (t *table) resolvedTimeField() string {
+	if t.timeField != "" {
+		return t.timeField
+	}
+	columns := columnsOf(t.rows)
+	for _, candidate := range []string{"time", "start_time"} {
+		if columns[candidate] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// =============================================================================
+// Scopes
+// =============================================================================
+
+// rowScope evaluates expressions on a single row
+type rowScope struct {
+	values  map[string]any
+	columns map[string]bool
+	object  string
+}
+
+func (s *rowScope) field(name string) (any, bool) {
+	column, ok := resolveColumn(s.columns, s.object, name)
+	if !ok {
+		return nil, false
+	}
+	return s.values[column], true
+}
+
+func (s *rowScope) aggregate(call *callNode) (any, error) {
+	return nil, fmt.Errorf("%s() is only allowed in compute and summarize", call.name)
+}
+
+// computeScope evaluates compute expressions over the related events of a record
+type computeScope struct {
+	run    *run
+	record *record
+}
+
+// field resolves object fields, so computations can combine them with aggregates
+func (s *computeScope) field(name string) (any, bool) {
+	return s.run.rowScope(s.record).field(name)
+}
+
+// aggregate aggregates the events of the most recent join that has the receiver field
+func (s *computeScope) aggregate(call *callNode) (any, error) {
+	idx := len(s.run.joins) - 1
+	if call.receiver != "" {
+		for i := len(s.run.joins) - 1; i >= 0; i-- {
+			if _, ok := aggregateColumn(s.run.joins[i].columns, s.run.joins[i].object, call.receiver); ok {
+				idx = i
+				break
+			}
+		}
+	}
+
+	j := s.run.joins[idx]
+	scope := &aggregateScope{
+		rows:      s.record.events[idx],
+		columns:   j.columns,
+		object:    j.object,
+		timeField: j.table.resolvedTimeField(),
+		loc:       s.run.loc,
+	}
+	return scope.aggregate(call)
+}
+
+// aggregateScope evaluates aggregates over a set of rows
+type aggregateScope struct {
+	rows      []map[string]any
+	columns   map[string]bool
+	object    string
+	timeField string
+	loc       *time.Location
+}
+
+func (s *aggregateScope) field(string) (any, bool) {
+	return nil, false
+}
+
+func (s *aggregateScope) aggregate(call *callNode) (any, error) {
+	switch call.name {
+	case "count":
+		if call.receiver == "" {
+			return int64(len(s.rows)), nil
+		}
+		values, err := s.values(call)
+		if err != nil {
+			return nil, err
+		}
+		distinct := make(map[string]bool)
+		for _, v := range values {
+			distinct[fmt.Sprintf("%T:%v", v, v)] = true
+		}
+		return int64(len(distinct)), nil
+
+	case "countif":
+		if len(call.args) != 1 {
+			return nil, fmt.Errorf("countif() takes one condition")
+		}
+		var n int64
+		for _, row := range s.rows {
+			ok, err := matches(call.args[0], &rowScope{values: row, columns: s.columns, object: s.object})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				n++
+			}
+		}
+		return n, nil
+
+	case "sum", "avg", "p95", "p05":
+		values, err := s.values(call)
+		if err != nil {
+			return nil, err
+		}
+		numbers := make([]float64, 0, len(values))
+		for _, v := range values {
+			f, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s(): %v is not a number", call.receiver, call.name, v)
+			}
+			numbers = append(numbers, f)
+		}
+		if len(numbers) == 0 {
+			return nil, nil
+		}
+		switch call.name {
+		case "sum":
+			return sum(numbers), nil
+		case "avg":
+			return sum(numbers) / float64(len(numbers)), nil
+		case "p95":
+			return percentile(numbers, 95), nil
+		default:
+			return percentile(numbers, 5), nil
+		}
+
+	case "min", "max":
+		values, err := s.values(call)
+		if err != nil {
+			return nil, err
+		}
+		var best any
+		for _, v := range values {
+			c, ok := compare(v, best)
+			if best == nil || (ok && ((call.name == "min" && c < 0) || (call.name == "max" && c > 0))) {
+				best = v
+			}
+		}
+		return best, nil
+
+	case "last":
+		return s.last(call)
+	}
+
+	return nil, fmt.Errorf("%w: %s() aggregate", ErrUnsupported, call.name)
+}
+
+// values returns the non-null values of the receiver field
+func (s *aggregateScope) values(call *callNode) ([]any, error) {
+	if call.receiver == "" {
+		return nil, fmt.Errorf("%s() requires a field, e.g. field.%s()", call.name, call.name)
+	}
+	column, ok := aggregateColumn(s.columns, s.object, call.receiver)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", call.receiver)
+	}
+
+	var values []any
+	for _, row := range s.rows {
+		if v := row[column]; v != nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// last returns the receiver value of the most recent row
+func (s *aggregateScope) last(call *callNode) (any, error) {
+	if call.receiver == "" {
+		return nil, fmt.Errorf("last() requires a field, e.g. field.last()")
+	}
+	column, ok := aggregateColumn(s.columns, s.object, call.receiver)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", call.receiver)
+	}
+
+	var (
+		value  any
+		latest time.Time
+	)
+	for i, row := range s.rows {
+		if s.timeField == "" {
+			value = row[column]
+			continue
+		}
+		ts, err := parseTimestamp(row[s.timeField], s.loc)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 || !ts.Before(latest) {
+			value, latest = row[column], ts
+		}
+	}
+	return value, nil
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// matches evaluates a condition; null results do not match
+func matches(cond node, s scope) (bool, error) {
+	value, err := eval(cond, s)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// parseAssignmentList parses a complete "alias = expr, ..." list
+func parseAssignmentList(body string) ([]assignment, error) {
+	p, err := newParser(body)
+	if err != nil {
+		return nil, err
+	}
+	items, err := p.assignments()
+	if err != nil {
+		return nil, err
+	}
+	return items, p.expectEnd()
+}
+
+// resolveColumn resolves a field reference to a column
+// Fields may omit the object name (name for device.name) or carry an extra
+// leading namespace (event.cpu_usage for cpu_usage).
+func resolveColumn(columns map[string]bool, object, field string) (string, bool) {
+	if columns[field] {
+		return field, true
+	}
+	if object != "" && columns[object+"."+field] {
+		return object + "." + field, true
+	}
+	if _, rest, ok := strings.Cut(field, "."); ok && columns[rest] {
+		return rest, true
+	}
+	return "", false
+}
+
+// aggregateColumn resolves the receiver of an aggregate
+// An object name stands for its identifier, so device.count() counts distinct devices.
+func aggregateColumn(columns map[string]bool, object, receiver string) (string, bool) {
+	if column, ok := resolveColumn(columns, object, receiver); ok {
+		return column, true
+	}
+	for _, id := range []string{".uid", ".name"} {
+		if columns[receiver+id] {
+			return receiver + id, true
+		}
+	}
+	return "", false
+}
+
+// joinKey returns the column relating events to objects
+func joinKey(object string, objectColumns, eventColumns map[string]bool) (string, error) {
+	for _, id := range []string{".uid", ".name"} {
+		if objectColumns[object+id] && eventColumns[object+id] {
+			return object + id, nil
+		}
+	}
+	return "", fmt.Errorf("events cannot be related to %ss: add a %s.uid or %s.name column to both fixtures",
+		object, object, object)
+}
+
+// columnsOf returns the union of the columns of rows
+func columnsOf(rows []map[string]any) map[string]bool {
+	columns := make(map[string]bool)
+	for _, row := range rows {
+		for column := range row {
+			columns[column] = true
+		}
+	}
+	return columns
+}
+
+// splitTableSelection splits "table [time selection]"
+func splitTableSelection(body string) (string, string) {
+	tableName, selection, _ := strings.Cut(strings.TrimSpace(body), " ")
+	return tableName, strings.TrimSpace(selection)
+}
+
+// splitList splits a comma separated list of fields
+func splitList(body string) []string {
+	var items []string
+	for _, item := range strings.Split(body, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// timestampLayouts are the accepted fixture timestamp formats
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a fixture timestamp; values without an offset are in loc
+func parseTimestamp(value any, loc *time.Location) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+	case nil:
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	if secs, ok := toFloat(value); ok {
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)).In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %v", value)
+}
+
+// sum adds up numbers
+func sum(numbers []float64) float64 {
+	var total float64
+	for _, n := range numbers {
+		total += n
+	}
+	return total
+}
+
+// percentile computes the p-th percentile with linear interpolation
+func percentile(numbers []float64, p float64) float64 {
+	sorted := append([]float64(nil), numbers...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,261 @@
+package nqltest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testNow is the evaluation time of the fixtures
+var testNow = time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+
+// newTestEvaluator creates an evaluator over testdata/fixtures
+func newTestEvaluator(t *testing.T) *Evaluator {
+	t.Helper()
+
+	data := NewDataset()
+	require.NoError(t, data.LoadDir("testdata/fixtures"))
+	return NewEvaluator(data, Options{Now: testNow})
+}
+
+// execute runs a query and returns its rows
+func execute(t *testing.T, e *Evaluator, query string) []map[string]any {
+	t.Helper()
+
+	resp, err := e.Execute(query, nil)
+	require.NoError(t, err)
+	require.Len(t, resp.Data, int(resp.Rows))
+	return resp.Data
+}
+
+// column returns the values of one column of the rows
+func column(rows []map[string]any, name string) []any {
+	values := make([]any, len(rows))
+	for i, row := range rows {
+		values[i] = row[name]
+	}
+	return values
+}
+
+func TestExecute_EventTimeSelection(t *testing.T) {
+	e := newTestEvaluator(t)
+
+	rows := execute(t, e, "execution.crashes")
+	assert.Len(t, rows, 3, "defaults to the past 24 hours")
+
+	rows = execute(t, e, "execution.crashes during past 7d")
+	assert.Len(t, rows, 4)
+
+	rows = execute(t, e, "execution.crashes from 2024-02-07 to 2024-02-07")
+	assert.Equal(t, []any{"LAPTOP-02"}, column(rows, "device.name"))
+}
+
+func TestExecute_WhereAndList(t *testing.T) {
+	e := newTestEvaluator(t)
+
+	rows := execute(t, e, `devices
+| where operating_system.platform == windows and device.entity != "APAC"
+| list device.name, entity
+| sort device.name desc`)
+
+	assert.Equal(t, []map[string]any{
+		{"device.name": "LAPTOP-04", "entity": "EMEA"},
+		{"device.name": "DESKTOP-01", "entity": "EMEA"},
+	}, rows)
+}
+
+func TestExecute_WithAndInclude(t *testing.T) {
+	e := newTestEvaluator(t)
+
+	rows := execute(t, e, `devices
+| with execution.crashes during past 7d
+| where binary.name == "outlook*"
+| compute crashes = number_of_crashes.sum()
+| list device.name, crashes`)
+	assert.Equal(t, []map[string]any{
+		{"device.name": "DESKTOP-01", "crashes": int64(2)},
+		{"device.name": "LAPTOP-02", "crashes": int64(3)},
+	}, rows)
+
+	rows = execute(t, e, `devices
+| include execution.crashes during past 7d
+| compute crash_events = count()
+| list device.name, crash_events`)
+	assert.Equal(t, []any{"DESKTOP-01", "LAPTOP-02", "MAC-03", "LAPTOP-04"}, column(rows, "device.name"))
+	assert.Equal(t, []any{int64(2), int64(1), int64(1), int64(0)}, column(rows, "crash_events"))
+}
+
+func TestExecute_Templates(t *testing.T) {
+	e := newTestEvaluator(t)
+	templates := nql.NewTemplates()
+
+	t.Run("DevicesWithCrashes", func(t *testing.T) {
+		rows := execute(t, e, templates.DevicesWithCrashes("during past 7d", "").Query())
+		assert.Equal(t, []any{"DESKTOP-01", "LAPTOP-02", "MAC-03"}, column(rows, "device.name"))
+		assert.Equal(t, []any{int64(3), int64(3), int64(1)}, column(rows, "total_crashes"))
+	})
+
+	t.Run("TopCrashingApplications", func(t *testing.T) {
+		rows := execute(t, e, templates.TopCrashingApplications("during past 7d", 2).Query())
+		assert.Equal(t, []map[string]any{
+			{"binary.name": "outlook.exe", "crash_count": int64(2), "device_count": int64(2)},
+			{"binary.name": "teams.exe", "crash_count": int64(1), "device_count": int64(1)},
+		}, rows)
+	})
+
+	t.Run("DevicesByPlatform", func(t *testing.T) {
+		rows := execute(t, e, templates.DevicesByPlatform("during past 7d").Query())
+		assert.Equal(t, []map[string]any{
+			{"operating_system.platform": "Windows", "device_count": int64(3)},
+			{"operating_system.platform": "macOS", "device_count": int64(1)},
+		}, rows)
+	})
+
+	t.Run("DevicesWithSlowBootTime", func(t *testing.T) {
+		rows := execute(t, e, templates.DevicesWithSlowBootTime(60, "during past 7d").Query())
+		assert.Equal(t, []map[string]any{
+			{"device.name": "LAPTOP-02", "avg_boot_time": int64(120)},
+			{"device.name": "DESKTOP-01", "avg_boot_time": int64(60)},
+		}, rows)
+	})
+
+	t.Run("UsersWithLowDEXScore", func(t *testing.T) {
+		rows := execute(t, e, templates.UsersWithLowDEXScore(50, "during past 7d").Query())
+		assert.Equal(t, []map[string]any{{"user.name": "alice", "user_dex": int64(45)}}, rows)
+	})
+
+	t.Run("OverallDEXScore", func(t *testing.T) {
+		rows := execute(t, e, templates.OverallDEXScore("during past 7d").Query())
+		assert.Equal(t, []map[string]any{{"overall_dex": int64(60)}}, rows)
+	})
+}
+
+func TestExecute_TimeBuckets(t *testing.T) {
+	e := newTestEvaluator(t)
+
+	rows := execute(t, e, "execution.crashes during past 7d | summarize crashes = number_of_crashes.sum() by 1d")
+	assert.Equal(t, []map[string]any{
+		{"start_time": "2024-02-05T00:00:00Z", "crashes": int64(1)},
+		{"start_time": "2024-02-07T00:00:00Z", "crashes": int64(3)},
+		{"start_time": "2024-02-08T00:00:00Z", "crashes": int64(3)},
+	}, rows)
+
+	rows = execute(t, e, `execution.crashes during past 7d
+| where device.name == "DESKTOP-01"
+| summarize crashes = count() by 15 min`)
+	assert.Equal(t, []any{"2024-02-08T09:00:00Z", "2024-02-08T09:15:00Z"}, column(rows, "start_time"))
+}
+
+func TestExecute_Location(t *testing.T) {
+	data := NewDataset()
+	require.NoError(t, data.LoadDir("testdata/fixtures"))
+
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	e := NewEvaluator(data, Options{Now: testNow, Location: sydney})
+
+	// Feb 8 09:00Z is Feb 8 20:00 in Sydney, Feb 7 15:00Z is Feb 8 02:00
+	rows := execute(t, e, "execution.crashes on Feb 8, 2024 | summarize crashes = number_of_crashes.sum()")
+	assert.Equal(t, []map[string]any{{"crashes": int64(6)}}, rows)
+}
+
+func TestExecute_Parameters(t *testing.T) {
+	e := newTestEvaluator(t)
+	query := `devices | where operating_system.platform == $platform | list device.name`
+
+	resp, err := e.Execute(query, map[string]string{"platform": "macOS"})
+	require.NoError(t, err)
+	assert.Equal(t, `devices | where operating_system.platform == macOS | list device.name`, resp.ExecutedQuery)
+	assert.Equal(t, []map[string]any{{"device.name": "MAC-03"}}, resp.Data)
+
+	_, err = e.Execute(query, nil)
+	assert.ErrorContains(t, err, "platform")
+}
+
+func TestExecute_Errors(t *testing.T) {
+	e := newTestEvaluator(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"unknown table", "printers", "printers"},
+		{"unknown field", "devices | where serial == 1", `unknown field "serial"`},
+		{"aggregate in where", "devices | where count() > 1", "only allowed in compute and summarize"},
+		{"statement after list", "devices | list name | compute x = 1", "compute"},
+		{"invalid bucket", "execution.crashes | summarize n = count() by 5 min", "multiple of 15"},
+		{"empty list", "devices | list", "list requires at least one field"},
+		{"empty sort", "devices | list name | sort", "sort requires at least one field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.Execute(tt.query, nil)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := e.Execute("execution.crashes | summarize n = count() by 1d | summarize m = n.sum()", nil)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestExecuteRequest(t *testing.T) {
+	e := newTestEvaluator(t).Register("#platforms", nql.NewTemplates().DevicesByPlatform("during past 7d").Query())
+
+	resp, err := e.ExecuteRequest(&nql.ExecuteRequest{QueryID: "#platforms"})
+	require.NoError(t, err)
+	assert.Equal(t, "#platforms", resp.QueryID)
+	assert.Equal(t, int64(2), resp.Rows)
+	assert.Equal(t, "2024-02-08T12:00:00Z", resp.ExecutionDateTime)
+
+	_, err = e.ExecuteRequest(&nql.ExecuteRequest{QueryID: "#missing"})
+	assert.ErrorIs(t, err, ErrUnknownQuery)
+}
+
+func TestRegisterResponder(t *testing.T) {
+	baseURL := "https://test.api.us.nexthink.cloud"
+	tokenURL := baseURL + "/api/v1/token"
+
+	httpClient := &http.Client{}
+	httpmock.ActivateNonDefault(httpClient)
+	t.Cleanup(httpmock.DeactivateAndReset)
+
+	httpmock.RegisterResponder("POST", tokenURL,
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{
+			"access_token": "mock-access-token",
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		}))
+
+	transport, err := client.NewTransport("client-id", "client-secret", "test-instance", "us",
+		client.WithLogger(zap.NewNop()),
+		client.WithBaseURL(baseURL),
+		client.WithCustomTokenURL(tokenURL),
+		client.WithTransport(httpClient.Transport),
+	)
+	require.NoError(t, err)
+	service := nql.NewService(transport)
+
+	e := newTestEvaluator(t).Register("#top_crashes", nql.NewTemplates().TopCrashingApplications("during past 7d", 1).Query())
+	e.RegisterResponder(baseURL)
+
+	result, _, err := service.ExecuteNQLV2(context.Background(), &nql.ExecuteRequest{QueryID: "#top_crashes"})
+	require.NoError(t, err)
+	assert.Equal(t, "#top_crashes", result.QueryID)
+	require.Len(t, result.Data, 1)
+	assert.Equal(t, "outlook.exe", result.Data[0]["binary.name"])
+
+	_, _, err = service.ExecuteNQLV2(context.Background(), &nql.ExecuteRequest{QueryID: "#missing"})
+	require.Error(t, err)
+	assert.True(t, client.IsNotFound(err))
+}
@@ -0,0 +1,750 @@
+package nqltest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// =============================================================================
+// Tokens
+// =============================================================================
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+// token is a lexical token of an NQL expression
+type token struct {
+	kind tokenKind
+	text string
+
+	// value is the parsed value of number tokens (with their unit applied)
+	value float64
+
+	// unit is the unit suffix of number tokens ("d", "min", "GB", ...)
+	unit string
+}
+
+// durationUnits are the duration literal suffixes, in seconds
+var durationUnits = map[string]float64{
+	"ms":  0.001,
+	"s":   1,
+	"min": 60,
+	"h":   3600,
+	"d":   86400,
+}
+
+// byteUnits are the byte size literal suffixes (binary multiples, as nql.ColumnTypeBytes)
+var byteUnits = map[string]float64{
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+}
+
+// comparisonOperators are the binary comparison operators
+var comparisonOperators = map[string]bool{"==": true, "!=": true, "=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// operators are the operator tokens, longest first
+var operators = []string{"==", "!=", "<=", ">=", "!in", "=", "<", ">", "+", "-", "*", "/", "(", ")", "[", "]", ",", "!"}
+
+// tokenize splits an expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in %q", expr)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			number := string(runes[start:i])
+			unitStart := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			tok, err := numberToken(number, string(runes[unitStart:i]))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.TrimSuffix(string(runes[start:i]), ".")})
+
+		default:
+			rest := string(runes[i:])
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(rest, op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q in %q", r, expr)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// numberToken builds a number token, applying a duration or byte size unit
+func numberToken(number, unit string) (token, error) {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q", number+unit)
+	}
+
+	tok := token{kind: tokenNumber, text: number + unit, value: value, unit: unit}
+	if unit == "" {
+		return tok, nil
+	}
+	if factor, ok := durationUnits[unit]; ok {
+		tok.value *= factor
+		return tok, nil
+	}
+	if factor, ok := byteUnits[strings.ToLower(unit)]; ok {
+		tok.value *= factor
+		return tok, nil
+	}
+	return token{}, fmt.Errorf("invalid number %q", number+unit)
+}
+
+// =============================================================================
+// Syntax Tree
+// =============================================================================
+
+// node is an expression syntax tree node
+type node interface{}
+
+// literalNode is a constant value
+type literalNode struct {
+	value any
+}
+
+// fieldNode is a field reference
+type fieldNode struct {
+	name string
+}
+
+// unaryNode is a prefix operation (-, not)
+type unaryNode struct {
+	op string
+	x  node
+}
+
+// binaryNode is an infix operation
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+// inNode is an "in" or "!in" membership test
+type inNode struct {
+	x      node
+	list   []node
+	negate bool
+}
+
+// callNode is a function or method call such as count() or crashes.sum()
+type callNode struct {
+	receiver string
+	name     string
+	args     []node
+}
+
+// assignment is an "alias = expression" item of compute and summarize
+type assignment struct {
+	alias string
+	expr  node
+}
+
+// =============================================================================
+// Parser
+// =============================================================================
+
+// parser is a recursive descent parser over tokens
+type parser struct {
+	tokens []token
+	pos    int
+	source string
+}
+
+// newParser tokenizes source for parsing
+func newParser(source string) (*parser, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens, source: source}, nil
+}
+
+// parseExpression parses a complete expression
+func parseExpression(source string) (node, error) {
+	p, err := newParser(source)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// assignments parses a comma separated list of "alias = expression"
+// It stops at the "by" keyword, which is left unconsumed.
+func (p *parser) assignments() ([]assignment, error) {
+	var items []assignment
+	for {
+		alias := p.next()
+		if alias.kind != tokenIdent {
+			return nil, p.errorf("expected alias, found %q", alias.text)
+		}
+		if op := p.next(); op.text != "=" {
+			return nil, p.errorf("expected = after %s", alias.text)
+		}
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, assignment{alias: alias.text, expr: expr})
+
+		if p.peek().text != "," {
+			return items, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword reports whether the next token is the given keyword
+func (p *parser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *parser) expectEnd() error {
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return p.errorf("unexpected %q", tok.text)
+	}
+	return nil
+}
+
+func (p *parser) expect(op string) error {
+	if tok := p.next(); tok.text != op {
+		return p.errorf("expected %q, found %q", op, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid expression %q: %s", p.source, fmt.Sprintf(format, args...))
+}
+
+// expression parses an "or" expression, the lowest precedence
+func (p *parser) expression() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.isKeyword("not") || p.peek().text == "!" {
+		p.next()
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "not", x: x}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenOperator && comparisonOperators[tok.text]:
+		p.next()
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		op := tok.text
+		if op == "=" {
+			op = "=="
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+
+	case tok.text == "!in" || p.isKeyword("in"):
+		p.next()
+		list, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{x: left, list: list, negate: tok.text == "!in"}, nil
+	}
+
+	return left, nil
+}
+
+// list parses a bracketed value list
+func (p *parser) list() ([]node, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var items []node
+	for p.peek().text != "]" {
+		item, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.peek().text != "," {
+			break
+		}
+		p.next()
+	}
+	return items, p.expect("]")
+}
+
+func (p *parser) additive() (node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.text == "+" || tok.text == "-"; tok = p.peek() {
+		p.next()
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.text == "*" || tok.text == "/"; tok = p.peek() {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek().text == "-" {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{value: tok.value}, nil
+
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.peek().text == "(" {
+			return p.call(tok.text)
+		}
+		return &fieldNode{name: tok.text}, nil
+
+	case tokenOperator:
+		if tok.text == "(" {
+			x, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+// call parses the arguments of a function or method call
+// "crashes.sum" becomes a sum call on the crashes field.
+func (p *parser) call(name string) (node, error) {
+	call := &callNode{name: strings.ToLower(name)}
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		call.receiver, call.name = name[:idx], strings.ToLower(name[idx+1:])
+	}
+
+	p.next() // (
+	for p.peek().text != ")" {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if p.peek().text != "," {
+			break
+		}
+		p.next()
+	}
+	return call, p.expect(")")
+}
+
+// =============================================================================
+// Evaluation
+// =============================================================================
+
+// scope resolves fields and aggregates during evaluation
+type scope interface {
+	// field returns the value of a field; ok is false for unknown fields
+	field(name string) (value any, ok bool)
+
+	// aggregate evaluates an aggregate call
+	aggregate(call *callNode) (any, error)
+}
+
+// eval evaluates an expression in a scope
+func eval(n node, s scope) (any, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+
+	case *fieldNode:
+		value, ok := s.field(n.name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", n.name)
+		}
+		return value, nil
+
+	case *unaryNode:
+		x, err := eval(n.x, s)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "not" {
+			return !truthy(x), nil
+		}
+		f, ok := toFloat(x)
+		if !ok {
+			return nil, nil
+		}
+		return -f, nil
+
+	case *binaryNode:
+		return evalBinary(n, s)
+
+	case *inNode:
+		x, err := eval(n.x, s)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, item := range n.list {
+			value, err := evalOperand(item, s)
+			if err != nil {
+				return nil, err
+			}
+			if equal(x, value) {
+				found = true
+				break
+			}
+		}
+		return found != n.negate, nil
+
+	case *callNode:
+		return s.aggregate(n)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %T", n)
+}
+
+// evalOperand evaluates the right operand of a comparison
+// Unknown bare identifiers are enumeration values such as windows or poor.
+func evalOperand(n node, s scope) (any, error) {
+	if field, ok := n.(*fieldNode); ok {
+		if value, known := s.field(field.name); known {
+			return value, nil
+		}
+		return field.name, nil
+	}
+	return eval(n, s)
+}
+
+// evalBinary evaluates a binary operation
+func evalBinary(n *binaryNode, s scope) (any, error) {
+	left, err := eval(n.left, s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "and":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := eval(n.right, s)
+		return truthy(right), err
+	case "or":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := eval(n.right, s)
+		return truthy(right), err
+	}
+
+	var right any
+	if comparisonOperators[n.op] {
+		right, err = evalOperand(n.right, s)
+	} else {
+		right, err = eval(n.right, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, ok := compare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	if !lok || !rok {
+		return nil, nil
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, nil
+		}
+		return l / r, nil
+	}
+}
+
+// =============================================================================
+// Values
+// =============================================================================
+
+// truthy reports whether a value is true (null and non-booleans are false)
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// toFloat converts numeric values to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case time.Duration:
+		return n.Seconds(), true
+	}
+	return 0, false
+}
+
+// equal compares two values the way NQL does
+// Strings compare case-insensitively and the right operand may use * wildcards.
+func equal(left, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		return ok && l == r
+	}
+	if l, ok := left.(bool); ok {
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+
+	l, r := fmt.Sprint(left), fmt.Sprint(right)
+	if strings.Contains(r, "*") {
+		return wildcardPattern(r).MatchString(l)
+	}
+	return strings.EqualFold(l, r)
+}
+
+// compare orders two numbers or two strings; ok is false otherwise
+func compare(left, right any) (int, bool) {
+	if left == nil || right == nil {
+		return 0, false
+	}
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if !lok || !rok {
+		return 0, false
+	}
+	return strings.Compare(l, r), true
+}
+
+// wildcardPattern converts an NQL wildcard string to a case-insensitive regexp
+func wildcardPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+}
+
+// normalizeNumber returns whole floats as int64 so counts and sums stay integers
+func normalizeNumber(v any) any {
+	f, ok := v.(float64)
+	if !ok || math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return v
+	}
+	return int64(f)
+}
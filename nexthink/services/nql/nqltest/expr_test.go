@@ -0,0 +1,127 @@
+package nqltest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval_RowExpressions(t *testing.T) {
+	scope := &rowScope{
+		values: map[string]any{
+			"device.name":               "LAPTOP-02",
+			"operating_system.platform": "Windows",
+			"crashes":                   int64(3),
+			"boot_time":                 90.0,
+			"memory":                    int64(8 << 30),
+			"is_virtual":                false,
+			"tag":                       nil,
+		},
+		columns: map[string]bool{
+			"device.name": true, "operating_system.platform": true, "crashes": true,
+			"boot_time": true, "memory": true, "is_virtual": true, "tag": true,
+		},
+		object: "device",
+	}
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`name == "laptop-02"`, true},
+		{`device.name == "LAPTOP*"`, true},
+		{`name == "DESKTOP*"`, false},
+		{`operating_system.platform == windows`, true},
+		{`operating_system.platform in [macOS, windows]`, true},
+		{`operating_system.platform !in [macOS, linux]`, true},
+		{`crashes >= 3 and boot_time > 60s`, true},
+		{`crashes > 5 or not is_virtual`, true},
+		{`is_virtual = false`, true},
+		{`boot_time < 1min`, false},
+		{`memory >= 8GB`, true},
+		{`tag == NULL`, true},
+		{`tag != NULL`, false},
+		{`tag > 1`, false},
+		{`crashes * 100 / 4`, 75.0},
+		{`-(crashes - 5)`, 2.0},
+		{`crashes / 0`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseExpression(tt.expr)
+			require.NoError(t, err)
+
+			got, err := eval(expr, scope)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEval_UnknownFieldAndAggregate(t *testing.T) {
+	scope := &rowScope{values: map[string]any{}, columns: map[string]bool{"a": true}}
+
+	expr, err := parseExpression("missing > 1")
+	require.NoError(t, err)
+	_, err = eval(expr, scope)
+	assert.ErrorContains(t, err, `unknown field "missing"`)
+
+	expr, err = parseExpression("a.sum() > 1")
+	require.NoError(t, err)
+	_, err = eval(expr, scope)
+	assert.ErrorContains(t, err, "only allowed in compute and summarize")
+}
+
+func TestParseExpression_Errors(t *testing.T) {
+	for _, expr := range []string{`a ==`, `(a == 1`, `a == "open`, `a == 1 1`, `a @ b`, `a > 5xyz`} {
+		_, err := parseExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestAggregateScope(t *testing.T) {
+	scope := &aggregateScope{
+		rows: []map[string]any{
+			{"time": "2024-02-08T09:00:00Z", "device.name": "A", "crashes": int64(2)},
+			{"time": "2024-02-08T11:00:00Z", "device.name": "A", "crashes": int64(6)},
+			{"time": "2024-02-08T10:00:00Z", "device.name": "B", "crashes": int64(4)},
+			{"time": "2024-02-08T08:00:00Z", "device.name": "C", "crashes": nil},
+		},
+		columns:   map[string]bool{"time": true, "device.name": true, "crashes": true},
+		object:    "execution",
+		timeField: "time",
+	}
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"count()", int64(4)},
+		{"device.count()", int64(3)},
+		{"countif(crashes >= 4)", int64(2)},
+		{"crashes.sum()", 12.0},
+		{"crashes.avg()", 4.0},
+		{"crashes.min()", int64(2)},
+		{"crashes.max()", int64(6)},
+		{"crashes.last()", int64(6)},
+		{"crashes.p95()", 5.8},
+		{"crashes.sum() * 100 / count()", 300.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseExpression(tt.expr)
+			require.NoError(t, err)
+
+			got, err := eval(expr, scope)
+			require.NoError(t, err)
+			if f, ok := tt.want.(float64); ok {
+				assert.InDelta(t, f, got, 1e-9)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package nqltest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// =============================================================================
+// Dataset
+// =============================================================================
+
+// Dataset holds fixture tables by NQL table name
+//
+// Inventory tables (devices, users, ...) hold one row per object; columns
+// without a namespace are qualified with the object name, so "name" in a
+// devices fixture is stored as "device.name". Event tables hold one row per
+// event, with a timestamp column and the fields of the objects they relate to
+// (e.g. "device.name" on execution.crashes).
+type Dataset struct {
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+// table is a fixture table
+type table struct {
+	name      string
+	rows      []map[string]any
+	timeField string
+}
+
+// NewDataset creates an empty dataset
+func NewDataset() *Dataset {
+	return &Dataset{tables: make(map[string]*table)}
+}
+
+// AddRows appends rows to a table, creating it if needed
+// Rows are copied; values should be strings, numbers, booleans or nil.
+func (d *Dataset) AddRows(tableName string, rows ...map[string]any) *Dataset {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := d.tableLocked(tableName)
+	object := objectName(tableName)
+	for _, row := range rows {
+		t.rows = append(t.rows, normalizeRow(row, object, nql.IsInventoryTable(tableName)))
+	}
+	return d
+}
+
+// SetTimeField sets the timestamp column of an event table
+// Defaults to "time" or "start_time", whichever the rows have.
+func (d *Dataset) SetTimeField(tableName, field string) *Dataset {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.tableLocked(tableName).timeField = field
+	return d
+}
+
+// Tables returns the table names in sorted order
+func (d *Dataset) Tables() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, 0, len(d.tables))
+	for name := range d.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rows returns a copy of the rows of a table
+func (d *Dataset) Rows(tableName string) []map[string]any {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	t, ok := d.tables[tableName]
+	if !ok {
+		return nil
+	}
+	rows := make([]map[string]any, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = maps.Clone(row)
+	}
+	return rows
+}
+
+// snapshot returns a table for evaluation
+func (d *Dataset) snapshot(tableName string) (*table, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	t, ok := d.tables[tableName]
+	if !ok {
+		return nil, false
+	}
+	return &table{name: t.name, rows: t.rows, timeField: t.timeField}, true
+}
+
+// tableLocked returns a table, creating it if needed; d.mu must be held
+func (d *Dataset) tableLocked(tableName string) *table {
+	t, ok := d.tables[tableName]
+	if !ok {
+		t = &table{name: tableName}
+		d.tables[tableName] = t
+	}
+	return t
+}
+
+// =============================================================================
+// Loading
+// =============================================================================
+
+// LoadJSON appends the rows of a JSON array of objects to a table
+func (d *Dataset) LoadJSON(tableName string, r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var rows []map[string]any
+	if err := decoder.Decode(&rows); err != nil {
+		return fmt.Errorf("failed to decode %s fixture: %w", tableName, err)
+	}
+	for _, row := range rows {
+		for key, value := range row {
+			row[key] = jsonValue(value)
+		}
+	}
+
+	d.AddRows(tableName, rows...)
+	return nil
+}
+
+// LoadCSV appends the rows of a CSV file with a header line to a table
+// Empty cells are null; numbers and true/false are converted.
+func (d *Dataset) LoadCSV(tableName string, r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read %s fixture: %w", tableName, err)
+	}
+	if len(records) == 0 {
+		d.AddRows(tableName)
+		return nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]any, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = csvValue(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	d.AddRows(tableName, rows...)
+	return nil
+}
+
+// LoadFile loads a .json or .csv fixture named after its table
+// Example: testdata/execution.crashes.csv loads the execution.crashes table
+func (d *Dataset) LoadFile(path string) error {
+	ext := filepath.Ext(path)
+	tableName := strings.TrimSuffix(filepath.Base(path), ext)
+
+	f, err := os.Open(path) //nolint:gosec // path is chosen by the caller
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(ext) {
+	case ".json":
+		return d.LoadJSON(tableName, f)
+	case ".csv":
+		return d.LoadCSV(tableName, f)
+	default:
+		return fmt.Errorf("unsupported fixture format: %s", path)
+	}
+}
+
+// LoadDir loads every .json and .csv fixture of a directory
+func (d *Dataset) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".csv":
+			if err := d.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// objectName returns the object name of a table
+// Example: devices -> device, device.devices -> device, execution.crashes -> execution
+func objectName(tableName string) string {
+	if namespace, _, ok := strings.Cut(tableName, "."); ok {
+		return namespace
+	}
+	switch {
+	case strings.HasSuffix(tableName, "ies"):
+		return strings.TrimSuffix(tableName, "ies") + "y"
+	default:
+		return strings.TrimSuffix(tableName, "s")
+	}
+}
+
+// normalizeRow copies a row, qualifying unqualified inventory columns
+func normalizeRow(row map[string]any, object string, inventory bool) map[string]any {
+	normalized := make(map[string]any, len(row))
+	for key, value := range row {
+		if inventory && !strings.Contains(key, ".") {
+			key = object + "." + key
+		}
+		normalized[key] = value
+	}
+	return normalized
+}
+
+// jsonValue converts decoded JSON numbers to int64 or float64
+func jsonValue(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return number.String()
+}
+
+// csvValue converts a CSV cell to null, a number, a boolean or a string
+func csvValue(cell string) any {
+	switch {
+	case cell == "":
+		return nil
+	case strings.EqualFold(cell, "true"):
+		return true
+	case strings.EqualFold(cell, "false"):
+		return false
+	}
+	if i, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return f
+	}
+	return cell
+}
@@ -0,0 +1,73 @@
+package nqltest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataset_LoadDir(t *testing.T) {
+	data := NewDataset()
+	require.NoError(t, data.LoadDir("testdata/fixtures"))
+
+	assert.Equal(t, []string{"devices", "dex.scores", "execution.crashes", "session.logins", "users"}, data.Tables())
+
+	devices := data.Rows("devices")
+	require.Len(t, devices, 4)
+	assert.Equal(t, "DESKTOP-01", devices[0]["device.name"], "inventory columns are qualified")
+	assert.Equal(t, "Windows", devices[0]["operating_system.platform"])
+
+	crashes := data.Rows("execution.crashes")
+	require.Len(t, crashes, 5)
+	assert.Equal(t, int64(2), crashes[0]["number_of_crashes"])
+	assert.Equal(t, "outlook.exe", crashes[0]["binary.name"])
+
+	logins := data.Rows("session.logins")
+	assert.Equal(t, int64(45), logins[0]["time_until_desktop_is_visible"])
+}
+
+func TestDataset_LoadCSVValues(t *testing.T) {
+	data := NewDataset()
+	csv := "name,count,ratio,enabled,note\nalpha,3,0.5,true,\n"
+	require.NoError(t, data.LoadCSV("binaries", strings.NewReader(csv)))
+
+	rows := data.Rows("binaries")
+	require.Len(t, rows, 1)
+	assert.Equal(t, map[string]any{
+		"binary.name":    "alpha",
+		"binary.count":   int64(3),
+		"binary.ratio":   0.5,
+		"binary.enabled": true,
+		"binary.note":    nil,
+	}, rows[0])
+}
+
+func TestDataset_RowsAreCopies(t *testing.T) {
+	row := map[string]any{"name": "DESKTOP-01"}
+	data := NewDataset().AddRows("devices", row)
+
+	row["name"] = "changed"
+	data.Rows("devices")[0]["device.name"] = "changed"
+
+	assert.Equal(t, "DESKTOP-01", data.Rows("devices")[0]["device.name"])
+}
+
+func TestDataset_LoadFileUnsupported(t *testing.T) {
+	err := NewDataset().LoadFile("testdata/fixtures/devices.yaml")
+	require.Error(t, err)
+}
+
+func TestObjectName(t *testing.T) {
+	tests := map[string]string{
+		"devices":           "device",
+		"binaries":          "binary",
+		"applications":      "application",
+		"device.devices":    "device",
+		"execution.crashes": "execution",
+	}
+	for table, want := range tests {
+		assert.Equal(t, want, objectName(table), table)
+	}
+}
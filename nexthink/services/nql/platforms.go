@@ -0,0 +1,227 @@
+package nql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Multi-platform execution runs one query against several platforms at once and
+// merges the results into a single result set
+
+// PlatformColumn is the synthetic column holding the platform of each merged row
+// It replaces any field of the same name returned by the query.
+const PlatformColumn = "platform"
+
+// =============================================================================
+// Multi-Platform Result
+// =============================================================================
+
+// PlatformResult is the outcome of the query on one platform
+type PlatformResult struct {
+	// Platform is the platform the query ran on
+	Platform string
+
+	// Response is the execute response (nil when Err is set)
+	Response *ExecuteNQLV2Response
+
+	// Err is the reason the query failed on this platform
+	Err error
+
+	// Duration is the time the execute call took
+	Duration time.Duration
+}
+
+// MultiPlatformResult is the merged result of a query run on several platforms
+// The embedded V2ResultSet holds the rows of every successful platform, in
+// platform order, each with a PlatformColumn value.
+type MultiPlatformResult struct {
+	*V2ResultSet
+
+	// Platforms holds one result per requested platform, in request order
+	Platforms []PlatformResult
+
+	// Columns is the union of the columns returned by all platforms, starting with
+	// PlatformColumn and followed by the other columns in sorted order
+	Columns []string
+
+	// MissingColumns lists, per platform, the columns that platform did not return
+	// Those cells are nil in the merged rows
+	MissingColumns map[string][]string
+}
+
+// Errors returns the errors of the platforms that failed, keyed by platform
+func (mr *MultiPlatformResult) Errors() map[string]error {
+	errs := make(map[string]error)
+	for _, result := range mr.Platforms {
+		if result.Err != nil {
+			errs[result.Platform] = result.Err
+		}
+	}
+	return errs
+}
+
+// Err returns the platform failures joined into one error, or nil when every platform succeeded
+func (mr *MultiPlatformResult) Err() error {
+	var errs []error
+	for _, result := range mr.Platforms {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("platform %s: %w", result.Platform, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Succeeded returns the platforms the query ran on successfully
+func (mr *MultiPlatformResult) Succeeded() []string {
+	var platforms []string
+	for _, result := range mr.Platforms {
+		if result.Err == nil {
+			platforms = append(platforms, result.Platform)
+		}
+	}
+	return platforms
+}
+
+// =============================================================================
+// Execute Across Platforms
+// =============================================================================
+
+// ExecuteAcrossPlatforms runs a query on several platforms concurrently and merges the results
+//
+// Each platform is executed with ExecuteNQLV2. The rows of every successful
+// platform are merged into one result set with a synthetic PlatformColumn, and
+// columns returned by only some platforms are filled with nil elsewhere.
+//
+// A failing platform does not fail the call: its error is reported in the
+// result (see Errors). An error is returned only for invalid input or when the
+// query failed on every platform; the result is returned in both cases.
+func (s *Service) ExecuteAcrossPlatforms(ctx context.Context, queryID string, platforms []string) (*MultiPlatformResult, error) {
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("at least one platform is required")
+	}
+
+	seen := make(map[string]bool, len(platforms))
+	for _, platform := range platforms {
+		if err := ValidateExecuteRequest(&ExecuteRequest{QueryID: queryID, Platform: platform}); err != nil {
+			return nil, err
+		}
+		if platform == "" {
+			return nil, fmt.Errorf("platform cannot be empty")
+		}
+		if seen[platform] {
+			return nil, fmt.Errorf("duplicate platform: %s", platform)
+		}
+		seen[platform] = true
+	}
+
+	s.client.GetLogger().Info("Executing NQL query across platforms",
+		zap.String("query_id", queryID),
+		zap.Strings("platforms", platforms))
+
+	results := make([]PlatformResult, len(platforms))
+	var wg sync.WaitGroup
+	for i, platform := range platforms {
+		wg.Go(func() {
+			start := time.Now()
+			resp, _, err := s.ExecuteNQLV2(ctx, &ExecuteRequest{QueryID: queryID, Platform: platform})
+			results[i] = PlatformResult{
+				Platform: platform,
+				Response: resp,
+				Err:      err,
+				Duration: time.Since(start),
+			}
+		})
+	}
+	wg.Wait()
+
+	merged := mergePlatformResults(queryID, results)
+
+	for _, result := range results {
+		if result.Err != nil {
+			s.client.GetLogger().Warn("Query failed on platform",
+				zap.String("query_id", queryID),
+				zap.String("platform", result.Platform),
+				zap.Error(result.Err))
+		}
+	}
+
+	if len(merged.Succeeded()) == 0 {
+		return merged, fmt.Errorf("query %s failed on every platform: %w", queryID, merged.Err())
+	}
+
+	return merged, nil
+}
+
+// mergePlatformResults merges the successful platform responses into one result set
+func mergePlatformResults(queryID string, results []PlatformResult) *MultiPlatformResult {
+	merged := &MultiPlatformResult{
+		Platforms:      results,
+		MissingColumns: make(map[string][]string),
+	}
+
+	// Union of the columns returned by each platform
+	platformColumns := make(map[string]map[string]bool)
+	union := make(map[string]bool)
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		columns := make(map[string]bool)
+		for _, row := range result.Response.Data {
+			for field := range row {
+				if field != PlatformColumn {
+					columns[field] = true
+					union[field] = true
+				}
+			}
+		}
+		platformColumns[result.Platform] = columns
+	}
+
+	sorted := make([]string, 0, len(union))
+	for field := range union {
+		sorted = append(sorted, field)
+	}
+	sort.Strings(sorted)
+	merged.Columns = append([]string{PlatformColumn}, sorted...)
+
+	resp := &ExecuteNQLV2Response{QueryID: queryID}
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+
+		// Platforms without rows cannot be said to be missing any column
+		if len(result.Response.Data) > 0 {
+			for _, field := range sorted {
+				if !platformColumns[result.Platform][field] {
+					merged.MissingColumns[result.Platform] = append(merged.MissingColumns[result.Platform], field)
+				}
+			}
+		}
+
+		if resp.ExecutedQuery == "" {
+			resp.ExecutedQuery = result.Response.ExecutedQuery
+			resp.ExecutionDateTime = result.Response.ExecutionDateTime
+		}
+
+		for _, row := range result.Response.Data {
+			mergedRow := make(map[string]any, len(merged.Columns))
+			for _, field := range sorted {
+				mergedRow[field] = row[field]
+			}
+			mergedRow[PlatformColumn] = result.Platform
+			resp.Data = append(resp.Data, mergedRow)
+		}
+	}
+	resp.Rows = int64(len(resp.Data))
+
+	merged.V2ResultSet = NewV2ResultSet(resp)
+	return merged
+}
@@ -0,0 +1,117 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerPlatformExecuteMock registers an ExecuteNQLV2 mock that answers per platform
+// Platforms without an entry in data fail with a 500 error.
+func registerPlatformExecuteMock(baseURL string, data map[string][]map[string]any) {
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute",
+		func(req *http.Request) (*http.Response, error) {
+			var body ExecuteRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return httpmock.NewStringResponse(400, "bad request"), nil
+			}
+			rows, ok := data[body.Platform]
+			if !ok {
+				return httpmock.NewJsonResponse(500, map[string]any{"message": "platform unavailable"})
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"queryId":       body.QueryID,
+				"executedQuery": "devices | list device.name",
+				"rows":          len(rows),
+				"data":          rows,
+			})
+		})
+}
+
+func TestExecuteAcrossPlatforms_MergesResults(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	registerPlatformExecuteMock(baseURL, map[string][]map[string]any{
+		"windows": {
+			{"device.name": "win-01", "device.os": "Windows 11"},
+			{"device.name": "win-02", "device.os": "Windows 10"},
+		},
+		"macos": {
+			{"device.name": "mac-01", "device.os": "macOS 14", "device.model": "MacBookPro18,1"},
+		},
+	})
+
+	result, err := service.ExecuteAcrossPlatforms(context.Background(), "#test_query", []string{"windows", "macos"})
+
+	require.NoError(t, err)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, []string{"windows", "macos"}, result.Succeeded())
+	assert.Equal(t, []string{PlatformColumn, "device.model", "device.name", "device.os"}, result.Columns)
+	assert.Equal(t, map[string][]string{"windows": {"device.model"}}, result.MissingColumns)
+	require.Equal(t, 3, result.Rows())
+	assert.Equal(t, int64(3), result.RowCount)
+
+	platform, err := result.GetString(2, PlatformColumn)
+	require.NoError(t, err)
+	assert.Equal(t, "macos", platform)
+
+	model, err := result.Get(0, "device.model")
+	require.NoError(t, err)
+	assert.Nil(t, model)
+}
+
+func TestExecuteAcrossPlatforms_PartialFailure(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	registerPlatformExecuteMock(baseURL, map[string][]map[string]any{
+		"windows": {{"device.name": "win-01"}},
+	})
+
+	result, err := service.ExecuteAcrossPlatforms(context.Background(), "#test_query", []string{"windows", "linux"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Rows())
+	require.Contains(t, result.Errors(), "linux")
+	assert.Error(t, result.Err())
+	assert.Equal(t, "linux", result.Platforms[1].Platform)
+	assert.Nil(t, result.Platforms[1].Response)
+}
+
+func TestExecuteAcrossPlatforms_AllFail(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	registerPlatformExecuteMock(baseURL, nil)
+
+	result, err := service.ExecuteAcrossPlatforms(context.Background(), "#test_query", []string{"windows", "linux"})
+
+	require.Error(t, err)
+	require.NotNil(t, result)
+	assert.Len(t, result.Errors(), 2)
+	assert.Equal(t, 0, result.Rows())
+}
+
+func TestExecuteAcrossPlatforms_Validation(t *testing.T) {
+	service, _ := setupMockClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		queryID   string
+		platforms []string
+	}{
+		{name: "no platforms", queryID: "#test_query"},
+		{name: "empty platform", queryID: "#test_query", platforms: []string{"windows", ""}},
+		{name: "duplicate platform", queryID: "#test_query", platforms: []string{"windows", "windows"}},
+		{name: "invalid query ID", queryID: "", platforms: []string{"windows"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ExecuteAcrossPlatforms(ctx, tt.queryID, tt.platforms)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package nql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"go.uber.org/zap"
+)
+
+// Poll strategies control how often export status is checked while waiting
+// for an export to complete
+
+// =============================================================================
+// Poll Strategy
+// =============================================================================
+
+// PollStrategy determines the wait between export status checks
+type PollStrategy interface {
+	// NextInterval returns the wait after the given number of status checks (starting at 1)
+	NextInterval(polls int) time.Duration
+}
+
+// DefaultPollStrategy returns an exponential strategy with jitter starting at
+// 2 seconds, growing by 1.5x per poll and capped at 30 seconds
+func DefaultPollStrategy() PollStrategy {
+	return NewCappedPollStrategy(NewExponentialJitterPollStrategy(2*time.Second, 1.5, 0.2), 30*time.Second)
+}
+
+// =============================================================================
+// Fixed
+// =============================================================================
+
+// FixedPollStrategy waits the same interval between every status check
+type FixedPollStrategy struct {
+	Interval time.Duration
+}
+
+// NewFixedPollStrategy creates a fixed interval poll strategy
+func NewFixedPollStrategy(interval time.Duration) *FixedPollStrategy {
+	return &FixedPollStrategy{Interval: interval}
+}
+
+// NextInterval implements PollStrategy
+func (s *FixedPollStrategy) NextInterval(polls int) time.Duration {
+	return s.Interval
+}
+
+// =============================================================================
+// Exponential
+// =============================================================================
+
+// ExponentialPollStrategy multiplies the wait by Multiplier after every status check
+// With Jitter set, each wait is randomised by up to ±Jitter (a fraction between 0 and 1)
+// so parallel exports do not poll in lockstep.
+type ExponentialPollStrategy struct {
+	Initial    time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// NewExponentialPollStrategy creates an exponential backoff poll strategy
+// A multiplier below 1 is treated as 2.
+func NewExponentialPollStrategy(initial time.Duration, multiplier float64) *ExponentialPollStrategy {
+	return &ExponentialPollStrategy{Initial: initial, Multiplier: multiplier}
+}
+
+// NewExponentialJitterPollStrategy creates an exponential backoff poll strategy with jitter
+// Example: NewExponentialJitterPollStrategy(2*time.Second, 2, 0.2) waits 2s, 4s, 8s... each ±20%
+func NewExponentialJitterPollStrategy(initial time.Duration, multiplier, jitter float64) *ExponentialPollStrategy {
+	return &ExponentialPollStrategy{Initial: initial, Multiplier: multiplier, Jitter: jitter}
+}
+
+// NextInterval implements PollStrategy
+func (s *ExponentialPollStrategy) NextInterval(polls int) time.Duration {
+	multiplier := s.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	wait := float64(s.Initial) * math.Pow(multiplier, float64(max(polls-1, 0)))
+
+	if s.Jitter > 0 {
+		jitter := min(s.Jitter, 1)
+		wait *= 1 - jitter + 2*jitter*rand.Float64() //nolint:gosec // jitter does not need a secure source
+	}
+
+	// Guard against overflow for long waits
+	if wait > float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(wait)
+}
+
+// =============================================================================
+// Capped
+// =============================================================================
+
+// CappedPollStrategy limits the wait returned by another strategy to Max
+type CappedPollStrategy struct {
+	Strategy PollStrategy
+	Max      time.Duration
+}
+
+// NewCappedPollStrategy caps the waits of strategy at maxInterval
+func NewCappedPollStrategy(strategy PollStrategy, maxInterval time.Duration) *CappedPollStrategy {
+	return &CappedPollStrategy{Strategy: strategy, Max: maxInterval}
+}
+
+// NextInterval implements PollStrategy
+func (s *CappedPollStrategy) NextInterval(polls int) time.Duration {
+	return min(s.Strategy.NextInterval(polls), s.Max)
+}
+
+// =============================================================================
+// Status Polling
+// =============================================================================
+
+// DefaultMaxStatusErrors is the number of consecutive transient status check
+// failures tolerated while waiting for an export
+const DefaultMaxStatusErrors = 3
+
+// pollConfig configures pollExportStatus
+type pollConfig struct {
+	strategy        PollStrategy
+	timeout         time.Duration
+	maxStatusErrors int
+
+	// limiter optionally throttles status checks (shared across concurrent exports)
+	limiter RateLimiter
+
+	// onStatus is called after every successful status check
+	onStatus func(status *NQLExportStatusResponse)
+}
+
+// pollExportStatus checks the export status until it reaches a terminal state
+//
+// Waits between checks come from the poll strategy. Transient failures (network
+// errors, 408, 429, 5xx) are tolerated up to maxStatusErrors consecutive times,
+// and a Retry-After header extends the following wait. On timeout the last known
+// status is returned together with the error.
+func (s *Service) pollExportStatus(ctx context.Context, exportID string, cfg pollConfig) (*NQLExportStatusResponse, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	var last *NQLExportStatusResponse
+	consecutiveErrors := 0
+
+	for polls := 1; ; polls++ {
+		if cfg.limiter != nil {
+			if err := cfg.limiter.Wait(timeoutCtx); err != nil {
+				return last, s.pollWaitError(ctx, timeoutCtx, cfg.timeout, last)
+			}
+		}
+
+		status, resp, err := s.GetNQLExportStatus(timeoutCtx, exportID)
+		wait := cfg.strategy.NextInterval(polls)
+
+		switch {
+		case err != nil && timeoutCtx.Err() != nil:
+			return last, s.pollWaitError(ctx, timeoutCtx, cfg.timeout, last)
+
+		case err != nil:
+			consecutiveErrors++
+			if !isTransientStatusError(resp) || consecutiveErrors > cfg.maxStatusErrors {
+				return last, fmt.Errorf("failed to get export status: %w", err)
+			}
+			wait = max(wait, retryAfter(resp))
+
+			s.client.GetLogger().Warn("Transient error checking export status",
+				zap.String("export_id", exportID),
+				zap.Int("consecutive_errors", consecutiveErrors),
+				zap.Duration("next_poll", wait),
+				zap.Error(err))
+
+		default:
+			consecutiveErrors = 0
+			last = status
+			if cfg.onStatus != nil {
+				cfg.onStatus(status)
+			}
+			if isTerminalStatus(status.Status) {
+				return status, nil
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timeoutCtx.Done():
+			timer.Stop()
+			return last, s.pollWaitError(ctx, timeoutCtx, cfg.timeout, last)
+		case <-timer.C:
+		}
+	}
+}
+
+// pollWaitError describes why waiting for an export stopped early
+func (s *Service) pollWaitError(parent, timeoutCtx context.Context, timeout time.Duration, last *NQLExportStatusResponse) error {
+	if parent.Err() != nil {
+		return fmt.Errorf("waiting for export cancelled: %w", parent.Err())
+	}
+
+	lastStatus := "unknown"
+	if last != nil {
+		lastStatus = last.Status
+	}
+
+	return fmt.Errorf("timeout waiting for export to complete after %v (status: %s): %w", timeout, lastStatus, timeoutCtx.Err())
+}
+
+// =============================================================================
+// Status Error Handling
+// =============================================================================
+
+// isTransientStatusError checks if a failed status check can be retried
+// Network failures (no response), 408, 429 and 5xx responses are transient.
+func isTransientStatusError(resp *interfaces.Response) bool {
+	if resp == nil || resp.StatusCode == 0 {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns the wait requested by a Retry-After header, or 0 if none
+// Both delay-seconds and HTTP-date forms are supported.
+func retryAfter(resp *interfaces.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	return retryAfterHeader(resp.Headers)
+}
+
+// retryAfterHeader returns the wait requested by the Retry-After header of h, or 0 if none
+func retryAfterHeader(h http.Header) time.Duration {
+	value := strings.TrimSpace(h.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,170 @@
+package nql
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedPollStrategy(t *testing.T) {
+	strategy := NewFixedPollStrategy(5 * time.Second)
+	for polls := 1; polls <= 5; polls++ {
+		assert.Equal(t, 5*time.Second, strategy.NextInterval(polls))
+	}
+}
+
+func TestExponentialPollStrategy(t *testing.T) {
+	strategy := NewExponentialPollStrategy(time.Second, 2)
+	assert.Equal(t, time.Second, strategy.NextInterval(1))
+	assert.Equal(t, 2*time.Second, strategy.NextInterval(2))
+	assert.Equal(t, 4*time.Second, strategy.NextInterval(3))
+	assert.Equal(t, 8*time.Second, strategy.NextInterval(4))
+
+	// A multiplier below 1 falls back to doubling
+	assert.Equal(t, 2*time.Second, NewExponentialPollStrategy(time.Second, 0).NextInterval(2))
+}
+
+func TestExponentialJitterPollStrategy(t *testing.T) {
+	strategy := NewExponentialJitterPollStrategy(10*time.Second, 2, 0.2)
+	for range 100 {
+		wait := strategy.NextInterval(2)
+		assert.GreaterOrEqual(t, wait, 16*time.Second)
+		assert.LessOrEqual(t, wait, 24*time.Second)
+	}
+}
+
+func TestCappedPollStrategy(t *testing.T) {
+	strategy := NewCappedPollStrategy(NewExponentialPollStrategy(time.Second, 2), 5*time.Second)
+	assert.Equal(t, 4*time.Second, strategy.NextInterval(3))
+	assert.Equal(t, 5*time.Second, strategy.NextInterval(4))
+	assert.Equal(t, 5*time.Second, strategy.NextInterval(50))
+
+	// Huge exponents are capped rather than overflowing
+	assert.Equal(t, 30*time.Second, DefaultPollStrategy().NextInterval(1000))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), retryAfter(nil))
+	assert.Equal(t, 7*time.Second, retryAfter(&interfaces.Response{
+		Headers: http.Header{"Retry-After": []string{"7"}},
+	}))
+
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	wait := retryAfter(&interfaces.Response{Headers: http.Header{"Retry-After": []string{future}}})
+	assert.Greater(t, wait, 50*time.Second)
+
+	assert.Equal(t, time.Duration(0), retryAfter(&interfaces.Response{
+		Headers: http.Header{"Retry-After": []string{"soon"}},
+	}))
+}
+
+func TestIsTransientStatusError(t *testing.T) {
+	assert.True(t, isTransientStatusError(nil))
+	assert.True(t, isTransientStatusError(&interfaces.Response{StatusCode: 429}))
+	assert.True(t, isTransientStatusError(&interfaces.Response{StatusCode: 503}))
+	assert.False(t, isTransientStatusError(&interfaces.Response{StatusCode: 404}))
+	assert.False(t, isTransientStatusError(&interfaces.Response{StatusCode: 401}))
+}
+
+// registerStatusSequence registers a status responder that returns the given
+// responders in order, repeating the last one
+func registerStatusSequence(baseURL, exportID string, responders ...httpmock.Responder) *atomic.Int32 {
+	var calls atomic.Int32
+	httpmock.RegisterResponder("GET", baseURL+"/api/v1/nql/status/"+exportID,
+		func(req *http.Request) (*http.Response, error) {
+			i := int(calls.Add(1)) - 1
+			return responders[min(i, len(responders)-1)](req)
+		})
+	return &calls
+}
+
+func TestWaitForNQLExportWithStrategy_ToleratesTransientErrors(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+
+	rateLimited := httpmock.NewStringResponse(429, `{"message":"rate limited"}`)
+	rateLimited.Header.Set("Retry-After", "0")
+
+	calls := registerStatusSequence(baseURL, "export-transient",
+		httpmock.NewStringResponder(503, `{"message":"unavailable"}`),
+		httpmock.ResponderFromResponse(rateLimited),
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{"status": ExportStatusInProgress}),
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{
+			"status":         ExportStatusCompleted,
+			"resultsFileUrl": "https://s3.amazonaws.com/nexthink-exports/export.csv",
+		}),
+	)
+
+	status, err := service.WaitForNQLExportWithStrategy(context.Background(), "export-transient",
+		NewFixedPollStrategy(time.Millisecond), 5*time.Second)
+
+	require.NoError(t, err)
+	assert.Equal(t, ExportStatusCompleted, status.Status)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestWaitForNQLExportWithStrategy_TooManyErrors(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	calls := registerStatusSequence(baseURL, "export-down", httpmock.NewStringResponder(503, `{}`))
+
+	_, err := service.WaitForNQLExportWithStrategy(context.Background(), "export-down",
+		NewFixedPollStrategy(time.Millisecond), 5*time.Second)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get export status")
+	assert.Equal(t, int32(DefaultMaxStatusErrors+1), calls.Load())
+}
+
+func TestWaitForNQLExportWithStrategy_PermanentError(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	calls := registerStatusSequence(baseURL, "export-missing", httpmock.NewStringResponder(404, `{}`))
+
+	_, err := service.WaitForNQLExportWithStrategy(context.Background(), "export-missing",
+		NewFixedPollStrategy(time.Millisecond), 5*time.Second)
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestWaitForNQLExportWithStrategy_Backoff(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	calls := registerStatusSequence(baseURL, "export-slow",
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{"status": ExportStatusInProgress}))
+
+	// 10ms, 20ms, 40ms, 80ms, 160ms... only a handful of polls fit in 300ms
+	_, err := service.WaitForNQLExportWithStrategy(context.Background(), "export-slow",
+		NewExponentialPollStrategy(10*time.Millisecond, 2), 300*time.Millisecond)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.LessOrEqual(t, calls.Load(), int32(6))
+}
+
+func TestExportWorkflow_PollStrategy(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+
+	httpmock.RegisterResponder("POST", baseURL+"/api/v1/nql/export",
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{"exportId": "export-strategy"}))
+	registerStatusSequence(baseURL, "export-strategy",
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{"status": ExportStatusInProgress}),
+		httpmock.NewJsonResponderOrPanic(200, map[string]any{"status": ExportStatusError, "errorDescription": "boom"}),
+	)
+
+	var polls int
+	opts := DefaultExportOptions().
+		WithPollStrategy(NewCappedPollStrategy(NewExponentialJitterPollStrategy(time.Millisecond, 2, 0.5), 5*time.Millisecond)).
+		WithOnProgress(func(status string, elapsed time.Duration) { polls++ })
+
+	_, err := service.ExportWorkflow(context.Background(), &ExportRequest{QueryID: "#test_query"}, opts)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "export failed: boom")
+	assert.Equal(t, 2, polls)
+}
@@ -0,0 +1,213 @@
+package nql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// Query runs an NQL query through the execute endpoint and falls back to the
+// export API when the result is truncated, so callers get the full result set
+// without choosing the endpoint up front
+
+// =============================================================================
+// Query Options
+// =============================================================================
+
+// QuerySource identifies the API that produced a query result
+type QuerySource string
+
+const (
+	// QuerySourceExecute means the rows came from ExecuteNQLV2
+	QuerySourceExecute QuerySource = "execute"
+
+	// QuerySourceExport means the rows came from an export
+	QuerySourceExport QuerySource = "export"
+)
+
+// QueryOptions configures QueryWithOptions
+type QueryOptions struct {
+	// MaxRows is the row count at which an execute result is treated as truncated
+	// Defaults to MaxExecuteRows if not specified
+	MaxRows int
+
+	// AlwaysExport skips the execute call and runs the query as an export
+	AlwaysExport bool
+
+	// Export configures the fallback export (poll strategy, timeout, callbacks)
+	// Writer and OutputPath are ignored. Format defaults to JSON. Either way the
+	// rows hold the JSON types the execute endpoint returns (float64 numbers,
+	// strings, bools and nil), so results do not depend on the path taken.
+	Export *ExportOptions
+}
+
+// DefaultQueryOptions returns query options with sensible defaults
+func DefaultQueryOptions() *QueryOptions {
+	return &QueryOptions{
+		MaxRows: MaxExecuteRows,
+		Export:  DefaultExportOptions().WithFormat(ExportFormatJSON).WithPollStrategy(DefaultPollStrategy()),
+	}
+}
+
+// =============================================================================
+// Query Result
+// =============================================================================
+
+// QueryResult is the full result of a query, whichever API produced it
+// The embedded V2ResultSet provides typed access, iteration and decoding.
+type QueryResult struct {
+	*V2ResultSet
+
+	// Source is the API that produced the rows
+	Source QuerySource
+
+	// Truncated is true when the execute result hit the row limit and the query
+	// was re-run as an export
+	Truncated bool
+
+	// Export is the fallback export result (nil when Source is QuerySourceExecute)
+	// Its Data is empty; the rows are in the result set
+	Export *ExportResult
+}
+
+// =============================================================================
+// Query
+// =============================================================================
+
+// Query runs a query and returns all of its rows
+// See QueryWithOptions; Query uses DefaultQueryOptions.
+func (s *Service) Query(ctx context.Context, req *ExecuteRequest) (*QueryResult, error) {
+	return s.QueryWithOptions(ctx, req, nil)
+}
+
+// QueryWithOptions runs a query and returns all of its rows
+//
+// The query is executed with ExecuteNQLV2. When the number of rows reaches
+// opts.MaxRows the execute result is assumed to be truncated, and the query is
+// re-run through the export API; the export is streamed and parsed row by row
+// into the result set without buffering the raw file.
+func (s *Service) QueryWithOptions(ctx context.Context, req *ExecuteRequest, opts *QueryOptions) (*QueryResult, error) {
+	if err := ValidateExecuteRequest(req); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = DefaultQueryOptions()
+	}
+	maxRows := opts.MaxRows
+	if maxRows <= 0 {
+		maxRows = MaxExecuteRows
+	}
+
+	if opts.AlwaysExport {
+		return s.queryViaExport(ctx, req, opts, nil)
+	}
+
+	resp, _, err := s.ExecuteNQLV2(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := max(int64(len(resp.Data)), resp.Rows)
+	if rows < int64(maxRows) {
+		return &QueryResult{
+			V2ResultSet: NewV2ResultSet(resp),
+			Source:      QuerySourceExecute,
+		}, nil
+	}
+
+	s.client.GetLogger().Info("Execute result truncated, falling back to export",
+		zap.String("query_id", req.QueryID),
+		zap.Int64("rows", rows),
+		zap.Int("max_rows", maxRows))
+
+	return s.queryViaExport(ctx, req, opts, resp)
+}
+
+// queryViaExport runs the query as an export and reads the rows as they are downloaded
+// executed is the truncated execute response, if any, and supplies the query metadata.
+func (s *Service) queryViaExport(ctx context.Context, req *ExecuteRequest, opts *QueryOptions, executed *ExecuteNQLV2Response) (*QueryResult, error) {
+	exportOpts := DefaultExportOptions().WithFormat(ExportFormatJSON)
+	if opts.Export != nil {
+		copied := *opts.Export
+		exportOpts = &copied
+	}
+	if exportOpts.Format == "" {
+		exportOpts.Format = ExportFormatJSON
+	}
+	exportOpts.OutputPath = ""
+	exportOpts.applyDefaults()
+
+	exportReq := &ExportRequest{
+		QueryID:    req.QueryID,
+		Platform:   req.Platform,
+		Parameters: req.Parameters,
+		Format:     exportOpts.Format,
+	}
+
+	pr, pw := io.Pipe()
+	exportOpts.Writer = pw
+
+	type exportOutcome struct {
+		result *ExportResult
+		err    error
+	}
+	done := make(chan exportOutcome, 1)
+	go func() {
+		result, err := s.ExportWorkflow(ctx, exportReq, exportOpts)
+		pw.CloseWithError(err)
+		done <- exportOutcome{result: result, err: err}
+	}()
+
+	data, readErr := readExportRows(pr, exportReq.Format)
+	// Unblock the export if reading stopped early
+	pr.CloseWithError(readErr)
+	outcome := <-done
+
+	// A failed export closes the pipe with its error, which the reader then reports;
+	// a failed read closes the pipe the other way and fails the export
+	switch {
+	case outcome.err != nil && (readErr == nil || errors.Is(readErr, outcome.err)):
+		return nil, fmt.Errorf("failed to export query %s: %w", req.QueryID, outcome.err)
+	case readErr != nil:
+		return nil, fmt.Errorf("failed to read export of query %s: %w", req.QueryID, readErr)
+	}
+
+	resp := &ExecuteNQLV2Response{
+		QueryID: req.QueryID,
+		Rows:    int64(len(data)),
+		Data:    data,
+	}
+	if executed != nil {
+		resp.ExecutedQuery = executed.ExecutedQuery
+		resp.ExecutionDateTime = executed.ExecutionDateTime
+	}
+
+	return &QueryResult{
+		V2ResultSet: NewV2ResultSet(resp),
+		Source:      QuerySourceExport,
+		Truncated:   executed != nil,
+		Export:      outcome.result,
+	}, nil
+}
+
+// readExportRows reads every row of an export stream as JSON-typed values
+func readExportRows(r io.Reader, format string) ([]map[string]any, error) {
+	reader, err := newExportReader(r, format, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []map[string]any
+	for reader.Next() {
+		data = append(data, reader.Row())
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
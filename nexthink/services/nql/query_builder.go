@@ -2,7 +2,9 @@ package nql
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Query builder provides a fluent API for constructing NQL queries programmatically
@@ -357,6 +359,169 @@ func (qb *QueryBuilder) Comment(text string) *QueryBuilder {
 	return qb
 }
 
+// =============================================================================
+// Accessors
+// =============================================================================
+
+// Table returns the table selection
+func (qb *QueryBuilder) Table() string {
+	return qb.table
+}
+
+// TimeSelection returns the time selection of the table, e.g. "during past 7d"
+func (qb *QueryBuilder) TimeSelection() string {
+	return qb.timeSelection
+}
+
+// WithClauses returns a copy of the with clauses
+func (qb *QueryBuilder) WithClauses() []string {
+	return slices.Clone(qb.withClauses)
+}
+
+// IncludeClauses returns a copy of the include clauses
+func (qb *QueryBuilder) IncludeClauses() []string {
+	return slices.Clone(qb.includeClauses)
+}
+
+// ComputeClauses returns a copy of the compute clauses ("alias = expression")
+func (qb *QueryBuilder) ComputeClauses() []string {
+	return slices.Clone(qb.computeClauses)
+}
+
+// WhereClauses returns a copy of the where conditions
+func (qb *QueryBuilder) WhereClauses() []string {
+	return slices.Clone(qb.whereClauses)
+}
+
+// ListFields returns a copy of the listed fields
+func (qb *QueryBuilder) ListFields() []string {
+	return slices.Clone(qb.listFields)
+}
+
+// SummarizeClauses returns a copy of the summarize clauses ("alias = expression")
+func (qb *QueryBuilder) SummarizeClauses() []string {
+	return slices.Clone(qb.summarizeClauses)
+}
+
+// SummarizeByFields returns a copy of the summarize group-by fields
+func (qb *QueryBuilder) SummarizeByFields() []string {
+	return slices.Clone(qb.summarizeBy)
+}
+
+// SortBy returns the sort field and direction; the field is empty when unsorted
+func (qb *QueryBuilder) SortBy() (string, SortDirection) {
+	return qb.sortField, qb.sortDirection
+}
+
+// LimitValue returns the limit, or 0 when there is none
+func (qb *QueryBuilder) LimitValue() int {
+	return qb.limitValue
+}
+
+// Comments returns a copy of the leading comments
+func (qb *QueryBuilder) Comments() []string {
+	return slices.Clone(qb.comments)
+}
+
+// =============================================================================
+// Mutators
+// =============================================================================
+
+// Clone returns a deep copy of the builder
+func (qb *QueryBuilder) Clone() *QueryBuilder {
+	clone := *qb
+	clone.withClauses = slices.Clone(qb.withClauses)
+	clone.includeClauses = slices.Clone(qb.includeClauses)
+	clone.computeClauses = slices.Clone(qb.computeClauses)
+	clone.whereClauses = slices.Clone(qb.whereClauses)
+	clone.listFields = slices.Clone(qb.listFields)
+	clone.summarizeClauses = slices.Clone(qb.summarizeClauses)
+	clone.summarizeBy = slices.Clone(qb.summarizeBy)
+	clone.comments = slices.Clone(qb.comments)
+	return &clone
+}
+
+// ClearTimeSelection removes the time selection
+func (qb *QueryBuilder) ClearTimeSelection() *QueryBuilder {
+	qb.timeSelection = ""
+	return qb
+}
+
+// RemoveWhere removes every where condition equal to condition
+// Conditions are compared with whitespace collapsed.
+func (qb *QueryBuilder) RemoveWhere(condition string) *QueryBuilder {
+	target := normalizeClause(condition)
+	qb.whereClauses = slices.DeleteFunc(slices.Clone(qb.whereClauses), func(c string) bool {
+		return normalizeClause(c) == target
+	})
+	return qb
+}
+
+// RemoveWhereAt removes the where condition at index; out of range indexes are ignored
+func (qb *QueryBuilder) RemoveWhereAt(index int) *QueryBuilder {
+	if index >= 0 && index < len(qb.whereClauses) {
+		qb.whereClauses = slices.Delete(slices.Clone(qb.whereClauses), index, index+1)
+	}
+	return qb
+}
+
+// ReplaceWhere replaces every where condition equal to old with replacement
+// Conditions are compared with whitespace collapsed.
+func (qb *QueryBuilder) ReplaceWhere(old, replacement string) *QueryBuilder {
+	target := normalizeClause(old)
+	clauses := slices.Clone(qb.whereClauses)
+	for i, c := range clauses {
+		if normalizeClause(c) == target {
+			clauses[i] = replacement
+		}
+	}
+	qb.whereClauses = clauses
+	return qb
+}
+
+// ClearWhere removes all where conditions
+func (qb *QueryBuilder) ClearWhere() *QueryBuilder {
+	qb.whereClauses = nil
+	return qb
+}
+
+// ClearList removes the list statement
+func (qb *QueryBuilder) ClearList() *QueryBuilder {
+	qb.listFields = nil
+	return qb
+}
+
+// ClearSummarize removes the summarize statement and its group-by fields
+func (qb *QueryBuilder) ClearSummarize() *QueryBuilder {
+	qb.summarizeClauses = nil
+	qb.summarizeBy = nil
+	return qb
+}
+
+// ClearSort removes the sort statement
+func (qb *QueryBuilder) ClearSort() *QueryBuilder {
+	qb.sortField = ""
+	qb.sortDirection = ""
+	return qb
+}
+
+// ClearLimit removes the limit statement
+func (qb *QueryBuilder) ClearLimit() *QueryBuilder {
+	qb.limitValue = 0
+	return qb
+}
+
+// ClearComments removes the leading comments
+func (qb *QueryBuilder) ClearComments() *QueryBuilder {
+	qb.comments = nil
+	return qb
+}
+
+// normalizeClause collapses whitespace for clause comparison
+func normalizeClause(clause string) string {
+	return whitespacePattern.ReplaceAllString(strings.TrimSpace(clause), " ")
+}
+
 // =============================================================================
 // Build
 // =============================================================================
@@ -454,6 +619,8 @@ func quoteValue(value string) string {
 // =============================================================================
 
 // Validate performs basic validation on the query
+// Time selections are also checked against data retention and the summarize
+// time bucket granularity, see ValidateTimeSelection and ValidateTimeGranularity.
 func (qb *QueryBuilder) Validate() error {
 	if qb.table == "" {
 		return fmt.Errorf("table selection is required (use From())")
@@ -469,6 +636,21 @@ func (qb *QueryBuilder) Validate() error {
 		return fmt.Errorf("compute clause requires a with or include clause")
 	}
 	
+	// Time selection retention and time bucket granularity, at the current time in UTC
+	if qb.timeSelection != "" {
+		if err := ValidateTimeSelection(qb.timeSelection); err != nil {
+			return err
+		}
+		for _, field := range qb.summarizeBy {
+			if _, err := ParseTimeGranularity(field); err != nil {
+				continue
+			}
+			if err := validateResolvableTimeSelection(qb.timeSelection, TimeGranularity(field), time.Now(), time.UTC); err != nil {
+				return err
+			}
+		}
+	}
+	
 	return nil
 }
 
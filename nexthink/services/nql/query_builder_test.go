@@ -159,6 +159,37 @@ func TestQueryBuilder_Validation_ComputeWithoutWith(t *testing.T) {
 	}
 }
 
+func TestQueryBuilder_Validation_TimeSelection(t *testing.T) {
+	qb := NewQueryBuilder().
+		From(TableExecutionCrashes).
+		During("during past 30d by 30s").
+		SummarizeCount("crashes")
+
+	if err := qb.Validate(); err == nil || !strings.Contains(err.Error(), "high-resolution data") {
+		t.Errorf("Expected high-resolution retention error, got: %v", err)
+	}
+
+	qb = NewQueryBuilder().
+		From(TableExecutionCrashes).
+		DuringPast(1, Days).
+		SummarizeCount("crashes").
+		SummarizeByTime(Granularity7Days)
+
+	if err := qb.Validate(); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Errorf("Expected granularity error, got: %v", err)
+	}
+
+	qb = NewQueryBuilder().
+		From(TableExecutionCrashes).
+		DuringPast(7, Days).
+		SummarizeCount("crashes").
+		SummarizeByTime(Granularity1Day)
+
+	if err := qb.Validate(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
 func TestQueryBuilder_Validation_ListAndSummarize(t *testing.T) {
 	qb := NewQueryBuilder().
 		FromDevices().
@@ -0,0 +1,157 @@
+package nql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query parsing
+// Turns NQL text back into a QueryBuilder. QueryBuilder emits statements in a
+// fixed order (with, include, compute, where, list or summarize, sort, limit).
+// Queries whose statements can be brought into that order without changing
+// their meaning are parsed; others are rejected with ErrQueryNotBuildable
+// instead of being silently rewritten.
+
+// ErrQueryNotBuildable is returned by ParseToBuilder for queries QueryBuilder cannot represent
+var ErrQueryNotBuildable = errors.New("query cannot be represented by QueryBuilder")
+
+// statementRank is the position of a statement keyword in QueryBuilder output
+var statementRank = map[string]int{
+	"with":      2,
+	"include":   3,
+	"compute":   4,
+	"where":     5,
+	"list":      6,
+	"summarize": 6,
+	"sort":      7,
+	"limit":     8,
+}
+
+// leadingWhereRank is the rank of where statements directly after the table selection
+// They filter the table before any join, which is equivalent to filtering it
+// after the with, include and compute statements.
+const leadingWhereRank = 1
+
+// ParseToBuilder parses an NQL query into a QueryBuilder
+//
+// Statement bodies are kept verbatim (with whitespace collapsed), so Build
+// returns an equivalent query. Where statements placed directly after the
+// table selection are moved after the computations, where QueryBuilder emits
+// them. Comments before the first statement become builder comments; other
+// comments are dropped.
+//
+// Returns an error wrapping ErrQueryNotBuildable for unsupported statements,
+// repeated list, summarize, sort or limit statements, sorts on several fields,
+// and statement orders QueryBuilder cannot reproduce, such as a where after
+// summarize or a compute after a where that follows a with.
+func ParseToBuilder(query string) (*QueryBuilder, error) {
+	statements, comments := SplitStatements(query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: query is empty", ErrQueryNotBuildable)
+	}
+
+	qb := NewQueryBuilder()
+	for _, comment := range comments {
+		if comment.Line < statements[0].Line ||
+			(comment.Line == statements[0].Line && comment.Column < statements[0].Column) {
+			qb.Comment(comment.Text)
+		}
+	}
+
+	table, timeSelection := splitTableSelection(statements[0].Body)
+	qb.From(table)
+	if timeSelection != "" {
+		qb.During(timeSelection)
+	}
+
+	notBuildable := func(stmt Statement, format string, args ...any) error {
+		return fmt.Errorf("%w: line %d: %s", ErrQueryNotBuildable, stmt.Line, fmt.Sprintf(format, args...))
+	}
+
+	lastRank := 0
+	var lastKeyword string
+	seen := make(map[string]bool)
+	var leadingWhere, where []string
+
+	for _, stmt := range statements[1:] {
+		rank, known := statementRank[stmt.Keyword]
+		if !known {
+			return nil, notBuildable(stmt, "unsupported statement %q", stmt.Keyword)
+		}
+		if stmt.Keyword == "where" && lastRank <= leadingWhereRank {
+			rank = leadingWhereRank
+		}
+		if rank < lastRank {
+			return nil, notBuildable(stmt, "%s cannot follow %s", stmt.Keyword, lastKeyword)
+		}
+		if rank >= statementRank["list"] && seen[stmt.Keyword] {
+			return nil, notBuildable(stmt, "repeated %s statement", stmt.Keyword)
+		}
+		if stmt.Body == "" {
+			return nil, notBuildable(stmt, "empty %s statement", stmt.Keyword)
+		}
+		lastRank, lastKeyword = rank, stmt.Keyword
+		seen[stmt.Keyword] = true
+
+		switch stmt.Keyword {
+		case "with":
+			qb.With(stmt.Body)
+		case "include":
+			qb.Include(stmt.Body)
+		case "compute":
+			qb.computeClauses = append(qb.computeClauses, stmt.Body)
+		case "where":
+			if rank == leadingWhereRank {
+				leadingWhere = append(leadingWhere, stmt.Body)
+			} else {
+				where = append(where, stmt.Body)
+			}
+		case "list":
+			qb.List(splitTopLevel(stmt.Body)...)
+		case "summarize":
+			body, groupBy := splitGroupBy(stmt.Body)
+			qb.summarizeClauses = append(qb.summarizeClauses, body)
+			if groupBy != "" {
+				qb.SummarizeBy(splitTopLevel(groupBy)...)
+			}
+		case "sort":
+			field, direction, err := parseSortBody(stmt.Body)
+			if err != nil {
+				return nil, notBuildable(stmt, "%v", err)
+			}
+			qb.Sort(field, direction)
+		case "limit":
+			n, err := strconv.Atoi(stmt.Body)
+			if err != nil || n <= 0 {
+				return nil, notBuildable(stmt, "invalid limit %q", stmt.Body)
+			}
+			qb.Limit(n)
+		}
+	}
+
+	if seen["list"] && seen["summarize"] {
+		return nil, fmt.Errorf("%w: cannot use both list and summarize in the same query", ErrQueryNotBuildable)
+	}
+
+	qb.whereClauses = append(leadingWhere, where...)
+	return qb, nil
+}
+
+// parseSortBody parses a single-field sort body ("field [asc|desc]")
+func parseSortBody(body string) (string, SortDirection, error) {
+	if len(splitTopLevel(body)) > 1 {
+		return "", "", fmt.Errorf("sort on several fields is not supported")
+	}
+
+	field, direction, _ := strings.Cut(body, " ")
+	switch strings.ToLower(strings.TrimSpace(direction)) {
+	case "", string(SortAsc):
+		return field, SortAsc, nil
+	case string(SortDesc):
+		return field, SortDesc, nil
+	default:
+		return "", "", fmt.Errorf("invalid sort direction %q", direction)
+	}
+}
@@ -0,0 +1,170 @@
+package nql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToBuilder_RoundTripsTemplates(t *testing.T) {
+	registry := DefaultTemplateRegistry()
+
+	for _, name := range registry.Names() {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := registry.Render(name, nil)
+			require.NoError(t, err)
+
+			qb, err := ParseToBuilder(tmpl.Query())
+			require.NoError(t, err)
+			assert.Equal(t, tmpl.Query(), qb.Build())
+		})
+	}
+}
+
+func TestParseToBuilder_Clauses(t *testing.T) {
+	query := `/* Devices with Outlook crashes */
+devices during past 7d
+| with execution.crashes during past 24h
+| compute total_crashes = number_of_crashes.sum()
+| where binary.name == "outlook.exe" and total_crashes > 2
+| summarize crash_count = total_crashes.sum(), devices = count() by operating_system.platform, device.entity
+| sort crash_count DESC
+| limit 50`
+
+	qb, err := ParseToBuilder(query)
+	require.NoError(t, err)
+
+	assert.Equal(t, "devices", qb.Table())
+	assert.Equal(t, "during past 7d", qb.TimeSelection())
+	assert.Equal(t, []string{"execution.crashes during past 24h"}, qb.WithClauses())
+	assert.Equal(t, []string{"total_crashes = number_of_crashes.sum()"}, qb.ComputeClauses())
+	assert.Equal(t, []string{`binary.name == "outlook.exe" and total_crashes > 2`}, qb.WhereClauses())
+	assert.Equal(t, []string{"crash_count = total_crashes.sum(), devices = count()"}, qb.SummarizeClauses())
+	assert.Equal(t, []string{"operating_system.platform", "device.entity"}, qb.SummarizeByFields())
+	assert.Equal(t, 50, qb.LimitValue())
+	assert.Equal(t, []string{"Devices with Outlook crashes"}, qb.Comments())
+
+	field, direction := qb.SortBy()
+	assert.Equal(t, "crash_count", field)
+	assert.Equal(t, SortDesc, direction)
+}
+
+func TestParseToBuilder_SameLineComments(t *testing.T) {
+	qb, err := ParseToBuilder(`/* hello */ devices /* dropped */ | list device.name`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "devices", qb.Table())
+	assert.Equal(t, []string{"hello"}, qb.Comments())
+	assert.Equal(t, "/* hello */\ndevices\n| list device.name", qb.Build())
+}
+
+func TestParseToBuilder_LeadingWhere(t *testing.T) {
+	query := `devices
+| where operating_system.platform == windows
+| where device.entity == "EMEA"
+| include device_performance.events during past 7d
+| compute cpu = event.cpu_usage.avg()
+| where cpu > 80
+| list device.name, cpu`
+
+	qb, err := ParseToBuilder(query)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"operating_system.platform == windows",
+		`device.entity == "EMEA"`,
+		"cpu > 80",
+	}, qb.WhereClauses())
+	assert.Equal(t, `devices
+| include device_performance.events during past 7d
+| compute cpu = event.cpu_usage.avg()
+| where operating_system.platform == windows
+| where device.entity == "EMEA"
+| where cpu > 80
+| list device.name, cpu`, qb.Build())
+}
+
+func TestParseToBuilder_NotBuildable(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty", "  "},
+		{"unsupported statement", "devices | with execution.crashes | summarize c = count() by device.name | top 10"},
+		{"where after summarize", "devices | summarize c = count() by platform | where c > 10"},
+		{"compute after event where", "devices | with execution.crashes | where binary.name == outlook.exe | compute c = count()"},
+		{"list and summarize", "devices | list device.name | summarize c = count()"},
+		{"repeated list", "devices | list device.name | list device.uid"},
+		{"sort on several fields", "devices | list device.name | sort device.name asc, device.uid desc"},
+		{"invalid sort direction", "devices | list device.name | sort device.name up"},
+		{"invalid limit", "devices | list device.name | limit ten"},
+		{"empty statement", "devices | where | list device.name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseToBuilder(tt.query)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrQueryNotBuildable), err.Error())
+		})
+	}
+}
+
+func TestParseToBuilder_ErrorLine(t *testing.T) {
+	_, err := ParseToBuilder("devices\n| list device.name\n| lookup x")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestQueryBuilder_EditParsedQuery(t *testing.T) {
+	qb, err := ParseToBuilder(`devices during past 7d
+| where operating_system.platform == windows
+| where  device.entity == "EMEA"
+| list device.name
+| sort device.name asc
+| limit 100`)
+	require.NoError(t, err)
+
+	qb.ReplaceWhere(`device.entity == "EMEA"`, `device.entity == "APAC"`).
+		ClearLimit().
+		During("during past 30d")
+
+	assert.Equal(t, `devices during past 30d
+| where operating_system.platform == windows
+| where device.entity == "APAC"
+| list device.name
+| sort device.name asc`, qb.Build())
+
+	qb.RemoveWhere("operating_system.platform  ==  windows")
+	assert.Equal(t, []string{`device.entity == "APAC"`}, qb.WhereClauses())
+
+	qb.RemoveWhereAt(5).RemoveWhereAt(0)
+	assert.Empty(t, qb.WhereClauses())
+}
+
+func TestQueryBuilder_CloneIsIndependent(t *testing.T) {
+	original := NewQueryBuilder().
+		FromDevices().
+		DuringPast(7, Days).
+		Where("a == 1").
+		List("device.name").
+		SortDesc("device.name").
+		Limit(10)
+
+	clone := original.Clone()
+	clone.Where("b == 2").List("device.uid").ClearSort().ClearTimeSelection()
+
+	assert.Equal(t, []string{"a == 1"}, original.WhereClauses())
+	assert.Equal(t, []string{"device.name"}, original.ListFields())
+	assert.Equal(t, "during past 7d", original.TimeSelection())
+	field, _ := original.SortBy()
+	assert.Equal(t, "device.name", field)
+
+	assert.Equal(t, "devices\n| where a == 1\n| where b == 2\n| list device.name, device.uid\n| limit 10", clone.Build())
+
+	// Accessors return copies
+	original.WhereClauses()[0] = "changed"
+	assert.Equal(t, []string{"a == 1"}, original.WhereClauses())
+}
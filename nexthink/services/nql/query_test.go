@@ -0,0 +1,149 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const queryTestExportJSON = `[
+{"device.name":"Device1","device.os":"Windows 10","number_of_crashes":1},
+{"device.name":"Device2","device.os":"macOS","number_of_crashes":0},
+{"device.name":"Device3","device.os":"Windows 11","number_of_crashes":4}
+]`
+
+// queryTestOptions returns query options that treat 2 rows as truncated
+func queryTestOptions() *QueryOptions {
+	opts := DefaultQueryOptions()
+	opts.MaxRows = 2
+	opts.Export = DefaultExportOptions().WithFormat(ExportFormatJSON)
+	return opts
+}
+
+func TestQuery_NotTruncated(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	mocks.NewNQLMock(baseURL).RegisterExecuteNQLV2Mock()
+
+	result, err := service.Query(context.Background(), &ExecuteRequest{QueryID: "#test_query"})
+
+	require.NoError(t, err)
+	assert.Equal(t, QuerySourceExecute, result.Source)
+	assert.False(t, result.Truncated)
+	assert.Nil(t, result.Export)
+	assert.Equal(t, 2, result.Rows())
+}
+
+func TestQuery_FallsBackToExport(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	nqlMock := mocks.NewNQLMock(baseURL)
+	nqlMock.RegisterExecuteNQLV2Mock()
+	server := newDownloadServer(t, []byte(queryTestExportJSON), nil)
+	nqlMock.RegisterCompletedExportMock("export-query", server.URL+"/export.json")
+
+	result, err := service.QueryWithOptions(context.Background(), &ExecuteRequest{QueryID: "#test_query"}, queryTestOptions())
+
+	require.NoError(t, err)
+	assert.Equal(t, QuerySourceExport, result.Source)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "export-query", result.Export.ExportID)
+	assert.Equal(t, "select device.name, device.os from devices", result.ExecutedQuery)
+	require.Equal(t, 3, result.Rows())
+
+	name, err := result.GetString(2, "device.name")
+	require.NoError(t, err)
+	assert.Equal(t, "Device3", name)
+
+	crashes, err := result.GetInt(2, "number_of_crashes")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), crashes)
+}
+
+func TestQuery_ExportMatchesExecuteTypes(t *testing.T) {
+	const exportJSON = `[{"device.name":"Device1","number_of_crashes":4,"device.last_seen":"2024-02-08T10:15:30Z","execution_duration":12.5,"hardware.memory":17179869184,"is_virtual":false,"tag":null}]`
+	const exportCSV = "device.name,number_of_crashes,device.last_seen,execution_duration,hardware.memory,is_virtual,tag\n" +
+		"Device1,4,2024-02-08T10:15:30Z,12.5,17179869184,false,\n"
+
+	// The execute endpoint returns the rows as decoded JSON
+	var want []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(exportJSON), &want))
+
+	for format, body := range map[string]string{ExportFormatJSON: exportJSON, ExportFormatCSV: exportCSV} {
+		t.Run(format, func(t *testing.T) {
+			service, baseURL := setupMockClient(t)
+			server := newDownloadServer(t, []byte(body), nil)
+			mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-types", server.URL+"/export."+format)
+
+			opts := queryTestOptions()
+			opts.AlwaysExport = true
+			opts.Export.Format = format
+
+			result, err := service.QueryWithOptions(context.Background(), &ExecuteRequest{QueryID: "#test_query"}, opts)
+			require.NoError(t, err)
+
+			row, err := result.GetRow(0)
+			require.NoError(t, err)
+			assert.Equal(t, want[0], row)
+		})
+	}
+}
+
+func TestQuery_AlwaysExportCSV(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	server := newDownloadServer(t, []byte(downloadTestCSV), nil)
+	mocks.NewNQLMock(baseURL).RegisterCompletedExportMock("export-csv", server.URL+"/export.csv")
+
+	opts := queryTestOptions()
+	opts.AlwaysExport = true
+	opts.Export.Format = ExportFormatCSV
+
+	result, err := service.QueryWithOptions(context.Background(), &ExecuteRequest{QueryID: "#test_query"}, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, QuerySourceExport, result.Source)
+	assert.False(t, result.Truncated)
+	require.Equal(t, 2, result.Rows())
+	platform, err := result.GetString(1, "operating_system.platform")
+	require.NoError(t, err)
+	assert.Equal(t, "macOS", platform)
+}
+
+func TestQuery_ExportFailure(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	nqlMock := mocks.NewNQLMock(baseURL)
+	nqlMock.RegisterExecuteNQLV2Mock()
+	server := newS3Server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	nqlMock.RegisterCompletedExportMock("export-denied", server.URL+"/export.json")
+
+	_, err := service.QueryWithOptions(context.Background(), &ExecuteRequest{QueryID: "#test_query"}, queryTestOptions())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to export query")
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestQuery_InvalidExportData(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	nqlMock := mocks.NewNQLMock(baseURL)
+	nqlMock.RegisterExecuteNQLV2Mock()
+	server := newDownloadServer(t, []byte(`[{"device.name": "Device1"}, not json`), nil)
+	nqlMock.RegisterCompletedExportMock("export-invalid", server.URL+"/export.json")
+
+	_, err := service.QueryWithOptions(context.Background(), &ExecuteRequest{QueryID: "#test_query"}, queryTestOptions())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read export")
+}
+
+func TestQuery_Validation(t *testing.T) {
+	service, _ := setupMockClient(t)
+
+	_, err := service.Query(context.Background(), &ExecuteRequest{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,420 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+)
+
+// Query contract registry
+// Services declare the saved queries they depend on together with the columns
+// and types they read. Verify executes each query and reports missing queries,
+// missing columns and type drift, so renames in the admin console fail fast at
+// startup or in a readiness probe instead of silently at runtime.
+
+// ErrContractViolation is returned by Verify when one or more contracts fail
+var ErrContractViolation = errors.New("nql query contract violation")
+
+// =============================================================================
+// Contracts
+// =============================================================================
+
+// QueryContract describes what a service expects from a saved query
+type QueryContract struct {
+	// QueryID is the saved query identifier (#query_name)
+	QueryID string
+
+	// Platform and Parameters are sent with the verification request
+	// Parameters can narrow the query so verification stays cheap.
+	Platform   string
+	Parameters map[string]string
+
+	// Columns maps each required column to its expected type
+	// An empty type only checks that the column is present.
+	Columns map[string]ColumnType
+
+	// AllowEmpty accepts an empty result instead of reporting IssueNoRows
+	// Columns cannot be checked when no rows are returned.
+	AllowEmpty bool
+
+	// Description documents why the query is needed
+	Description string
+}
+
+// Registry holds the query contracts of a service
+type Registry struct {
+	mu        sync.RWMutex
+	contracts []QueryContract
+}
+
+// NewRegistry creates an empty query contract registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a contract to the registry
+// Returns an error for an invalid query ID or one that is already registered.
+func (r *Registry) Register(contract QueryContract) error {
+	if err := validateQueryID(contract.QueryID); err != nil {
+		return err
+	}
+	for column, t := range contract.Columns {
+		if column == "" {
+			return fmt.Errorf("contract %s: column name cannot be empty", contract.QueryID)
+		}
+		if t != "" && !isKnownColumnType(t) {
+			return fmt.Errorf("contract %s: column %s has unknown type %q", contract.QueryID, column, t)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.contracts {
+		if existing.QueryID == contract.QueryID && existing.Platform == contract.Platform {
+			return fmt.Errorf("contract for query %s is already registered", contract.QueryID)
+		}
+	}
+	r.contracts = append(r.contracts, contract)
+	return nil
+}
+
+// MustRegister adds a contract and panics on error
+// Intended for package-level registration of static contracts.
+func (r *Registry) MustRegister(contract QueryContract) *Registry {
+	if err := r.Register(contract); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Contracts returns the registered contracts in registration order
+func (r *Registry) Contracts() []QueryContract {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.contracts)
+}
+
+// isKnownColumnType reports whether t is one of the ColumnType constants
+func isKnownColumnType(t ColumnType) bool {
+	switch t {
+	case ColumnTypeString, ColumnTypeInt, ColumnTypeFloat, ColumnTypeBool,
+		ColumnTypeDateTime, ColumnTypeDuration, ColumnTypeBytes:
+		return true
+	default:
+		return false
+	}
+}
+
+// =============================================================================
+// Verification Report
+// =============================================================================
+
+// ContractIssueKind classifies a contract violation
+type ContractIssueKind string
+
+const (
+	IssueQueryMissing  ContractIssueKind = "query_missing"
+	IssueQueryFailed   ContractIssueKind = "query_failed"
+	IssueNoRows        ContractIssueKind = "no_rows"
+	IssueColumnMissing ContractIssueKind = "column_missing"
+	IssueTypeMismatch  ContractIssueKind = "type_mismatch"
+)
+
+// ContractIssue is a single contract violation
+type ContractIssue struct {
+	QueryID  string            `json:"query_id"`
+	Kind     ContractIssueKind `json:"kind"`
+	Column   string            `json:"column,omitempty"`
+	Expected ColumnType        `json:"expected,omitempty"`
+	Observed ColumnType        `json:"observed,omitempty"`
+	Message  string            `json:"message"`
+	Err      error             `json:"-"`
+}
+
+// String returns a one-line description of the issue
+func (i ContractIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.QueryID, i.Message)
+}
+
+// ContractResult is the verification outcome for one contract
+type ContractResult struct {
+	QueryID  string          `json:"query_id"`
+	Platform string          `json:"platform,omitempty"`
+	Rows     int             `json:"rows"`
+	Duration time.Duration   `json:"duration"`
+	Issues   []ContractIssue `json:"issues,omitempty"`
+}
+
+// OK reports whether the contract holds
+func (r ContractResult) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// VerifyReport is the outcome of verifying every contract in a registry
+type VerifyReport struct {
+	CheckedAt time.Time        `json:"checked_at"`
+	Results   []ContractResult `json:"results"`
+}
+
+// OK reports whether every contract holds
+func (r *VerifyReport) OK() bool {
+	for _, result := range r.Results {
+		if !result.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Issues returns all issues across contracts
+func (r *VerifyReport) Issues() []ContractIssue {
+	var issues []ContractIssue
+	for _, result := range r.Results {
+		issues = append(issues, result.Issues...)
+	}
+	return issues
+}
+
+// Err returns nil when every contract holds, otherwise an error wrapping
+// ErrContractViolation that lists the issues
+func (r *VerifyReport) Err() error {
+	issues := r.Issues()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Errorf("%w: %d issue(s): %s", ErrContractViolation, len(issues), strings.Join(lines, "; "))
+}
+
+// =============================================================================
+// Verification
+// =============================================================================
+
+// ContractExecutor executes the queries a registry verifies
+// *Service implements ContractExecutor.
+type ContractExecutor interface {
+	ExecuteNQLV2(ctx context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error)
+}
+
+// Verify executes every registered query and checks it against its contract
+//
+// The report is always returned. The error is nil when every contract holds,
+// wraps ErrContractViolation when any contract fails, or is the context error
+// when ctx is cancelled. Queries run sequentially in registration order and
+// through ExecuteNQLV2, so an enabled execute cache also serves verification.
+func (r *Registry) Verify(ctx context.Context, svc ContractExecutor) (*VerifyReport, error) {
+	contracts := r.Contracts()
+	report := &VerifyReport{
+		CheckedAt: time.Now(),
+		Results:   make([]ContractResult, 0, len(contracts)),
+	}
+
+	for _, contract := range contracts {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, verifyContract(ctx, svc, contract))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	return report, report.Err()
+}
+
+// Check verifies the registry and returns only the error
+// Suitable as a readiness probe callback.
+func (r *Registry) Check(ctx context.Context, svc ContractExecutor) error {
+	_, err := r.Verify(ctx, svc)
+	return err
+}
+
+// verifyContract executes one query and compares its result with the contract
+func verifyContract(ctx context.Context, svc ContractExecutor, contract QueryContract) ContractResult {
+	result := ContractResult{QueryID: contract.QueryID, Platform: contract.Platform}
+	addIssue := func(issue ContractIssue) {
+		issue.QueryID = contract.QueryID
+		result.Issues = append(result.Issues, issue)
+	}
+
+	start := time.Now()
+	resp, _, err := svc.ExecuteNQLV2(ctx, &ExecuteRequest{
+		QueryID:    contract.QueryID,
+		Platform:   contract.Platform,
+		Parameters: contract.Parameters,
+	})
+	result.Duration = time.Since(start)
+
+	var statusErr interfaces.StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.HTTPStatus() == http.StatusNotFound:
+		addIssue(ContractIssue{Kind: IssueQueryMissing, Message: "query does not exist", Err: err})
+		return result
+	case err != nil:
+		addIssue(ContractIssue{Kind: IssueQueryFailed, Message: fmt.Sprintf("query failed: %v", err), Err: err})
+		return result
+	}
+
+	result.Rows = len(resp.Data)
+	if result.Rows == 0 {
+		if !contract.AllowEmpty && len(contract.Columns) > 0 {
+			addIssue(ContractIssue{Kind: IssueNoRows, Message: "query returned no rows, columns cannot be verified"})
+		}
+		return result
+	}
+
+	columns := make([]string, 0, len(contract.Columns))
+	for column := range contract.Columns {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	for _, column := range columns {
+		expected := contract.Columns[column]
+		present, observed := observeColumn(resp.Data, column)
+		switch {
+		case !present:
+			addIssue(ContractIssue{
+				Kind:     IssueColumnMissing,
+				Column:   column,
+				Expected: expected,
+				Message:  fmt.Sprintf("column %s is missing", column),
+			})
+		case expected != "" && observed != "" && !columnTypeCompatible(expected, observed):
+			addIssue(ContractIssue{
+				Kind:     IssueTypeMismatch,
+				Column:   column,
+				Expected: expected,
+				Observed: observed,
+				Message:  fmt.Sprintf("column %s is %s, expected %s", column, observed, expected),
+			})
+		}
+	}
+
+	return result
+}
+
+// observeColumn reports whether any row has the column and the type of its
+// values. Integers widen to float when the column mixes both, and numbers
+// mixed with formatted durations or byte sizes keep the formatted type; any
+// other mix is reported as string.
+func observeColumn(rows []map[string]any, column string) (bool, ColumnType) {
+	present := false
+	var observed ColumnType
+	for _, row := range rows {
+		val, ok := row[column]
+		if !ok {
+			continue
+		}
+		present = true
+
+		t := observedValueType(val)
+		switch {
+		case t == "" || t == observed:
+		case observed == "":
+			observed = t
+		case isNumericColumnType(observed) && isNumericColumnType(t):
+			observed = ColumnTypeFloat
+		case isNumericColumnType(observed) && isFormattedColumnType(t):
+			observed = t
+		case isFormattedColumnType(observed) && isNumericColumnType(t):
+		default:
+			return true, ColumnTypeString
+		}
+	}
+	return present, observed
+}
+
+// observedValueType returns the column type of a decoded JSON value, or "" for null
+func observedValueType(val any) ColumnType {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case bool:
+		return ColumnTypeBool
+	case int, int32, int64:
+		return ColumnTypeInt
+	case float64:
+		if v == math.Trunc(v) {
+			return ColumnTypeInt
+		}
+		return ColumnTypeFloat
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return ColumnTypeInt
+		}
+		return ColumnTypeFloat
+	case map[string]any:
+		if _, err := dateTimeFromMap(v); err == nil {
+			return ColumnTypeDateTime
+		}
+		return ColumnTypeString
+	case string:
+		if v == "" {
+			return ""
+		}
+		if _, err := parseTimeValue(v, time.UTC); err == nil {
+			return ColumnTypeDateTime
+		}
+		if _, ok := parseFormattedDuration(v); ok {
+			return ColumnTypeDuration
+		}
+		if isFormattedByteSize(v) {
+			return ColumnTypeBytes
+		}
+		return ColumnTypeString
+	default:
+		return ColumnTypeString
+	}
+}
+
+// isNumericColumnType reports whether t is an int or float column
+func isNumericColumnType(t ColumnType) bool {
+	return t == ColumnTypeInt || t == ColumnTypeFloat
+}
+
+// isFormattedColumnType reports whether t is a duration or byte size column
+func isFormattedColumnType(t ColumnType) bool {
+	return t == ColumnTypeDuration || t == ColumnTypeBytes
+}
+
+// isFormattedByteSize reports whether s is a number followed by a byte size unit
+func isFormattedByteSize(s string) bool {
+	match := byteSizePattern.FindStringSubmatch(s)
+	return match != nil && match[2] != ""
+}
+
+// columnTypeCompatible reports whether values observed as observed satisfy expected
+// Durations and byte sizes arrive as numbers or formatted strings, an int
+// column satisfies a float contract, and datetimes, durations and byte sizes
+// satisfy a string contract since they are strings on the wire.
+func columnTypeCompatible(expected, observed ColumnType) bool {
+	switch expected {
+	case ColumnTypeFloat:
+		return isNumericColumnType(observed)
+	case ColumnTypeDuration, ColumnTypeBytes:
+		return observed == expected || isNumericColumnType(observed)
+	case ColumnTypeString:
+		switch observed {
+		case ColumnTypeString, ColumnTypeDateTime, ColumnTypeDuration, ColumnTypeBytes:
+			return true
+		}
+		return false
+	default:
+		return expected == observed
+	}
+}
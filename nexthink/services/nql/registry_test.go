@@ -0,0 +1,204 @@
+package nql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/client"
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/interfaces"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerContractMock serves rows per query ID and 404 for unknown queries
+func registerContractMock(baseURL string, rows map[string][]map[string]any) {
+	httpmock.RegisterResponder("POST", baseURL+"/api/v2/nql/execute",
+		func(req *http.Request) (*http.Response, error) {
+			var body ExecuteRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return httpmock.NewStringResponse(400, "bad request"), nil
+			}
+			data, ok := rows[body.QueryID]
+			if !ok {
+				return httpmock.NewJsonResponse(404, map[string]any{"message": "query not found"})
+			}
+			return httpmock.NewJsonResponse(200, map[string]any{
+				"queryId": body.QueryID,
+				"rows":    len(data),
+				"data":    data,
+			})
+		})
+}
+
+func TestRegistry_Register(t *testing.T) {
+	reg := NewRegistry()
+
+	require.NoError(t, reg.Register(QueryContract{QueryID: "#devices", Columns: map[string]ColumnType{"device.name": ColumnTypeString}}))
+	require.NoError(t, reg.Register(QueryContract{QueryID: "#devices", Platform: "macos"}))
+
+	assert.Error(t, reg.Register(QueryContract{QueryID: "#devices"}), "duplicate")
+	assert.Error(t, reg.Register(QueryContract{QueryID: "devices"}), "missing #")
+	assert.Error(t, reg.Register(QueryContract{QueryID: "#bad", Columns: map[string]ColumnType{"x": "uuid"}}))
+	assert.Error(t, reg.Register(QueryContract{QueryID: "#bad", Columns: map[string]ColumnType{"": ColumnTypeInt}}))
+	assert.Panics(t, func() { reg.MustRegister(QueryContract{QueryID: "#devices"}) })
+
+	assert.Len(t, reg.Contracts(), 2)
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	service, baseURL := setupMockClient(t, client.WithRetryCount(0))
+	registerContractMock(baseURL, map[string][]map[string]any{
+		"#devices_with_crashes": {
+			{"device.name": "LAPTOP-1", "crash_count": 3, "last_seen": "2024-02-08T10:00:00Z", "ratio": 0.5},
+			{"device.name": "LAPTOP-2", "crash_count": 1, "last_seen": "2024-02-08T11:00:00Z", "ratio": 1},
+		},
+		"#renamed_columns": {
+			{"name": "LAPTOP-1", "crash_count": "three"},
+		},
+		"#empty": {},
+	})
+
+	reg := NewRegistry().
+		MustRegister(QueryContract{
+			QueryID: "#devices_with_crashes",
+			Columns: map[string]ColumnType{
+				"device.name": ColumnTypeString,
+				"crash_count": ColumnTypeInt,
+				"last_seen":   ColumnTypeDateTime,
+				"ratio":       ColumnTypeFloat,
+			},
+		}).
+		MustRegister(QueryContract{
+			QueryID: "#renamed_columns",
+			Columns: map[string]ColumnType{
+				"device.name": "",
+				"crash_count": ColumnTypeInt,
+			},
+		}).
+		MustRegister(QueryContract{QueryID: "#deleted_query"}).
+		MustRegister(QueryContract{QueryID: "#empty", Columns: map[string]ColumnType{"a": ColumnTypeInt}}).
+		MustRegister(QueryContract{QueryID: "#empty", Platform: "macos", AllowEmpty: true, Columns: map[string]ColumnType{"a": ColumnTypeInt}})
+
+	report, err := reg.Verify(context.Background(), service)
+	require.ErrorIs(t, err, ErrContractViolation)
+	require.Len(t, report.Results, 5)
+	assert.False(t, report.OK())
+
+	assert.True(t, report.Results[0].OK(), report.Results[0].Issues)
+	assert.Equal(t, 2, report.Results[0].Rows)
+
+	issues := report.Results[1].Issues
+	require.Len(t, issues, 2)
+	assert.Equal(t, IssueTypeMismatch, issues[0].Kind)
+	assert.Equal(t, "crash_count", issues[0].Column)
+	assert.Equal(t, ColumnTypeString, issues[0].Observed)
+	assert.Equal(t, IssueColumnMissing, issues[1].Kind)
+	assert.Equal(t, "device.name", issues[1].Column)
+
+	require.Len(t, report.Results[2].Issues, 1)
+	assert.Equal(t, IssueQueryMissing, report.Results[2].Issues[0].Kind)
+
+	require.Len(t, report.Results[3].Issues, 1)
+	assert.Equal(t, IssueNoRows, report.Results[3].Issues[0].Kind)
+	assert.True(t, report.Results[4].OK())
+
+	assert.Len(t, report.Issues(), 4)
+	assert.Contains(t, err.Error(), "#deleted_query: query does not exist")
+}
+
+func TestRegistry_Check(t *testing.T) {
+	service, baseURL := setupMockClient(t)
+	registerContractMock(baseURL, map[string][]map[string]any{
+		"#devices": {{"device.name": "LAPTOP-1", "crash_count": 2}},
+	})
+
+	reg := NewRegistry().MustRegister(QueryContract{
+		QueryID: "#devices",
+		Columns: map[string]ColumnType{"crash_count": ColumnTypeFloat, "device.name": ColumnTypeString},
+	})
+	assert.NoError(t, reg.Check(context.Background(), service))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, reg.Check(ctx, service), context.Canceled)
+}
+
+// stubExecutor returns fixed rows for every query
+type stubExecutor struct {
+	rows []map[string]any
+}
+
+func (s stubExecutor) ExecuteNQLV2(_ context.Context, req *ExecuteRequest) (*ExecuteNQLV2Response, *interfaces.Response, error) {
+	return &ExecuteNQLV2Response{QueryID: req.QueryID, Rows: int64(len(s.rows)), Data: s.rows}, nil, nil
+}
+
+func TestRegistry_CheckWithStubExecutor(t *testing.T) {
+	reg := NewRegistry().MustRegister(QueryContract{
+		QueryID: "#devices",
+		Columns: map[string]ColumnType{"device.name": ColumnTypeString},
+	})
+
+	assert.NoError(t, reg.Check(context.Background(), stubExecutor{rows: []map[string]any{{"device.name": "LAPTOP-1"}}}))
+	assert.ErrorIs(t, reg.Check(context.Background(), stubExecutor{}), ErrContractViolation)
+}
+
+func TestRegistry_VerifyFormattedValues(t *testing.T) {
+	reg := NewRegistry().MustRegister(QueryContract{
+		QueryID: "#boot_times",
+		Columns: map[string]ColumnType{
+			"device.name":    ColumnTypeString,
+			"boot_duration":  ColumnTypeDuration,
+			"memory":         ColumnTypeBytes,
+			"plain_duration": ColumnTypeDuration,
+			"label":          ColumnTypeString,
+		},
+	})
+
+	report, err := reg.Verify(context.Background(), stubExecutor{rows: []map[string]any{
+		{"device.name": "LAPTOP-1", "boot_duration": "12.3 s", "memory": "512 MB", "plain_duration": float64(12), "label": "1 h"},
+		{"device.name": "LAPTOP-2", "boot_duration": "2 min 5 s", "memory": "16 GB", "plain_duration": 1.5, "label": "2 GB"},
+	}})
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.Issues())
+
+	reg = NewRegistry().MustRegister(QueryContract{
+		QueryID: "#boot_times",
+		Columns: map[string]ColumnType{"boot_duration": ColumnTypeBytes, "memory": ColumnTypeDuration},
+	})
+	report, err = reg.Verify(context.Background(), stubExecutor{rows: []map[string]any{
+		{"boot_duration": "12.3 s", "memory": "512 MB"},
+	}})
+	require.ErrorIs(t, err, ErrContractViolation)
+	assert.Len(t, report.Issues(), 2)
+}
+
+func TestObserveColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []map[string]any
+		present  bool
+		observed ColumnType
+	}{
+		{"missing", []map[string]any{{"b": 1}}, false, ""},
+		{"all null", []map[string]any{{"a": nil}}, true, ""},
+		{"int", []map[string]any{{"a": float64(1)}, {"a": nil}}, true, ColumnTypeInt},
+		{"int and float widen", []map[string]any{{"a": float64(1)}, {"a": 1.5}}, true, ColumnTypeFloat},
+		{"datetime object", []map[string]any{{"a": map[string]any{"year": float64(2024), "month": float64(2), "day": float64(8)}}}, true, ColumnTypeDateTime},
+		{"mixed", []map[string]any{{"a": true}, {"a": "yes"}}, true, ColumnTypeString},
+		{"formatted duration", []map[string]any{{"a": "12.3 s"}, {"a": "2 h 5 min"}}, true, ColumnTypeDuration},
+		{"formatted byte size", []map[string]any{{"a": "512 MB"}, {"a": "1.5 GB"}}, true, ColumnTypeBytes},
+		{"numbers and byte sizes", []map[string]any{{"a": float64(0)}, {"a": "512 MB"}}, true, ColumnTypeBytes},
+		{"duration and text", []map[string]any{{"a": "12.3 s"}, {"a": "slow"}}, true, ColumnTypeString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			present, observed := observeColumn(tt.rows, "a")
+			assert.Equal(t, tt.present, present)
+			assert.Equal(t, tt.observed, observed)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package nql
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -161,6 +163,81 @@ func (rs *V1ResultSet) GetBool(row, col int) (bool, error) {
 	return b, nil
 }
 
+// GetDuration retrieves a duration value
+// Accepts formatted strings ("12.3 s", "150 ms", "2 h 5 min") and plain numbers in seconds
+func (rs *V1ResultSet) GetDuration(row, col int) (time.Duration, error) {
+	val, err := rs.Get(row, col)
+	if err != nil {
+		return 0, err
+	}
+	
+	d, err := parseDurationValue(val, time.Second)
+	if err != nil {
+		return 0, fmt.Errorf("value at [%d][%d]: %w", row, col, err)
+	}
+	
+	return d, nil
+}
+
+// GetBytes retrieves a byte size value in bytes
+// Accepts formatted strings ("1.5 GB", "512 KB") and plain numbers
+func (rs *V1ResultSet) GetBytes(row, col int) (int64, error) {
+	val, err := rs.Get(row, col)
+	if err != nil {
+		return 0, err
+	}
+	
+	n, err := parseByteSizeValue(val)
+	if err != nil {
+		return 0, fmt.Errorf("value at [%d][%d]: %w", row, col, err)
+	}
+	
+	return n, nil
+}
+
+// GetTime retrieves a datetime value in UTC
+// Accepts DateTime objects, ISO 8601 strings and Unix timestamps; nil returns the zero time
+func (rs *V1ResultSet) GetTime(row, col int) (time.Time, error) {
+	return rs.GetTimeIn(row, col, time.UTC)
+}
+
+// GetTimeIn retrieves a datetime value, interpreting values without a zone in loc
+func (rs *V1ResultSet) GetTimeIn(row, col int, loc *time.Location) (time.Time, error) {
+	val, err := rs.Get(row, col)
+	if err != nil {
+		return time.Time{}, err
+	}
+	
+	t, err := parseTimeValue(val, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("value at [%d][%d]: %w", row, col, err)
+	}
+	
+	return t, nil
+}
+
+// GetEnum retrieves a string value that must be one of the allowed values
+func (rs *V1ResultSet) GetEnum(row, col int, allowed ...string) (string, error) {
+	val, err := rs.Get(row, col)
+	if err != nil {
+		return "", err
+	}
+	
+	str, err := checkEnumValue(val, allowed)
+	if err != nil {
+		return "", fmt.Errorf("value at [%d][%d]: %w", row, col, err)
+	}
+	
+	return str, nil
+}
+
+// ColumnTypes returns the type of each column
+// Types come from the executed query's list, compute and summarize statements
+// where possible (see InferQueryColumnTypes) and from the column name otherwise.
+func (rs *V1ResultSet) ColumnTypes() map[string]ColumnType {
+	return columnTypes(rs.ExecutedQuery, rs.Headers)
+}
+
 // GetRow retrieves an entire row
 func (rs *V1ResultSet) GetRow(row int) ([]any, error) {
 	if row < 0 || row >= rs.Rows() {
@@ -225,6 +302,28 @@ func (rs *V1ResultSet) GetByColumnName(row int, columnName string) (any, error)
 	return rs.Get(row, colIdx)
 }
 
+// ColumnNames returns the column names in header order
+func (rs *V1ResultSet) ColumnNames() []string {
+	return rs.Headers
+}
+
+// RowMap retrieves a row as a map keyed by column name
+func (rs *V1ResultSet) RowMap(row int) (map[string]any, error) {
+	values, err := rs.GetRow(row)
+	if err != nil {
+		return nil, err
+	}
+	
+	rowMap := make(map[string]any, len(rs.Headers))
+	for i, header := range rs.Headers {
+		if i < len(values) {
+			rowMap[header] = values[i]
+		}
+	}
+	
+	return rowMap, nil
+}
+
 // =============================================================================
 // V2 Result Set
 // =============================================================================
@@ -367,6 +466,82 @@ func (rs *V2ResultSet) GetBool(row int, field string) (bool, error) {
 	return b, nil
 }
 
+// GetDuration retrieves a duration value
+// Accepts formatted strings ("12.3 s", "150 ms", "2 h 5 min") and plain numbers in seconds
+func (rs *V2ResultSet) GetDuration(row int, field string) (time.Duration, error) {
+	val, err := rs.Get(row, field)
+	if err != nil {
+		return 0, err
+	}
+	
+	d, err := parseDurationValue(val, time.Second)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d: %w", field, row, err)
+	}
+	
+	return d, nil
+}
+
+// GetBytes retrieves a byte size value in bytes
+// Accepts formatted strings ("1.5 GB", "512 KB") and plain numbers
+func (rs *V2ResultSet) GetBytes(row int, field string) (int64, error) {
+	val, err := rs.Get(row, field)
+	if err != nil {
+		return 0, err
+	}
+	
+	n, err := parseByteSizeValue(val)
+	if err != nil {
+		return 0, fmt.Errorf("field '%s' at row %d: %w", field, row, err)
+	}
+	
+	return n, nil
+}
+
+// GetTime retrieves a datetime value in UTC
+// Accepts ISO 8601 strings, DateTime objects and Unix timestamps; nil returns the zero time
+func (rs *V2ResultSet) GetTime(row int, field string) (time.Time, error) {
+	return rs.GetTimeIn(row, field, time.UTC)
+}
+
+// GetTimeIn retrieves a datetime value, interpreting values without a zone in loc
+func (rs *V2ResultSet) GetTimeIn(row int, field string, loc *time.Location) (time.Time, error) {
+	val, err := rs.Get(row, field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	
+	t, err := parseTimeValue(val, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("field '%s' at row %d: %w", field, row, err)
+	}
+	
+	return t, nil
+}
+
+// GetEnum retrieves a string value that must be one of the allowed values
+// Example: platform, err := rs.GetEnum(0, "operating_system.platform", nql.PlatformWindows, nql.PlatformMacOS, nql.PlatformLinux)
+func (rs *V2ResultSet) GetEnum(row int, field string, allowed ...string) (string, error) {
+	val, err := rs.Get(row, field)
+	if err != nil {
+		return "", err
+	}
+	
+	str, err := checkEnumValue(val, allowed)
+	if err != nil {
+		return "", fmt.Errorf("field '%s' at row %d: %w", field, row, err)
+	}
+	
+	return str, nil
+}
+
+// ColumnTypes returns the type of each column
+// Types come from the executed query's list, compute and summarize statements
+// where possible (see InferQueryColumnTypes) and from the column name otherwise.
+func (rs *V2ResultSet) ColumnTypes() map[string]ColumnType {
+	return columnTypes(rs.ExecutedQuery, rs.ColumnNames())
+}
+
 // GetRow retrieves an entire row
 func (rs *V2ResultSet) GetRow(row int) (map[string]any, error) {
 	if row < 0 || row >= rs.Rows() {
@@ -390,6 +565,30 @@ func (rs *V2ResultSet) Fields() []string {
 	return fields
 }
 
+// ColumnNames returns the sorted union of field names across all rows
+// Unlike Fields, the result is stable and includes fields missing from the first row
+func (rs *V2ResultSet) ColumnNames() []string {
+	seen := make(map[string]bool)
+	for _, row := range rs.data {
+		for field := range row {
+			seen[field] = true
+		}
+	}
+	
+	names := make([]string, 0, len(seen))
+	for field := range seen {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	
+	return names
+}
+
+// RowMap retrieves a row as a map keyed by field name (alias for GetRow)
+func (rs *V2ResultSet) RowMap(row int) (map[string]any, error) {
+	return rs.GetRow(row)
+}
+
 // HasField checks if a field exists in the result set
 func (rs *V2ResultSet) HasField(field string) bool {
 	if rs.Rows() == 0 {
@@ -493,3 +692,36 @@ func ConvertV1ToV2(v1 *V1ResultSet) *V2ResultSet {
 		data:              v2Data,
 	}
 }
+
+// checkEnumValue converts a value to a string and checks it against the allowed values
+// An empty allowed list accepts any value
+func checkEnumValue(val any, allowed []string) (string, error) {
+	str := coerceString(val)
+	if len(allowed) == 0 {
+		return str, nil
+	}
+	
+	for _, a := range allowed {
+		if str == a {
+			return str, nil
+		}
+	}
+	
+	return "", fmt.Errorf("value %q is not one of %s", str, strings.Join(allowed, ", "))
+}
+
+// columnTypes resolves the type of each column from the executed query and the column names
+func columnTypes(executedQuery string, columns []string) map[string]ColumnType {
+	queryTypes := InferQueryColumnTypes(executedQuery)
+	
+	types := make(map[string]ColumnType, len(columns))
+	for _, column := range columns {
+		if t, ok := queryTypes[column]; ok {
+			types[column] = t
+		} else {
+			types[column] = InferColumnType(column)
+		}
+	}
+	
+	return types
+}
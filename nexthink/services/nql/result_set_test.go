@@ -592,3 +592,121 @@ func TestV1ResultSet_IterateRows(t *testing.T) {
 		t.Errorf("Expected 2 iterations, got %d", count)
 	}
 }
+
+func TestV2ResultSet_TypedValues(t *testing.T) {
+	resultSet := NewV2ResultSet(&ExecuteNQLV2Response{
+		Data: []map[string]any{
+			{
+				"boot.duration":             "12.3 s",
+				"device.memory":             "1.5 GB",
+				"device.last_seen":          "2024-02-08T10:15:30Z",
+				"operating_system.platform": "Windows",
+				"uptime":                    float64(90),
+			},
+		},
+	})
+
+	d, err := resultSet.GetDuration(0, "boot.duration")
+	if err != nil || d != 12300*time.Millisecond {
+		t.Errorf("Expected 12.3s, got %v (err: %v)", d, err)
+	}
+
+	d, err = resultSet.GetDuration(0, "uptime")
+	if err != nil || d != 90*time.Second {
+		t.Errorf("Expected 90s, got %v (err: %v)", d, err)
+	}
+
+	b, err := resultSet.GetBytes(0, "device.memory")
+	if err != nil || b != 3*(1<<29) {
+		t.Errorf("Expected 1.5 GiB, got %d (err: %v)", b, err)
+	}
+
+	ts, err := resultSet.GetTime(0, "device.last_seen")
+	if err != nil || !ts.Equal(time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v (err: %v)", ts, err)
+	}
+
+	platform, err := resultSet.GetEnum(0, "operating_system.platform", PlatformWindows, PlatformMacOS)
+	if err != nil || platform != PlatformWindows {
+		t.Errorf("Expected Windows, got %q (err: %v)", platform, err)
+	}
+
+	if _, err := resultSet.GetEnum(0, "operating_system.platform", PlatformLinux); err == nil {
+		t.Error("Expected error for value outside the enum")
+	}
+
+	if _, err := resultSet.GetDuration(0, "operating_system.platform"); err == nil {
+		t.Error("Expected error for non-duration value")
+	}
+}
+
+func TestV1ResultSet_TypedValues(t *testing.T) {
+	resultSet := NewV1ResultSet(&ExecuteNQLV1Response{
+		ExecutedQuery: "devices | list device.name, device.last_seen, boot.duration, device.memory",
+		Headers:       []string{"device.name", "device.last_seen", "boot.duration", "device.memory"},
+		Data: [][]any{
+			{"device-01", map[string]any{"year": float64(2024), "month": float64(2), "day": float64(8), "hour": float64(10)}, "150 ms", "512 KB"},
+		},
+	})
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	ts, err := resultSet.GetTimeIn(0, 1, paris)
+	if err != nil || !ts.Equal(time.Date(2024, 2, 8, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v (err: %v)", ts, err)
+	}
+
+	d, err := resultSet.GetDuration(0, 2)
+	if err != nil || d != 150*time.Millisecond {
+		t.Errorf("Expected 150ms, got %v (err: %v)", d, err)
+	}
+
+	b, err := resultSet.GetBytes(0, 3)
+	if err != nil || b != 512*1024 {
+		t.Errorf("Expected 512 KiB, got %d (err: %v)", b, err)
+	}
+
+	types := resultSet.ColumnTypes()
+	if types["device.last_seen"] != ColumnTypeDateTime || types["boot.duration"] != ColumnTypeDuration {
+		t.Errorf("Unexpected column types: %v", types)
+	}
+}
+
+func TestV2ResultSet_ColumnTypesFromQuery(t *testing.T) {
+	resultSet := NewV2ResultSet(&ExecuteNQLV2Response{
+		ExecutedQuery: "devices | summarize crashes = number_of_crashes.sum(), slowest = boot.duration.max(), total = count() by operating_system.platform",
+		Data: []map[string]any{
+			{"operating_system.platform": "Windows", "crashes": float64(3), "slowest": "20 s", "total": float64(2), "other": "x"},
+		},
+	})
+
+	types := resultSet.ColumnTypes()
+	expected := map[string]ColumnType{
+		"operating_system.platform": ColumnTypeString,
+		"crashes":                   ColumnTypeInt,
+		"slowest":                   ColumnTypeDuration,
+		"total":                     ColumnTypeInt,
+		"other":                     ColumnTypeString,
+	}
+	for column, want := range expected {
+		if types[column] != want {
+			t.Errorf("Column %s: expected %s, got %s", column, want, types[column])
+		}
+	}
+}
+
+func TestDateTime_Time(t *testing.T) {
+	dt := &DateTime{Year: 2024, Month: 2, Day: 8, Hour: 10, Minute: 15, Second: 30}
+
+	if got := dt.Time(nil); !got.Equal(time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC)) {
+		t.Errorf("Unexpected UTC time %v", got)
+	}
+
+	loc := time.FixedZone("UTC+2", 2*3600)
+	if got := dt.Time(loc); !got.Equal(time.Date(2024, 2, 8, 8, 15, 30, 0, time.UTC)) {
+		t.Errorf("Unexpected zoned time %v", got)
+	}
+}
@@ -0,0 +1,331 @@
+package nql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result writers serialise result sets and streamed export rows to CSV, NDJSON,
+// Parquet and XLSX with a stable column order and typed columns
+
+// =============================================================================
+// Write Options
+// =============================================================================
+
+// WriteOptions configures the result set writers
+type WriteOptions struct {
+	// Columns selects and orders the output columns
+	// Defaults to the V1 headers, the sorted V2 column names, or the export file
+	// columns (in file order)
+	Columns []string
+
+	// Headers optionally renames columns in the output (column name -> header)
+	Headers map[string]string
+
+	// Types optionally overrides the type of columns
+	// By default types are inferred from the executed query and column names
+	// (see ColumnTypes), or taken from the export reader
+	Types map[string]ColumnType
+
+	// SheetName is the XLSX worksheet name
+	// Defaults to "Results" if not specified; at most 31 characters,
+	// without []:*?/\ or a leading or trailing apostrophe
+	SheetName string
+
+	// RowGroupSize is the number of rows per Parquet row group
+	// Defaults to DefaultParquetRowGroupSize if not specified
+	RowGroupSize int
+}
+
+// =============================================================================
+// Row Tables
+// =============================================================================
+
+// rowTable is the source of a write: typed columns and a row iterator
+type rowTable struct {
+	columns []string
+	headers []string
+	types   []ColumnType
+	rows    iter.Seq2[int, map[string]any]
+
+	// err reports an error from the row iterator once it is exhausted
+	err func() error
+}
+
+// newRowTable resolves the output columns, headers and types
+func newRowTable(columns []string, typeOf func(string) ColumnType, rows iter.Seq2[int, map[string]any], opts *WriteOptions) *rowTable {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	if len(opts.Columns) > 0 {
+		columns = opts.Columns
+	}
+
+	table := &rowTable{
+		columns: columns,
+		headers: make([]string, len(columns)),
+		types:   make([]ColumnType, len(columns)),
+		rows:    rows,
+		err:     func() error { return nil },
+	}
+
+	for i, column := range columns {
+		table.headers[i] = column
+		if header, ok := opts.Headers[column]; ok {
+			table.headers[i] = header
+		}
+
+		if t, ok := opts.Types[column]; ok {
+			table.types[i] = t
+		} else {
+			table.types[i] = typeOf(column)
+		}
+	}
+
+	return table
+}
+
+// typeLookup returns a column type function backed by a type map, falling back to the column name
+func typeLookup(types map[string]ColumnType) func(string) ColumnType {
+	return func(column string) ColumnType {
+		if t, ok := types[column]; ok {
+			return t
+		}
+		return InferColumnType(column)
+	}
+}
+
+// writeTable returns the table for a V2 result set
+func (rs *V2ResultSet) writeTable(opts *WriteOptions) *rowTable {
+	return newRowTable(rs.ColumnNames(), typeLookup(rs.ColumnTypes()), rs.All(), opts)
+}
+
+// writeTable returns the table for a V1 result set
+func (rs *V1ResultSet) writeTable(opts *WriteOptions) *rowTable {
+	var rowErr error
+	rows := func(yield func(int, map[string]any) bool) {
+		for i := range rs.Rows() {
+			row, err := rs.RowMap(i)
+			if err != nil {
+				rowErr = err
+				return
+			}
+			if !yield(i, row) {
+				return
+			}
+		}
+	}
+	table := newRowTable(rs.ColumnNames(), typeLookup(rs.ColumnTypes()), rows, opts)
+	table.err = func() error { return rowErr }
+	return table
+}
+
+// writeTable returns the table for the remaining rows of an export reader
+func (r *ExportReader) writeTable(opts *WriteOptions) *rowTable {
+	table := newRowTable(r.Columns(), r.ColumnType, r.All(), opts)
+	table.err = r.Err
+	return table
+}
+
+// values converts a row to typed values in column order
+// Blank strings in non-string columns become nil (null).
+func (t *rowTable) values(index int, row map[string]any, dst []any) error {
+	for i, column := range t.columns {
+		dst[i] = nil
+		val := row[column]
+		if s, ok := val.(string); ok && t.types[i] != ColumnTypeString && strings.TrimSpace(s) == "" {
+			continue
+		}
+		if val == nil {
+			continue
+		}
+
+		var (
+			typed any
+			err   error
+		)
+		switch t.types[i] {
+		case ColumnTypeInt:
+			typed, err = coerceInt64(val)
+		case ColumnTypeFloat:
+			typed, err = coerceFloat64(val)
+		case ColumnTypeBool:
+			typed, err = coerceBool(val)
+		case ColumnTypeBytes:
+			typed, err = parseByteSizeValue(val)
+		case ColumnTypeDuration:
+			typed, err = parseDurationValue(val, time.Second)
+		case ColumnTypeDateTime:
+			typed, err = parseTimeValue(val, time.UTC)
+		default:
+			typed = stringValue(val)
+		}
+		if err != nil {
+			return fmt.Errorf("row %d: column %s (%s): %w", index, column, t.types[i], err)
+		}
+		dst[i] = typed
+	}
+	return nil
+}
+
+// stringValue renders a value as text, encoding objects and arrays as JSON
+func stringValue(val any) string {
+	switch val.(type) {
+	case map[string]any, []any:
+		data, err := json.Marshal(val)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return coerceString(val)
+}
+
+// textValue renders a typed value for text formats
+func textValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	default:
+		return coerceString(v)
+	}
+}
+
+// =============================================================================
+// CSV
+// =============================================================================
+
+// WriteCSV writes the result set as CSV with a header row
+func (rs *V2ResultSet) WriteCSV(w io.Writer, opts *WriteOptions) error {
+	return writeCSV(w, rs.writeTable(opts))
+}
+
+// WriteCSV writes the result set as CSV with a header row
+func (rs *V1ResultSet) WriteCSV(w io.Writer, opts *WriteOptions) error {
+	return writeCSV(w, rs.writeTable(opts))
+}
+
+// WriteCSV writes the remaining rows as CSV with a header row
+// Rows are streamed, so exports of any size are converted in constant memory.
+func (r *ExportReader) WriteCSV(w io.Writer, opts *WriteOptions) error {
+	return writeCSV(w, r.writeTable(opts))
+}
+
+// writeCSV writes a table as CSV
+// Datetimes are written in RFC 3339 and durations in Go duration syntax ("1m30s").
+func writeCSV(w io.Writer, table *rowTable) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(table.headers); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	values := make([]any, len(table.columns))
+	record := make([]string, len(table.columns))
+	for index, row := range table.rows {
+		if err := table.values(index, row, values); err != nil {
+			return err
+		}
+		for i, val := range values {
+			record[i] = textValue(val)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row %d: %w", index, err)
+		}
+	}
+	if err := table.err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// =============================================================================
+// NDJSON
+// =============================================================================
+
+// WriteNDJSON writes the result set as newline-delimited JSON objects
+func (rs *V2ResultSet) WriteNDJSON(w io.Writer, opts *WriteOptions) error {
+	return writeNDJSON(w, rs.writeTable(opts))
+}
+
+// WriteNDJSON writes the result set as newline-delimited JSON objects
+func (rs *V1ResultSet) WriteNDJSON(w io.Writer, opts *WriteOptions) error {
+	return writeNDJSON(w, rs.writeTable(opts))
+}
+
+// WriteNDJSON writes the remaining rows as newline-delimited JSON objects
+func (r *ExportReader) WriteNDJSON(w io.Writer, opts *WriteOptions) error {
+	return writeNDJSON(w, r.writeTable(opts))
+}
+
+// writeNDJSON writes a table as one JSON object per line, keys in column order
+// Datetimes are RFC 3339 strings and durations Go duration strings.
+func writeNDJSON(w io.Writer, table *rowTable) error {
+	bw := bufio.NewWriter(w)
+
+	keys := make([][]byte, len(table.headers))
+	for i, header := range table.headers {
+		key, err := json.Marshal(header)
+		if err != nil {
+			return fmt.Errorf("failed to encode column %s: %w", header, err)
+		}
+		keys[i] = key
+	}
+
+	var line bytes.Buffer
+	values := make([]any, len(table.columns))
+	for index, row := range table.rows {
+		if err := table.values(index, row, values); err != nil {
+			return err
+		}
+
+		line.Reset()
+		line.WriteByte('{')
+		for i, val := range values {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.Write(keys[i])
+			line.WriteByte(':')
+
+			if d, ok := val.(time.Duration); ok {
+				val = d.String()
+			}
+			data, err := json.Marshal(val)
+			if err != nil {
+				return fmt.Errorf("row %d: failed to encode column %s: %w", index, table.columns[i], err)
+			}
+			line.Write(data)
+		}
+		line.WriteString("}\n")
+
+		if _, err := bw.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("failed to write NDJSON row %d: %w", index, err)
+		}
+	}
+	if err := table.err(); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write NDJSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package nql
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writerDevicesV1() *V1ResultSet {
+	return NewV1ResultSet(&ExecuteNQLV1Response{
+		Headers: []string{"device.name", "number_of_crashes", "last_seen", "boot_duration"},
+		Data: [][]any{
+			{"device-01", float64(3), "2024-02-08T10:15:30Z", float64(90)},
+			{"device, 02", nil, "", "150 ms"},
+		},
+	})
+}
+
+func TestWriteCSV_V1(t *testing.T) {
+	var buf bytes.Buffer
+	err := writerDevicesV1().WriteCSV(&buf, &WriteOptions{
+		Headers: map[string]string{"device.name": "Device"},
+		Types:   map[string]ColumnType{"boot_duration": ColumnTypeDuration},
+	})
+	require.NoError(t, err)
+
+	expected := "Device,number_of_crashes,last_seen,boot_duration\n" +
+		"device-01,3,2024-02-08T10:15:30Z,1m30s\n" +
+		"\"device, 02\",,,150ms\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteCSV_V2ColumnSelection(t *testing.T) {
+	var buf bytes.Buffer
+	err := analyticsDevices().WriteCSV(&buf, &WriteOptions{Columns: []string{"platform", "device.name"}})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "platform,device.name", lines[0])
+	assert.Equal(t, "Windows,win-01", lines[1])
+}
+
+func TestWriteCSV_InvalidValue(t *testing.T) {
+	rs := NewResultSetFromRows([]map[string]any{{"count": "many"}})
+
+	err := rs.WriteCSV(io.Discard, &WriteOptions{Types: map[string]ColumnType{"count": ColumnTypeInt}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "row 0: column count (int)")
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := writerDevicesV1().WriteNDJSON(&buf, &WriteOptions{
+		Types: map[string]ColumnType{"boot_duration": ColumnTypeDuration},
+	})
+	require.NoError(t, err)
+
+	expected := `{"device.name":"device-01","number_of_crashes":3,"last_seen":"2024-02-08T10:15:30Z","boot_duration":"1m30s"}` + "\n" +
+		`{"device.name":"device, 02","number_of_crashes":null,"last_seen":null,"boot_duration":"150ms"}` + "\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExportReader_WriteCSV(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader(exportReaderCSV), ExportFormatCSV)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = reader.WriteCSV(&buf, &WriteOptions{
+		Columns: []string{"device.name", "hardware.memory", "execution_duration"},
+	})
+	require.NoError(t, err)
+
+	expected := "device.name,hardware.memory,execution_duration\n" +
+		"device-01,17179869184,12.5s\n" +
+		"device-02,8589934592,150ms\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	err := writerDevicesV1().WriteParquet(&buf, &WriteOptions{RowGroupSize: 1})
+	require.NoError(t, err)
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), file.NumRows())
+	assert.Len(t, file.RowGroups(), 2)
+
+	schema := file.Schema()
+	var order []string
+	for _, field := range schema.Fields() {
+		order = append(order, field.Name())
+	}
+	assert.Equal(t, []string{"device.name", "number_of_crashes", "last_seen", "boot_duration"}, order, "columns keep the result order")
+
+	columns := []struct {
+		name string
+		kind parquet.Kind
+	}{
+		{"device.name", parquet.ByteArray},
+		{"number_of_crashes", parquet.Int64},
+		{"last_seen", parquet.Int64},
+		{"boot_duration", parquet.Int64},
+	}
+	index := make(map[string]int, len(columns))
+	for _, column := range columns {
+		leaf, ok := schema.Lookup(column.name)
+		require.True(t, ok, column.name)
+		assert.Equal(t, column.kind, leaf.Node.Type().Kind(), column.name)
+		assert.True(t, leaf.Node.Optional(), column.name)
+		index[column.name] = leaf.ColumnIndex
+	}
+
+	rows := make([]parquet.Row, 2)
+	n, err := parquet.NewReader(file).ReadRows(rows)
+	if !errors.Is(err, io.EOF) {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, n)
+
+	value := func(row int, column string) parquet.Value {
+		return rows[row][index[column]]
+	}
+	assert.Equal(t, "device-01", string(value(0, "device.name").ByteArray()))
+	assert.Equal(t, int64(3), value(0, "number_of_crashes").Int64())
+	assert.Equal(t, time.Date(2024, 2, 8, 10, 15, 30, 0, time.UTC).UnixMilli(), value(0, "last_seen").Int64())
+	assert.Equal(t, int64(90000), value(0, "boot_duration").Int64())
+
+	assert.Equal(t, "device, 02", string(value(1, "device.name").ByteArray()))
+	assert.True(t, value(1, "number_of_crashes").IsNull())
+	assert.True(t, value(1, "last_seen").IsNull())
+	assert.Equal(t, int64(150), value(1, "boot_duration").Int64())
+
+	lastSeen, _ := schema.Lookup("last_seen")
+	timestamp, ok := lastSeen.Node.Type().LogicalType().Value.(*format.TimestampType)
+	require.True(t, ok, "last_seen is a TIMESTAMP")
+	assert.True(t, timestamp.IsAdjustedToUTC)
+}
+
+func TestWriteParquet_ExportReader(t *testing.T) {
+	reader, err := NewExportReader(strings.NewReader("device.name,avg_cpu,device.is_virtual\ndevice-01,0.25,true\ndevice-02,,false\n"), ExportFormatCSV)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, reader.WriteParquet(&buf, nil))
+
+	type row struct {
+		Name    *string  `parquet:"device.name,optional"`
+		CPU     *float64 `parquet:"avg_cpu,optional"`
+		Virtual *bool    `parquet:"device.is_virtual,optional"`
+	}
+	rows, err := parquet.Read[row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, "device-01", *rows[0].Name)
+	assert.InDelta(t, 0.25, *rows[0].CPU, 0.0001)
+	assert.True(t, *rows[0].Virtual)
+	assert.Nil(t, rows[1].CPU)
+	assert.False(t, *rows[1].Virtual)
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "AZ", xlsxColumnName(51))
+	assert.Equal(t, "BA", xlsxColumnName(52))
+	assert.Equal(t, "XFD", xlsxColumnName(16383))
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := writerDevicesV1().WriteXLSX(&buf, &WriteOptions{
+		SheetName: "Devices & crashes",
+		Headers:   map[string]string{"device.name": "Device"},
+	})
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		parts[f.Name] = string(content)
+	}
+
+	require.Contains(t, parts, "[Content_Types].xml")
+	require.Contains(t, parts, "xl/styles.xml")
+	assert.Contains(t, parts["xl/workbook.xml"], `name="Devices &amp; crashes"`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">Device</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>3</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" s="1"><v>45330.42743055555</v></c>`)
+	assert.Contains(t, sheet, `<c r="D2" s="2"><v>0.0010416666666666667</v></c>`)
+	assert.NotContains(t, sheet, `r="B3"`)
+}
+
+func TestWriteXLSX_InvalidSheetName(t *testing.T) {
+	for _, name := range []string{"Crashes: last 7d", "a/b", "[devices]", "'quoted'", strings.Repeat("x", 32)} {
+		var buf bytes.Buffer
+		err := writerDevicesV1().WriteXLSX(&buf, &WriteOptions{SheetName: name})
+		assert.ErrorContains(t, err, "invalid XLSX sheet name", name)
+		assert.Zero(t, buf.Len(), name)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writerDevicesV1().WriteXLSX(&buf, &WriteOptions{SheetName: strings.Repeat("é", 31)}))
+}
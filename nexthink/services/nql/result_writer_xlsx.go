@@ -0,0 +1,249 @@
+package nql
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// XLSX output for result sets
+// Writes a single-sheet Office Open XML workbook using inline strings, so rows
+// are streamed straight into the sheet without a shared string table
+
+// MaxXLSXRows is the maximum number of rows in an Excel worksheet, including the header row
+const MaxXLSXRows = 1048576
+
+// ErrTooManyXLSXRows is returned when a result set does not fit in a single worksheet
+var ErrTooManyXLSXRows = errors.New("result set exceeds the Excel worksheet row limit")
+
+// Cell style indexes defined in xlsxStyles
+const (
+	xlsxStyleDateTime = 1
+	xlsxStyleDuration = 2
+)
+
+// excelEpoch is day zero of the Excel 1900 date system (accounting for the 1900 leap year bug)
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// WriteXLSX writes the result set as an Excel workbook with a header row
+// Numbers and booleans are written as native cells, datetimes as date cells
+// (UTC, "yyyy-mm-dd hh:mm:ss") and durations as "[h]:mm:ss" cells.
+func (rs *V2ResultSet) WriteXLSX(w io.Writer, opts *WriteOptions) error {
+	return writeXLSX(w, rs.writeTable(opts), opts)
+}
+
+// WriteXLSX writes the result set as an Excel workbook (see V2ResultSet.WriteXLSX)
+func (rs *V1ResultSet) WriteXLSX(w io.Writer, opts *WriteOptions) error {
+	return writeXLSX(w, rs.writeTable(opts), opts)
+}
+
+// WriteXLSX writes the remaining rows as an Excel workbook (see V2ResultSet.WriteXLSX)
+// Rows are streamed into the worksheet as they are read.
+func (r *ExportReader) WriteXLSX(w io.Writer, opts *WriteOptions) error {
+	return writeXLSX(w, r.writeTable(opts), opts)
+}
+
+// maxSheetNameLength is the longest worksheet name Excel accepts
+const maxSheetNameLength = 31
+
+// validateSheetName checks a worksheet name against Excel's naming rules
+func validateSheetName(name string) error {
+	if n := utf8.RuneCountInString(name); n > maxSheetNameLength {
+		return fmt.Errorf("invalid XLSX sheet name %q: %d characters, at most %d allowed", name, n, maxSheetNameLength)
+	}
+	if i := strings.IndexAny(name, `[]:*?/\`); i >= 0 {
+		return fmt.Errorf("invalid XLSX sheet name %q: character %q is not allowed", name, name[i])
+	}
+	if strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'") {
+		return fmt.Errorf("invalid XLSX sheet name %q: cannot start or end with an apostrophe", name)
+	}
+	return nil
+}
+
+// writeXLSX writes a table as a single-sheet workbook
+func writeXLSX(w io.Writer, table *rowTable, opts *WriteOptions) error {
+	sheetName := "Results"
+	if opts != nil && opts.SheetName != "" {
+		sheetName = opts.SheetName
+	}
+	if err := validateSheetName(sheetName); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to create XLSX part %s: %w", part.name, err)
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return fmt.Errorf("failed to write XLSX part %s: %w", part.name, err)
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("failed to create XLSX worksheet: %w", err)
+	}
+	if err := writeXLSXSheet(fw, table); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write XLSX file: %w", err)
+	}
+	return nil
+}
+
+// writeXLSXSheet streams the worksheet XML
+func writeXLSXSheet(w io.Writer, table *rowTable) error {
+	bw := bufio.NewWriter(w)
+	var sb strings.Builder
+
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	sb.WriteString(`<row r="1">`)
+	for i, header := range table.headers {
+		writeXLSXCell(&sb, i, 1, header)
+	}
+	sb.WriteString(`</row>`)
+
+	rowNum := 1
+	values := make([]any, len(table.columns))
+	for index, row := range table.rows {
+		if err := table.values(index, row, values); err != nil {
+			return err
+		}
+
+		rowNum++
+		if rowNum > MaxXLSXRows {
+			return fmt.Errorf("%w (%d rows)", ErrTooManyXLSXRows, MaxXLSXRows)
+		}
+
+		if _, err := bw.WriteString(sb.String()); err != nil {
+			return fmt.Errorf("failed to write XLSX worksheet: %w", err)
+		}
+		sb.Reset()
+
+		fmt.Fprintf(&sb, `<row r="%d">`, rowNum)
+		for i, val := range values {
+			writeXLSXCell(&sb, i, rowNum, val)
+		}
+		sb.WriteString(`</row>`)
+	}
+	if err := table.err(); err != nil {
+		return err
+	}
+
+	sb.WriteString(`</sheetData></worksheet>`)
+	if _, err := bw.WriteString(sb.String()); err != nil {
+		return fmt.Errorf("failed to write XLSX worksheet: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write XLSX worksheet: %w", err)
+	}
+	return nil
+}
+
+// writeXLSXCell appends a cell for a typed value; nil values produce no cell
+func writeXLSXCell(sb *strings.Builder, col, row int, val any) {
+	ref := xlsxColumnName(col) + strconv.Itoa(row)
+
+	switch v := val.(type) {
+	case nil:
+	case int64:
+		fmt.Fprintf(sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			writeXLSXString(sb, ref, strconv.FormatFloat(v, 'g', -1, 64))
+			return
+		}
+		fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(sb, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+	case time.Time:
+		serial := v.UTC().Sub(excelEpoch).Hours() / 24
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDateTime, strconv.FormatFloat(serial, 'f', -1, 64))
+	case time.Duration:
+		days := v.Hours() / 24
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDuration, strconv.FormatFloat(days, 'f', -1, 64))
+	default:
+		writeXLSXString(sb, ref, textValue(v))
+	}
+}
+
+// writeXLSXString appends an inline string cell
+func writeXLSXString(sb *strings.Builder, ref, s string) {
+	fmt.Fprintf(sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(s))
+}
+
+// xlsxColumnName returns the spreadsheet column letters for a zero-based column index
+func xlsxColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+// xmlEscape escapes text for XML content, dropping characters XML cannot represent
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="[h]:mm:ss"/></numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// Thursday 8 February 2024, 10:17:30 UTC
+	from := time.Date(2024, 2, 8, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 2, 8, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 2, 8, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 2, 8, 10, 25, 0, 0, time.UTC)},
+		{"0 6 * * *", time.Date(2024, 2, 9, 6, 0, 0, 0, time.UTC)},
+		{"30 2 1,15 * *", time.Date(2024, 2, 15, 2, 30, 0, 0, time.UTC)},
+		{"0 8 * * MON-FRI", time.Date(2024, 2, 9, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * sat,sun", time.Date(2024, 2, 10, 8, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Restricted day-of-month and day-of-week match either (the 13th or a Friday)
+		{"0 0 13 * 5", time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC)},
+		// A stepped day-of-month is restricted too (an odd day or a Monday)
+		{"0 0 */2 * MON", time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC)},
+		// A day-of-month covering every day is unrestricted, so only Mondays match
+		{"0 0 1-31 * MON", time.Date(2024, 2, 12, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 2, 8, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 2, 8, 10, 19, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseSchedule_TimeZone(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.NoError(t, err)
+
+	schedule, err := ParseSchedule("0 6 * * *")
+	require.NoError(t, err)
+
+	next := schedule.Next(time.Date(2024, 2, 8, 10, 0, 0, 0, time.UTC).In(zurich))
+	assert.Equal(t, time.Date(2024, 2, 9, 5, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseSchedule_Impossible(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@sometimes",
+		"@every soon",
+		"@every 10ms",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseSchedule(expr)
+			assert.Error(t, err)
+		})
+	}
+}
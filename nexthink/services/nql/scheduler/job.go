@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"gopkg.in/yaml.v3"
+)
+
+// =============================================================================
+// Jobs
+// =============================================================================
+
+// Job is a query that runs on a cron schedule
+type Job struct {
+	// Name uniquely identifies the job in status and history
+	Name string `yaml:"name" json:"name"`
+
+	// QueryID is the NQL query identifier (format: #query_name)
+	QueryID string `yaml:"query_id" json:"queryId"`
+
+	// Platform optionally specifies the platform for the query
+	Platform string `yaml:"platform,omitempty" json:"platform,omitempty"`
+
+	// Parameters optionally supplies values for the query parameters
+	Parameters map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+
+	// Schedule is the cron expression (see ParseSchedule)
+	Schedule string `yaml:"schedule" json:"schedule"`
+
+	// TimeZone is the IANA zone the schedule is evaluated in
+	// Defaults to the scheduler's location (UTC) if not specified
+	TimeZone string `yaml:"time_zone,omitempty" json:"timeZone,omitempty"`
+
+	// Timeout bounds a single run, including writing to the sinks
+	// Defaults to no timeout if not specified
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Jitter delays each run by a random duration in [0, Jitter) so jobs
+	// sharing a schedule do not hit the API at the same instant
+	Jitter time.Duration `yaml:"jitter,omitempty" json:"jitter,omitempty"`
+
+	// Sinks names the scheduler sinks that receive the results
+	// Defaults to every sink added to the scheduler if not specified
+	Sinks []string `yaml:"sinks,omitempty" json:"sinks,omitempty"`
+}
+
+// Validate checks the job definition
+func (j *Job) Validate() error {
+	if j.Name == "" {
+		return fmt.Errorf("job name cannot be empty")
+	}
+	if err := nql.ValidateExecuteRequest(j.request()); err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if _, err := ParseSchedule(j.Schedule); err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if _, err := j.location(); err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	if j.Timeout < 0 {
+		return fmt.Errorf("job %s: timeout cannot be negative", j.Name)
+	}
+	if j.Jitter < 0 {
+		return fmt.Errorf("job %s: jitter cannot be negative", j.Name)
+	}
+	return nil
+}
+
+// request returns the execute request for the job
+func (j *Job) request() *nql.ExecuteRequest {
+	return &nql.ExecuteRequest{
+		QueryID:    j.QueryID,
+		Platform:   j.Platform,
+		Parameters: j.Parameters,
+	}
+}
+
+// location returns the time zone of the job schedule
+func (j *Job) location() (*time.Location, error) {
+	if j.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(j.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", j.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// =============================================================================
+// YAML Configuration
+// =============================================================================
+
+// jobsFile is the YAML document read by LoadJobs
+type jobsFile struct {
+	Jobs []Job `yaml:"jobs"`
+}
+
+// LoadJobs reads job definitions from YAML
+// Durations use Go syntax ("90s", "5m"). Unknown keys are rejected so typos
+// surface at startup rather than as silently ignored settings.
+//
+// Example:
+//
+//	jobs:
+//	  - name: daily-crashes
+//	    query_id: "#crashes_last_day"
+//	    platform: windows
+//	    parameters:
+//	      min_crashes: "3"
+//	    schedule: "0 6 * * *"
+//	    time_zone: Europe/Zurich
+//	    timeout: 10m
+//	    jitter: 30s
+//	    sinks: [reports]
+func LoadJobs(r io.Reader) ([]Job, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var file jobsFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse job definitions: %w", err)
+	}
+
+	for i := range file.Jobs {
+		if err := file.Jobs[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Jobs, nil
+}
+
+// LoadJobsFile reads job definitions from a YAML file (see LoadJobs)
+func LoadJobsFile(path string) ([]Job, error) {
+	f, err := os.Open(path) //nolint:gosec // path is supplied by the caller
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job definitions: %w", err)
+	}
+	defer f.Close()
+
+	return LoadJobs(f)
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJobsYAML = `
+jobs:
+  - name: daily-crashes
+    query_id: "#crashes_last_day"
+    platform: windows
+    parameters:
+      min_crashes: "3"
+    schedule: "0 6 * * *"
+    time_zone: Europe/Zurich
+    timeout: 10m
+    jitter: 30s
+    sinks: [reports]
+  - name: inventory
+    query_id: "#device_inventory"
+    schedule: "@hourly"
+`
+
+func TestLoadJobs(t *testing.T) {
+	jobs, err := LoadJobs(strings.NewReader(testJobsYAML))
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	assert.Equal(t, Job{
+		Name:       "daily-crashes",
+		QueryID:    "#crashes_last_day",
+		Platform:   "windows",
+		Parameters: map[string]string{"min_crashes": "3"},
+		Schedule:   "0 6 * * *",
+		TimeZone:   "Europe/Zurich",
+		Timeout:    10 * time.Minute,
+		Jitter:     30 * time.Second,
+		Sinks:      []string{"reports"},
+	}, jobs[0])
+	assert.Equal(t, "#device_inventory", jobs[1].QueryID)
+}
+
+func TestLoadJobsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testJobsYAML), 0o600))
+
+	jobs, err := LoadJobsFile(path)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	_, err = LoadJobsFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestLoadJobs_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":     "jobs:\n  - name: a\n    query_id: \"#a\"\n    schedule: \"@daily\"\n    schedul: \"@hourly\"\n",
+		"missing name":    "jobs:\n  - query_id: \"#a\"\n    schedule: \"@daily\"\n",
+		"invalid query":   "jobs:\n  - name: a\n    query_id: a\n    schedule: \"@daily\"\n",
+		"invalid cron":    "jobs:\n  - name: a\n    query_id: \"#a\"\n    schedule: \"61 * * * *\"\n",
+		"invalid zone":    "jobs:\n  - name: a\n    query_id: \"#a\"\n    schedule: \"@daily\"\n    time_zone: Mars/Olympus\n",
+		"invalid timeout": "jobs:\n  - name: a\n    query_id: \"#a\"\n    schedule: \"@daily\"\n    timeout: soon\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadJobs(strings.NewReader(data))
+			assert.Error(t, err)
+		})
+	}
+}
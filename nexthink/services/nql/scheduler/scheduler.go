@@ -0,0 +1,555 @@
+// Package scheduler runs NQL queries on cron schedules and delivers the results to sinks.
+//
+// Jobs are defined in code or loaded from YAML (see LoadJobs). Each run goes
+// through nql.Service.Query, so truncated results fall back to an export
+// automatically. Runs are jittered, a job never overlaps with itself, and the
+// run history and last error of every job are available for health checks.
+//
+// Example:
+//
+//	sched := scheduler.New(client.NQL, nil)
+//	files, _ := scheduler.NewFileSink("/var/lib/reports", scheduler.FormatCSV)
+//	_ = sched.AddSink("reports", files)
+//
+//	jobs, _ := scheduler.LoadJobsFile("jobs.yaml")
+//	for _, job := range jobs {
+//		_ = sched.AddJob(job)
+//	}
+//
+//	go sched.Run(ctx)
+//
+//	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+//		if err := sched.Health(); err != nil {
+//			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//		}
+//	})
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrJobNotFound is returned for an unknown job name
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobRunning is returned by RunNow when the job is already running
+	ErrJobRunning = errors.New("job is already running")
+
+	// ErrSchedulerRunning is returned when jobs or sinks are added after Run has started
+	ErrSchedulerRunning = errors.New("scheduler is already running")
+)
+
+// DefaultHistorySize is the default number of runs kept per job
+const DefaultHistorySize = 20
+
+// Querier runs a query and returns all of its rows
+// *nql.Service implements Querier.
+type Querier interface {
+	Query(ctx context.Context, req *nql.ExecuteRequest) (*nql.QueryResult, error)
+}
+
+// =============================================================================
+// Options
+// =============================================================================
+
+// Options configures a Scheduler
+type Options struct {
+	// Logger receives run and error logs
+	// Defaults to a no-op logger if nil
+	Logger *zap.Logger
+
+	// HistorySize is the number of runs kept per job
+	// Defaults to DefaultHistorySize if not specified
+	HistorySize int
+}
+
+// DefaultOptions returns scheduler options with sensible defaults
+func DefaultOptions() *Options {
+	return &Options{
+		Logger:      zap.NewNop(),
+		HistorySize: DefaultHistorySize,
+	}
+}
+
+// =============================================================================
+// Runs and Status
+// =============================================================================
+
+// Run records a single execution of a job
+type Run struct {
+	// Job is the job name
+	Job string `json:"job"`
+
+	// StartedAt is the time the run started (after jitter)
+	StartedAt time.Time `json:"startedAt"`
+
+	// Duration is the time taken by the query and the sinks
+	Duration time.Duration `json:"duration"`
+
+	// Rows is the number of rows returned
+	Rows int `json:"rows"`
+
+	// Source is the API that produced the rows
+	Source nql.QuerySource `json:"source,omitempty"`
+
+	// Err is the query or sink error, nil if the run succeeded
+	Err error `json:"-"`
+
+	// Error is the text of Err, for JSON status output
+	Error string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the run completed without error
+func (r *Run) Succeeded() bool {
+	return r.Err == nil
+}
+
+// JobStatus summarises the state of a job
+type JobStatus struct {
+	// Job is the job definition
+	Job Job `json:"job"`
+
+	// Running reports whether a run is in progress
+	Running bool `json:"running"`
+
+	// NextRun is the next scheduled run time (zero if the scheduler is not running)
+	NextRun time.Time `json:"nextRun,omitzero"`
+
+	// LastRun is the most recent completed run (nil if the job has not run)
+	LastRun *Run `json:"lastRun,omitempty"`
+
+	// LastSuccess is the start time of the most recent successful run
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+
+	// LastError is the error of the most recent run (nil if it succeeded)
+	LastError error `json:"-"`
+
+	// ConsecutiveFailures counts failed runs since the last success
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	// Runs, Failures and Skipped count completed, failed and skipped runs
+	// A run is skipped when its scheduled time arrives while the job is still running
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	Skipped  int `json:"skipped"`
+}
+
+// Healthy reports whether the last run of the job succeeded (or the job has not run yet)
+func (s *JobStatus) Healthy() bool {
+	return s.LastError == nil
+}
+
+// =============================================================================
+// Scheduler
+// =============================================================================
+
+// jobState is the runtime state of a scheduled job
+type jobState struct {
+	job      Job
+	schedule Schedule
+	loc      *time.Location
+
+	// mu guards the fields below
+	mu      sync.Mutex
+	running bool
+	nextRun time.Time
+	history []Run
+	status  JobStatus
+}
+
+// Scheduler runs jobs on their cron schedules
+type Scheduler struct {
+	querier Querier
+	logger  *zap.Logger
+	history int
+
+	mu      sync.Mutex
+	jobs    map[string]*jobState
+	sinks   map[string]Sink
+	started bool
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler that runs queries with querier (usually the client's NQL service)
+// opts may be nil to use DefaultOptions.
+func New(querier Querier, opts *Options) *Scheduler {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	history := opts.HistorySize
+	if history <= 0 {
+		history = DefaultHistorySize
+	}
+
+	return &Scheduler{
+		querier: querier,
+		logger:  logger,
+		history: history,
+		jobs:    make(map[string]*jobState),
+		sinks:   make(map[string]Sink),
+	}
+}
+
+// AddSink registers a named sink
+// Jobs send their results to the sinks they name, or to every sink if they name none.
+func (s *Scheduler) AddSink(name string, sink Sink) error {
+	if name == "" {
+		return fmt.Errorf("sink name cannot be empty")
+	}
+	if sink == nil {
+		return fmt.Errorf("sink %s cannot be nil", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return ErrSchedulerRunning
+	}
+	if _, exists := s.sinks[name]; exists {
+		return fmt.Errorf("sink %s already exists", name)
+	}
+	s.sinks[name] = sink
+	return nil
+}
+
+// AddJob validates and registers a job
+// Sinks named by the job must be added first.
+func (s *Scheduler) AddJob(job Job) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	loc, err := job.location()
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return ErrSchedulerRunning
+	}
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s already exists", job.Name)
+	}
+	for _, name := range job.Sinks {
+		if _, ok := s.sinks[name]; !ok {
+			return fmt.Errorf("job %s: sink %s not found", job.Name, name)
+		}
+	}
+
+	s.jobs[job.Name] = &jobState{
+		job:      job,
+		schedule: schedule,
+		loc:      loc,
+		status:   JobStatus{Job: job},
+	}
+	return nil
+}
+
+// Run runs the jobs on their schedules until ctx is cancelled
+// In-progress runs are cancelled with ctx, and Run returns once they have
+// finished. Run can only be called once.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return ErrSchedulerRunning
+	}
+	s.started = true
+	jobs := s.sortedJobs()
+	s.mu.Unlock()
+
+	s.logger.Info("Starting NQL scheduler", zap.Int("jobs", len(jobs)))
+
+	var loops sync.WaitGroup
+	for _, state := range jobs {
+		loops.Go(func() {
+			s.loop(ctx, state)
+		})
+	}
+
+	<-ctx.Done()
+	loops.Wait()
+	s.wg.Wait()
+
+	s.logger.Info("NQL scheduler stopped")
+	return nil
+}
+
+// loop waits for each scheduled time of a job and starts the run
+func (s *Scheduler) loop(ctx context.Context, state *jobState) {
+	for {
+		next := state.schedule.Next(time.Now().In(state.loc))
+		if next.IsZero() {
+			s.logger.Warn("Job schedule has no future run times", zap.String("job", state.job.Name))
+			return
+		}
+
+		state.mu.Lock()
+		state.nextRun = next
+		state.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next) + jitter(state.job.Jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !state.tryStart() {
+			state.mu.Lock()
+			state.status.Skipped++
+			state.mu.Unlock()
+			s.logger.Warn("Skipping job run, previous run still in progress", zap.String("job", state.job.Name))
+			continue
+		}
+
+		s.wg.Go(func() {
+			s.execute(ctx, state)
+		})
+	}
+}
+
+// RunNow runs a job immediately, outside its schedule, and returns the run
+// Returns ErrJobRunning if the job is already running.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*Run, error) {
+	s.mu.Lock()
+	state, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	if !state.tryStart() {
+		return nil, fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+
+	run := s.execute(ctx, state)
+	return &run, nil
+}
+
+// tryStart marks the job as running, reporting false if it already is
+func (j *jobState) tryStart() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running {
+		return false
+	}
+	j.running = true
+	return true
+}
+
+// execute runs the query, writes the result to the sinks and records the run
+// The job must have been marked as running with tryStart.
+func (s *Scheduler) execute(ctx context.Context, state *jobState) Run {
+	job := state.job
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	run := Run{Job: job.Name, StartedAt: time.Now()}
+	s.logger.Info("Running NQL job",
+		zap.String("job", job.Name),
+		zap.String("query_id", job.QueryID))
+
+	err := s.runJob(ctx, job, &run)
+
+	run.Duration = time.Since(run.StartedAt)
+	if err != nil {
+		run.Err = err
+		run.Error = err.Error()
+		s.logger.Error("NQL job failed",
+			zap.String("job", job.Name),
+			zap.Duration("duration", run.Duration),
+			zap.Error(err))
+	} else {
+		s.logger.Info("NQL job completed",
+			zap.String("job", job.Name),
+			zap.Int("rows", run.Rows),
+			zap.Duration("duration", run.Duration))
+	}
+
+	state.record(run, s.history)
+	return run
+}
+
+// runJob queries the job and delivers the result
+// A panic in the querier or a sink is recovered and returned as an error, so
+// the run is recorded as failed and the job can run again.
+func (s *Scheduler) runJob(ctx context.Context, job Job, run *Run) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("NQL job panicked",
+				zap.String("job", job.Name),
+				zap.Any("panic", r),
+				zap.ByteString("stack", debug.Stack()))
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	result, err := s.querier.Query(ctx, job.request())
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	run.Rows = result.Rows()
+	run.Source = result.Source
+	return s.deliver(ctx, &Result{QueryResult: result, Job: job, StartedAt: run.StartedAt})
+}
+
+// deliver writes the result to the job's sinks, attempting every sink even if one fails
+func (s *Scheduler) deliver(ctx context.Context, result *Result) error {
+	names := result.Job.Sinks
+
+	s.mu.Lock()
+	if len(names) == 0 {
+		for name := range s.sinks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	sinks := make([]Sink, len(names))
+	for i, name := range names {
+		sinks[i] = s.sinks[name]
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for i, sink := range sinks {
+		if err := sink.Write(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record stores a completed run and clears the running flag
+func (j *jobState) record(run Run, historySize int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.running = false
+	j.history = append(j.history, run)
+	if len(j.history) > historySize {
+		j.history = j.history[len(j.history)-historySize:]
+	}
+
+	j.status.Runs++
+	j.status.LastError = run.Err
+	if run.Err != nil {
+		j.status.Failures++
+		j.status.ConsecutiveFailures++
+	} else {
+		j.status.LastSuccess = run.StartedAt
+		j.status.ConsecutiveFailures = 0
+	}
+}
+
+// snapshot returns the current status of the job
+func (j *jobState) snapshot() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	status.Running = j.running
+	status.NextRun = j.nextRun
+	if n := len(j.history); n > 0 {
+		last := j.history[n-1]
+		status.LastRun = &last
+	}
+	return status
+}
+
+// sortedJobs returns the jobs ordered by name; the caller must hold s.mu
+func (s *Scheduler) sortedJobs() []*jobState {
+	jobs := make([]*jobState, 0, len(s.jobs))
+	for _, state := range s.jobs {
+		jobs = append(jobs, state)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].job.Name < jobs[j].job.Name
+	})
+	return jobs
+}
+
+// Status returns the status of every job, ordered by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	jobs := s.sortedJobs()
+	s.mu.Unlock()
+
+	statuses := make([]JobStatus, len(jobs))
+	for i, state := range jobs {
+		statuses[i] = state.snapshot()
+	}
+	return statuses
+}
+
+// JobStatus returns the status of a job
+func (s *Scheduler) JobStatus(name string) (JobStatus, error) {
+	s.mu.Lock()
+	state, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return state.snapshot(), nil
+}
+
+// History returns the recent runs of a job, oldest first
+func (s *Scheduler) History(name string) ([]Run, error) {
+	s.mu.Lock()
+	state, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return append([]Run(nil), state.history...), nil
+}
+
+// Health returns an error naming every job whose last run failed, or nil if all are healthy
+func (s *Scheduler) Health() error {
+	var errs []error
+	for _, status := range s.Status() {
+		if !status.Healthy() {
+			errs = append(errs, fmt.Errorf("job %s: %d consecutive failures: %w",
+				status.Job.Name, status.ConsecutiveFailures, status.LastError))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jitter returns a random delay in [0, limit)
+func jitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit) //nolint:gosec // jitter does not need a secure source
+}
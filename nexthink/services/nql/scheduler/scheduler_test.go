@@ -0,0 +1,301 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// fakeQuerier returns canned rows and records the requests it receives
+type fakeQuerier struct {
+	mu       sync.Mutex
+	requests []*nql.ExecuteRequest
+	err      error
+	delay    time.Duration
+	calls    atomic.Int32
+}
+
+func (q *fakeQuerier) Query(ctx context.Context, req *nql.ExecuteRequest) (*nql.QueryResult, error) {
+	q.calls.Add(1)
+	q.mu.Lock()
+	q.requests = append(q.requests, req)
+	err := q.err
+	q.mu.Unlock()
+
+	if q.delay > 0 {
+		select {
+		case <-time.After(q.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &nql.QueryResult{
+		V2ResultSet: nql.NewV2ResultSet(&nql.ExecuteNQLV2Response{
+			QueryID: req.QueryID,
+			Data: []map[string]any{
+				{"device.name": "device-01", "number_of_crashes": float64(3)},
+				{"device.name": "device-02", "number_of_crashes": float64(0)},
+			},
+		}),
+		Source: nql.QuerySourceExecute,
+	}, nil
+}
+
+// recordingSink collects the results written to it
+type recordingSink struct {
+	mu      sync.Mutex
+	results []*Result
+	err     error
+}
+
+func (s *recordingSink) Write(_ context.Context, result *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return s.err
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.results)
+}
+
+func testJob(name string) Job {
+	return Job{
+		Name:       name,
+		QueryID:    "#crashes",
+		Platform:   "windows",
+		Parameters: map[string]string{"min_crashes": "1"},
+		Schedule:   "@daily",
+	}
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	querier := &fakeQuerier{}
+	reports, other := &recordingSink{}, &recordingSink{}
+
+	sched := New(querier, nil)
+	require.NoError(t, sched.AddSink("reports", reports))
+	require.NoError(t, sched.AddSink("other", other))
+
+	job := testJob("crashes")
+	job.Sinks = []string{"reports"}
+	require.NoError(t, sched.AddJob(job))
+
+	run, err := sched.RunNow(context.Background(), "crashes")
+	require.NoError(t, err)
+	assert.True(t, run.Succeeded())
+	assert.Equal(t, 2, run.Rows)
+	assert.Equal(t, nql.QuerySourceExecute, run.Source)
+
+	require.Len(t, querier.requests, 1)
+	assert.Equal(t, &nql.ExecuteRequest{
+		QueryID:    "#crashes",
+		Platform:   "windows",
+		Parameters: map[string]string{"min_crashes": "1"},
+	}, querier.requests[0])
+
+	require.Equal(t, 1, reports.count())
+	assert.Equal(t, 0, other.count())
+	assert.Equal(t, "crashes", reports.results[0].Job.Name)
+	assert.Equal(t, run.StartedAt, reports.results[0].StartedAt)
+
+	_, err = sched.RunNow(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestScheduler_DefaultSinks(t *testing.T) {
+	first, second := &recordingSink{}, &recordingSink{}
+
+	sched := New(&fakeQuerier{}, nil)
+	require.NoError(t, sched.AddSink("first", first))
+	require.NoError(t, sched.AddSink("second", second))
+	require.NoError(t, sched.AddJob(testJob("crashes")))
+
+	_, err := sched.RunNow(context.Background(), "crashes")
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.count())
+	assert.Equal(t, 1, second.count())
+}
+
+func TestScheduler_AddJobErrors(t *testing.T) {
+	sched := New(&fakeQuerier{}, nil)
+	require.NoError(t, sched.AddJob(testJob("crashes")))
+
+	assert.Error(t, sched.AddJob(testJob("crashes")), "duplicate name")
+
+	job := testJob("unknown-sink")
+	job.Sinks = []string{"missing"}
+	assert.Error(t, sched.AddJob(job))
+
+	job = testJob("bad-cron")
+	job.Schedule = "every day"
+	assert.Error(t, sched.AddJob(job))
+
+	assert.Error(t, sched.AddSink("", &recordingSink{}))
+	assert.Error(t, sched.AddSink("nil", nil))
+}
+
+func TestScheduler_StatusAndHealth(t *testing.T) {
+	querier := &fakeQuerier{}
+	sink := &recordingSink{}
+
+	sched := New(querier, &Options{HistorySize: 2})
+	require.NoError(t, sched.AddSink("reports", sink))
+	require.NoError(t, sched.AddJob(testJob("crashes")))
+	require.NoError(t, sched.AddJob(testJob("inventory")))
+
+	ctx := context.Background()
+	_, err := sched.RunNow(ctx, "crashes")
+	require.NoError(t, err)
+	require.NoError(t, sched.Health())
+
+	querier.mu.Lock()
+	querier.err = errQueryFailed
+	querier.mu.Unlock()
+
+	for range 2 {
+		run, err := sched.RunNow(ctx, "crashes")
+		require.NoError(t, err)
+		assert.ErrorIs(t, run.Err, errQueryFailed)
+		assert.NotEmpty(t, run.Error)
+	}
+
+	status, err := sched.JobStatus("crashes")
+	require.NoError(t, err)
+	assert.False(t, status.Healthy())
+	assert.Equal(t, 3, status.Runs)
+	assert.Equal(t, 2, status.Failures)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.False(t, status.LastSuccess.IsZero())
+	require.NotNil(t, status.LastRun)
+	assert.ErrorIs(t, status.LastRun.Err, errQueryFailed)
+
+	history, err := sched.History("crashes")
+	require.NoError(t, err)
+	assert.Len(t, history, 2, "history is capped at HistorySize")
+
+	health := sched.Health()
+	require.Error(t, health)
+	assert.ErrorIs(t, health, errQueryFailed)
+	assert.Contains(t, health.Error(), "job crashes: 2 consecutive failures")
+
+	statuses := sched.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "crashes", statuses[0].Job.Name)
+	assert.Equal(t, "inventory", statuses[1].Job.Name)
+	assert.True(t, statuses[1].Healthy())
+	assert.Nil(t, statuses[1].LastRun)
+
+	querier.mu.Lock()
+	querier.err = nil
+	querier.mu.Unlock()
+
+	_, err = sched.RunNow(ctx, "crashes")
+	require.NoError(t, err)
+	assert.NoError(t, sched.Health())
+}
+
+func TestScheduler_SinkErrors(t *testing.T) {
+	failing := &recordingSink{err: errors.New("disk full")}
+	working := &recordingSink{}
+
+	sched := New(&fakeQuerier{}, nil)
+	require.NoError(t, sched.AddSink("failing", failing))
+	require.NoError(t, sched.AddSink("working", working))
+	require.NoError(t, sched.AddJob(testJob("crashes")))
+
+	run, err := sched.RunNow(context.Background(), "crashes")
+	require.NoError(t, err)
+	require.Error(t, run.Err)
+	assert.Contains(t, run.Err.Error(), "sink failing: disk full")
+	assert.Equal(t, 1, working.count(), "remaining sinks still receive the result")
+}
+
+// panickingSink panics on every write
+type panickingSink struct{}
+
+func (panickingSink) Write(context.Context, *Result) error {
+	panic("sink exploded")
+}
+
+func TestScheduler_SinkPanic(t *testing.T) {
+	sched := New(&fakeQuerier{}, nil)
+	require.NoError(t, sched.AddSink("panicking", panickingSink{}))
+	require.NoError(t, sched.AddJob(testJob("crashes")))
+
+	for range 2 {
+		run, err := sched.RunNow(context.Background(), "crashes")
+		require.NoError(t, err, "the job is not left running")
+		require.Error(t, run.Err)
+		assert.Contains(t, run.Err.Error(), "job panicked: sink exploded")
+	}
+
+	status, err := sched.JobStatus("crashes")
+	require.NoError(t, err)
+	assert.False(t, status.Running)
+	assert.Equal(t, 2, status.Failures)
+}
+
+func TestScheduler_Timeout(t *testing.T) {
+	sched := New(&fakeQuerier{delay: time.Second}, nil)
+
+	job := testJob("slow")
+	job.Timeout = 20 * time.Millisecond
+	require.NoError(t, sched.AddJob(job))
+
+	run, err := sched.RunNow(context.Background(), "slow")
+	require.NoError(t, err)
+	assert.ErrorIs(t, run.Err, context.DeadlineExceeded)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	querier := &fakeQuerier{delay: 1500 * time.Millisecond}
+	sink := &recordingSink{}
+
+	sched := New(querier, nil)
+	require.NoError(t, sched.AddSink("reports", sink))
+
+	job := testJob("frequent")
+	job.Schedule = "@every 1s"
+	require.NoError(t, sched.AddJob(job))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3500*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- sched.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		status, _ := sched.JobStatus("frequent")
+		return status.Running && !status.NextRun.IsZero()
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, err := sched.RunNow(context.Background(), "frequent")
+	assert.ErrorIs(t, err, ErrJobRunning)
+
+	require.NoError(t, <-done)
+	assert.ErrorIs(t, sched.Run(context.Background()), ErrSchedulerRunning)
+	assert.ErrorIs(t, sched.AddJob(testJob("late")), ErrSchedulerRunning)
+
+	status, err := sched.JobStatus("frequent")
+	require.NoError(t, err)
+	assert.False(t, status.Running)
+	assert.GreaterOrEqual(t, status.Runs, 1)
+	assert.GreaterOrEqual(t, status.Skipped, 1, "ticks during a run are skipped")
+	assert.Equal(t, int(querier.calls.Load()), status.Runs)
+}
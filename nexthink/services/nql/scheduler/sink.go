@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// =============================================================================
+// Sinks
+// =============================================================================
+
+// Result is the output of a successful job run, passed to each sink
+// The embedded QueryResult provides the rows and the nql writers.
+type Result struct {
+	*nql.QueryResult
+
+	// Job is the job definition that produced the result
+	Job Job
+
+	// StartedAt is the time the run started
+	StartedAt time.Time
+}
+
+// Sink receives job results
+// Sinks are shared by concurrent job runs and must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, result *Result) error
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(ctx context.Context, result *Result) error
+
+// Write calls f(ctx, result)
+func (f SinkFunc) Write(ctx context.Context, result *Result) error {
+	return f(ctx, result)
+}
+
+// =============================================================================
+// Output Formats
+// =============================================================================
+
+// Format is the serialisation used by the file, writer and HTTP sinks
+type Format string
+
+const (
+	// FormatNDJSON writes one JSON object per row
+	FormatNDJSON Format = "ndjson"
+
+	// FormatJSON writes the rows as a JSON array
+	FormatJSON Format = "json"
+
+	// FormatCSV writes CSV with a header row
+	FormatCSV Format = "csv"
+
+	// FormatParquet writes a Parquet file
+	FormatParquet Format = "parquet"
+
+	// FormatXLSX writes an Excel workbook
+	FormatXLSX Format = "xlsx"
+)
+
+// contentType returns the MIME type of the format
+func (f Format) contentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// validate checks the format is supported
+func (f Format) validate() error {
+	switch f {
+	case FormatNDJSON, FormatJSON, FormatCSV, FormatParquet, FormatXLSX:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sink format %q", f)
+	}
+}
+
+// writeResult serialises the result rows in the given format
+func writeResult(w io.Writer, result *Result, format Format, opts *nql.WriteOptions) error {
+	rs := result.V2ResultSet
+
+	switch format {
+	case FormatJSON:
+		data, err := rs.ToJSON()
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write result: %w", err)
+		}
+		return nil
+	case FormatCSV:
+		return rs.WriteCSV(w, opts)
+	case FormatParquet:
+		return rs.WriteParquet(w, opts)
+	case FormatXLSX:
+		return rs.WriteXLSX(w, opts)
+	case FormatNDJSON, "":
+		return rs.WriteNDJSON(w, opts)
+	default:
+		return format.validate()
+	}
+}
+
+// =============================================================================
+// Writer Sink
+// =============================================================================
+
+// WriterSink writes results to an io.Writer, such as stdout or a log file
+// Writes are serialised so concurrent runs do not interleave.
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+
+	// WriteOptions optionally selects, renames and types the output columns
+	WriteOptions *nql.WriteOptions
+}
+
+// NewWriterSink creates a sink that writes results to w
+// format defaults to FormatNDJSON if empty.
+func NewWriterSink(w io.Writer, format Format) (*WriterSink, error) {
+	if format == "" {
+		format = FormatNDJSON
+	}
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	return &WriterSink{w: w, format: format}, nil
+}
+
+// NewStdoutSink creates a sink that writes results to standard output
+func NewStdoutSink(format Format) (*WriterSink, error) {
+	return NewWriterSink(os.Stdout, format)
+}
+
+// Write writes the result rows
+func (s *WriterSink) Write(_ context.Context, result *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeResult(s.w, result, s.format, s.WriteOptions)
+}
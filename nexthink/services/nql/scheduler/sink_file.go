@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// FileSink writes each result to a new file in a directory
+// Files are named <job>_<start time>.<format>, e.g. daily-crashes_20240208T060000Z.csv,
+// and are written to a temporary file first so readers never see partial output.
+type FileSink struct {
+	dir    string
+	format Format
+
+	// WriteOptions optionally selects, renames and types the output columns
+	WriteOptions *nql.WriteOptions
+}
+
+// NewFileSink creates a sink that writes result files to dir
+// The directory is created if it does not exist. format defaults to FormatCSV if empty.
+func NewFileSink(dir string, format Format) (*FileSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file sink directory cannot be empty")
+	}
+	if format == "" {
+		format = FormatCSV
+	}
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create file sink directory: %w", err)
+	}
+	return &FileSink{dir: dir, format: format}, nil
+}
+
+// Path returns the file path used for a result
+func (s *FileSink) Path(result *Result) string {
+	name := fmt.Sprintf("%s_%s.%s",
+		sanitizeFileName(result.Job.Name),
+		result.StartedAt.UTC().Format("20060102T150405Z"),
+		s.format)
+	return filepath.Join(s.dir, name)
+}
+
+// Write writes the result to a new file
+func (s *FileSink) Write(_ context.Context, result *Result) error {
+	path := s.Path(result)
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create result file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeResult(tmp, result, s.format, s.WriteOptions); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
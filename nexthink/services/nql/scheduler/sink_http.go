@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+)
+
+// HTTPSink POSTs each result to a URL
+// The request carries the job name and query ID in the X-NQL-Job and
+// X-NQL-Query-ID headers; any non-2xx response fails the sink.
+type HTTPSink struct {
+	url    string
+	format Format
+
+	// Client is the HTTP client used for requests
+	// Defaults to http.DefaultClient if nil
+	Client *http.Client
+
+	// Headers are added to every request (e.g. Authorization)
+	Headers map[string]string
+
+	// WriteOptions optionally selects, renames and types the output columns
+	WriteOptions *nql.WriteOptions
+}
+
+// NewHTTPSink creates a sink that POSTs results to url
+// format defaults to FormatNDJSON if empty.
+func NewHTTPSink(url string, format Format) (*HTTPSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("HTTP sink URL cannot be empty")
+	}
+	if format == "" {
+		format = FormatNDJSON
+	}
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	return &HTTPSink{url: url, format: format}, nil
+}
+
+// Write POSTs the result
+func (s *HTTPSink) Write(ctx context.Context, result *Result) error {
+	var body bytes.Buffer
+	if err := writeResult(&body, result, s.format, s.WriteOptions); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP sink request: %w", err)
+	}
+	req.Header.Set("Content-Type", s.format.contentType())
+	req.Header.Set("X-NQL-Job", result.Job.Name)
+	req.Header.Set("X-NQL-Query-ID", result.Job.QueryID)
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP sink request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP sink returned status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
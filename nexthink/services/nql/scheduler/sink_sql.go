@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches the table and column names accepted by SQLSink
+// Identifiers are interpolated into the INSERT statement, so anything else is rejected.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Placeholder returns the bind parameter for the n-th value (1-based) of a statement
+type Placeholder func(n int) string
+
+// QuestionPlaceholder uses "?" parameters (MySQL, SQLite, SQL Server drivers)
+func QuestionPlaceholder(int) string { return "?" }
+
+// DollarPlaceholder uses "$n" parameters (PostgreSQL)
+func DollarPlaceholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// SQLSink inserts result rows into a database table
+// Each result is inserted in a single transaction, so a failed run leaves no
+// partial rows. The table must already exist.
+type SQLSink struct {
+	db    *sql.DB
+	table string
+
+	// Columns maps result fields to table columns
+	// Defaults to every result field, with characters other than letters,
+	// digits and underscores replaced by underscores (device.name -> device_name)
+	Columns map[string]string
+
+	// JobColumn optionally stores the job name with each row
+	JobColumn string
+
+	// RunAtColumn optionally stores the run start time with each row
+	RunAtColumn string
+
+	// Placeholder formats bind parameters
+	// Defaults to QuestionPlaceholder if nil
+	Placeholder Placeholder
+}
+
+// NewSQLSink creates a sink that inserts rows into table
+// table may be schema qualified (reporting.device_crashes).
+func NewSQLSink(db *sql.DB, table string) (*SQLSink, error) {
+	if db == nil {
+		return nil, fmt.Errorf("SQL sink database cannot be nil")
+	}
+	if !identifierPattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid SQL sink table name %q", table)
+	}
+	return &SQLSink{db: db, table: table}, nil
+}
+
+// Write inserts the result rows
+func (s *SQLSink) Write(ctx context.Context, result *Result) error {
+	fields, columns, err := s.columns(result)
+	if err != nil {
+		return err
+	}
+	if result.Rows() == 0 {
+		return nil
+	}
+
+	placeholder := s.Placeholder
+	if placeholder == nil {
+		placeholder = QuestionPlaceholder
+	}
+	params := make([]string, len(columns))
+	for i := range params {
+		params[i] = placeholder(i + 1)
+	}
+	//nolint:gosec // table and column names are validated against identifierPattern
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", s.table, strings.Join(columns, ", "), strings.Join(params, ", "))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin SQL sink transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after a successful commit
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL sink insert: %w", err)
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(columns))
+	for i, row := range result.All() {
+		for j, field := range fields {
+			args[j] = sqlValue(row[field])
+		}
+		if s.JobColumn != "" {
+			args[len(fields)] = result.Job.Name
+		}
+		if s.RunAtColumn != "" {
+			args[len(columns)-1] = result.StartedAt
+		}
+
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert row %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit SQL sink transaction: %w", err)
+	}
+	return nil
+}
+
+// columns returns the result fields and the matching insert columns
+// The job and run time columns, when configured, follow the field columns.
+func (s *SQLSink) columns(result *Result) ([]string, []string, error) {
+	var fields []string
+	if len(s.Columns) > 0 {
+		for field := range s.Columns {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	} else {
+		fields = result.ColumnNames()
+	}
+
+	columns := make([]string, 0, len(fields)+2)
+	for _, field := range fields {
+		column, ok := s.Columns[field]
+		if !ok {
+			column = columnName(field)
+		}
+		columns = append(columns, column)
+	}
+	if s.JobColumn != "" {
+		columns = append(columns, s.JobColumn)
+	}
+	if s.RunAtColumn != "" {
+		columns = append(columns, s.RunAtColumn)
+	}
+
+	for _, column := range columns {
+		if !identifierPattern.MatchString(column) || strings.Contains(column, ".") {
+			return nil, nil, fmt.Errorf("invalid SQL sink column name %q", column)
+		}
+	}
+
+	return fields, columns, nil
+}
+
+// columnName derives a column name from a result field
+func columnName(field string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, field)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// sqlValue converts a result value to a driver value; objects and arrays are stored as JSON
+func sqlValue(val any) any {
+	switch val.(type) {
+	case map[string]any, []any:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	default:
+		return val
+	}
+}
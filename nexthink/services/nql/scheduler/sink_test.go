@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deploymenttheory/go-api-sdk-nexthink/nexthink/services/nql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testResult() *Result {
+	return &Result{
+		QueryResult: &nql.QueryResult{
+			V2ResultSet: nql.NewV2ResultSet(&nql.ExecuteNQLV2Response{
+				Data: []map[string]any{
+					{"device.name": "device-01", "number_of_crashes": float64(3), "tags": []any{"vip"}},
+					{"device.name": "device-02", "number_of_crashes": float64(0), "tags": nil},
+				},
+			}),
+			Source: nql.QuerySourceExecute,
+		},
+		Job:       testJob("daily crashes"),
+		StartedAt: time.Date(2024, 2, 8, 6, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewWriterSink(&buf, "")
+	require.NoError(t, err)
+	sink.WriteOptions = &nql.WriteOptions{Columns: []string{"device.name", "number_of_crashes"}}
+
+	require.NoError(t, sink.Write(context.Background(), testResult()))
+	assert.Equal(t,
+		`{"device.name":"device-01","number_of_crashes":3}`+"\n"+
+			`{"device.name":"device-02","number_of_crashes":0}`+"\n",
+		buf.String())
+
+	_, err = NewWriterSink(&buf, "yaml")
+	assert.Error(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	sink, err := NewFileSink(dir, FormatCSV)
+	require.NoError(t, err)
+
+	result := testResult()
+	require.NoError(t, sink.Write(context.Background(), result))
+
+	path := sink.Path(result)
+	assert.Equal(t, filepath.Join(dir, "daily_crashes_20240208T060000Z.csv"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"device.name,number_of_crashes,tags\n"+
+			"device-01,3,\"[\"\"vip\"\"]\"\n"+
+			"device-02,0,\n",
+		string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	_, err = NewFileSink("", FormatCSV)
+	assert.Error(t, err)
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		gotHeaders http.Header
+		gotBody    []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink, err := NewHTTPSink(server.URL, FormatJSON)
+	require.NoError(t, err)
+	sink.Headers = map[string]string{"Authorization": "Bearer token"}
+
+	require.NoError(t, sink.Write(context.Background(), testResult()))
+	assert.Equal(t, "application/json", gotHeaders.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", gotHeaders.Get("Authorization"))
+	assert.Equal(t, "daily crashes", gotHeaders.Get("X-NQL-Job"))
+	assert.Equal(t, "#crashes", gotHeaders.Get("X-NQL-Query-ID"))
+	assert.JSONEq(t,
+		`[{"device.name":"device-01","number_of_crashes":3,"tags":["vip"]},{"device.name":"device-02","number_of_crashes":0,"tags":null}]`,
+		string(gotBody))
+}
+
+func TestHTTPSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ingest unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink, err := NewHTTPSink(server.URL, "")
+	require.NoError(t, err)
+
+	err = sink.Write(context.Background(), testResult())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 503: ingest unavailable")
+}
+
+func TestSQLSink(t *testing.T) {
+	db, rec := openRecordingDB(t)
+
+	sink, err := NewSQLSink(db, "reporting.device_crashes")
+	require.NoError(t, err)
+	sink.JobColumn = "job"
+	sink.RunAtColumn = "run_at"
+	sink.Placeholder = DollarPlaceholder
+
+	result := testResult()
+	require.NoError(t, sink.Write(context.Background(), result))
+
+	assert.Equal(t, []string{
+		"INSERT INTO reporting.device_crashes (device_name, number_of_crashes, tags, job, run_at) VALUES ($1, $2, $3, $4, $5)",
+	}, rec.prepared)
+	assert.Equal(t, [][]driver.Value{
+		{"device-01", float64(3), `["vip"]`, "daily crashes", result.StartedAt},
+		{"device-02", float64(0), nil, "daily crashes", result.StartedAt},
+	}, rec.executed)
+	assert.Equal(t, 1, rec.commits)
+}
+
+func TestSQLSink_ColumnMapping(t *testing.T) {
+	db, rec := openRecordingDB(t)
+
+	sink, err := NewSQLSink(db, "crashes")
+	require.NoError(t, err)
+	sink.Columns = map[string]string{"device.name": "device", "number_of_crashes": "crashes"}
+
+	require.NoError(t, sink.Write(context.Background(), testResult()))
+	assert.Equal(t, []string{"INSERT INTO crashes (device, crashes) VALUES (?, ?)"}, rec.prepared)
+
+	sink.Columns = map[string]string{"device.name": "device; DROP TABLE crashes"}
+	assert.Error(t, sink.Write(context.Background(), testResult()))
+}
+
+func TestSQLSink_Rollback(t *testing.T) {
+	db, rec := openRecordingDB(t)
+	rec.execErr = errors.New("constraint violation")
+
+	sink, err := NewSQLSink(db, "crashes")
+	require.NoError(t, err)
+
+	err = sink.Write(context.Background(), testResult())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to insert row 0")
+	assert.Equal(t, 0, rec.commits)
+	assert.Equal(t, 1, rec.rollbacks)
+
+	_, err = NewSQLSink(db, "crashes; --")
+	assert.Error(t, err)
+}
+
+// =============================================================================
+// Recording SQL driver
+// =============================================================================
+
+// recordingDriver is a minimal database/sql driver that records statements
+type recordingDriver struct {
+	mu        sync.Mutex
+	prepared  []string
+	executed  [][]driver.Value
+	commits   int
+	rollbacks int
+	execErr   error
+}
+
+var (
+	recordingDriversMu sync.Mutex
+	recordingDrivers   = map[string]*recordingDriver{}
+)
+
+func init() {
+	sql.Register("scheduler-recording", recordingConnector{})
+}
+
+// openRecordingDB opens a database backed by a fresh recording driver
+func openRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+
+	rec := &recordingDriver{}
+	recordingDriversMu.Lock()
+	recordingDrivers[t.Name()] = rec
+	recordingDriversMu.Unlock()
+
+	db, err := sql.Open("scheduler-recording", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, rec
+}
+
+type recordingConnector struct{}
+
+func (recordingConnector) Open(name string) (driver.Conn, error) {
+	recordingDriversMu.Lock()
+	defer recordingDriversMu.Unlock()
+	return &recordingConn{rec: recordingDrivers[name]}, nil
+}
+
+type recordingConn struct {
+	rec *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.rec.prepared = append(c.rec.prepared, query)
+	return &recordingStmt{rec: c.rec}, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *recordingConn) Commit() error {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.rec.commits++
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.rec.rollbacks++
+	return nil
+}
+
+type recordingStmt struct {
+	rec *recordingDriver
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	if s.rec.execErr != nil {
+		return nil, s.rec.execErr
+	}
+	s.rec.executed = append(s.rec.executed, append([]driver.Value(nil), args...))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
@@ -0,0 +1,248 @@
+package nql
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Statement splitting for raw NQL query text
+// Breaks a query into its pipe-separated statements while tracking source positions
+
+// =============================================================================
+// Statement
+// =============================================================================
+
+// Statement represents a single pipe-separated statement of an NQL query
+type Statement struct {
+	// Keyword is the lower-cased statement keyword (where, list, summarize, ...)
+	// The leading table selection statement has an empty keyword
+	Keyword string
+
+	// Body is the statement text following the keyword, with whitespace collapsed
+	Body string
+
+	// Line is the 1-based line on which the statement starts
+	Line int
+
+	// Column is the 1-based column on which the statement starts
+	Column int
+}
+
+// IsTableSelection reports whether the statement is the leading table selection
+func (s Statement) IsTableSelection() bool {
+	return s.Keyword == ""
+}
+
+// String returns the statement as NQL text
+func (s Statement) String() string {
+	if s.IsTableSelection() {
+		return s.Body
+	}
+	if s.Body == "" {
+		return s.Keyword
+	}
+	return s.Keyword + " " + s.Body
+}
+
+// QueryComment represents a /* ... */ comment block found in an NQL query
+type QueryComment struct {
+	// Text is the trimmed comment content without the delimiters
+	Text string
+
+	// Line is the 1-based line on which the comment starts
+	Line int
+
+	// Column is the 1-based column on which the comment starts
+	Column int
+
+	// Standalone is true when no query code shares the comment's line
+	Standalone bool
+}
+
+// whitespacePattern matches runs of whitespace for body normalisation
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// =============================================================================
+// Splitting
+// =============================================================================
+
+// SplitStatements splits an NQL query into statements and comments
+//
+// Pipes inside string literals and comments are ignored. Comments are removed
+// from statement bodies but reported separately with their line numbers.
+func SplitStatements(query string) ([]Statement, []QueryComment) {
+	code, comments := stripComments(query)
+
+	var statements []Statement
+	line, col := 1, 1
+	segStart, segLine, segCol := 0, 1, 1
+	var quote rune
+
+	flush := func(end int) {
+		segment := code[segStart:end]
+		trimmedLeft := strings.TrimLeft(segment, " \t\r\n")
+		if strings.TrimSpace(segment) == "" {
+			return
+		}
+
+		// Advance the start position past leading whitespace
+		startLine, startCol := segLine, segCol
+		for _, r := range segment[:len(segment)-len(trimmedLeft)] {
+			if r == '\n' {
+				startLine++
+				startCol = 1
+			} else {
+				startCol++
+			}
+		}
+
+		text := strings.TrimSpace(whitespacePattern.ReplaceAllString(segment, " "))
+		stmt := Statement{Line: startLine, Column: startCol}
+
+		// Only the first statement is a table selection; subsequent ones carry a keyword
+		if len(statements) > 0 {
+			keyword, body, _ := strings.Cut(text, " ")
+			stmt.Keyword = strings.ToLower(keyword)
+			stmt.Body = strings.TrimSpace(body)
+		} else {
+			stmt.Body = text
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	for i, r := range code {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '|':
+			flush(i)
+			segStart, segLine, segCol = i+1, line, col+1
+		}
+
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	flush(len(code))
+
+	// Mark comments that sit on a line without code
+	codeLines := make(map[int]bool)
+	for i, l := range strings.Split(code, "\n") {
+		if strings.TrimSpace(l) != "" {
+			codeLines[i+1] = true
+		}
+	}
+	for i := range comments {
+		comments[i].Standalone = !codeLines[comments[i].Line]
+	}
+
+	return statements, comments
+}
+
+// stripComments replaces comment blocks with spaces (preserving newlines so
+// positions stay stable) and returns the extracted comments
+func stripComments(query string) (string, []QueryComment) {
+	var (
+		b        strings.Builder
+		comments []QueryComment
+		quote    byte
+		line     = 1
+		lineHead int
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote == 0 && c == '/' && i+1 < len(query) && query[i+1] == '*' {
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			raw := query[i:min(i+2+end+2, len(query))]
+			comments = append(comments, QueryComment{
+				Text:   strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(raw, "/*"), "*/")),
+				Line:   line,
+				Column: utf8.RuneCountInString(query[lineHead:i]) + 1,
+			})
+			for j := 0; j < len(raw); j++ {
+				if raw[j] == '\n' {
+					b.WriteByte('\n')
+					line++
+					lineHead = i + j + 1
+				} else {
+					b.WriteByte(' ')
+				}
+			}
+			i += len(raw) - 1
+			continue
+		}
+
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == '\n':
+			line++
+			lineHead = i + 1
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String(), comments
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// splitTableSelection splits a table selection statement into table and time selection
+// Example: "devices during past 7d" -> ("devices", "during past 7d")
+func splitTableSelection(body string) (string, string) {
+	table, rest, _ := strings.Cut(strings.TrimSpace(body), " ")
+	return table, strings.TrimSpace(rest)
+}
+
+// splitTopLevel splits a comma separated list, ignoring commas inside
+// parentheses, brackets and string literals
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		quote rune
+		start int
+	)
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+
+	return parts
+}
@@ -0,0 +1,613 @@
+package nql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// User-defined query templates
+// Templates are declared in YAML or JSON with typed, defaulted and validated
+// parameters and render into a QueryBuilder:
+//
+//	templates:
+//	  - name: DevicesWithCrashes
+//	    description: Devices with application crashes
+//	    category: devices
+//	    parameters:
+//	      - {name: period, type: time_selection, default: during past 7d}
+//	      - {name: binary_name, type: string}
+//	    query:
+//	      from: devices
+//	      with: ["execution.crashes {{period}}"]
+//	      compute: ["total_crashes = number_of_crashes.sum()"]
+//	      where: ["binary.name == {{binary_name}}"]
+//	      sort: total_crashes desc
+//
+// A clause referencing an optional parameter that has no value and no default
+// is left out, so optional filters need no extra syntax.
+
+// =============================================================================
+// Parameter Types
+// =============================================================================
+
+// TemplateParamType is the type of a template parameter
+type TemplateParamType string
+
+const (
+	// TemplateParamString renders as a quoted NQL string literal
+	TemplateParamString TemplateParamType = "string"
+
+	// TemplateParamIdentifier renders a field, table or name fragment as is
+	TemplateParamIdentifier TemplateParamType = "identifier"
+
+	TemplateParamInt   TemplateParamType = "int"
+	TemplateParamFloat TemplateParamType = "float"
+	TemplateParamBool  TemplateParamType = "bool"
+
+	// TemplateParamDuration renders an NQL duration (15min, 2h, 7d)
+	// Accepts duration strings and time.Duration values.
+	TemplateParamDuration TemplateParamType = "duration"
+
+	// TemplateParamTimeSelection renders a time selection ("during past 7d")
+	TemplateParamTimeSelection TemplateParamType = "time_selection"
+)
+
+var (
+	// templateNamePattern matches template names
+	templateNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+	// templateParamNamePattern matches parameter names
+	templateParamNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// templatePlaceholderPattern matches {{parameter}} placeholders
+	templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+	// templateIdentifierPattern matches identifier parameter values
+	templateIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+	// templateAliasPattern matches the "alias =" prefix of compute and summarize clauses
+	templateAliasPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*=([^=].*)$`)
+
+	// nqlDurationPattern matches NQL duration literals
+	nqlDurationPattern = regexp.MustCompile(`^\d+(min|h|d)$`)
+)
+
+// ErrTemplateParameter is wrapped by errors about template parameter values
+var ErrTemplateParameter = errors.New("invalid template parameter")
+
+// =============================================================================
+// Definitions
+// =============================================================================
+
+// TemplateParameter declares a template parameter
+type TemplateParameter struct {
+	// Name is referenced in the query as {{name}}
+	Name string `yaml:"name" json:"name"`
+
+	// Type defaults to string
+	Type TemplateParamType `yaml:"type,omitempty" json:"type,omitempty"`
+
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Required parameters must be supplied when rendering
+	Required bool `yaml:"required,omitempty" json:"required,omitempty"`
+
+	// Default is used when the parameter is not supplied
+	Default any `yaml:"default,omitempty" json:"default,omitempty"`
+
+	// Values restricts the parameter to a set of values
+	Values []string `yaml:"values,omitempty" json:"values,omitempty"`
+
+	// Min and Max bound int and float parameters
+	Min *float64 `yaml:"min,omitempty" json:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty" json:"max,omitempty"`
+
+	// Pattern is a regular expression string and identifier values must match
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+
+	// pattern is Pattern compiled by validate
+	pattern *regexp.Regexp
+}
+
+// TemplateQuery declares the clauses of a template query
+// Each field maps to the QueryBuilder method of the same name. Compute and
+// summarize clauses are written as "alias = expression"; sort as "field [asc|desc]".
+type TemplateQuery struct {
+	From        string   `yaml:"from" json:"from"`
+	During      string   `yaml:"during,omitempty" json:"during,omitempty"`
+	With        []string `yaml:"with,omitempty" json:"with,omitempty"`
+	Include     []string `yaml:"include,omitempty" json:"include,omitempty"`
+	Compute     []string `yaml:"compute,omitempty" json:"compute,omitempty"`
+	Where       []string `yaml:"where,omitempty" json:"where,omitempty"`
+	List        []string `yaml:"list,omitempty" json:"list,omitempty"`
+	Summarize   []string `yaml:"summarize,omitempty" json:"summarize,omitempty"`
+	SummarizeBy []string `yaml:"summarize_by,omitempty" json:"summarize_by,omitempty"`
+	Sort        string   `yaml:"sort,omitempty" json:"sort,omitempty"`
+	Limit       string   `yaml:"limit,omitempty" json:"limit,omitempty"`
+	Comment     string   `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// TemplateDefinition is a declarative query template
+type TemplateDefinition struct {
+	Name        string              `yaml:"name" json:"name"`
+	Description string              `yaml:"description,omitempty" json:"description,omitempty"`
+	Category    string              `yaml:"category,omitempty" json:"category,omitempty"`
+	Parameters  []TemplateParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Query       TemplateQuery       `yaml:"query" json:"query"`
+}
+
+// templateDocument is the layout of template files
+type templateDocument struct {
+	Templates []*TemplateDefinition `yaml:"templates" json:"templates"`
+}
+
+// ParseTemplateDefinitions reads template definitions from YAML or JSON
+// The document holds a top-level "templates" list. Unknown fields are errors
+// and every definition is validated.
+func ParseTemplateDefinitions(r io.Reader) ([]*TemplateDefinition, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var doc templateDocument
+	if err := decoder.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	for i, def := range doc.Templates {
+		if def == nil {
+			return nil, fmt.Errorf("template %d is empty", i)
+		}
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return doc.Templates, nil
+}
+
+// =============================================================================
+// Validation
+// =============================================================================
+
+// Validate checks the definition, its parameters and their defaults, and that
+// every placeholder refers to a declared parameter
+func (d *TemplateDefinition) Validate() error {
+	if !templateNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid template name %q", d.Name)
+	}
+
+	declared := make(map[string]bool, len(d.Parameters))
+	for i := range d.Parameters {
+		p := &d.Parameters[i]
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("template %s: %w", d.Name, err)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf("template %s: parameter %s is declared twice", d.Name, p.Name)
+		}
+		declared[p.Name] = true
+	}
+
+	q := d.Query
+	if strings.TrimSpace(q.From) == "" {
+		return fmt.Errorf("template %s: query.from is required", d.Name)
+	}
+	for _, clause := range d.clauses() {
+		for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(clause, -1) {
+			if !declared[match[1]] {
+				return fmt.Errorf("template %s: placeholder {{%s}} refers to an undeclared parameter", d.Name, match[1])
+			}
+		}
+	}
+	for _, clause := range slices.Concat(q.Compute, q.Summarize) {
+		if !templateAliasPattern.MatchString(clause) {
+			return fmt.Errorf("template %s: clause %q must have the form \"alias = expression\"", d.Name, clause)
+		}
+	}
+	if q.Sort != "" {
+		if _, _, err := parseTemplateSort(q.Sort); err != nil {
+			return fmt.Errorf("template %s: %w", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// clone returns a deep copy of the definition
+func (d *TemplateDefinition) clone() *TemplateDefinition {
+	copied := *d
+	copied.Parameters = slices.Clone(d.Parameters)
+	for i := range copied.Parameters {
+		p := &copied.Parameters[i]
+		p.Values = slices.Clone(p.Values)
+		if p.Min != nil {
+			minValue := *p.Min
+			p.Min = &minValue
+		}
+		if p.Max != nil {
+			maxValue := *p.Max
+			p.Max = &maxValue
+		}
+	}
+
+	q := &copied.Query
+	q.With = slices.Clone(q.With)
+	q.Include = slices.Clone(q.Include)
+	q.Compute = slices.Clone(q.Compute)
+	q.Where = slices.Clone(q.Where)
+	q.List = slices.Clone(q.List)
+	q.Summarize = slices.Clone(q.Summarize)
+	q.SummarizeBy = slices.Clone(q.SummarizeBy)
+	return &copied
+}
+
+// clauses returns every templated clause of the query
+func (d *TemplateDefinition) clauses() []string {
+	q := d.Query
+	clauses := []string{q.From, q.During, q.Sort, q.Limit, q.Comment}
+	return slices.Concat(clauses, q.With, q.Include, q.Compute, q.Where, q.List, q.Summarize, q.SummarizeBy)
+}
+
+// kind returns the parameter type, defaulting to string
+func (p *TemplateParameter) kind() TemplateParamType {
+	if p.Type == "" {
+		return TemplateParamString
+	}
+	return p.Type
+}
+
+// validate checks the parameter declaration and its default
+func (p *TemplateParameter) validate() error {
+	if !templateParamNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid parameter name %q", p.Name)
+	}
+
+	switch p.kind() {
+	case TemplateParamString, TemplateParamIdentifier, TemplateParamInt, TemplateParamFloat,
+		TemplateParamBool, TemplateParamDuration, TemplateParamTimeSelection:
+	default:
+		return fmt.Errorf("parameter %s has unknown type %q", p.Name, p.Type)
+	}
+
+	p.pattern = nil
+	if p.Pattern != "" {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("parameter %s has invalid pattern: %w", p.Name, err)
+		}
+		p.pattern = re
+	}
+	if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+		return fmt.Errorf("parameter %s has min greater than max", p.Name)
+	}
+	if p.Default != nil {
+		if _, err := p.render(p.Default, true); err != nil {
+			return fmt.Errorf("parameter %s has invalid default: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Rendering
+// =============================================================================
+
+// Render builds a QueryBuilder from the definition and parameter values
+// Missing parameters take their defaults; unknown parameters, missing required
+// parameters and invalid values are errors wrapping ErrTemplateParameter.
+func (d *TemplateDefinition) Render(params map[string]any) (*QueryBuilder, error) {
+	return d.render(params, true)
+}
+
+// render builds a QueryBuilder from the definition
+// Without checked, int and float values skip the Min and Max constraints and
+// time selections are inserted as given, as the Templates methods always did.
+func (d *TemplateDefinition) render(params map[string]any, checked bool) (*QueryBuilder, error) {
+	values, err := d.resolve(params, checked)
+	if err != nil {
+		return nil, err
+	}
+	expand := func(text string) (string, bool) {
+		return expandTemplate(text, values)
+	}
+
+	q := d.Query
+	qb := NewQueryBuilder()
+
+	from, ok := expand(q.From)
+	if !ok {
+		return nil, fmt.Errorf("template %s: query.from references a parameter without a value", d.Name)
+	}
+	qb.From(from)
+
+	if comment, ok := expand(q.Comment); ok && comment != "" {
+		qb.Comment(comment)
+	}
+	if during, ok := expand(q.During); ok && during != "" {
+		qb.During(during)
+	}
+	for _, clause := range expandAll(q.With, values) {
+		qb.With(clause)
+	}
+	for _, clause := range expandAll(q.Include, values) {
+		qb.Include(clause)
+	}
+	for _, clause := range expandAll(q.Compute, values) {
+		alias, expr := splitTemplateAlias(clause)
+		qb.Compute(alias, expr)
+	}
+	for _, clause := range expandAll(q.Where, values) {
+		qb.Where(clause)
+	}
+	if fields := expandAll(q.List, values); len(fields) > 0 {
+		qb.List(fields...)
+	}
+	for _, clause := range expandAll(q.Summarize, values) {
+		alias, expr := splitTemplateAlias(clause)
+		qb.Summarize(alias, expr)
+	}
+	if fields := expandAll(q.SummarizeBy, values); len(fields) > 0 {
+		qb.SummarizeBy(fields...)
+	}
+	if sort, ok := expand(q.Sort); ok && sort != "" {
+		field, direction, err := parseTemplateSort(sort)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", d.Name, err)
+		}
+		qb.Sort(field, direction)
+	}
+	if limit, ok := expand(q.Limit); ok && limit != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("template %s: invalid limit %q", d.Name, limit)
+		}
+		qb.Limit(n)
+	}
+
+	return qb, nil
+}
+
+// Template renders the definition into a Template
+func (d *TemplateDefinition) Template(params map[string]any) (*Template, error) {
+	qb, err := d.Render(params)
+	if err != nil {
+		return nil, err
+	}
+	return newTemplate(qb), nil
+}
+
+// resolve converts the supplied parameters and defaults into rendered values
+// Parameters without a value are absent from the result.
+func (d *TemplateDefinition) resolve(params map[string]any, checked bool) (map[string]string, error) {
+	declared := make(map[string]bool, len(d.Parameters))
+	values := make(map[string]string, len(d.Parameters))
+
+	for i := range d.Parameters {
+		p := &d.Parameters[i]
+		declared[p.Name] = true
+
+		v, ok := params[p.Name]
+		if !ok || v == nil {
+			v = p.Default
+		}
+		if v == nil {
+			if p.Required {
+				return nil, fmt.Errorf("%w: template %s: parameter %s is required", ErrTemplateParameter, d.Name, p.Name)
+			}
+			continue
+		}
+
+		rendered, err := p.render(v, checked)
+		if err != nil {
+			return nil, fmt.Errorf("%w: template %s: parameter %s: %w", ErrTemplateParameter, d.Name, p.Name, err)
+		}
+		values[p.Name] = rendered
+	}
+
+	for name := range params {
+		if !declared[name] {
+			return nil, fmt.Errorf("%w: template %s has no parameter %s", ErrTemplateParameter, d.Name, name)
+		}
+	}
+	return values, nil
+}
+
+// render validates a parameter value and returns its NQL text
+// See TemplateDefinition.render for the checks skipped without checked.
+func (p *TemplateParameter) render(v any, checked bool) (string, error) {
+	switch p.kind() {
+	case TemplateParamInt:
+		n, err := coerceInt64(v)
+		if err != nil {
+			return "", err
+		}
+		if checked {
+			if err := p.checkRange(float64(n)); err != nil {
+				return "", err
+			}
+		}
+		return p.checkValue(strconv.FormatInt(n, 10))
+
+	case TemplateParamFloat:
+		f, err := coerceFloat64(v)
+		if err != nil {
+			return "", err
+		}
+		if checked {
+			if err := p.checkRange(f); err != nil {
+				return "", err
+			}
+		}
+		return p.checkValue(strconv.FormatFloat(f, 'f', -1, 64))
+
+	case TemplateParamBool:
+		b, err := coerceBool(v)
+		if err != nil {
+			return "", err
+		}
+		return p.checkValue(strconv.FormatBool(b))
+
+	case TemplateParamDuration:
+		s, err := nqlDuration(v)
+		if err != nil {
+			return "", err
+		}
+		return p.checkValue(s)
+
+	case TemplateParamIdentifier:
+		s := strings.TrimSpace(coerceString(v))
+		if !templateIdentifierPattern.MatchString(s) {
+			return "", fmt.Errorf("%q is not a valid identifier", s)
+		}
+		return p.checkValue(s)
+
+	case TemplateParamTimeSelection:
+		s := strings.TrimSpace(coerceString(v))
+		if !checked {
+			return s, nil
+		}
+		if strings.ContainsAny(s, "|\n\"'") || strings.Contains(s, "/*") {
+			return "", fmt.Errorf("%q is not a valid time selection", s)
+		}
+		if err := NewQueryValidator().ValidateTimeSelection(s); err != nil {
+			return "", err
+		}
+		return p.checkValue(s)
+
+	default:
+		s, err := p.checkValue(coerceString(v))
+		if err != nil {
+			return "", err
+		}
+		return quoteNQLString(s)
+	}
+}
+
+// checkValue applies the Values and Pattern constraints
+func (p *TemplateParameter) checkValue(s string) (string, error) {
+	if len(p.Values) > 0 {
+		if !slices.Contains(p.Values, s) {
+			return "", fmt.Errorf("%q is not one of %s", s, strings.Join(p.Values, ", "))
+		}
+	}
+	if p.Pattern != "" {
+		// Definitions that were not validated, or changed since, compile on use
+		re := p.pattern
+		if re == nil || re.String() != p.Pattern {
+			var err error
+			if re, err = regexp.Compile(p.Pattern); err != nil {
+				return "", err
+			}
+		}
+		if !re.MatchString(s) {
+			return "", fmt.Errorf("%q does not match %s", s, p.Pattern)
+		}
+	}
+	return s, nil
+}
+
+// checkRange applies the Min and Max constraints
+func (p *TemplateParameter) checkRange(f float64) error {
+	if p.Min != nil && f < *p.Min {
+		return fmt.Errorf("%v is less than the minimum %v", f, *p.Min)
+	}
+	if p.Max != nil && f > *p.Max {
+		return fmt.Errorf("%v is greater than the maximum %v", f, *p.Max)
+	}
+	return nil
+}
+
+// nqlDuration converts a duration string or time.Duration to an NQL duration literal
+func nqlDuration(v any) (string, error) {
+	if d, ok := v.(time.Duration); ok {
+		switch {
+		case d <= 0 || d%time.Minute != 0:
+			return "", fmt.Errorf("duration %s must be a positive whole number of minutes", d)
+		case d%(24*time.Hour) == 0:
+			return fmt.Sprintf("%dd", d/(24*time.Hour)), nil
+		case d%time.Hour == 0:
+			return fmt.Sprintf("%dh", d/time.Hour), nil
+		default:
+			return fmt.Sprintf("%dmin", d/time.Minute), nil
+		}
+	}
+
+	s := strings.TrimSpace(coerceString(v))
+	if !nqlDurationPattern.MatchString(s) {
+		return "", fmt.Errorf("%q is not an NQL duration (e.g. 15min, 2h, 7d)", s)
+	}
+	return s, nil
+}
+
+// quoteNQLString quotes a value as an NQL string literal
+// Values containing both quote characters cannot be represented.
+func quoteNQLString(s string) (string, error) {
+	switch {
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`, nil
+	case !strings.Contains(s, `'`):
+		return `'` + s + `'`, nil
+	default:
+		return "", fmt.Errorf("string %q contains both quote characters", s)
+	}
+}
+
+// expandTemplate replaces {{name}} placeholders with their rendered values
+// ok is false when a placeholder has no value.
+func expandTemplate(text string, values map[string]string) (string, bool) {
+	ok := true
+	out := templatePlaceholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := templatePlaceholderPattern.FindStringSubmatch(match)[1]
+		value, found := values[name]
+		if !found {
+			ok = false
+		}
+		return value
+	})
+	return strings.TrimSpace(out), ok
+}
+
+// expandAll expands clauses, leaving out those with unset placeholders
+func expandAll(clauses []string, values map[string]string) []string {
+	out := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		if expanded, ok := expandTemplate(clause, values); ok && expanded != "" {
+			out = append(out, expanded)
+		}
+	}
+	return out
+}
+
+// splitTemplateAlias splits an "alias = expression" clause
+func splitTemplateAlias(clause string) (string, string) {
+	match := templateAliasPattern.FindStringSubmatch(clause)
+	if match == nil {
+		return clause, ""
+	}
+	return match[1], strings.TrimSpace(match[2])
+}
+
+// parseTemplateSort parses a "field [asc|desc]" sort clause
+func parseTemplateSort(sort string) (string, SortDirection, error) {
+	fields := strings.Fields(sort)
+	switch {
+	case len(fields) == 1:
+		return fields[0], SortAsc, nil
+	case len(fields) == 2 && strings.EqualFold(fields[1], string(SortAsc)):
+		return fields[0], SortAsc, nil
+	case len(fields) == 2 && strings.EqualFold(fields[1], string(SortDesc)):
+		return fields[0], SortDesc, nil
+	default:
+		return "", "", fmt.Errorf("invalid sort %q, expected \"field [asc|desc]\"", sort)
+	}
+}
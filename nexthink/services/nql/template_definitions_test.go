@@ -0,0 +1,138 @@
+package nql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTemplateDefinition(t *testing.T, src string) *TemplateDefinition {
+	t.Helper()
+	defs, err := ParseTemplateDefinitions(strings.NewReader(src))
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	return defs[0]
+}
+
+func TestTemplateDefinition_ParameterTypes(t *testing.T) {
+	def := parseTemplateDefinition(t, `
+templates:
+  - name: Typed
+    parameters:
+      - {name: name, type: string}
+      - {name: field, type: identifier, values: [device.name, user.name]}
+      - {name: ratio, type: float, min: 0, max: 1}
+      - {name: active, type: bool}
+      - {name: bucket, type: duration}
+      - {name: period, type: time_selection, default: during past 7d}
+    query:
+      from: devices
+      during: "{{period}}"
+      where:
+        - "{{field}} == {{name}}"
+        - score >= {{ratio}}
+        - is_active == {{active}}
+      summarize: ["total = count()"]
+      summarize_by: ["{{bucket}}"]
+`)
+
+	qb, err := def.Render(map[string]any{
+		"name":   `O'Brien`,
+		"field":  "user.name",
+		"ratio":  0.25,
+		"active": "true",
+		"bucket": 6 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `devices during past 7d
+| where user.name == "O'Brien"
+| where score >= 0.25
+| where is_active == true
+| summarize total = count() by 6h`, qb.Build())
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{"unknown parameter", map[string]any{"nope": 1}, "has no parameter nope"},
+		{"value not allowed", map[string]any{"field": "device.uid"}, "is not one of"},
+		{"identifier injection", map[string]any{"field": "x | list"}, "not a valid identifier"},
+		{"below minimum", map[string]any{"ratio": -1}, "less than the minimum"},
+		{"above maximum", map[string]any{"ratio": "1.5"}, "greater than the maximum"},
+		{"bad bool", map[string]any{"active": "maybe"}, "cannot convert"},
+		{"bad duration", map[string]any{"bucket": "7 days"}, "not an NQL duration"},
+		{"sub-minute duration", map[string]any{"bucket": 30 * time.Second}, "whole number of minutes"},
+		{"time selection injection", map[string]any{"period": "during past 7d | list x"}, "not a valid time selection"},
+		{"both quotes", map[string]any{"name": `a"b'c`}, "both quote characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := def.Render(tt.params)
+			require.ErrorIs(t, err, ErrTemplateParameter)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestTemplateDefinition_OptionalAndRequired(t *testing.T) {
+	def := parseTemplateDefinition(t, `
+templates:
+  - name: Filtered
+    parameters:
+      - {name: platform, type: string}
+      - {name: limit, type: int, required: true}
+    query:
+      from: devices
+      where: ["operating_system.platform == {{platform}}"]
+      list: [device.name]
+      limit: "{{limit}}"
+`)
+
+	qb, err := def.Render(map[string]any{"limit": 10})
+	require.NoError(t, err)
+	assert.Equal(t, "devices\n| list device.name\n| limit 10", qb.Build())
+
+	_, err = def.Render(nil)
+	assert.ErrorIs(t, err, ErrTemplateParameter)
+	assert.ErrorContains(t, err, "limit is required")
+
+	tmpl, err := def.Template(map[string]any{"limit": 5, "platform": "macOS"})
+	require.NoError(t, err)
+	assert.Contains(t, tmpl.Query(), `where operating_system.platform == "macOS"`)
+	assert.NotNil(t, tmpl.QueryBuilder())
+}
+
+func TestParseTemplateDefinitions_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unknown field", "templates:\n  - name: A\n    query: {from: devices}\n    owner: me\n", "field owner not found"},
+		{"missing from", "templates:\n  - name: A\n    query: {list: [device.name]}\n", "query.from is required"},
+		{"bad name", "templates:\n  - name: 'a b'\n    query: {from: devices}\n", "invalid template name"},
+		{"undeclared placeholder", "templates:\n  - name: A\n    query: {from: devices, where: ['x == {{y}}']}\n", "undeclared parameter"},
+		{"unknown type", "templates:\n  - name: A\n    parameters: [{name: x, type: date}]\n    query: {from: devices}\n", "unknown type"},
+		{"invalid default", "templates:\n  - name: A\n    parameters: [{name: x, type: int, default: abc}]\n    query: {from: devices}\n", "invalid default"},
+		{"duplicate parameter", "templates:\n  - name: A\n    parameters: [{name: x}, {name: x}]\n    query: {from: devices}\n", "declared twice"},
+		{"bad compute", "templates:\n  - name: A\n    query: {from: devices, with: [web.errors], compute: ['x.sum()']}\n", "alias = expression"},
+		{"bad sort", "templates:\n  - name: A\n    query: {from: devices, sort: 'x sideways'}\n", "invalid sort"},
+		{"min above max", "templates:\n  - name: A\n    parameters: [{name: x, type: int, min: 5, max: 1}]\n    query: {from: devices}\n", "min greater than max"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplateDefinitions(strings.NewReader(tt.src))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	defs, err := ParseTemplateDefinitions(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, defs)
+}
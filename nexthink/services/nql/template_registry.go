@@ -0,0 +1,239 @@
+package nql
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Template registry
+// Holds the built-in template definitions merged with user definitions loaded
+// from YAML or JSON files, and renders templates by name.
+
+// ErrTemplateNotFound is returned when no template has the requested name
+var ErrTemplateNotFound = errors.New("template not found")
+
+//go:embed templates_builtin.yaml
+var builtinTemplatesYAML []byte
+
+// builtinTemplateDefinitions parses the embedded built-in definitions once
+// The definitions are shared; registries hold copies.
+var builtinTemplateDefinitions = sync.OnceValues(func() ([]*TemplateDefinition, error) {
+	return ParseTemplateDefinitions(bytes.NewReader(builtinTemplatesYAML))
+})
+
+// builtinTemplate returns the shared built-in definition with the given name
+func builtinTemplate(name string) (*TemplateDefinition, error) {
+	defs, err := builtinTemplateDefinitions()
+	if err != nil {
+		return nil, fmt.Errorf("nql: invalid built-in templates: %w", err)
+	}
+	for _, def := range defs {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// =============================================================================
+// Registry
+// =============================================================================
+
+// TemplateInfo describes a registered template
+type TemplateInfo struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Category    string              `json:"category,omitempty"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty"`
+	BuiltIn     bool                `json:"built_in"`
+}
+
+// TemplateRegistry holds template definitions by name
+// The registry keeps its own copies: changing a definition after registering
+// it, or one returned by Get, does not affect the registry.
+type TemplateRegistry struct {
+	mu      sync.RWMutex
+	defs    map[string]*TemplateDefinition
+	builtIn map[string]bool
+}
+
+// NewTemplateRegistry creates an empty template registry
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{
+		defs:    make(map[string]*TemplateDefinition),
+		builtIn: make(map[string]bool),
+	}
+}
+
+// DefaultTemplateRegistry creates a registry holding the built-in templates
+// The built-ins have the names of the Templates methods and render the same queries.
+func DefaultTemplateRegistry() *TemplateRegistry {
+	defs, err := builtinTemplateDefinitions()
+	if err != nil {
+		// The embedded file is covered by tests
+		panic(fmt.Sprintf("nql: invalid built-in templates: %v", err))
+	}
+
+	r := NewTemplateRegistry()
+	for _, def := range defs {
+		r.defs[def.Name] = def.clone()
+		r.builtIn[def.Name] = true
+	}
+	return r
+}
+
+// Register validates and adds a definition
+// Returns an error if a template with the same name is already registered.
+func (r *TemplateRegistry) Register(def *TemplateDefinition) error {
+	if def == nil {
+		return fmt.Errorf("template definition cannot be nil")
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.defs[def.Name]; exists {
+		return fmt.Errorf("template %s is already registered", def.Name)
+	}
+	r.defs[def.Name] = def.clone()
+	return nil
+}
+
+// Load reads definitions from YAML or JSON and merges them into the registry
+// Loaded definitions replace registered ones with the same name, so files can
+// override built-in templates.
+func (r *TemplateRegistry) Load(reader io.Reader) error {
+	defs, err := ParseTemplateDefinitions(reader)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		if seen[def.Name] {
+			return fmt.Errorf("template %s is defined twice", def.Name)
+		}
+		seen[def.Name] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, def := range defs {
+		r.defs[def.Name] = def
+		delete(r.builtIn, def.Name)
+	}
+	return nil
+}
+
+// LoadFile merges the definitions of a YAML or JSON file into the registry
+func (r *TemplateRegistry) LoadFile(path string) error {
+	f, err := os.Open(path) //nolint:gosec // path is chosen by the caller
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := r.Load(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadDir merges every .yaml, .yml and .json file of a directory, in name order
+func (r *TemplateRegistry) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if err := r.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns a copy of the definition with the given name
+func (r *TemplateRegistry) Get(name string) (*TemplateDefinition, error) {
+	def, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return def.clone(), nil
+}
+
+// lookup returns the registered definition with the given name
+func (r *TemplateRegistry) lookup(name string) (*TemplateDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, ok := r.defs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return def, nil
+}
+
+// Names returns the registered template names in sorted order
+func (r *TemplateRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.defs))
+	for name := range r.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List describes the registered templates sorted by category and name
+func (r *TemplateRegistry) List() []TemplateInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]TemplateInfo, 0, len(r.defs))
+	for name, def := range r.defs {
+		infos = append(infos, TemplateInfo{
+			Name:        name,
+			Description: def.Description,
+			Category:    def.Category,
+			Parameters:  def.clone().Parameters,
+			BuiltIn:     r.builtIn[name],
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Category != infos[j].Category {
+			return infos[i].Category < infos[j].Category
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Render renders the named template with the given parameters
+func (r *TemplateRegistry) Render(name string, params map[string]any) (*Template, error) {
+	def, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return def.Template(params)
+}
@@ -0,0 +1,183 @@
+package nql
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplateRegistry_MatchesTemplates(t *testing.T) {
+	templates := NewTemplates()
+	registry := DefaultTemplateRegistry()
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   *Template
+	}{
+		{"DevicesWithCrashes", map[string]any{"period": "during past 7d", "binary_name": "outlook.exe"}, templates.DevicesWithCrashes("during past 7d", "outlook.exe")},
+		{"DevicesWithCrashes", map[string]any{"period": "during past 24h"}, templates.DevicesWithCrashes("during past 24h", "")},
+		{"DevicesWithHighMemoryUsage", map[string]any{"threshold": 85, "period": "during past 1d"}, templates.DevicesWithHighMemoryUsage(85, "during past 1d")},
+		{"DevicesByPlatform", nil, templates.DevicesByPlatform("during past 7d")},
+		{"DevicesWithSlowBootTime", map[string]any{"threshold": 45}, templates.DevicesWithSlowBootTime(45, "during past 7d")},
+		{"UsersWithWebErrors", map[string]any{"app_name": "Salesforce"}, templates.UsersWithWebErrors("during past 7d", "Salesforce")},
+		{"UsersWithPoorCollaborationQuality", nil, templates.UsersWithPoorCollaborationQuality("during past 7d")},
+		{"ApplicationsWithHighErrorRate", map[string]any{"min_page_views": 500}, templates.ApplicationsWithHighErrorRate(500, "during past 60min")},
+		{"TopCrashingApplications", map[string]any{"limit": 25}, templates.TopCrashingApplications("during past 7d", 25)},
+		{"WebPageLoadPerformance", map[string]any{"app_name": "Jira"}, templates.WebPageLoadPerformance("Jira", "during past 7d")},
+		{"WebPageLoadPerformance", nil, templates.WebPageLoadPerformance("", "during past 7d")},
+		{"NetworkConnectivityIssues", nil, templates.NetworkConnectivityIssues("during past 7d")},
+		{"OverallDEXScore", nil, templates.OverallDEXScore("during past 7d")},
+		{"DEXScoreByPlatform", nil, templates.DEXScoreByPlatform("during past 7d")},
+		{"UsersWithLowDEXScore", map[string]any{"threshold": 40}, templates.UsersWithLowDEXScore(40, "during past 7d")},
+		{"DEXScoreImpactByComponent", nil, templates.DEXScoreImpactByComponent("logon_speed", "during past 7d")},
+		{"DEXScoreImpactByComponent", map[string]any{"component": "boot_speed"}, templates.DEXScoreImpactByComponent("boot_speed", "during past 7d")},
+		{"DevicesWithSystemCrashes", nil, templates.DevicesWithSystemCrashes(3, "during past 7d")},
+		{"BinariesWithHighCrashRate", map[string]any{"threshold": 5}, templates.BinariesWithHighCrashRate(5, "during past 7d")},
+		{"WorkflowExecutionSuccess", nil, templates.WorkflowExecutionSuccess("during past 7d")},
+		{"RemoteActionSavingsEstimate", map[string]any{"cost_per_execution": 25}, templates.RemoteActionSavingsEstimate(25, "during past 7d")},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Render(tt.name, tt.params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Query(), got.Query())
+		})
+		covered[tt.name] = true
+	}
+
+	// Every built-in method has a definition of the same name
+	for _, name := range templates.GetAllTemplates() {
+		assert.True(t, covered[name], "no parity test for %s", name)
+	}
+	assert.Equal(t, len(templates.GetAllTemplates()), len(registry.Names()))
+}
+
+const customTemplatesYAML = `
+templates:
+  - name: DevicesWithCrashes
+    description: Overridden built-in
+    category: custom
+    parameters:
+      - name: window
+        type: duration
+        default: 7d
+    query:
+      from: devices
+      during: past {{window}}
+      list: [device.name]
+
+  - name: StaleDevices
+    description: Devices not seen recently
+    category: custom
+    parameters:
+      - name: days
+        type: int
+        required: true
+        min: 1
+    query:
+      from: devices
+      where: ["device.days_since_last_seen >= {{days}}"]
+      list: [device.name, device.last_seen]
+      sort: device.last_seen asc
+`
+
+func TestTemplateRegistry_LoadMergesAndOverrides(t *testing.T) {
+	registry := DefaultTemplateRegistry()
+	builtIns := len(registry.Names())
+
+	require.NoError(t, registry.Load(strings.NewReader(customTemplatesYAML)))
+	assert.Len(t, registry.Names(), builtIns+1)
+
+	tmpl, err := registry.Render("StaleDevices", map[string]any{"days": "30"})
+	require.NoError(t, err)
+	assert.Equal(t, "devices\n| where device.days_since_last_seen >= 30\n| list device.name, device.last_seen\n| sort device.last_seen asc", tmpl.Query())
+
+	tmpl, err = registry.Render("DevicesWithCrashes", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "devices past 7d\n| list device.name", tmpl.Query())
+
+	var overridden, custom *TemplateInfo
+	infos := registry.List()
+	for i := range infos {
+		switch infos[i].Name {
+		case "DevicesWithCrashes":
+			overridden = &infos[i]
+		case "StaleDevices":
+			custom = &infos[i]
+		}
+	}
+	require.NotNil(t, overridden)
+	require.NotNil(t, custom)
+	assert.False(t, overridden.BuiltIn)
+	assert.False(t, custom.BuiltIn)
+	assert.Equal(t, "Devices not seen recently", custom.Description)
+	assert.True(t, sort.SliceIsSorted(infos, func(i, j int) bool {
+		return infos[i].Category < infos[j].Category
+	}), "sorted by category")
+
+	_, err = registry.Render("Missing", nil)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateRegistry_RegisterAndLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "custom.yaml"), []byte(customTemplatesYAML), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "more.json"), []byte(`{"templates": [{"name": "AllUsers", "query": {"from": "users", "list": ["user.name"]}}]}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o600))
+
+	registry := NewTemplateRegistry()
+	require.NoError(t, registry.LoadDir(dir))
+	assert.Equal(t, []string{"AllUsers", "DevicesWithCrashes", "StaleDevices"}, registry.Names())
+
+	err := registry.Register(&TemplateDefinition{Name: "AllUsers", Query: TemplateQuery{From: "users"}})
+	assert.Error(t, err, "duplicate")
+	require.NoError(t, registry.Register(&TemplateDefinition{Name: "AllDevices", Query: TemplateQuery{From: "devices"}}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("templates:\n  - name: Broken\n    query: {from: devices, groupby: x}\n"), 0o600))
+	err = registry.LoadDir(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken.yaml")
+}
+
+func TestTemplateRegistry_DefinitionsAreCopies(t *testing.T) {
+	registry := DefaultTemplateRegistry()
+	want, err := registry.Render("DevicesWithCrashes", nil)
+	require.NoError(t, err)
+
+	def, err := registry.Get("DevicesWithCrashes")
+	require.NoError(t, err)
+	def.Query.From = "users"
+	def.Query.With[0] = "web.errors {{period}}"
+	def.Parameters[0].Default = "during past 1d"
+
+	got, err := DefaultTemplateRegistry().Render("DevicesWithCrashes", nil)
+	require.NoError(t, err)
+	assert.Equal(t, want.Query(), got.Query(), "built-ins are not shared")
+	got, err = registry.Render("DevicesWithCrashes", nil)
+	require.NoError(t, err)
+	assert.Equal(t, want.Query(), got.Query())
+	assert.Equal(t, want.Query(), NewTemplates().DevicesWithCrashes("during past 7d", "").Query())
+
+	custom := &TemplateDefinition{
+		Name:       "Platform",
+		Parameters: []TemplateParameter{{Name: "platform", Pattern: "^[A-Za-z]+$"}},
+		Query:      TemplateQuery{From: "devices", Where: []string{"operating_system.platform == {{platform}}"}},
+	}
+	require.NoError(t, registry.Register(custom))
+	require.NotNil(t, custom.Parameters[0].pattern, "patterns compile when validated")
+	custom.Parameters[0].Pattern = "^$"
+	custom.Query.Where[0] = "device.name == {{platform}}"
+
+	tmpl, err := registry.Render("Platform", map[string]any{"platform": "Windows"})
+	require.NoError(t, err)
+	assert.Equal(t, `devices
+| where operating_system.platform == "Windows"`, tmpl.Query())
+}
@@ -1,7 +1,5 @@
 package nql
 
-import "fmt"
-
 // Query templates provide pre-built queries for common use cases
 // Simplifies query construction for frequently used patterns
 
@@ -13,6 +11,7 @@ import "fmt"
 type Template struct {
 	query string
 	qb    *QueryBuilder
+	err   error
 }
 
 // Query returns the NQL query string (for creating in Nexthink admin)
@@ -41,6 +40,11 @@ func (t *Template) ToRequestWithPlatform(queryID, platform string) *ExecuteReque
 	}
 }
 
+// Err returns the error rendering the template, if any
+func (t *Template) Err() error {
+	return t.err
+}
+
 // newTemplate creates a new template from a QueryBuilder
 func newTemplate(qb *QueryBuilder) *Template {
 	return &Template{
@@ -54,6 +58,9 @@ func newTemplate(qb *QueryBuilder) *Template {
 // =============================================================================
 
 // Templates provides access to pre-built query templates
+// Each method renders the built-in definition of the same name (see
+// DefaultTemplateRegistry). Arguments the definition rejects, such as a
+// threshold out of range, give a template with an empty query and an Err.
 type Templates struct{}
 
 // NewTemplates creates a new templates instance
@@ -69,61 +76,29 @@ func NewTemplates() *Templates {
 // period: e.g., "during past 7d"
 // binaryName: e.g., "outlook.exe" (supports wildcards like "outlook*")
 func (t *Templates) DevicesWithCrashes(period, binaryName string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		With(fmt.Sprintf("execution.crashes %s", period)).
-		ComputeSum("total_crashes", "number_of_crashes")
-	
-	if binaryName != "" {
-		qb.WhereEquals(FieldBinaryName, binaryName)
-	}
-	
-	qb.SortDesc("total_crashes")
-	
-	return newTemplate(qb)
+	return t.render("DevicesWithCrashes", map[string]any{
+		"period":      period,
+		"binary_name": optionalString(binaryName),
+	})
 }
 
 // DevicesWithHighMemoryUsage returns devices with memory usage above threshold
 // threshold: memory threshold in GB (e.g., 90)
 // period: e.g., "during past 7d"
 func (t *Templates) DevicesWithHighMemoryUsage(threshold int, period string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		During(period).
-		Include(fmt.Sprintf("device_performance.events %s", period)).
-		Compute("memory_usage_ratio", "event.system_drive_usage.avg()/event.system_drive_capacity.avg()*100").
-		WhereGreaterEqual("memory_usage_ratio", fmt.Sprintf("%d", threshold)).
-		List("device.name", "memory_usage_ratio").
-		SortDesc("memory_usage_ratio")
-	
-	return newTemplate(qb)
+	return t.render("DevicesWithHighMemoryUsage", map[string]any{"threshold": threshold, "period": period})
 }
 
 // DevicesByPlatform returns device counts grouped by OS platform
 func (t *Templates) DevicesByPlatform(period string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		During(period).
-		SummarizeCount("device_count").
-		SummarizeBy(FieldOSPlatform).
-		SortDesc("device_count")
-	
-	return newTemplate(qb)
+	return t.render("DevicesByPlatform", map[string]any{"period": period})
 }
 
 // DevicesWithSlowBootTime returns devices with boot time above threshold
 // threshold: boot time threshold in seconds (e.g., 60)
 // period: e.g., "during past 7d"
 func (t *Templates) DevicesWithSlowBootTime(threshold int, period string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		With(fmt.Sprintf("session.logins %s", period)).
-		ComputeAvg("avg_boot_time", FieldTimeUntilDesktopIsVisible).
-		WhereGreaterEqual("avg_boot_time", fmt.Sprintf("%ds", threshold)).
-		List("device.name", "avg_boot_time").
-		SortDesc("avg_boot_time")
-	
-	return newTemplate(qb)
+	return t.render("DevicesWithSlowBootTime", map[string]any{"threshold": threshold, "period": period})
 }
 
 // =============================================================================
@@ -134,32 +109,15 @@ func (t *Templates) DevicesWithSlowBootTime(threshold int, period string) *Templ
 // period: e.g., "during past 7d"
 // appName: application name (optional, empty string for all apps)
 func (t *Templates) UsersWithWebErrors(period, appName string) *Template {
-	qb := NewQueryBuilder().
-		FromUsers().
-		With(fmt.Sprintf("web.errors %s", period)).
-		ComputeSum("total_errors", FieldNumberOfErrors)
-
-	if appName != "" {
-		qb.WhereEquals(FieldApplicationName, appName)
-	}
-
-	qb.List("user.name", "total_errors").
-		SortDesc("total_errors")
-	
-	return newTemplate(qb)
+	return t.render("UsersWithWebErrors", map[string]any{
+		"period":   period,
+		"app_name": optionalString(appName),
+	})
 }
 
 // UsersWithPoorCollaborationQuality returns users with poor collaboration quality
 func (t *Templates) UsersWithPoorCollaborationQuality(period string) *Template {
-	qb := NewQueryBuilder().
-		FromUsers().
-		With(fmt.Sprintf("collaboration.sessions %s", period)).
-		Where("session.audio.quality == poor or session.video.quality == poor").
-		ComputeCount("poor_sessions").
-		List("user.name", "poor_sessions").
-		SortDesc("poor_sessions")
-	
-	return newTemplate(qb)
+	return t.render("UsersWithPoorCollaborationQuality", map[string]any{"period": period})
 }
 
 // =============================================================================
@@ -170,33 +128,12 @@ func (t *Templates) UsersWithPoorCollaborationQuality(period string) *Template {
 // minPageViews: minimum page views threshold to avoid false positives
 // period: e.g., "during past 60min"
 func (t *Templates) ApplicationsWithHighErrorRate(minPageViews int, period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableApplications).
-		With(fmt.Sprintf("web.page_views %s", period)).
-		Where("is_soft_navigation = false").
-		ComputeSum("total_page_views", "number_of_page_views").
-		With(fmt.Sprintf("web.errors %s", period)).
-		ComputeSum("error_count", "error.number_of_errors").
-		Summarize("error_ratio", "error_count.sum() * 100 / total_page_views.sum()").
-		SummarizeBy(FieldApplicationName).
-		WhereGreater("total_page_views", fmt.Sprintf("%d", minPageViews)).
-		SortDesc("error_ratio")
-	
-	return newTemplate(qb)
+	return t.render("ApplicationsWithHighErrorRate", map[string]any{"min_page_views": minPageViews, "period": period})
 }
 
 // TopCrashingApplications returns applications with the most crashes
 func (t *Templates) TopCrashingApplications(period string, limit int) *Template {
-	qb := NewQueryBuilder().
-		From(TableExecutionCrashes).
-		During(period).
-		SummarizeCount("crash_count").
-		Summarize("device_count", "device.count()").
-		SummarizeBy(FieldBinaryName).
-		SortDesc("crash_count").
-		Limit(limit)
-	
-	return newTemplate(qb)
+	return t.render("TopCrashingApplications", map[string]any{"period": period, "limit": limit})
 }
 
 // =============================================================================
@@ -205,35 +142,15 @@ func (t *Templates) TopCrashingApplications(period string, limit int) *Template
 
 // WebPageLoadPerformance returns average page load times by application
 func (t *Templates) WebPageLoadPerformance(appName, period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableWebPageViews).
-		During(period)
-
-	if appName != "" {
-		qb.WhereEquals(FieldApplicationName, appName)
-	}
-
-	qb.Summarize("backend_time", "page_load_time.backend.avg()").
-		Summarize("network_time", "page_load_time.network.avg()").
-		Summarize("client_time", "page_load_time.client.avg()").
-		SummarizeBy(FieldApplicationName).
-		SortDesc("backend_time")
-	
-	return newTemplate(qb)
+	return t.render("WebPageLoadPerformance", map[string]any{
+		"app_name": optionalString(appName),
+		"period":   period,
+	})
 }
 
 // NetworkConnectivityIssues returns devices with connectivity issues
 func (t *Templates) NetworkConnectivityIssues(period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableConnectivityEvents).
-		During(period).
-		Where("wifi.signal_strength.avg <= -67 or wifi.noise_level.avg >= -80").
-		SummarizeAvg("avg_signal", "wifi.signal_strength").
-		SummarizeAvg("avg_noise", "wifi.noise_level").
-		SummarizeBy("device.name").
-		SortAsc("avg_signal")
-	
-	return newTemplate(qb)
+	return t.render("NetworkConnectivityIssues", map[string]any{"period": period})
 }
 
 // =============================================================================
@@ -242,66 +159,29 @@ func (t *Templates) NetworkConnectivityIssues(period string) *Template {
 
 // OverallDEXScore returns the overall DEX score for a population
 func (t *Templates) OverallDEXScore(period string) *Template {
-	qb := NewQueryBuilder().
-		FromUsers().
-		Include(fmt.Sprintf("dex.scores %s", period)).
-		ComputeAvg("dex_per_user", "value").
-		Where("dex_per_user != NULL").
-		SummarizeAvg("overall_dex", "dex_per_user")
-	
-	return newTemplate(qb)
+	return t.render("OverallDEXScore", map[string]any{"period": period})
 }
 
 // DEXScoreByPlatform returns DEX scores grouped by OS platform
 func (t *Templates) DEXScoreByPlatform(period string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		Include(fmt.Sprintf("dex.scores %s", period)).
-		ComputeAvg("dex_per_device", "value").
-		Where("dex_per_device != NULL").
-		SummarizeAvg("dex_score", "dex_per_device").
-		SummarizeBy(FieldOSPlatform).
-		SortDesc("dex_score")
-	
-	return newTemplate(qb)
+	return t.render("DEXScoreByPlatform", map[string]any{"period": period})
 }
 
 // UsersWithLowDEXScore returns users with DEX scores below threshold
 // threshold: DEX score threshold (e.g., 50)
 func (t *Templates) UsersWithLowDEXScore(threshold int, period string) *Template {
-	qb := NewQueryBuilder().
-		FromUsers().
-		With(fmt.Sprintf("dex.scores %s", period)).
-		ComputeAvg("user_dex", "value").
-		WhereLess("user_dex", fmt.Sprintf("%d", threshold)).
-		List("user.name", "user_dex").
-		SortAsc("user_dex")
-	
-	return newTemplate(qb)
+	return t.render("UsersWithLowDEXScore", map[string]any{"threshold": threshold, "period": period})
 }
 
 // DEXScoreImpactByComponent returns DEX score impact by component
+// Unknown components fall back to logon_speed.
 func (t *Templates) DEXScoreImpactByComponent(component, period string) *Template {
-	impactField := "endpoint.logon_speed_score_impact" // default
-
+	params := map[string]any{"period": period}
 	switch component {
-	case "boot_speed":
-		impactField = "endpoint.boot_speed_score_impact"
-	case "software_reliability":
-		impactField = "endpoint.software_reliability_score_impact"
-	case "virtual_session_lag":
-		impactField = "endpoint.virtual_session_lag_score_impact"
+	case "boot_speed", "software_reliability", "virtual_session_lag":
+		params["component"] = component
 	}
-
-	qb := NewQueryBuilder().
-		FromUsers().
-		Include(fmt.Sprintf("dex.scores %s", period)).
-		ComputeAvg("impact_per_user", impactField).
-		ComputeAvg("dex_per_user", "value").
-		Where("dex_per_user != NULL").
-		Summarize("total_impact", "(impact_per_user.avg()*countif(impact_per_user != NULL))/countif(dex_per_user != NULL)")
-	
-	return newTemplate(qb)
+	return t.render("DEXScoreImpactByComponent", params)
 }
 
 // =============================================================================
@@ -312,30 +192,13 @@ func (t *Templates) DEXScoreImpactByComponent(component, period string) *Templat
 // threshold: number of crashes (e.g., 3)
 // period: e.g., "during past 7d"
 func (t *Templates) DevicesWithSystemCrashes(threshold int, period string) *Template {
-	qb := NewQueryBuilder().
-		FromDevices().
-		With(fmt.Sprintf("device_performance.system_crashes %s", period)).
-		ComputeSum("crash_count", "number_of_system_crashes").
-		WhereGreaterEqual("crash_count", fmt.Sprintf("%d", threshold)).
-		List("device.name", "crash_count").
-		SortDesc("crash_count")
-	
-	return newTemplate(qb)
+	return t.render("DevicesWithSystemCrashes", map[string]any{"threshold": threshold, "period": period})
 }
 
 // BinariesWithHighCrashRate returns binaries with crash counts above threshold
 // threshold: minimum crash count
 func (t *Templates) BinariesWithHighCrashRate(threshold int, period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableExecutionCrashes).
-		During(period).
-		SummarizeCount("total_crashes").
-		Summarize("devices_affected", "device.count()").
-		SummarizeBy(FieldBinaryName).
-		WhereGreaterEqual("total_crashes", fmt.Sprintf("%d", threshold)).
-		SortDesc("total_crashes")
-	
-	return newTemplate(qb)
+	return t.render("BinariesWithHighCrashRate", map[string]any{"threshold": threshold, "period": period})
 }
 
 // =============================================================================
@@ -344,30 +207,36 @@ func (t *Templates) BinariesWithHighCrashRate(threshold int, period string) *Tem
 
 // WorkflowExecutionSuccess returns successful workflow executions
 func (t *Templates) WorkflowExecutionSuccess(period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableWorkflowExecutions).
-		During(period).
-		WhereEquals("status", ExecutionStatusSuccess).
-		SummarizeSum("executions", "number_of_executions").
-		SummarizeBy("workflow.name").
-		SortDesc("executions")
-	
-	return newTemplate(qb)
+	return t.render("WorkflowExecutionSuccess", map[string]any{"period": period})
 }
 
 // RemoteActionSavingsEstimate returns estimated cost savings from remote actions
 // costPerExecution: estimated cost saved per successful execution
 func (t *Templates) RemoteActionSavingsEstimate(costPerExecution int, period string) *Template {
-	qb := NewQueryBuilder().
-		From(TableRemoteActionExecutions).
-		During(period).
-		WhereEquals("status", ExecutionStatusSuccess).
-		WhereEquals("purpose", PurposeRemediation).
-		Summarize("amt_saved", fmt.Sprintf("(number_of_executions.sum()) * (%d)", costPerExecution)).
-		SummarizeBy("remote_action.name").
-		SortDesc("amt_saved")
-	
-	return newTemplate(qb)
+	return t.render("RemoteActionSavingsEstimate", map[string]any{"cost_per_execution": costPerExecution, "period": period})
+}
+
+// render renders a built-in definition for a Templates method
+// Values are not checked against the definition's ranges and time selection
+// rules, so the methods keep accepting every argument they did before the
+// definitions existed. Use DefaultTemplateRegistry().Render for strict checks.
+func (t *Templates) render(name string, params map[string]any) *Template {
+	def, err := builtinTemplate(name)
+	if err == nil {
+		var qb *QueryBuilder
+		if qb, err = def.render(params, false); err == nil {
+			return newTemplate(qb)
+		}
+	}
+	return &Template{qb: NewQueryBuilder(), err: err}
+}
+
+// optionalString returns nil for an empty string, leaving the parameter unset
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
 }
 
 // =============================================================================
@@ -375,6 +244,9 @@ func (t *Templates) RemoteActionSavingsEstimate(costPerExecution int, period str
 // =============================================================================
 
 // GetAllTemplates returns a list of all available template names
+//
+// Deprecated: use DefaultTemplateRegistry().Names() or List(), which also
+// include descriptions, parameters and user-defined templates.
 func (t *Templates) GetAllTemplates() []string {
 	return []string{
 		"DevicesWithCrashes",
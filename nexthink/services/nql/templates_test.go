@@ -1,6 +1,7 @@
 package nql
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -241,3 +242,32 @@ func TestTemplate_Methods(t *testing.T) {
 		t.Errorf("ToRequest() created invalid request: %+v", req)
 	}
 }
+
+func TestTemplates_ArgumentsOutsideDefinitionRules(t *testing.T) {
+	templates := NewTemplates()
+
+	// The methods keep rendering values outside the built-in definition's range
+	tmpl := templates.UsersWithLowDEXScore(150, "during past 7d")
+	if tmpl.Err() != nil {
+		t.Fatalf("Expected no error, got: %v", tmpl.Err())
+	}
+	if !strings.Contains(tmpl.Query(), "user_dex < 150") {
+		t.Errorf("Expected threshold 150 in query, got: %s", tmpl.Query())
+	}
+
+	// and time selections the time_selection parameter type would reject
+	tmpl = templates.DevicesByPlatform("during past 30d by 30s")
+	if tmpl.Err() != nil || !strings.Contains(tmpl.Query(), "during past 30d by 30s") {
+		t.Errorf("Expected the period to be kept as given, got: %v %s", tmpl.Err(), tmpl.Query())
+	}
+
+	// The registry applies the definition's rules
+	if _, err := DefaultTemplateRegistry().Render("UsersWithLowDEXScore", map[string]any{"threshold": 150}); !errors.Is(err, ErrTemplateParameter) {
+		t.Errorf("Expected ErrTemplateParameter from the registry, got: %v", err)
+	}
+
+	tmpl = templates.DEXScoreImpactByComponent("unknown", "during past 7d")
+	if tmpl.Err() != nil || !strings.Contains(tmpl.Query(), "endpoint.logon_speed_score_impact") {
+		t.Errorf("Expected unknown components to fall back to logon_speed, got: %v %s", tmpl.Err(), tmpl.Query())
+	}
+}
@@ -2,6 +2,9 @@ package nql
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,6 +29,9 @@ func (tu TimeUnit) String() string {
 	return string(tu)
 }
 
+// HighResolutionRetention is how far back high-resolution (by 30s) data is kept
+const HighResolutionRetention = 48 * time.Hour
+
 // =============================================================================
 // Time Granularity (for summarize by)
 // =============================================================================
@@ -62,8 +68,20 @@ func (tg TimeGranularity) String() string {
 // =============================================================================
 
 // TimeSelection represents a time selection clause in NQL
+//
+// Absolute times are rendered in their own location unless In sets the time
+// zone in which the Nexthink instance interprets dates. Once set, a time.Time
+// in any other location is converted first, so a day in Sydney is not silently
+// read as the same calendar day in UTC.
 type TimeSelection struct {
-	clause string
+	clause      string
+	loc         *time.Location
+	granularity TimeGranularity
+
+	// start and end are set for selections built from time.Time values
+	// end is exclusive; the rendered "to" is the last second of the window
+	start time.Time
+	end   time.Time
 }
 
 // NewTimeSelection creates a new time selection builder
@@ -71,10 +89,35 @@ func NewTimeSelection() *TimeSelection {
 	return &TimeSelection{}
 }
 
+// In sets the time zone the Nexthink instance interprets dates in
+// Selections built from time.Time values are converted and rendered again in
+// the new zone.
+// Example: In(time.UTC).Between(zurichStart, zurichEnd)
+func (ts *TimeSelection) In(loc *time.Location) *TimeSelection {
+	ts.loc = loc
+	if ts.isAbsolute() {
+		ts.render()
+	}
+	return ts
+}
+
+// Location returns the time zone the selection is interpreted in
+// Without In, this is the location of the start time for selections built
+// from time.Time values and UTC otherwise.
+func (ts *TimeSelection) Location() *time.Location {
+	if ts.loc != nil {
+		return ts.loc
+	}
+	if ts.isAbsolute() {
+		return ts.start.Location()
+	}
+	return time.UTC
+}
+
 // DuringPast creates a "during past" time selection
 // Example: DuringPast(7, Days) -> "during past 7d"
 func (ts *TimeSelection) DuringPast(value int, unit TimeUnit) *TimeSelection {
-	ts.clause = fmt.Sprintf("during past %d%s", value, unit)
+	ts.setClause(fmt.Sprintf("during past %d%s", value, unit))
 	return ts
 }
 
@@ -97,20 +140,48 @@ func (ts *TimeSelection) FromRelative(value int, unit TimeUnit) *TimeSelectionFr
 	}
 }
 
+// Between creates a "from ... to ..." time selection covering [start, end)
+// Both times are rendered in start's location, or converted to the one set with In.
+// Example: Between(start, start.AddDate(0, 0, 7)) -> "from 2024-02-01 00:00:00 to 2024-02-07 23:59:59"
+func (ts *TimeSelection) Between(start, end time.Time) *TimeSelection {
+	ts.start, ts.end = start, end
+	ts.render()
+	return ts
+}
+
 // On creates an "on" time selection for a specific date
 // Example: On("Feb 8, 2024") -> "on Feb 8, 2024"
 func (ts *TimeSelection) On(date string) *TimeSelection {
-	ts.clause = fmt.Sprintf("on %s", date)
+	ts.setClause(fmt.Sprintf("on %s", date))
 	return ts
 }
 
-// OnTime creates an "on" time selection with time.Time
+// OnTime creates a time selection for the calendar day of t in t's location
+// The day renders as "on" unless In sets a location where it is not a whole
+// day, in which case it renders as a from/to range.
 // Example: OnTime(time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC)) -> "on 2024-02-08"
 func (ts *TimeSelection) OnTime(t time.Time) *TimeSelection {
-	ts.clause = fmt.Sprintf("on %s", t.Format("2006-01-02"))
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return ts.Between(start, start.AddDate(0, 0, 1))
+}
+
+// AlignTo sets the summarize time bucket granularity used with this selection
+// Windows built from time.Time values are widened to whole buckets in the
+// selection's location, so the first and last buckets are complete.
+// Example: AlignTo(Granularity1Day)
+func (ts *TimeSelection) AlignTo(granularity TimeGranularity) *TimeSelection {
+	ts.granularity = granularity
+	if ts.isAbsolute() {
+		ts.render()
+	}
 	return ts
 }
 
+// Granularity returns the bucket granularity set with AlignTo
+func (ts *TimeSelection) Granularity() TimeGranularity {
+	return ts.granularity
+}
+
 // ByHighResolution adds high-resolution qualifier for VDI data (30s resolution)
 // Only works with VDI event data for the past 2 days
 // Example: DuringPast(1, Days).ByHighResolution() -> "during past 1d by 30s"
@@ -119,6 +190,16 @@ func (ts *TimeSelection) ByHighResolution() *TimeSelection {
 	return ts
 }
 
+// Window resolves the selection to an absolute window at now
+func (ts *TimeSelection) Window(now time.Time) (TimeWindow, error) {
+	return ResolveTimeSelection(ts.clause, now, ts.Location())
+}
+
+// Validate checks the selection against the retention and granularity rules at now
+func (ts *TimeSelection) Validate(now time.Time) error {
+	return validateTimeSelection(ts.clause, ts.granularity, now, ts.Location())
+}
+
 // String returns the time selection clause
 func (ts *TimeSelection) String() string {
 	return ts.clause
@@ -129,6 +210,36 @@ func (ts *TimeSelection) Build() string {
 	return ts.clause
 }
 
+// setClause sets a clause that is not built from time.Time values
+func (ts *TimeSelection) setClause(clause string) {
+	ts.clause = clause
+	ts.start, ts.end = time.Time{}, time.Time{}
+}
+
+// isAbsolute reports whether the selection was built from time.Time values
+func (ts *TimeSelection) isAbsolute() bool {
+	return !ts.start.IsZero() || !ts.end.IsZero()
+}
+
+// render renders a selection built from time.Time values
+func (ts *TimeSelection) render() {
+	loc := ts.Location()
+	start, end := ts.start.In(loc), ts.end.In(loc)
+	if bucket, err := ParseTimeGranularity(string(ts.granularity)); err == nil {
+		start, end = alignDown(start, bucket), alignUp(end, bucket)
+	}
+
+	highResolution := IsHighResolution(ts.clause)
+	if isMidnight(start) && end.Equal(start.AddDate(0, 0, 1)) {
+		ts.clause = fmt.Sprintf("on %s", FormatDate(start))
+	} else {
+		ts.clause = fmt.Sprintf("from %s to %s", FormatDateTime(start), FormatDateTime(end.Add(-time.Second)))
+	}
+	if highResolution {
+		ts.clause += " by 30s"
+	}
+}
+
 // =============================================================================
 // TimeSelectionFrom (for absolute date ranges)
 // =============================================================================
@@ -141,14 +252,18 @@ type TimeSelectionFrom struct {
 
 // To completes the "from ... to ..." clause
 func (tsf *TimeSelectionFrom) To(date string) *TimeSelection {
-	tsf.ts.clause = fmt.Sprintf("from %s to %s", tsf.fromDate, date)
+	tsf.ts.setClause(fmt.Sprintf("from %s to %s", tsf.fromDate, date))
 	return tsf.ts
 }
 
 // ToTime completes the "from ... to ..." clause with time.Time
+// The time is rendered in its own location, or converted to the one set with In.
 func (tsf *TimeSelectionFrom) ToTime(t time.Time) *TimeSelection {
-	toDate := t.Format("2006-01-02 15:04:05")
-	tsf.ts.clause = fmt.Sprintf("from %s to %s", tsf.fromDate, toDate)
+	toDate := FormatDateTime(t)
+	if tsf.ts.loc != nil {
+		toDate = FormatDateTimeIn(t, tsf.ts.loc)
+	}
+	tsf.ts.setClause(fmt.Sprintf("from %s to %s", tsf.fromDate, toDate))
 	return tsf.ts
 }
 
@@ -165,7 +280,7 @@ type TimeSelectionFromRelative struct {
 
 // ToRelative completes the "from ... ago to ... ago" clause
 func (tsfr *TimeSelectionFromRelative) ToRelative(value int, unit TimeUnit) *TimeSelection {
-	tsfr.ts.clause = fmt.Sprintf("from %d%s ago to %d%s ago", tsfr.fromValue, tsfr.fromUnit, value, unit)
+	tsfr.ts.setClause(fmt.Sprintf("from %d%s ago to %d%s ago", tsfr.fromValue, tsfr.fromUnit, value, unit))
 	return tsfr.ts
 }
 
@@ -231,6 +346,16 @@ func FormatDateTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
 
+// FormatDateTimeIn formats a time.Time for NQL date-time expressions in loc
+// Use it for times in another zone than the Nexthink instance.
+// Example: 2024-02-08 15:30:00 in Europe/Zurich -> 2024-02-08 14:30:00 in UTC
+func FormatDateTimeIn(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return FormatDateTime(t.In(loc))
+}
+
 // FormatDate formats a time.Time for NQL date expressions
 // Example: 2024-02-08
 func FormatDate(t time.Time) string {
@@ -263,12 +388,108 @@ func ParseRelativeTime(d time.Duration) string {
 // =============================================================================
 
 // ValidateTimeSelection validates a time selection string
+// Selections are checked against the high-resolution retention at the current
+// time in UTC. Selections with query parameters, or that this package cannot
+// resolve, are only checked for emptiness; use ValidateTimeSelectionAt to
+// reject those too.
 func ValidateTimeSelection(selection string) error {
 	if selection == "" {
 		return fmt.Errorf("time selection cannot be empty")
 	}
-	
-	// Basic validation - could be expanded
-	// For now, just check that it's not empty
-	return nil
+
+	return validateResolvableTimeSelection(selection, "", time.Now(), time.UTC)
+}
+
+// ValidateTimeSelectionAt resolves a time selection string at now in loc (UTC if nil)
+// Rejects selections that cannot be resolved and high-resolution selections
+// beyond the retention; selections with query parameters are only checked for emptiness.
+func ValidateTimeSelectionAt(selection string, now time.Time, loc *time.Location) error {
+	return ValidateTimeGranularity(selection, "", now, loc)
+}
+
+// ValidateTimeGranularity validates a summarize time bucket granularity against a selection
+// Checks that the granularity is valid, fits in the window and is retained
+// for the window's age at now in loc (UTC if nil).
+func ValidateTimeGranularity(selection string, granularity TimeGranularity, now time.Time, loc *time.Location) error {
+	if selection == "" {
+		return fmt.Errorf("time selection cannot be empty")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return validateTimeSelection(selection, granularity, now, loc)
+}
+
+// =============================================================================
+// Time Selection Analysis
+// =============================================================================
+
+var (
+	// relativeDurationPattern matches a whole NQL relative duration such as "7d", "24h", "15min" or "30s"
+	relativeDurationPattern = regexp.MustCompile(`^(\d+)\s*(min|h|d|s)$`)
+
+	// duringPastPattern matches "during past <duration>"
+	duringPastPattern = regexp.MustCompile(`(?i)during\s+past\s+(\d+\s*(?:min|h|d|s))\b`)
+
+	// fromAgoPattern matches "from <duration> ago"
+	fromAgoPattern = regexp.MustCompile(`(?i)from\s+(\d+\s*(?:min|h|d|s))\s+ago\b`)
+)
+
+// ParseNQLDuration converts an NQL relative duration ("7d", "24h", "15min", "30s") to a time.Duration
+// The value must be a whole number and a unit only; fractions, signs and
+// surrounding text are rejected.
+func ParseNQLDuration(value string) (time.Duration, error) {
+	match := relativeDurationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid NQL duration: %s", value)
+	}
+
+	amount, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid NQL duration: %s", value)
+	}
+
+	switch match[2] {
+	case "s":
+		return time.Duration(amount) * time.Second, nil
+	case "min":
+		return time.Duration(amount) * time.Minute, nil
+	case "h":
+		return time.Duration(amount) * time.Hour, nil
+	default:
+		return time.Duration(amount) * 24 * time.Hour, nil
+	}
+}
+
+// TimeSelectionLookback returns how far back from now a relative time selection reaches
+// Only "during past ..." and "from ... ago to ... ago" selections can be resolved;
+// the boolean result is false for absolute or unrecognised selections.
+func TimeSelectionLookback(selection string) (time.Duration, bool) {
+	if match := duringPastPattern.FindStringSubmatch(selection); match != nil {
+		d, err := ParseNQLDuration(match[1])
+		return d, err == nil
+	}
+
+	if match := fromAgoPattern.FindStringSubmatch(selection); match != nil {
+		d, err := ParseNQLDuration(match[1])
+		return d, err == nil
+	}
+
+	return 0, false
+}
+
+// IsHighResolution checks if a time selection requests high-resolution (by 30s) data
+func IsHighResolution(selection string) bool {
+	return strings.Contains(strings.ToLower(selection), "by 30s")
+}
+
+// hasTimeSelection checks if a clause contains a time selection keyword
+func hasTimeSelection(clause string) bool {
+	lower := " " + strings.ToLower(clause) + " "
+	for _, keyword := range []string{" during past ", " from ", " on "} {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
 }
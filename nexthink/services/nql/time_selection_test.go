@@ -1,6 +1,7 @@
 package nql
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -184,3 +185,268 @@ func TestTimeSelection_Empty(t *testing.T) {
 		t.Errorf("Expected empty string for empty time selection, got: %s", result)
 	}
 }
+
+func TestTimeSelection_BetweenConvertsToLocation(t *testing.T) {
+	zurich := time.FixedZone("CET", 3600)
+	start := time.Date(2024, 2, 1, 0, 0, 0, 0, zurich)
+	end := start.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		expected string
+	}{
+		{"default start location", nil, "from 2024-02-01 00:00:00 to 2024-02-07 23:59:59"},
+		{"instance in UTC", time.UTC, "from 2024-01-31 23:00:00 to 2024-02-07 22:59:59"},
+		{"instance in Zurich", zurich, "from 2024-02-01 00:00:00 to 2024-02-07 23:59:59"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewTimeSelection().In(tt.loc).Between(start, end).Build()
+			if result != tt.expected {
+				t.Errorf("Expected %s, got: %s", tt.expected, result)
+			}
+
+			window, err := NewTimeSelection().In(tt.loc).Between(start, end).Window(end)
+			if err != nil {
+				t.Fatalf("Window failed: %v", err)
+			}
+			if !window.Start.Equal(start) || !window.End.Equal(end) {
+				t.Errorf("Expected window %s/%s, got: %s", start, end, window)
+			}
+		})
+	}
+}
+
+func TestTimeSelection_OnTimeInOtherZone(t *testing.T) {
+	sydney := time.FixedZone("AEDT", 11*3600)
+	day := time.Date(2024, 2, 8, 10, 0, 0, 0, sydney)
+
+	// Without In, the day is rendered in its own location
+	result := NewTimeSelection().OnTime(day).Build()
+	if result != "on 2024-02-08" {
+		t.Errorf("Expected on 2024-02-08, got: %s", result)
+	}
+
+	expected := "from 2024-02-07 13:00:00 to 2024-02-08 12:59:59"
+	result = NewTimeSelection().In(time.UTC).OnTime(day).Build()
+	if result != expected {
+		t.Errorf("Expected %s, got: %s", expected, result)
+	}
+
+	// Re-rendered when the location changes afterwards
+	result = NewTimeSelection().OnTime(day).In(time.UTC).Build()
+	if result != expected {
+		t.Errorf("Expected %s, got: %s", expected, result)
+	}
+}
+
+func TestTimeSelection_AlignTo(t *testing.T) {
+	start := time.Date(2024, 2, 8, 10, 7, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 8, 11, 52, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		granularity TimeGranularity
+		expected    string
+	}{
+		{"15 minutes", Granularity15Min, "from 2024-02-08 10:00:00 to 2024-02-08 11:59:59"},
+		{"1 hour", Granularity1Hour, "from 2024-02-08 10:00:00 to 2024-02-08 11:59:59"},
+		{"1 day", Granularity1Day, "on 2024-02-08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTimeSelection().Between(start, end).AlignTo(tt.granularity)
+			if ts.Build() != tt.expected {
+				t.Errorf("Expected %s, got: %s", tt.expected, ts.Build())
+			}
+			if ts.Granularity() != tt.granularity {
+				t.Errorf("Expected granularity %s, got: %s", tt.granularity, ts.Granularity())
+			}
+			if err := ts.Validate(end); err != nil {
+				t.Errorf("Aligned selection should be valid: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveTimeSelection(t *testing.T) {
+	zurich := time.FixedZone("CET", 3600)
+	now := time.Date(2024, 2, 8, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		selection string
+		loc       *time.Location
+		start     time.Time
+		end       time.Time
+	}{
+		{"during past 24h", nil, now.Add(-24 * time.Hour), now},
+		{"during past 15min by 30s", nil, now.Add(-15 * time.Minute), now},
+		{"during past 7d", nil, time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), now},
+		{"during past 7d", zurich, time.Date(2024, 2, 2, 0, 0, 0, 0, zurich), now},
+		{"from 1d ago to 1d ago", zurich, time.Date(2024, 2, 7, 0, 0, 0, 0, zurich), time.Date(2024, 2, 8, 0, 0, 0, 0, zurich)},
+		{"from 21d ago to 13d ago", nil, time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 27, 0, 0, 0, 0, time.UTC)},
+		{"from 2024-02-01 to 2024-02-07", nil, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC)},
+		{"from 2024-02-01 08:00:00 to 2024-02-01 17:59:59", zurich, time.Date(2024, 2, 1, 8, 0, 0, 0, zurich), time.Date(2024, 2, 1, 18, 0, 0, 0, zurich)},
+		{"on Feb 8, 2024", zurich, time.Date(2024, 2, 8, 0, 0, 0, 0, zurich), time.Date(2024, 2, 9, 0, 0, 0, 0, zurich)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selection, func(t *testing.T) {
+			window, err := ResolveTimeSelection(tt.selection, now, tt.loc)
+			if err != nil {
+				t.Fatalf("ResolveTimeSelection failed: %v", err)
+			}
+			if !window.Start.Equal(tt.start) || !window.End.Equal(tt.end) {
+				t.Errorf("Expected %s/%s, got: %s", tt.start, tt.end, window)
+			}
+		})
+	}
+
+	for _, selection := range []string{"", "past week", "from $window_start to $window_end", "on someday"} {
+		if _, err := ResolveTimeSelection(selection, now, nil); !errors.Is(err, ErrInvalidTimeSelection) {
+			t.Errorf("Expected ErrInvalidTimeSelection for %q, got: %v", selection, err)
+		}
+	}
+}
+
+func TestTimeSelection_Validate(t *testing.T) {
+	now := time.Date(2024, 2, 8, 10, 30, 0, 0, time.UTC)
+	unaligned := NewTimeSelection().Between(now.Add(-2*time.Hour-7*time.Minute), now)
+
+	tests := []struct {
+		name    string
+		ts      *TimeSelection
+		wantErr bool
+	}{
+		{"high resolution within retention", NewTimeSelection().DuringPast(1, Days).ByHighResolution(), false},
+		{"high resolution beyond retention", NewTimeSelection().DuringPast(3, Days).ByHighResolution(), true},
+		{"15 min buckets within retention", NewTimeSelection().DuringPast(7, Days).AlignTo(Granularity15Min), false},
+		{"15 min buckets beyond retention", NewTimeSelection().DuringPast(30, Days).AlignTo(Granularity15Min), true},
+		{"daily buckets within retention", NewTimeSelection().DuringPast(30, Days).AlignTo(Granularity1Day), false},
+		{"daily buckets beyond retention", NewTimeSelection().FromRelative(45, Days).ToRelative(31, Days).AlignTo(Granularity1Day), true},
+		{"bucket longer than window", NewTimeSelection().DuringPast(1, Days).AlignTo(Granularity7Days), true},
+		{"invalid granularity", NewTimeSelection().DuringPast(1, Days).AlignTo("10 min"), true},
+		{"window ends before it starts", NewTimeSelection().FromRelative(1, Days).ToRelative(3, Days), true},
+		{"absolute window not aligned", NewTimeSelection().From("2024-02-08 08:07:00").To("2024-02-08 09:59:59").AlignTo(Granularity15Min), true},
+		{"absolute window aligned by AlignTo", unaligned.AlignTo(Granularity15Min), false},
+		{"query parameters are not resolved", NewTimeSelection().From("$window_start").To("$window_end").AlignTo(Granularity1Day), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ts.Validate(now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTimeSelection) {
+				t.Errorf("Expected ErrInvalidTimeSelection, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTimeSelection(t *testing.T) {
+	now := time.Date(2024, 2, 8, 10, 30, 0, 0, time.UTC)
+
+	if err := ValidateTimeSelection(""); err == nil {
+		t.Error("Expected error for empty selection")
+	}
+	// Selections that cannot be resolved are left to the server
+	for _, selection := range []string{"past week", "from $window_start to $window_end"} {
+		if err := ValidateTimeSelection(selection); err != nil {
+			t.Errorf("Expected %q to pass, got: %v", selection, err)
+		}
+	}
+	if err := ValidateTimeSelectionAt("past week", now, nil); !errors.Is(err, ErrInvalidTimeSelection) {
+		t.Errorf("Expected ErrInvalidTimeSelection, got: %v", err)
+	}
+
+	// High-resolution retention is checked at the current time
+	if err := ValidateTimeSelection("during past 30d by 30s"); !errors.Is(err, ErrInvalidTimeSelection) {
+		t.Errorf("Expected ErrInvalidTimeSelection, got: %v", err)
+	}
+	if err := ValidateTimeSelection("during past 1d by 30s"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := ValidateTimeSelectionAt("during past 1d by 30s", now, nil); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if err := ValidateTimeGranularity("during past 30d", Granularity15Min, now, nil); !errors.Is(err, ErrInvalidTimeSelection) {
+		t.Errorf("Expected ErrInvalidTimeSelection, got: %v", err)
+	}
+}
+
+func TestSummarizeByTime_StartTimeColumn(t *testing.T) {
+	for _, granularity := range []TimeGranularity{Granularity15Min, Granularity1Hour, Granularity1Day} {
+		query := NewQueryBuilder().
+			From(TableExecutionCrashes).
+			DuringPast(7, Days).
+			Summarize("crashes", "count()").
+			SummarizeByTime(granularity).
+			Build()
+
+		types := InferQueryColumnTypes(query)
+		if types["start_time"] != ColumnTypeDateTime {
+			t.Errorf("Expected start_time column for %q buckets, got: %v", granularity, types)
+		}
+	}
+}
+
+func TestParseTimeGranularity(t *testing.T) {
+	tests := []struct {
+		granularity string
+		expected    time.Duration
+		wantErr     bool
+	}{
+		{Granularity15Min, 15 * time.Minute, false},
+		{Granularity45Min, 45 * time.Minute, false},
+		{Granularity12Hours, 12 * time.Hour, false},
+		{Granularity7Days, 7 * 24 * time.Hour, false},
+		{"1d", 24 * time.Hour, false},
+		{"10 min", 0, true},
+		{"0 h", 0, true},
+		{"1 week", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granularity, func(t *testing.T) {
+			d, err := ParseTimeGranularity(tt.granularity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeGranularity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d != tt.expected {
+				t.Errorf("Expected %v, got: %v", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestFormatDateTimeIn(t *testing.T) {
+	dt := time.Date(2024, 2, 8, 15, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	result := FormatDateTimeIn(dt, time.UTC)
+	expected := "2024-02-08 14:30:00"
+
+	if result != expected {
+		t.Errorf("Expected %s, got: %s", expected, result)
+	}
+}
+
+func TestTimeSelectionFrom_ToTimeInOtherZone(t *testing.T) {
+	zurich := time.FixedZone("CET", 3600)
+	end := time.Date(2024, 2, 8, 10, 0, 0, 0, zurich)
+
+	result := NewTimeSelection().From("2024-02-01").ToTime(end).Build()
+	if result != "from 2024-02-01 to 2024-02-08 10:00:00" {
+		t.Errorf("Expected time rendered in its own location, got: %s", result)
+	}
+
+	result = NewTimeSelection().In(time.UTC).From("2024-02-01").ToTime(end).Build()
+	if result != "from 2024-02-01 to 2024-02-08 09:00:00" {
+		t.Errorf("Expected time converted to UTC, got: %s", result)
+	}
+}
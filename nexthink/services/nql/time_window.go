@@ -0,0 +1,324 @@
+package nql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time windows
+// Resolves time selections to absolute windows in the time zone of the
+// Nexthink instance and checks them against data retention and bucket rules.
+
+// ErrInvalidTimeSelection is returned for time selections that cannot be resolved or break retention rules
+var ErrInvalidTimeSelection = errors.New("invalid time selection")
+
+const (
+	// FineGranularityRetention is how far back sub-day buckets (15 min to 12 h) of sampled event data are kept
+	FineGranularityRetention = 8 * 24 * time.Hour
+
+	// DailyGranularityRetention is how far back daily buckets of sampled event data are kept
+	DailyGranularityRetention = 30 * 24 * time.Hour
+)
+
+// =============================================================================
+// Time Window
+// =============================================================================
+
+// TimeWindow is the absolute window a time selection covers
+type TimeWindow struct {
+	// Start is the first instant of the window, in the instance time zone
+	Start time.Time
+
+	// End is the first instant after the window, in the instance time zone
+	End time.Time
+}
+
+// Duration returns the length of the window
+func (w TimeWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// Contains reports whether t falls in the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// String returns the window as an ISO 8601 interval
+// Example: 2024-02-01T00:00:00+01:00/2024-02-08T00:00:00+01:00
+func (w TimeWindow) String() string {
+	return w.Start.Format(time.RFC3339) + "/" + w.End.Format(time.RFC3339)
+}
+
+// =============================================================================
+// Resolution
+// =============================================================================
+
+var (
+	// highResolutionSuffixPattern matches the trailing "by 30s" of a selection
+	highResolutionSuffixPattern = regexp.MustCompile(`(?i)\s+by\s+30s$`)
+
+	// windowDuringPastPattern matches a whole "during past <n><unit>" selection
+	windowDuringPastPattern = regexp.MustCompile(`(?i)^during\s+past\s+(\d+)\s*(min|h|d)$`)
+
+	// windowFromAgoPattern matches a whole "from <n><unit> ago to <n><unit> ago" selection
+	windowFromAgoPattern = regexp.MustCompile(`(?i)^from\s+(\d+)\s*(min|h|d)\s+ago\s+to\s+(\d+)\s*(min|h|d)\s+ago$`)
+
+	// windowFromToPattern matches a whole "from <date> to <date>" selection
+	windowFromToPattern = regexp.MustCompile(`(?i)^from\s+(.+?)\s+to\s+(.+)$`)
+
+	// windowOnPattern matches a whole "on <date>" selection
+	windowOnPattern = regexp.MustCompile(`(?i)^on\s+(.+)$`)
+
+	// granularityPattern matches a bucket granularity such as "15 min", "1 h" or "1d"
+	granularityPattern = regexp.MustCompile(`^(\d+)\s*(min|h|d)$`)
+)
+
+// windowDateLayouts are the absolute date formats of time selections,
+// with the precision each of them denotes
+var windowDateLayouts = []struct {
+	layout    string
+	precision func(time.Time) time.Time
+}{
+	{"2006-01-02 15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02 15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"Jan 2, 2006", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+}
+
+// ResolveTimeSelection resolves a time selection to an absolute window at now
+//
+// loc is the time zone the Nexthink instance interprets dates in (UTC if nil).
+// Relative selections in days cover whole days in loc: "during past 7d" starts
+// at midnight six days before now, and "from 1d ago to 1d ago" is yesterday.
+// Selections in minutes and hours are counted back from now. Absolute dates
+// and date-times are inclusive, so "on 2024-02-08" ends at midnight the next day.
+func ResolveTimeSelection(selection string, now time.Time, loc *time.Location) (TimeWindow, error) {
+	window, _, err := resolveTimeSelection(selection, now, loc)
+	return window, err
+}
+
+// resolveTimeSelection resolves a selection and reports whether it is absolute
+func resolveTimeSelection(selection string, now time.Time, loc *time.Location) (TimeWindow, bool, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	s := highResolutionSuffixPattern.ReplaceAllString(strings.TrimSpace(selection), "")
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidTimeSelection, selection, fmt.Sprintf(format, args...))
+	}
+
+	if strings.Contains(s, "$") {
+		return TimeWindow{}, false, invalid("query parameters cannot be resolved")
+	}
+
+	if match := windowDuringPastPattern.FindStringSubmatch(s); match != nil {
+		start, err := relativeInstant(match[1], match[2], now, 1)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		return TimeWindow{Start: start, End: now}, false, nil
+	}
+
+	if match := windowFromAgoPattern.FindStringSubmatch(s); match != nil {
+		start, err := relativeInstant(match[1], match[2], now, 0)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		end, err := relativeInstant(match[3], match[4], now, 1)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		return TimeWindow{Start: start, End: end}, false, nil
+	}
+
+	if match := windowFromToPattern.FindStringSubmatch(s); match != nil {
+		start, _, err := parseWindowDate(match[1], loc)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		_, end, err := parseWindowDate(match[2], loc)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		return TimeWindow{Start: start, End: end}, true, nil
+	}
+
+	if match := windowOnPattern.FindStringSubmatch(s); match != nil {
+		start, _, err := parseWindowDate(match[1], loc)
+		if err != nil {
+			return TimeWindow{}, false, invalid("%v", err)
+		}
+		day := startOfDay(start)
+		return TimeWindow{Start: day, End: day.AddDate(0, 0, 1)}, true, nil
+	}
+
+	return TimeWindow{}, false, invalid("unrecognised time selection")
+}
+
+// relativeInstant resolves "<amount><unit> ago" at now
+// Day offsets are whole days in now's location, shifted by shift days: a
+// shift of 1 resolves "1d" to the end of yesterday instead of its start.
+func relativeInstant(amount, unit string, now time.Time, shift int) (time.Time, error) {
+	n, err := strconv.Atoi(amount)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid amount %s", amount)
+	}
+
+	switch strings.ToLower(unit) {
+	case "min":
+		return now.Add(-time.Duration(n) * time.Minute), nil
+	case "h":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	default:
+		return startOfDay(now).AddDate(0, 0, shift-n), nil
+	}
+}
+
+// parseWindowDate parses an absolute date of a selection in loc
+// Returns the first instant it denotes and the first instant after it.
+func parseWindowDate(value string, loc *time.Location) (time.Time, time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range windowDateLayouts {
+		if t, err := time.ParseInLocation(layout.layout, value, loc); err == nil {
+			return t, layout.precision(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// =============================================================================
+// Granularity
+// =============================================================================
+
+// ParseTimeGranularity converts a bucket granularity ("15 min", "2 h", "1 d") to a time.Duration
+// Minute granularities must be multiples of 15; hours and days must be whole numbers.
+func ParseTimeGranularity(granularity string) (time.Duration, error) {
+	match := granularityPattern.FindStringSubmatch(strings.TrimSpace(granularity))
+	if match == nil {
+		return 0, fmt.Errorf("invalid time granularity: %q", granularity)
+	}
+
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid time granularity: %q", granularity)
+	}
+
+	switch match[2] {
+	case "min":
+		if n%15 != 0 {
+			return 0, fmt.Errorf("invalid time granularity: %q (minutes must be a multiple of 15)", granularity)
+		}
+		return time.Duration(n) * time.Minute, nil
+	case "h":
+		return time.Duration(n) * time.Hour, nil
+	default:
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+}
+
+// alignDown returns the start of the bucket containing t
+// Buckets start at midnight in t's location; day buckets align to midnight.
+func alignDown(t time.Time, bucket time.Duration) time.Time {
+	midnight := startOfDay(t)
+	if bucket >= 24*time.Hour {
+		return midnight
+	}
+	return midnight.Add(t.Sub(midnight) / bucket * bucket)
+}
+
+// alignUp returns t when it starts a bucket, and the start of the next bucket otherwise
+func alignUp(t time.Time, bucket time.Duration) time.Time {
+	down := alignDown(t, bucket)
+	switch {
+	case down.Equal(t):
+		return t
+	case bucket >= 24*time.Hour:
+		return down.AddDate(0, 0, 1)
+	default:
+		return down.Add(bucket)
+	}
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// isMidnight reports whether t is midnight in its location
+func isMidnight(t time.Time) bool {
+	return t.Equal(startOfDay(t))
+}
+
+// =============================================================================
+// Validation
+// =============================================================================
+
+// validateResolvableTimeSelection checks a selection like validateTimeSelection
+// but lets selections that cannot be resolved pass
+func validateResolvableTimeSelection(selection string, granularity TimeGranularity, now time.Time, loc *time.Location) error {
+	if _, _, err := resolveTimeSelection(selection, now, loc); err != nil {
+		return nil
+	}
+	return validateTimeSelection(selection, granularity, now, loc)
+}
+
+// validateTimeSelection checks a selection against retention and granularity rules at now
+// Selections with query parameters are not resolved and pass.
+func validateTimeSelection(selection string, granularity TimeGranularity, now time.Time, loc *time.Location) error {
+	if strings.Contains(selection, "$") {
+		return nil
+	}
+
+	window, absolute, err := resolveTimeSelection(selection, now, loc)
+	if err != nil {
+		return err
+	}
+
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidTimeSelection, selection, fmt.Sprintf(format, args...))
+	}
+
+	if !window.End.After(window.Start) {
+		return invalid("the window ends before it starts")
+	}
+
+	age := now.Sub(window.Start)
+	if IsHighResolution(selection) && age > HighResolutionRetention {
+		return invalid("high-resolution data is only retained for %v but the time selection reaches back %v",
+			HighResolutionRetention, age.Round(time.Minute))
+	}
+
+	if granularity == "" {
+		return nil
+	}
+
+	bucket, err := ParseTimeGranularity(string(granularity))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTimeSelection, err)
+	}
+	if bucket > window.Duration() {
+		return invalid("%s buckets are longer than the %v window", granularity, window.Duration())
+	}
+
+	retention := FineGranularityRetention
+	if bucket >= 24*time.Hour {
+		retention = DailyGranularityRetention
+	}
+	if age > retention {
+		return invalid("%s buckets are only retained for %v but the time selection reaches back %v",
+			granularity, retention, age.Round(time.Minute))
+	}
+
+	if absolute && (!alignDown(window.Start, bucket).Equal(window.Start) || !alignUp(window.End, bucket).Equal(window.End)) {
+		return invalid("the window %s is not aligned to %s buckets, so its first or last bucket is partial (use AlignTo)",
+			window, granularity)
+	}
+
+	return nil
+}
@@ -0,0 +1,416 @@
+package nql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value coercion helpers for NQL result data
+// Converts the loosely typed values returned by the API (numbers, formatted
+// strings, V1 DateTime objects) into Go types
+
+// =============================================================================
+// Numbers and Booleans
+// =============================================================================
+
+// coerceInt64 converts a result value to int64
+func coerceInt64(val any) (int64, error) {
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float32:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := v.Float64()
+		return int64(f), err
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("cannot convert %q to int64", v)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to int64", val)
+	}
+}
+
+// coerceFloat64 converts a result value to float64
+func coerceFloat64(val any) (float64, error) {
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case time.Duration:
+		return v.Seconds(), nil
+	case time.Time:
+		return float64(v.UnixNano()) / float64(time.Second), nil
+	case string:
+		s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "%"))
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %q to float64", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float64", val)
+	}
+}
+
+// jsonScalarValue converts a CSV cell to the value JSON decoding would produce
+// Empty cells become nil, numbers float64 and true/false bool; anything else stays a string.
+func jsonScalarValue(raw string) any {
+	switch raw {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return raw
+}
+
+// coerceBool converts a result value to bool
+func coerceBool(val any) (bool, error) {
+	switch v := val.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("cannot convert %q to bool", v)
+		}
+		return b, nil
+	case float64:
+		return v != 0, nil
+	case int:
+		return v != 0, nil
+	case int64:
+		return v != 0, nil
+	default:
+		return false, fmt.Errorf("cannot convert %T to bool", val)
+	}
+}
+
+// coerceString converts a result value to its string representation
+func coerceString(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *DateTime:
+		if v == nil {
+			return ""
+		}
+		return dateTimeToTime(v, time.UTC).Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// =============================================================================
+// Durations
+// =============================================================================
+
+// durationComponentPattern matches one "<number> <unit>" component of a formatted duration
+var durationComponentPattern = regexp.MustCompile(`(?i)(-?\d+(?:\.\d+)?)\s*(ns|us|µs|ms|s|sec|secs|second|seconds|min|mins|minute|minutes|h|hr|hrs|hour|hours|d|day|days|w|week|weeks)\b`)
+
+// durationUnits maps duration unit spellings to their length
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond, "us": time.Microsecond, "µs": time.Microsecond, "ms": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseDurationValue parses an NQL duration value
+//
+// Accepts formatted strings ("12.3 s", "150 ms", "2 h 5 min", "3d"), Go duration
+// strings ("1h30m") and plain numbers. Plain numbers are interpreted in the given
+// unit (seconds when unit is zero).
+func parseDurationValue(val any, unit time.Duration) (time.Duration, error) {
+	if unit <= 0 {
+		unit = time.Second
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case time.Duration:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(unit)), nil
+		}
+		if d, ok := parseFormattedDuration(s); ok {
+			return d, nil
+		}
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		return 0, fmt.Errorf("cannot convert %q to duration", v)
+	default:
+		f, err := coerceFloat64(val)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %T to duration", val)
+		}
+		return time.Duration(f * float64(unit)), nil
+	}
+}
+
+// parseFormattedDuration parses a string made up entirely of "<number> <unit>" components
+func parseFormattedDuration(s string) (time.Duration, bool) {
+	matches := durationComponentPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+	pos := 0
+	for _, m := range matches {
+		if strings.TrimSpace(s[pos:m[0]]) != "" {
+			return 0, false
+		}
+		amount, err := strconv.ParseFloat(s[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, false
+		}
+		total += time.Duration(amount * float64(durationUnits[strings.ToLower(s[m[4]:m[5]])]))
+		pos = m[1]
+	}
+
+	if strings.TrimSpace(s[pos:]) != "" {
+		return 0, false
+	}
+
+	return total, true
+}
+
+// =============================================================================
+// Byte Sizes
+// =============================================================================
+
+// byteSizePattern matches a formatted byte size such as "1.5 GB" or "512KiB"
+var byteSizePattern = regexp.MustCompile(`(?i)^\s*(-?\d+(?:\.\d+)?)\s*(b|bytes?|kb|kib|mb|mib|gb|gib|tb|tib|pb|pib)?\s*$`)
+
+// byteSizeUnits maps byte size units to their multiplier (binary multiples,
+// matching ExportResult.SizeFormatted)
+var byteSizeUnits = map[string]float64{
+	"":      1,
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"kb":    1 << 10,
+	"kib":   1 << 10,
+	"mb":    1 << 20,
+	"mib":   1 << 20,
+	"gb":    1 << 30,
+	"gib":   1 << 30,
+	"tb":    1 << 40,
+	"tib":   1 << 40,
+	"pb":    1 << 50,
+	"pib":   1 << 50,
+}
+
+// parseByteSizeValue parses an NQL byte size value
+// Accepts plain numbers (bytes) and formatted strings such as "1.5 GB" or "512 KB"
+func parseByteSizeValue(val any) (int64, error) {
+	s, ok := val.(string)
+	if !ok {
+		return coerceInt64(val)
+	}
+
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+
+	match := byteSizePattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("cannot convert %q to byte size", s)
+	}
+
+	amount, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %q to byte size", s)
+	}
+
+	return int64(math.Round(amount * byteSizeUnits[strings.ToLower(match[2])])), nil
+}
+
+// =============================================================================
+// Date and Time
+// =============================================================================
+
+// timeLayouts are the datetime layouts accepted for string values
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTimeValue parses an NQL datetime value
+//
+// Accepts ISO 8601 strings, V1 DateTime objects (as *DateTime, DateTime or the
+// decoded JSON map) and Unix timestamps in seconds. Values without an explicit
+// offset are interpreted in loc (UTC when loc is nil).
+func parseTimeValue(val any, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case *DateTime:
+		if v == nil {
+			return time.Time{}, nil
+		}
+		return dateTimeToTime(v, loc), nil
+	case DateTime:
+		return dateTimeToTime(&v, loc), nil
+	case map[string]any:
+		dt, err := dateTimeFromMap(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return dateTimeToTime(dt, loc), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return time.Time{}, nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot convert %q to time", v)
+	default:
+		secs, err := coerceFloat64(val)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot convert %T to time", val)
+		}
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)).In(loc), nil
+	}
+}
+
+// dateTimeFromMap converts a decoded V1 DateTime JSON object to a DateTime
+func dateTimeFromMap(m map[string]any) (*DateTime, error) {
+	dt := &DateTime{}
+	fields := map[string]*int64{
+		"year":   &dt.Year,
+		"month":  &dt.Month,
+		"day":    &dt.Day,
+		"hour":   &dt.Hour,
+		"minute": &dt.Minute,
+		"second": &dt.Second,
+	}
+
+	if _, ok := m["year"]; !ok {
+		return nil, fmt.Errorf("object is not a DateTime: missing year")
+	}
+
+	for key, target := range fields {
+		if raw, ok := m[key]; ok {
+			n, err := coerceInt64(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DateTime %s: %w", key, err)
+			}
+			*target = n
+		}
+	}
+
+	return dt, nil
+}
+
+// Time converts the DateTime to a time.Time in loc
+// The API returns no zone, so the fields are interpreted in loc (UTC when nil).
+func (dt *DateTime) Time(loc *time.Location) time.Time {
+	return dateTimeToTime(dt, loc)
+}
+
+// dateTimeToTime converts a DateTime to a time.Time in the given location
+// The API does not include a zone, so the fields are interpreted in loc (UTC when nil)
+func dateTimeToTime(dt *DateTime, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(
+		int(dt.Year), time.Month(dt.Month), int(dt.Day),
+		int(dt.Hour), int(dt.Minute), int(dt.Second),
+		0, loc,
+	)
+}